@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/ical"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// CalendarToken is a newly issued calendar feed token
+type CalendarToken struct {
+	Token string `json:"token"`
+	URL   string `json:"url"` // the player's own feed
+}
+
+// feedHistory is how far back calendar feeds include past events.
+const feedHistory = 90 * 24 * time.Hour
+
+// eventUID is the stable UID of an event in every feed it appears in.
+func eventUID(eventID int) string {
+	return "event-" + strconv.Itoa(eventID) + "@vivacity"
+}
+
+// rsvpSummary describes an event's RSVPs, ending with userID's own answer,
+// which it also returns.
+func rsvpSummary(e store.Event, rsvps []store.RSVP, userID int) (string, store.RSVPStatus) {
+	counts := map[store.RSVPStatus]int{}
+	var own store.RSVPStatus
+	for _, r := range rsvps {
+		counts[r.Status]++
+		if r.UserID == userID {
+			own = r.Status
+		}
+	}
+
+	var lines []string
+	if e.Opponent != "" {
+		lines = append(lines, "Opponent: "+e.Opponent)
+	}
+	lines = append(lines, fmt.Sprintf("RSVPs: %d yes, %d maybe, %d no", counts[store.RSVPYes], counts[store.RSVPMaybe], counts[store.RSVPNo]))
+	if own == "" {
+		lines = append(lines, "Your RSVP: none yet")
+	} else {
+		lines = append(lines, "Your RSVP: "+string(own))
+	}
+	return strings.Join(lines, "\n"), own
+}
+
+// teamCalendar converts a team's events into calendar entries, with the
+// RSVP of userID. prefix goes in front of every title.
+func teamCalendar(ctx context.Context, s *store.Store, teamID, userID int, prefix string, now time.Time) ([]ical.Event, error) {
+	events, err := s.Events.ListEvents(ctx, teamID, now.Add(-feedHistory), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ical.Event
+	for _, e := range events {
+		rsvps, err := s.Events.ListRSVPs(ctx, e.ID)
+		if err != nil {
+			return nil, err
+		}
+		description, own := rsvpSummary(e, rsvps, userID)
+		entry := ical.Event{
+			UID:         eventUID(e.ID),
+			Start:       e.Start,
+			End:         e.End,
+			Summary:     prefix + e.Title,
+			Location:    e.Location,
+			Description: description,
+			Cancelled:   e.Cancelled,
+			Transparent: own == store.RSVPNo,
+		}
+
+		rule, loc, err := e.Rule()
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", e.ID, err)
+		}
+		if loc == nil {
+			entries = append(entries, entry)
+			continue
+		}
+		entry.Zone = loc
+		entry.RRule = rule.UTCUntil(loc).String()
+		entries = append(entries, entry)
+
+		exceptions, err := s.Events.ListEventExceptions(ctx, e.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range exceptions {
+			override := entry
+			override.RRule = ""
+			override.RecurrenceID = x.OriginalStart
+			override.Start, override.End = x.Start, x.End
+			override.Summary = prefix + x.Title
+			override.Location = x.Location
+			override.Cancelled = e.Cancelled || x.Cancelled
+			entries = append(entries, override)
+		}
+	}
+	return entries, nil
+}
+
+func writeCalendar(w http.ResponseWriter, r *http.Request, cal ical.Calendar) {
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		log.Printf("Error encoding calendar: %v", err)
+		response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(buf.Bytes())
+}
+
+// TeamCalendarHandler serves a team's events as an iCalendar feed. The
+// caller is identified by their feed token.
+func TeamCalendarHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		team, err := s.Teams.GetTeam(r.Context(), teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+
+		now := time.Now()
+		entries, err := teamCalendar(r.Context(), s, teamID, caller.UserID, "", now)
+		if err != nil {
+			log.Printf("Error building calendar for team %d: %v", teamID, err)
+			response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCalendar(w, r, ical.Calendar{Name: team.Name, Now: now, Events: entries})
+	}
+}
+
+// PlayerCalendarHandler serves the events of every team the player belongs
+// to as one iCalendar feed. Feeds are personal: the feed token must belong
+// to the {user_id} player.
+func PlayerCalendarHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		if caller.UserID != userID {
+			response.Error(w, r, "Forbidden: feed token belongs to another player", http.StatusForbidden)
+			return
+		}
+
+		now := time.Now()
+		var entries []ical.Event
+		for _, m := range caller.Memberships {
+			team, err := s.Teams.GetTeam(r.Context(), m.TeamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			teamEntries, err := teamCalendar(r.Context(), s, m.TeamID, caller.UserID, "["+team.Name+"] ", now)
+			if err != nil {
+				log.Printf("Error building calendar for team %d: %v", m.TeamID, err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			entries = append(entries, teamEntries...)
+		}
+		writeCalendar(w, r, ical.Calendar{Name: "Vivacity: " + caller.Battletag, Now: now, Events: entries})
+	}
+}
+
+// CalendarTokenHandler issues (POST) or revokes (DELETE) the player's
+// calendar feed token. Issuing a token revokes the previous one, and the
+// token itself is only shown once.
+func CalendarTokenHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		switch r.Method {
+		case http.MethodPost:
+			token, hash, err := middleware.NewToken()
+			if err != nil {
+				log.Printf("Error generating feed token: %v", err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if err := s.FeedTokens.SetFeedToken(r.Context(), userID, hash); err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			response.JSON(w, http.StatusCreated, CalendarToken{
+				Token: token,
+				URL:   "/api/players/" + strconv.Itoa(userID) + "/calendar.ics?token=" + token,
+			})
+
+		case http.MethodDelete:
+			if err := s.FeedTokens.DeleteFeedToken(r.Context(), userID); err != nil {
+				response.StoreError(w, r, err, "No calendar token to revoke")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestCalendarFeeds(t *testing.T) {
+	s, h, alpha, members := newTeam(t, "coach", "player")
+	coach, player := members[0], members[1]
+	ctx := context.Background()
+	beta, _ := s.Teams.CreateTeam(ctx, "Beta")
+	s.Memberships.AddMember(ctx, store.Member{TeamID: beta.ID, UserID: coach.ID, Role: "coach"})
+
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	scrim := api.EventRequest{Type: "scrim", Opponent: "Team Bravo", StartTime: start, EndTime: start.Add(2 * time.Hour)}
+	rr := do(t, h, http.MethodPost, "/teams/"+strconv.Itoa(alpha.ID)+"/events", scrim, coach.ID)
+	var created api.Event
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	do(t, h, http.MethodPut, "/teams/"+strconv.Itoa(alpha.ID)+"/events/"+strconv.Itoa(created.ID)+"/rsvp", map[string]string{"status": "yes"}, coach.ID)
+	practice := api.EventRequest{Type: "practice", StartTime: start, EndTime: start.Add(time.Hour), Recurrence: "FREQ=WEEKLY;UNTIL=20991231", Timezone: "Europe/Berlin"}
+	if rr := do(t, h, http.MethodPost, "/teams/"+strconv.Itoa(beta.ID)+"/events", practice, coach.ID); rr.Code != http.StatusCreated {
+		t.Fatalf("POST recurring event returned %v: %s", rr.Code, rr.Body)
+	}
+
+	// Feeds ignore the session and need a token
+	feed := "/players/" + strconv.Itoa(coach.ID) + "/calendar.ics"
+	if rr := do(t, h, http.MethodGet, feed, nil, coach.ID); rr.Code != http.StatusUnauthorized {
+		t.Errorf("feed without a token returned %v, want 401", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPost, "/players/"+strconv.Itoa(coach.ID)+"/calendar-token", nil, player.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("token for another player returned %v, want 403", rr.Code)
+	}
+	rr = do(t, h, http.MethodPost, "/players/"+strconv.Itoa(coach.ID)+"/calendar-token", nil, coach.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST calendar-token returned %v: %s", rr.Code, rr.Body)
+	}
+	var token api.CalendarToken
+	if err := json.NewDecoder(rr.Body).Decode(&token); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = do(t, h, http.MethodGet, feed+"?token="+token.Token, nil, 0)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("player feed returned %v %q: %s", rr.Code, rr.Header().Get("Content-Type"), rr.Body)
+	}
+	body := strings.ReplaceAll(rr.Body.String(), "\r\n ", "") // unfold long lines
+	for _, want := range []string{
+		"UID:event-" + strconv.Itoa(created.ID) + "@vivacity",
+		"SUMMARY:[Alpha] Scrim vs Team Bravo",
+		"Your RSVP: yes",
+		"SUMMARY:[Beta] Practice",
+		"RRULE:FREQ=WEEKLY;UNTIL=20991231T225959Z",
+		"TZID:Europe/Berlin",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("player feed is missing %q", want)
+		}
+	}
+
+	teamFeed := "/teams/" + strconv.Itoa(alpha.ID) + "/calendar.ics?token=" + token.Token
+	if rr := do(t, h, http.MethodGet, teamFeed, nil, 0); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "SUMMARY:Scrim vs Team Bravo") {
+		t.Errorf("team feed returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodGet, "/players/"+strconv.Itoa(player.ID)+"/calendar.ics?token="+token.Token, nil, 0); rr.Code != http.StatusForbidden {
+		t.Errorf("another player's feed returned %v, want 403", rr.Code)
+	}
+
+	// The player's own token can't read a team they aren't on
+	rr = do(t, h, http.MethodPost, "/players/"+strconv.Itoa(player.ID)+"/calendar-token", nil, player.ID)
+	var playerToken api.CalendarToken
+	json.NewDecoder(rr.Body).Decode(&playerToken)
+	if rr := do(t, h, http.MethodGet, "/teams/"+strconv.Itoa(beta.ID)+"/calendar.ics?token="+playerToken.Token, nil, 0); rr.Code != http.StatusForbidden {
+		t.Errorf("non-member team feed returned %v, want 403", rr.Code)
+	}
+
+	// Reissuing revokes the old token, and so does DELETE
+	rr = do(t, h, http.MethodPost, "/players/"+strconv.Itoa(coach.ID)+"/calendar-token", nil, coach.ID)
+	var reissued api.CalendarToken
+	json.NewDecoder(rr.Body).Decode(&reissued)
+	if rr := do(t, h, http.MethodGet, feed+"?token="+token.Token, nil, 0); rr.Code != http.StatusUnauthorized {
+		t.Errorf("old token returned %v, want 401", rr.Code)
+	}
+	if rr := do(t, h, http.MethodDelete, "/players/"+strconv.Itoa(coach.ID)+"/calendar-token", nil, coach.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE calendar-token returned %v", rr.Code)
+	}
+	if rr := do(t, h, http.MethodGet, feed+"?token="+reissued.Token, nil, 0); rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token returned %v, want 401", rr.Code)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/notify"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// DiscordSettings configures a team's Discord notifications
+type DiscordSettings struct {
+	WebhookURL      string `json:"webhook_url"`
+	ReminderMinutes *int   `json:"reminder_minutes"` // default 60, 0 turns reminders off
+	Quorum          int    `json:"quorum"`           // yes RSVPs an event needs, 0 turns the check off
+	QuorumMinutes   *int   `json:"quorum_minutes"`   // default 1440
+}
+
+// maxNoticeMinutes caps how far ahead reminders and quorum checks can run.
+const maxNoticeMinutes = 7 * 24 * 60
+
+// maskWebhook hides the secret token at the end of a webhook URL.
+func maskWebhook(u string) string {
+	if i := strings.LastIndex(u, "/"); i >= 0 && i < len(u)-1 {
+		return u[:i+1] + "…"
+	}
+	return u
+}
+
+// DiscordSettingsHandler reads, replaces and removes a team's Discord
+// webhook. The webhook token is never shown again after it is set.
+func DiscordSettingsHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			set, err := s.Notifications.GetDiscordSettings(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Discord notifications are not set up")
+				return
+			}
+			response.JSON(w, http.StatusOK, DiscordSettings{
+				WebhookURL:      maskWebhook(set.WebhookURL),
+				ReminderMinutes: &set.ReminderMinutes,
+				Quorum:          set.Quorum,
+				QuorumMinutes:   &set.QuorumMinutes,
+			})
+
+		case http.MethodPut:
+			var req DiscordSettings
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			set := store.DiscordSettings{
+				TeamID:          teamID,
+				WebhookURL:      strings.TrimSpace(req.WebhookURL),
+				ReminderMinutes: 60,
+				Quorum:          req.Quorum,
+				QuorumMinutes:   24 * 60,
+			}
+			if req.ReminderMinutes != nil {
+				set.ReminderMinutes = *req.ReminderMinutes
+			}
+			if req.QuorumMinutes != nil {
+				set.QuorumMinutes = *req.QuorumMinutes
+			}
+			if !notify.ValidWebhookURL(set.WebhookURL) {
+				response.Error(w, r, "webhook_url must be a Discord webhook URL (https://discord.com/api/webhooks/...)", http.StatusBadRequest)
+				return
+			}
+			if set.ReminderMinutes < 0 || set.ReminderMinutes > maxNoticeMinutes || set.QuorumMinutes < 0 || set.QuorumMinutes > maxNoticeMinutes {
+				response.Error(w, r, "reminder_minutes and quorum_minutes must be between 0 and 10080", http.StatusBadRequest)
+				return
+			}
+			if set.Quorum < 0 {
+				response.Error(w, r, "quorum must not be negative", http.StatusBadRequest)
+				return
+			}
+			if err := s.Notifications.SetDiscordSettings(r.Context(), set); err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			// Queue reminders with the new lead time; ones queued with the old
+			// one are skipped when they come due
+			if err := notify.ScheduleReminders(r.Context(), s, teamID, time.Now()); err != nil {
+				log.Printf("Error queueing reminders for team %d: %v", teamID, err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			if err := s.Notifications.DeleteDiscordSettings(r.Context(), teamID); err != nil {
+				response.StoreError(w, r, err, "Discord notifications are not set up")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// DiscordLink is the Discord account linked to a player
+type DiscordLink struct {
+	DiscordUserID   string    `json:"discord_user_id"`
+	DiscordUsername string    `json:"discord_username"`
+	LinkedAt        time.Time `json:"linked_at"`
+}
+
+// DiscordLinkCode is a one-time code for the bot's /link command
+type DiscordLinkCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// linkCodeLifetime is how long a link code can be redeemed for.
+const linkCodeLifetime = 15 * time.Minute
+
+// DiscordLinkHandler shows (GET) or removes (DELETE) the player's linked
+// Discord account, or issues a one-time code (POST) to link one by running
+// /link in Discord. A new code replaces any earlier unused one.
+func DiscordLinkHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			link, err := s.DiscordLinks.GetDiscordLink(r.Context(), userID)
+			if err != nil {
+				response.StoreError(w, r, err, "No Discord account linked")
+				return
+			}
+			response.JSON(w, http.StatusOK, DiscordLink{
+				DiscordUserID:   link.DiscordUserID,
+				DiscordUsername: link.DiscordUsername,
+				LinkedAt:        link.LinkedAt,
+			})
+
+		case http.MethodPost:
+			code, hash, err := discord.NewLinkCode()
+			if err != nil {
+				log.Printf("Error generating Discord link code: %v", err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			expires := time.Now().Add(linkCodeLifetime).UTC().Truncate(time.Second)
+			if err := s.DiscordLinks.SetDiscordLinkCode(r.Context(), userID, hash, expires); err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			response.JSON(w, http.StatusCreated, DiscordLinkCode{Code: code, ExpiresAt: expires})
+
+		case http.MethodDelete:
+			if err := s.DiscordLinks.DeleteDiscordLink(r.Context(), userID); err != nil {
+				response.StoreError(w, r, err, "No Discord account linked")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/schedule"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+const (
+	// maxInteractionBody caps the size of an interaction request.
+	maxInteractionBody = 64 << 10
+	// botScheduleDays is how far ahead /schedule looks.
+	botScheduleDays = 7
+	// botEventDays is how far ahead /rsvp suggests events.
+	botEventDays = 30
+)
+
+// replyError is a problem the Discord user can fix. Its message is shown to
+// them as the reply; any other error is logged and answered generically.
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+
+const errNotLinked = replyError("Your Discord account isn't linked to Vivacity yet. Generate a code on your profile page, then run `/link`.")
+
+// DiscordInteractionsHandler answers the bot's slash commands. Discord signs
+// every request with the application's key, and requests that don't verify
+// or are too old are rejected with 401 as Discord's endpoint check expects.
+func DiscordInteractionsHandler(s *store.Store, publicKey ed25519.PublicKey) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxInteractionBody))
+		if err != nil {
+			response.Error(w, r, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if !discord.Verify(publicKey, r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp"), body, time.Now()) {
+			response.Error(w, r, "Invalid request signature", http.StatusUnauthorized)
+			return
+		}
+		var in discord.Interaction
+		if err := json.Unmarshal(body, &in); err != nil {
+			response.Error(w, r, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		switch in.Type {
+		case discord.InteractionPing:
+			response.JSON(w, http.StatusOK, discord.Response{Type: discord.ResponsePong})
+		case discord.InteractionCommand:
+			response.JSON(w, http.StatusOK, botCommand(r.Context(), s, in, time.Now()))
+		case discord.InteractionAutocomplete:
+			response.JSON(w, http.StatusOK, botAutocomplete(r.Context(), s, in, time.Now()))
+		default:
+			response.Error(w, r, "Unsupported interaction type", http.StatusBadRequest)
+		}
+	}
+}
+
+// botCommand runs a slash command. Replies are only shown to the caller,
+// except the schedule and best time, which are useful to the whole channel.
+func botCommand(ctx context.Context, s *store.Store, in discord.Interaction, now time.Time) discord.Response {
+	caller := in.Caller()
+	if caller == nil || in.Data == nil {
+		return discord.Reply("This command can't be used here.", true)
+	}
+
+	var content string
+	var err error
+	ephemeral := true
+	if in.Data.Name == "link" {
+		content, err = botLink(ctx, s, caller, in.Data, now)
+	} else {
+		var p *middleware.Principal
+		if p, err = botPrincipal(ctx, s, caller.ID); err == nil {
+			switch in.Data.Name {
+			case "availability":
+				content, err = botAvailability(ctx, s, p, in.Data, now)
+			case "schedule":
+				content, err = botSchedule(ctx, s, p, in.Data, now)
+				ephemeral = false
+			case "rsvp":
+				content, err = botRSVP(ctx, s, p, in.Data)
+			case "bestslot":
+				content, err = botBestSlot(ctx, s, p, in.Data, now)
+				ephemeral = false
+			default:
+				err = replyError("Unknown command.")
+			}
+		}
+	}
+
+	var re replyError
+	switch {
+	case errors.As(err, &re):
+		return discord.Reply(re.Error(), true)
+	case err != nil:
+		log.Printf("Error running Discord command /%s for %s: %v", in.Data.Name, caller.ID, err)
+		return discord.Reply("Something went wrong. Please try again later.", true)
+	}
+	return discord.Reply(content, ephemeral)
+}
+
+// botPrincipal loads the player linked to a Discord account.
+func botPrincipal(ctx context.Context, s *store.Store, discordUserID string) (*middleware.Principal, error) {
+	userID, err := s.DiscordLinks.DiscordLinkUser(ctx, discordUserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := middleware.LoadPrincipal(ctx, s, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errNotLinked
+	}
+	return p, err
+}
+
+// botTeams returns the teams the caller can pick: their own, or every team
+// for an admin.
+func botTeams(ctx context.Context, s *store.Store, p *middleware.Principal) ([]store.Team, error) {
+	if p.IsAdmin {
+		return s.Teams.ListTeams(ctx)
+	}
+	var teams []store.Team
+	for _, m := range p.Memberships {
+		t, err := s.Teams.GetTeam(ctx, m.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, nil
+}
+
+// botTeam picks the team a command is about: the team option, by ID or
+// name, else the caller's only team.
+func botTeam(ctx context.Context, s *store.Store, p *middleware.Principal, data *discord.CommandData) (store.Team, error) {
+	opt, given := data.Option("team")
+	if !given && len(p.Memberships) == 1 {
+		return s.Teams.GetTeam(ctx, p.Memberships[0].TeamID)
+	}
+	if !given && len(p.Memberships) == 0 {
+		return store.Team{}, replyError("You aren't on a team yet.")
+	}
+
+	teams, err := botTeams(ctx, s, p)
+	if err != nil {
+		return store.Team{}, err
+	}
+	if given {
+		want := opt.String()
+		for _, t := range teams {
+			if strconv.Itoa(t.ID) == want || strings.EqualFold(t.Name, want) {
+				return t, nil
+			}
+		}
+		return store.Team{}, replyError(fmt.Sprintf("You aren't on a team called %q.", want))
+	}
+	names := make([]string, 0, len(teams))
+	for _, t := range teams {
+		names = append(names, t.Name)
+	}
+	return store.Team{}, replyError("You're on more than one team. Pick one with the `team` option: " + strings.Join(names, ", ") + ".")
+}
+
+// botWeek returns the start of the week named by the week option, in the
+// grid's timezone.
+func botWeek(grid store.Grid, data *discord.CommandData, now time.Time) (time.Time, error) {
+	loc, err := store.LoadTimezone(grid.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	weekStart := store.WeekStart(now, loc)
+	if opt, ok := data.Option("week"); ok && opt.String() == "next" {
+		weekStart = weekStart.AddDate(0, 0, 7)
+	}
+	return weekStart, nil
+}
+
+// botZone is the caller's profile timezone, or UTC.
+func botZone(p *middleware.Principal) *time.Location {
+	if loc, err := store.LoadTimezone(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// botLink redeems a link code from the caller's profile page.
+func botLink(ctx context.Context, s *store.Store, caller *discord.User, data *discord.CommandData, now time.Time) (string, error) {
+	opt, _ := data.Option("code")
+	link := store.DiscordLink{DiscordUserID: caller.ID, DiscordUsername: caller.Username}
+	link, err := s.DiscordLinks.RedeemDiscordLinkCode(ctx, discord.HashLinkCode(opt.String()), link, now)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", replyError("That code is invalid or has expired. Generate a new one on your profile page.")
+	}
+	if err != nil {
+		return "", err
+	}
+	player, err := s.Players.GetPlayer(ctx, link.UserID)
+	if err != nil {
+		return "", err
+	}
+	return "Linked! You're now using Vivacity as **" + player.Username + "**.", nil
+}
+
+// botAvailability lists the slots the caller has marked for a week, in
+// their own timezone.
+func botAvailability(ctx context.Context, s *store.Store, p *middleware.Principal, data *discord.CommandData, now time.Time) (string, error) {
+	team, err := botTeam(ctx, s, p, data)
+	if err != nil {
+		return "", err
+	}
+	grid, err := s.Grids.GetGrid(ctx, team.ID)
+	if err != nil {
+		return "", err
+	}
+	weekStart, err := botWeek(grid, data, now)
+	if err != nil {
+		return "", err
+	}
+	slots, err := teamSlots(ctx, s, team.ID)
+	if err != nil {
+		return "", err
+	}
+	available, err := s.Availability.ListAvailability(ctx, team.ID, p.UserID, weekStart, weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return "", err
+	}
+	loc := botZone(p)
+	shown, err := weekSlots(grid, slots, weekStart, loc, available)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Your availability for **%s**, week of %s (%s):\n", team.Name, weekStart.In(loc).Format("Jan 2"), loc)
+	day, count := "", 0
+	for _, slot := range shown {
+		if !slot.Available {
+			continue
+		}
+		if slot.Day != day {
+			day = slot.Day
+			fmt.Fprintf(&b, "\n**%s**:", day)
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(" " + slot.Time)
+		count++
+	}
+	if count == 0 {
+		return fmt.Sprintf("You haven't marked any availability for **%s** that week. Set it on the scheduling page.", team.Name), nil
+	}
+	return b.String(), nil
+}
+
+// botSchedule lists the team's events for the next week with RSVP counts.
+func botSchedule(ctx context.Context, s *store.Store, p *middleware.Principal, data *discord.CommandData, now time.Time) (string, error) {
+	team, err := botTeam(ctx, s, p, data)
+	if err != nil {
+		return "", err
+	}
+	occurrences, err := store.ListOccurrences(ctx, s.Events, team.ID, now, now.AddDate(0, 0, botScheduleDays))
+	if err != nil {
+		return "", err
+	}
+	if len(occurrences) == 0 {
+		return fmt.Sprintf("Nothing is scheduled for **%s** in the next %d days.", team.Name, botScheduleDays), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** — next %d days:\n", team.Name, botScheduleDays)
+	rsvps := map[int][]store.RSVP{}
+	for _, o := range occurrences {
+		if _, ok := rsvps[o.ID]; !ok {
+			if rsvps[o.ID], err = s.Events.ListRSVPs(ctx, o.ID); err != nil {
+				return "", err
+			}
+		}
+		line := fmt.Sprintf("`#%d` **%s** — %s (%s)", o.ID, o.Title, discord.Timestamp(o.Start, "F"), discord.Timestamp(o.Start, "R"))
+		if o.Cancelled {
+			b.WriteString("~~" + line + "~~ cancelled\n")
+			continue
+		}
+		counts := map[store.RSVPStatus]int{}
+		for _, r := range rsvps[o.ID] {
+			counts[r.Status]++
+		}
+		fmt.Fprintf(&b, "%s · %d going, %d maybe, %d not going\n", line, counts[store.RSVPYes], counts[store.RSVPMaybe], counts[store.RSVPNo])
+	}
+	b.WriteString("\nAnswer with `/rsvp`.")
+	return b.String(), nil
+}
+
+// botRSVP records the caller's answer to an event.
+func botRSVP(ctx context.Context, s *store.Store, p *middleware.Principal, data *discord.CommandData) (string, error) {
+	opt, _ := data.Option("event")
+	eventID, err := opt.Int()
+	if err != nil {
+		return "", replyError("Pick an event from the list.")
+	}
+	e, err := s.Events.GetEvent(ctx, eventID)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", replyError("That event doesn't exist.")
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, ok := p.Membership(e.TeamID); !ok {
+		return "", replyError("That event isn't on one of your teams.")
+	}
+	if e.Cancelled {
+		return "", replyError("That event has been cancelled.")
+	}
+	statusOpt, _ := data.Option("status")
+	status := store.RSVPStatus(strings.ToLower(statusOpt.String()))
+	if !status.Valid() {
+		return "", replyError("Answer yes, no or maybe.")
+	}
+	if err := s.Events.SetRSVP(ctx, store.RSVP{EventID: e.ID, UserID: p.UserID, Status: status}); err != nil {
+		return "", err
+	}
+
+	answer := map[store.RSVPStatus]string{
+		store.RSVPYes:   "You're going to",
+		store.RSVPMaybe: "You might make",
+		store.RSVPNo:    "You're not going to",
+	}[status]
+	when := discord.Timestamp(e.Start, "F")
+	if e.Recurrence != "" {
+		when = "every occurrence of the series"
+	}
+	return fmt.Sprintf("%s **%s** (%s).", answer, e.Title, when), nil
+}
+
+// botBestSlot suggests the best window to meet that hasn't started yet.
+func botBestSlot(ctx context.Context, s *store.Store, p *middleware.Principal, data *discord.CommandData, now time.Time) (string, error) {
+	team, err := botTeam(ctx, s, p, data)
+	if err != nil {
+		return "", err
+	}
+	grid, err := s.Grids.GetGrid(ctx, team.ID)
+	if err != nil {
+		return "", err
+	}
+	weekStart, err := botWeek(grid, data, now)
+	if err != nil {
+		return "", err
+	}
+	opts := schedule.Options{MinDuration: time.Duration(grid.SlotMinutes) * time.Minute, Limit: maxSummaryWindows}
+	sum, members, err := summarizeWeek(ctx, s, team.ID, grid, weekStart, opts)
+	if err != nil {
+		return "", err
+	}
+
+	for _, win := range sum.Windows {
+		if win.Start.Before(now) {
+			continue
+		}
+		reply := fmt.Sprintf("Best time for **%s**: %s – %s (%s), %d of %d available.",
+			team.Name, discord.Timestamp(win.Start, "F"), discord.Timestamp(win.End, "t"), discord.FormatDuration(win.Duration()), len(win.Attendees), len(members))
+		if len(win.Missing) > 0 {
+			names := make([]string, 0, len(win.Missing))
+			for _, m := range win.Missing {
+				names = append(names, m.Username)
+			}
+			reply += "\nMissing: " + strings.Join(names, ", ")
+		}
+		return reply, nil
+	}
+	return fmt.Sprintf("No time that week works for anyone on **%s** yet. Ask the team to mark their availability.", team.Name), nil
+}
+
+// botAutocomplete suggests teams and upcoming events as the caller types.
+// Unlinked callers get no suggestions.
+func botAutocomplete(ctx context.Context, s *store.Store, in discord.Interaction, now time.Time) discord.Response {
+	caller := in.Caller()
+	focused, ok := in.Data.Focused()
+	if caller == nil || !ok {
+		return discord.Suggest(nil)
+	}
+	p, err := botPrincipal(ctx, s, caller.ID)
+	if err != nil {
+		if !errors.Is(err, errNotLinked) {
+			log.Printf("Error loading Discord user %s: %v", caller.ID, err)
+		}
+		return discord.Suggest(nil)
+	}
+	typed := strings.ToLower(focused.String())
+
+	var choices []discord.Choice
+	switch focused.Name {
+	case "team":
+		teams, err := botTeams(ctx, s, p)
+		if err != nil {
+			log.Printf("Error listing teams for Discord user %s: %v", caller.ID, err)
+			break
+		}
+		for _, t := range teams {
+			if strings.Contains(strings.ToLower(t.Name), typed) {
+				choices = append(choices, discord.Choice{Name: t.Name, Value: strconv.Itoa(t.ID)})
+			}
+		}
+
+	case "event":
+		// One choice per event, at its next occurrence, across the caller's
+		// teams
+		loc := botZone(p)
+		seen := map[int]bool{}
+		var upcoming []store.Occurrence
+		for _, m := range p.Memberships {
+			occurrences, err := store.ListOccurrences(ctx, s.Events, m.TeamID, now, now.AddDate(0, 0, botEventDays))
+			if err != nil {
+				log.Printf("Error listing events for Discord user %s: %v", caller.ID, err)
+				return discord.Suggest(nil)
+			}
+			upcoming = append(upcoming, occurrences...)
+		}
+		slices.SortFunc(upcoming, func(a, b store.Occurrence) int { return a.Start.Compare(b.Start) })
+		for _, o := range upcoming {
+			if seen[o.ID] || o.Cancelled || !strings.Contains(strings.ToLower(o.Title), typed) {
+				continue
+			}
+			seen[o.ID] = true
+			name := o.Start.In(loc).Format("Mon Jan 2 15:04") + " · " + o.Title
+			choices = append(choices, discord.Choice{Name: name, Value: o.ID})
+		}
+	}
+	return discord.Suggest(choices)
+}
@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestDiscordBot(t *testing.T) {
+	s, _, team, members := newTeam(t, "coach", "player")
+	coach, player := members[0], members[1]
+	ctx := context.Background()
+	other, _ := s.Teams.CreateTeam(ctx, "Beta")
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := api.NewRouter(api.Options{Store: s, Authenticate: testAuth(s), DiscordPublicKey: pub})
+
+	// interact sends a signed interaction from the Discord user "42"
+	interact := func(typ discord.InteractionType, name string, options map[string]any, focused string) discord.Response {
+		t.Helper()
+		in := map[string]any{"id": "1", "type": typ, "member": map[string]any{"user": map[string]string{"id": "42", "username": "johnny"}}}
+		if name != "" {
+			var opts []map[string]any
+			for k, v := range options {
+				opts = append(opts, map[string]any{"name": k, "value": v, "focused": k == focused})
+			}
+			in["data"] = map[string]any{"name": name, "options": opts}
+		}
+		body, _ := json.Marshal(in)
+		req := httptest.NewRequest(http.MethodPost, "/discord/interactions", bytes.NewReader(body))
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Signature-Timestamp", ts)
+		req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(priv, append([]byte(ts), body...))))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("/%s returned %v: %s", name, rr.Code, rr.Body)
+		}
+		var resp discord.Response
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := interact(discord.InteractionPing, "", nil, ""); resp.Type != discord.ResponsePong {
+		t.Errorf("PING answered with %+v", resp)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(`{"type":1}`))
+	req.Header.Set("X-Signature-Timestamp", "1700000000")
+	req.Header.Set("X-Signature-Ed25519", strings.Repeat("00", ed25519.SignatureSize))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature returned %v, want 401", rr.Code)
+	}
+	// A correctly signed but stale request is a replay
+	req = httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(`{"type":1}`))
+	req.Header.Set("X-Signature-Timestamp", "1700000000")
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(priv, []byte(`1700000000{"type":1}`))))
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("stale signature returned %v, want 401", rr.Code)
+	}
+
+	// Commands need a linked account
+	if resp := interact(discord.InteractionCommand, "schedule", nil, ""); !strings.Contains(resp.Data.Content, "isn't linked") || resp.Data.Flags != discord.FlagEphemeral {
+		t.Errorf("unlinked /schedule replied %+v", resp.Data)
+	}
+	linkPath := "/players/" + strconv.Itoa(player.ID) + "/discord-link"
+	if rr := do(t, h, http.MethodPost, linkPath, nil, coach.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("code for another player returned %v, want 403", rr.Code)
+	}
+	rr = do(t, h, http.MethodPost, linkPath, nil, player.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST discord-link returned %v: %s", rr.Code, rr.Body)
+	}
+	var code api.DiscordLinkCode
+	if err := json.NewDecoder(rr.Body).Decode(&code); err != nil {
+		t.Fatal(err)
+	}
+	if resp := interact(discord.InteractionCommand, "link", map[string]any{"code": "WRONG-CODE"}, ""); !strings.Contains(resp.Data.Content, "invalid or has expired") {
+		t.Errorf("wrong code replied %q", resp.Data.Content)
+	}
+	typed := strings.ToLower(strings.ReplaceAll(code.Code, "-", ""))
+	if resp := interact(discord.InteractionCommand, "link", map[string]any{"code": typed}, ""); !strings.Contains(resp.Data.Content, player.Username) {
+		t.Fatalf("/link replied %q", resp.Data.Content)
+	}
+	if resp := interact(discord.InteractionCommand, "link", map[string]any{"code": typed}, ""); !strings.Contains(resp.Data.Content, "invalid or has expired") {
+		t.Errorf("reused code replied %q", resp.Data.Content)
+	}
+	rr = do(t, h, http.MethodGet, linkPath, nil, player.ID)
+	var link api.DiscordLink
+	if err := json.NewDecoder(rr.Body).Decode(&link); err != nil || link.DiscordUserID != "42" || link.DiscordUsername != "johnny" {
+		t.Errorf("GET discord-link returned %v %+v", rr.Code, link)
+	}
+
+	// /schedule and /rsvp
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	e, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventScrim, Title: "Scrim vs Bravo", Start: start, End: start.Add(2 * time.Hour)})
+	resp := interact(discord.InteractionCommand, "schedule", nil, "")
+	if !strings.Contains(resp.Data.Content, "**Scrim vs Bravo** — <t:"+strconv.FormatInt(start.Unix(), 10)+":F>") || resp.Data.Flags != 0 {
+		t.Errorf("/schedule replied %+v", resp.Data)
+	}
+	resp = interact(discord.InteractionAutocomplete, "rsvp", map[string]any{"event": "scr", "status": "yes"}, "event")
+	if resp.Type != discord.ResponseAutocomplete || len(resp.Data.Choices) != 1 || resp.Data.Choices[0].Value != float64(e.ID) {
+		t.Errorf("event autocomplete returned %+v", resp.Data)
+	}
+	resp = interact(discord.InteractionCommand, "rsvp", map[string]any{"event": e.ID, "status": "yes"}, "")
+	if !strings.HasPrefix(resp.Data.Content, "You're going to **Scrim vs Bravo**") {
+		t.Errorf("/rsvp replied %q", resp.Data.Content)
+	}
+	rsvps, _ := s.Events.ListRSVPs(ctx, e.ID)
+	if len(rsvps) != 1 || rsvps[0].UserID != player.ID || rsvps[0].Status != store.RSVPYes {
+		t.Errorf("unexpected RSVPs: %+v", rsvps)
+	}
+	beta, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: other.ID, Type: store.EventPractice, Title: "Practice", Start: start, End: start.Add(time.Hour)})
+	if resp := interact(discord.InteractionCommand, "rsvp", map[string]any{"event": beta.ID, "status": "yes"}, ""); !strings.Contains(resp.Data.Content, "isn't on one of your teams") {
+		t.Errorf("/rsvp to another team replied %q", resp.Data.Content)
+	}
+
+	// /availability and /bestslot for next week
+	nextWeek := store.WeekStart(time.Now(), time.UTC).AddDate(0, 0, 7).Format("2006-01-02")
+	availability := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Tuesday", Time: "19:00"}, {Day: "Tuesday", Time: "21:00"}}}
+	for _, id := range []int{player.ID, coach.ID} {
+		if rr := do(t, h, http.MethodPost, "/teams/"+strconv.Itoa(team.ID)+"/availability?week="+nextWeek, availability, id); rr.Code != http.StatusOK {
+			t.Fatalf("POST availability returned %v: %s", rr.Code, rr.Body)
+		}
+	}
+	resp = interact(discord.InteractionCommand, "availability", map[string]any{"week": "next"}, "")
+	if !strings.Contains(resp.Data.Content, "**Tuesday**: 19:00, 21:00") {
+		t.Errorf("/availability replied %q", resp.Data.Content)
+	}
+	resp = interact(discord.InteractionCommand, "bestslot", map[string]any{"week": "next", "team": "alpha"}, "")
+	if !strings.Contains(resp.Data.Content, "(4h), 2 of 2 available") {
+		t.Errorf("/bestslot replied %q", resp.Data.Content)
+	}
+
+	if rr := do(t, h, http.MethodDelete, linkPath, nil, player.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE discord-link returned %v", rr.Code)
+	}
+	if resp := interact(discord.InteractionCommand, "schedule", nil, ""); !strings.Contains(resp.Data.Content, "isn't linked") {
+		t.Errorf("/schedule after unlinking replied %q", resp.Data.Content)
+	}
+}
@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func TestDiscordSettings(t *testing.T) {
+	s, h, team, members := newTeam(t, "captain", "coach")
+	captain, coach := members[0], members[1]
+	ctx := context.Background()
+	path := "/teams/" + strconv.Itoa(team.ID) + "/discord"
+
+	if rr := do(t, h, http.MethodGet, path, nil, coach.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("GET before setup returned %v, want 404", rr.Code)
+	}
+	webhook := "https://discord.com/api/webhooks/123/secret-token"
+	if rr := do(t, h, http.MethodPut, path, map[string]any{"webhook_url": webhook}, coach.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("coach PUT returned %v, want 403", rr.Code)
+	}
+	for _, bad := range []map[string]any{
+		{"webhook_url": "https://example.com/api/webhooks/123/x"},
+		{"webhook_url": "http://discord.com/api/webhooks/123/x"},
+		{"webhook_url": webhook, "reminder_minutes": -5},
+		{"webhook_url": webhook, "quorum_minutes": 20000},
+	} {
+		if rr := do(t, h, http.MethodPut, path, bad, captain.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("PUT %v returned %v, want 400", bad, rr.Code)
+		}
+	}
+	if rr := do(t, h, http.MethodPut, path, map[string]any{"webhook_url": webhook, "quorum": 5}, captain.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("PUT returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr := do(t, h, http.MethodGet, path, nil, coach.ID)
+	var got api.DiscordSettings
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.WebhookURL != "https://discord.com/api/webhooks/123/…" || *got.ReminderMinutes != 60 || got.Quorum != 5 || *got.QuorumMinutes != 1440 {
+		t.Errorf("unexpected settings: %+v", got)
+	}
+
+	// Creating an event queues an announcement
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	body := api.EventRequest{Type: "practice", StartTime: start, EndTime: start.Add(time.Hour)}
+	if rr := do(t, h, http.MethodPost, "/teams/"+strconv.Itoa(team.ID)+"/events", body, coach.ID); rr.Code != http.StatusCreated {
+		t.Fatalf("POST event returned %v: %s", rr.Code, rr.Body)
+	}
+	queued, err := s.Notifications.ClaimOutbox(ctx, time.Now(), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || !strings.Contains(string(queued[0].Payload), "New practice") {
+		t.Errorf("unexpected outbox: %+v", queued)
+	}
+
+	if rr := do(t, h, http.MethodDelete, path, nil, captain.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned %v", rr.Code)
+	}
+	if rr := do(t, h, http.MethodGet, path, nil, coach.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE returned %v, want 404", rr.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// EmailPreferences is where a player gets email and which emails they want
+type EmailPreferences struct {
+	Email              string `json:"email"`
+	Reminders          bool   `json:"reminders"`
+	ReminderMinutes    *int   `json:"reminder_minutes"` // default 60
+	Digest             bool   `json:"digest"`
+	AvailabilityAlerts bool   `json:"availability_alerts"`
+}
+
+// EmailPreferencesHandler reads, replaces and removes the player's email
+// preferences. Every kind of email is off unless turned on here.
+func EmailPreferencesHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			p, err := s.Email.GetEmailPreferences(r.Context(), userID)
+			if err != nil {
+				response.StoreError(w, r, err, "Email notifications are not set up")
+				return
+			}
+			response.JSON(w, http.StatusOK, EmailPreferences{
+				Email:              p.Email,
+				Reminders:          p.Reminders,
+				ReminderMinutes:    &p.ReminderMinutes,
+				Digest:             p.Digest,
+				AvailabilityAlerts: p.AvailabilityAlerts,
+			})
+
+		case http.MethodPut:
+			var req EmailPreferences
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			p := store.EmailPreferences{
+				UserID:             userID,
+				Reminders:          req.Reminders,
+				ReminderMinutes:    60,
+				Digest:             req.Digest,
+				AvailabilityAlerts: req.AvailabilityAlerts,
+			}
+			addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+			if err != nil || addr.Name != "" {
+				response.Error(w, r, "email must be an email address", http.StatusBadRequest)
+				return
+			}
+			p.Email = addr.Address
+			if req.ReminderMinutes != nil {
+				p.ReminderMinutes = *req.ReminderMinutes
+			}
+			if p.ReminderMinutes < 1 || p.ReminderMinutes > maxNoticeMinutes {
+				response.Error(w, r, "reminder_minutes must be between 1 and "+strconv.Itoa(maxNoticeMinutes), http.StatusBadRequest)
+				return
+			}
+			if err := s.Email.SetEmailPreferences(r.Context(), p); err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			if err := s.Email.DeleteEmailPreferences(r.Context(), userID); err != nil {
+				response.StoreError(w, r, err, "Email notifications are not set up")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestEmailPreferences(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 1001, "John#1234")
+	other, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Jane#1234")
+	h := newRouter(s)
+	path := "/players/" + strconv.Itoa(player.ID) + "/email-preferences"
+
+	if rr := do(t, h, http.MethodGet, path, nil, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("GET before setup returned %v, want 404", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPut, path, map[string]any{"email": "john@example.com"}, other.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("PUT by another player returned %v, want 403", rr.Code)
+	}
+	for _, bad := range []map[string]any{
+		{"email": "not an address"},
+		{"email": "John <john@example.com>"},
+		{"email": "john@example.com", "reminder_minutes": 0},
+	} {
+		if rr := do(t, h, http.MethodPut, path, bad, player.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("PUT %v returned %v, want 400", bad, rr.Code)
+		}
+	}
+	if rr := do(t, h, http.MethodPut, path, map[string]any{"email": " john@example.com ", "digest": true}, player.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("PUT returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr := do(t, h, http.MethodGet, path, nil, player.ID)
+	var got api.EmailPreferences
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != "john@example.com" || !got.Digest || got.Reminders || *got.ReminderMinutes != 60 {
+		t.Errorf("unexpected preferences: %+v", got)
+	}
+
+	if rr := do(t, h, http.MethodDelete, path, nil, player.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned %v", rr.Code)
+	}
+	if prefs, _ := s.Email.ListEmailPreferences(ctx); len(prefs) != 0 {
+		t.Errorf("preferences remain after DELETE: %+v", prefs)
+	}
+}
@@ -0,0 +1,668 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/notify"
+	"github.com/KhrisKringle/Vivacity_website-main/server/recur"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Event is a dated team activity, or one occurrence of a recurring one
+type Event struct {
+	ID            int        `json:"event_id"`
+	TeamID        int        `json:"team_id"`
+	Type          string     `json:"type"`
+	Title         string     `json:"title"`
+	StartTime     time.Time  `json:"start_time"`
+	EndTime       time.Time  `json:"end_time"`
+	Opponent      string     `json:"opponent,omitempty"`
+	Location      string     `json:"location,omitempty"`
+	Recurrence    string     `json:"recurrence,omitempty"`
+	Timezone      string     `json:"timezone,omitempty"`
+	OriginalStart *time.Time `json:"original_start,omitempty"`
+	Overridden    bool       `json:"overridden,omitempty"`
+	Cancelled     bool       `json:"cancelled"`
+	RSVPs         []RSVP     `json:"rsvps,omitempty"`
+	Exceptions    []Event    `json:"exceptions,omitempty"`
+}
+
+// RSVP is one member's answer to an event
+type RSVP struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
+
+// RSVPRequest answers an event: yes, no or maybe.
+type RSVPRequest struct {
+	Status string `json:"status"`
+}
+
+// EventRequest creates or updates an event
+type EventRequest struct {
+	Type       string    `json:"type"`
+	Title      string    `json:"title"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	Opponent   string    `json:"opponent"`
+	Location   string    `json:"location"`
+	Recurrence string    `json:"recurrence"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=TU,TH
+	Timezone   string    `json:"timezone"`   // zone the rule repeats in
+}
+
+// OccurrenceRequest overrides or cancels one occurrence of a recurring
+// event. Omitted fields keep the series' values.
+type OccurrenceRequest struct {
+	OriginalStart time.Time `json:"original_start"`
+	Cancelled     bool      `json:"cancelled"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	Title         string    `json:"title"`
+	Opponent      string    `json:"opponent"`
+	Location      string    `json:"location"`
+}
+
+// Validate checks the lengths of the free-text fields and the timezone.
+func (req EventRequest) Validate() []response.FieldError {
+	var f response.Fields
+	f.MaxLength("title", req.Title, maxNameLength)
+	f.MaxLength("opponent", req.Opponent, maxNameLength)
+	f.MaxLength("location", req.Location, maxNameLength)
+	f.Timezone("timezone", req.Timezone)
+	return f
+}
+
+// Validate checks that the occurrence is named and the lengths of the
+// free-text fields.
+func (req OccurrenceRequest) Validate() []response.FieldError {
+	var f response.Fields
+	if req.OriginalStart.IsZero() {
+		f.Add("original_start", "is required")
+	}
+	f.MaxLength("title", req.Title, maxNameLength)
+	f.MaxLength("opponent", req.Opponent, maxNameLength)
+	f.MaxLength("location", req.Location, maxNameLength)
+	return f
+}
+
+// maxEventRange caps how far apart ?from= and ?to= may be when listing
+// events, since recurring events are expanded into every occurrence.
+const maxEventRange = 366 * 24 * time.Hour
+
+// defaultEventRange is how far apart ?from= and ?to= are when only one of
+// them is given.
+const defaultEventRange = 90 * 24 * time.Hour
+
+func newEvent(e store.Event, loc *time.Location) Event {
+	return Event{
+		ID:         e.ID,
+		TeamID:     e.TeamID,
+		Type:       string(e.Type),
+		Title:      e.Title,
+		StartTime:  e.Start.In(loc),
+		EndTime:    e.End.In(loc),
+		Opponent:   e.Opponent,
+		Location:   e.Location,
+		Recurrence: e.Recurrence,
+		Timezone:   e.Timezone,
+		Cancelled:  e.Cancelled,
+	}
+}
+
+func newOccurrence(o store.Occurrence, loc *time.Location) Event {
+	resp := newEvent(o.Event, loc)
+	if o.Recurrence != "" {
+		original := o.OriginalStart.In(loc)
+		resp.OriginalStart = &original
+		resp.Overridden = o.Overridden
+	}
+	return resp
+}
+
+func newRSVPs(rsvps []store.RSVP) []RSVP {
+	resp := make([]RSVP, 0, len(rsvps))
+	for _, r := range rsvps {
+		resp = append(resp, RSVP{UserID: r.UserID, Username: r.Username, Status: string(r.Status)})
+	}
+	return resp
+}
+
+// event validates the request and returns the event it describes. The title
+// defaults to the type, plus the opponent when there is one, and a recurring
+// event repeats in zone unless the request names a timezone.
+func (req EventRequest) event(teamID int, zone string) (store.Event, error) {
+	e := store.Event{
+		TeamID:   teamID,
+		Type:     store.EventType(strings.ToLower(req.Type)),
+		Title:    strings.TrimSpace(req.Title),
+		Start:    req.StartTime,
+		End:      req.EndTime,
+		Opponent: strings.TrimSpace(req.Opponent),
+		Location: strings.TrimSpace(req.Location),
+	}
+	if !e.Type.Valid() {
+		return e, errors.New("type must be scrim, match, practice or meeting")
+	}
+	if e.Start.IsZero() || e.End.IsZero() {
+		return e, errors.New("start_time and end_time are required")
+	}
+	if !e.End.After(e.Start) {
+		return e, errors.New("end_time must be after start_time")
+	}
+	if e.Title == "" {
+		e.Title = strings.ToUpper(string(e.Type[:1])) + string(e.Type[1:])
+		if e.Opponent != "" {
+			e.Title += " vs " + e.Opponent
+		}
+	}
+	if strings.TrimSpace(req.Recurrence) == "" {
+		return e, nil
+	}
+
+	rule, err := recur.Parse(req.Recurrence)
+	if err != nil {
+		return e, err
+	}
+	e.Recurrence = rule.String()
+	e.Timezone = zone
+	if req.Timezone != "" {
+		e.Timezone = req.Timezone
+	}
+	loc, err := store.LoadTimezone(e.Timezone)
+	if err != nil {
+		return e, errors.New("timezone must be an IANA name such as Europe/Berlin")
+	}
+	if !rule.Includes(e.Start, loc, e.Start) {
+		return e, errors.New("start_time must be the first occurrence of the recurrence rule")
+	}
+	return e, nil
+}
+
+// gridZone returns the timezone of the team's grid, which recurring events
+// repeat in by default.
+func gridZone(w http.ResponseWriter, r *http.Request, s *store.Store, teamID int) (string, bool) {
+	grid, err := s.Grids.GetGrid(r.Context(), teamID)
+	if err != nil {
+		response.StoreError(w, r, err, "Team not found")
+		return "", false
+	}
+	return grid.Timezone, true
+}
+
+// teamEvent loads the {event_id} event, writing a 404 unless it belongs to
+// the {team_id} team.
+func teamEvent(w http.ResponseWriter, r *http.Request, s *store.Store) (store.Event, bool) {
+	teamID, _ := urlParamInt(r, "team_id")
+	eventID, _ := urlParamInt(r, "event_id")
+	e, err := s.Events.GetEvent(r.Context(), eventID)
+	if err != nil {
+		response.StoreError(w, r, err, "Event not found")
+		return store.Event{}, false
+	}
+	if e.TeamID != teamID {
+		response.Error(w, r, "Event not found", http.StatusNotFound)
+		return store.Event{}, false
+	}
+	return e, true
+}
+
+// parseTimeParam reads an optional RFC 3339 query parameter.
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, errors.New(name + " must be an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// EventsHandler lists a team's events and creates new ones. GET accepts
+// ?from= and ?to= (RFC 3339) and defaults to the next 90 days; recurring
+// events are expanded into one entry per occurrence. Times are shown in the
+// caller's timezone unless ?tz= overrides it.
+func EventsHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			from, err := parseTimeParam(r, "from")
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			to, err := parseTimeParam(r, "to")
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			switch {
+			case from.IsZero() && to.IsZero():
+				from = time.Now().Add(-24 * time.Hour)
+			case from.IsZero():
+				from = to.Add(-defaultEventRange)
+			}
+			if to.IsZero() {
+				to = from.Add(defaultEventRange)
+			}
+			if to.Before(from) {
+				response.Error(w, r, "to must not be before from", http.StatusBadRequest)
+				return
+			}
+			if to.Sub(from) > maxEventRange {
+				response.Error(w, r, "from and to must be at most 366 days apart", http.StatusBadRequest)
+				return
+			}
+			occurrences, err := store.ListOccurrences(r.Context(), s.Events, teamID, from, to)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			resp := make([]Event, 0, len(occurrences))
+			for _, o := range occurrences {
+				resp = append(resp, newOccurrence(o, loc))
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodPost:
+			var req EventRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			zone, ok := gridZone(w, r, s, teamID)
+			if !ok {
+				return
+			}
+			e, err := req.event(teamID, zone)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			e.CreatedBy = caller.UserID
+			if e, err = s.Events.CreateEvent(r.Context(), e); err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			notify.EventChanged(r.Context(), s, notify.Created, firstOccurrence(e))
+			response.JSON(w, http.StatusCreated, newEvent(e, loc))
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// EventHandler reads, updates and cancels a single event. Cancelling keeps
+// the event and its RSVPs but marks it as cancelled; for a recurring event
+// it cancels the whole series.
+func EventHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, ok := teamEvent(w, r, s)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			rsvps, err := s.Events.ListRSVPs(r.Context(), e.ID)
+			if err != nil {
+				response.StoreError(w, r, err, "Event not found")
+				return
+			}
+			resp := newEvent(e, loc)
+			resp.RSVPs = newRSVPs(rsvps)
+			if e.Recurrence != "" {
+				exceptions, err := s.Events.ListEventExceptions(r.Context(), e.ID)
+				if err != nil {
+					response.StoreError(w, r, err, "Event not found")
+					return
+				}
+				for _, x := range exceptions {
+					resp.Exceptions = append(resp.Exceptions, newOccurrence(applyException(e, x), loc))
+				}
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodPut:
+			var req EventRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			zone := e.Timezone
+			if zone == "" {
+				if zone, ok = gridZone(w, r, s, e.TeamID); !ok {
+					return
+				}
+			}
+			updated, err := req.event(e.TeamID, zone)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			updated.ID = e.ID
+			if err := s.Events.UpdateEvent(r.Context(), updated); err != nil {
+				response.StoreError(w, r, err, "Event not found")
+				return
+			}
+			updated.Cancelled = e.Cancelled
+			notify.EventChanged(r.Context(), s, notify.Updated, firstOccurrence(updated))
+			response.JSON(w, http.StatusOK, newEvent(updated, loc))
+
+		case http.MethodDelete:
+			if err := s.Events.CancelEvent(r.Context(), e.ID); err != nil {
+				response.StoreError(w, r, err, "Event not found")
+				return
+			}
+			if !e.Cancelled {
+				e.Cancelled = true
+				notify.EventChanged(r.Context(), s, notify.Cancelled, firstOccurrence(e))
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RSVPHandler records the caller's answer to an event.
+func RSVPHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		e, ok := teamEvent(w, r, s)
+		if !ok {
+			return
+		}
+
+		var req RSVPRequest
+		if !response.Decode(w, r, &req) {
+			return
+		}
+		status := store.RSVPStatus(strings.ToLower(req.Status))
+		if !status.Valid() {
+			response.Error(w, r, "status must be yes, no or maybe", http.StatusBadRequest)
+			return
+		}
+		if e.Cancelled {
+			response.Error(w, r, "Event has been cancelled", http.StatusConflict)
+			return
+		}
+		if err := s.Events.SetRSVP(r.Context(), store.RSVP{EventID: e.ID, UserID: caller.UserID, Status: status}); err != nil {
+			response.StoreError(w, r, err, "Event not found")
+			return
+		}
+		response.JSON(w, http.StatusOK, RSVP{UserID: caller.UserID, Username: caller.Battletag, Status: string(status)})
+	}
+}
+
+// firstOccurrence is the occurrence notifications describe for a whole
+// event or series.
+func firstOccurrence(e store.Event) store.Occurrence {
+	return store.Occurrence{Event: e, OriginalStart: e.Start}
+}
+
+// applyException returns the occurrence of e that x overrides.
+func applyException(e store.Event, x store.EventException) store.Occurrence {
+	o := store.Occurrence{Event: e, OriginalStart: x.OriginalStart, Overridden: true}
+	o.Start, o.End = x.Start, x.End
+	o.Title, o.Opponent, o.Location = x.Title, x.Opponent, x.Location
+	o.Cancelled = e.Cancelled || x.Cancelled
+	return o
+}
+
+// OccurrencesHandler changes single occurrences of a recurring event. PUT
+// overrides or cancels the occurrence the rule starts at original_start;
+// DELETE ?original_start= drops that exception again.
+func OccurrencesHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, ok := teamEvent(w, r, s)
+		if !ok {
+			return
+		}
+		rule, ruleZone, err := e.Rule()
+		if err != nil {
+			log.Printf("Error reading recurrence of event %d: %v", e.ID, err)
+			response.Error(w, r, "Invalid recurrence rule", http.StatusInternalServerError)
+			return
+		}
+		if ruleZone == nil {
+			response.Error(w, r, "Event does not repeat", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var req OccurrenceRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			if !rule.Includes(e.Start, ruleZone, req.OriginalStart) {
+				response.Error(w, r, "Occurrence not found", http.StatusNotFound)
+				return
+			}
+
+			x := store.EventException{
+				EventID:       e.ID,
+				OriginalStart: req.OriginalStart,
+				Cancelled:     req.Cancelled,
+				Start:         req.OriginalStart,
+				Title:         strings.TrimSpace(req.Title),
+				Opponent:      strings.TrimSpace(req.Opponent),
+				Location:      strings.TrimSpace(req.Location),
+			}
+			if !req.StartTime.IsZero() {
+				x.Start = req.StartTime
+			}
+			x.End = x.Start.Add(e.End.Sub(e.Start))
+			if !req.EndTime.IsZero() {
+				x.End = req.EndTime
+			}
+			if !x.End.After(x.Start) {
+				response.Error(w, r, "end_time must be after start_time", http.StatusBadRequest)
+				return
+			}
+			if x.Title == "" {
+				x.Title = e.Title
+			}
+			if x.Opponent == "" {
+				x.Opponent = e.Opponent
+			}
+			if x.Location == "" {
+				x.Location = e.Location
+			}
+			if err := s.Events.SetEventException(r.Context(), x); err != nil {
+				response.StoreError(w, r, err, "Event not found")
+				return
+			}
+			o := applyException(e, x)
+			change := notify.Updated
+			if x.Cancelled {
+				change = notify.Cancelled
+			}
+			notify.EventChanged(r.Context(), s, change, o)
+			response.JSON(w, http.StatusOK, newOccurrence(o, loc))
+
+		case http.MethodDelete:
+			original, err := parseTimeParam(r, "original_start")
+			if err != nil || original.IsZero() {
+				response.Error(w, r, "original_start must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			if err := s.Events.DeleteEventException(r.Context(), e.ID, original); err != nil {
+				response.StoreError(w, r, err, "Occurrence not found")
+				return
+			}
+			restored := store.Occurrence{Event: e, OriginalStart: original, Overridden: true}
+			restored.Start, restored.End = original, original.Add(e.End.Sub(e.Start))
+			notify.EventChanged(r.Context(), s, notify.Updated, restored)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// EventFromSummaryHandler creates an event for a window suggested by the
+// availability summary. The start and end times must line up with back-to-
+// back slots of the team's grid. Every member whose availability covers the
+// window is RSVPed yes.
+func EventFromSummaryHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req EventRequest
+		if !response.Decode(w, r, &req) {
+			return
+		}
+		grid, err := s.Grids.GetGrid(r.Context(), teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		e, err := req.event(teamID, grid.Timezone)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slots, err := teamSlots(r.Context(), s, teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		ok, err = alignsWithSlots(grid, slots, e.Start, e.End)
+		if err != nil {
+			log.Printf("Error placing slots for team %d: %v", teamID, err)
+			response.Error(w, r, "Invalid team grid", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			response.Error(w, r, "start_time and end_time must match back-to-back slots of the team's grid", http.StatusBadRequest)
+			return
+		}
+
+		e.CreatedBy = caller.UserID
+		if e, err = s.Events.CreateEvent(r.Context(), e); err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		notify.EventChanged(r.Context(), s, notify.Created, firstOccurrence(e))
+
+		members, err := s.Memberships.ListMembers(r.Context(), teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		availability, err := s.Availability.ListTeamAvailability(r.Context(), teamID, e.Start, e.End)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		for _, m := range members {
+			if !store.Covers(store.MergeIntervals(availability[m.UserID]), e.Start, e.End) {
+				continue
+			}
+			if err := s.Events.SetRSVP(r.Context(), store.RSVP{EventID: e.ID, UserID: m.UserID, Status: store.RSVPYes}); err != nil {
+				response.StoreError(w, r, err, "Event not found")
+				return
+			}
+		}
+
+		rsvps, err := s.Events.ListRSVPs(r.Context(), e.ID)
+		if err != nil {
+			response.StoreError(w, r, err, "Event not found")
+			return
+		}
+		resp := newEvent(e, loc)
+		resp.RSVPs = newRSVPs(rsvps)
+		response.JSON(w, http.StatusCreated, resp)
+	}
+}
+
+// alignsWithSlots reports whether [start, end) is exactly a run of
+// back-to-back slots in the week holding start.
+func alignsWithSlots(grid store.Grid, slots []store.TimeSlot, start, end time.Time) (bool, error) {
+	loc, err := store.LoadTimezone(grid.Timezone)
+	if err != nil {
+		return false, err
+	}
+	weekStart := store.WeekStart(start, loc)
+	var intervals []store.Interval
+	for _, slot := range slots {
+		iv, err := grid.SlotInterval(slot, weekStart)
+		if err != nil {
+			return false, err
+		}
+		intervals = append(intervals, iv)
+	}
+
+	at := start
+	for !at.Equal(end) {
+		next := -1
+		for i, iv := range intervals {
+			if iv.Start.Equal(at) {
+				next = i
+				break
+			}
+		}
+		if next < 0 || intervals[next].End.After(end) {
+			return false, nil
+		}
+		at = intervals[next].End
+	}
+	return true, nil
+}
@@ -0,0 +1,236 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestEventsHandler(t *testing.T) {
+	s, h, team, members := newTeam(t, "coach", "player")
+	coach, player := members[0], members[1]
+	ctx := context.Background()
+	eventsPath := "/teams/" + strconv.Itoa(team.ID) + "/events"
+
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	body := api.EventRequest{Type: "scrim", Opponent: "Team Bravo", Location: "Discord #scrims", StartTime: start, EndTime: start.Add(2 * time.Hour)}
+	if rr := do(t, h, http.MethodPost, eventsPath, body, player.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("player POST returned %v, want 403", rr.Code)
+	}
+	rr := do(t, h, http.MethodPost, eventsPath, body, coach.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST returned %v: %s", rr.Code, rr.Body)
+	}
+	var created api.Event
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Title != "Scrim vs Team Bravo" || created.Type != "scrim" || !created.StartTime.Equal(start) {
+		t.Errorf("unexpected event: %+v", created)
+	}
+	eventPath := eventsPath + "/" + strconv.Itoa(created.ID)
+
+	body.Type = "tournament"
+	if rr := do(t, h, http.MethodPost, eventsPath, body, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown type returned %v, want 400", rr.Code)
+	}
+
+	if rr := do(t, h, http.MethodPut, eventPath+"/rsvp", map[string]string{"status": "maybe"}, player.ID); rr.Code != http.StatusOK {
+		t.Fatalf("RSVP returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodPut, eventPath+"/rsvp", map[string]string{"status": "perhaps"}, player.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad RSVP returned %v, want 400", rr.Code)
+	}
+
+	rr = do(t, h, http.MethodGet, eventPath, nil, player.ID)
+	var got api.Event
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.RSVPs) != 1 || got.RSVPs[0].Username != player.Username || got.RSVPs[0].Status != "maybe" {
+		t.Errorf("unexpected RSVPs: %+v", got.RSVPs)
+	}
+
+	update := api.EventRequest{Type: "match", Title: "Finals", StartTime: start, EndTime: start.Add(3 * time.Hour)}
+	if rr := do(t, h, http.MethodPut, eventPath, update, coach.ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT returned %v: %s", rr.Code, rr.Body)
+	}
+
+	if rr := do(t, h, http.MethodDelete, eventPath, nil, coach.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned %v", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPut, eventPath+"/rsvp", map[string]string{"status": "yes"}, player.ID); rr.Code != http.StatusConflict {
+		t.Errorf("RSVP to a cancelled event returned %v, want 409", rr.Code)
+	}
+
+	rr = do(t, h, http.MethodGet, eventsPath, nil, player.ID)
+	var list []api.Event
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Title != "Finals" || !list[0].Cancelled {
+		t.Errorf("unexpected events: %+v", list)
+	}
+
+	// Another team's URL can't reach the event
+	other, _ := s.Teams.CreateTeam(ctx, "Beta")
+	s.Memberships.AddMember(ctx, store.Member{TeamID: other.ID, UserID: player.ID, Role: "player"})
+	if rr := do(t, h, http.MethodGet, "/teams/"+strconv.Itoa(other.ID)+"/events/"+strconv.Itoa(created.ID), nil, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("cross-team GET returned %v, want 404", rr.Code)
+	}
+}
+
+func TestEventFromSummary(t *testing.T) {
+	_, h, team, members := newTeam(t, "coach", "player")
+	coach, player := members[0], members[1]
+	teamPath := "/teams/" + strconv.Itoa(team.ID) + "/"
+
+	grid := api.Grid{SlotMinutes: 60, Timezone: "Europe/Berlin", Days: []api.GridDay{{Weekday: "Friday", Start: "18:00", End: "22:00"}}}
+	if rr := do(t, h, http.MethodPut, teamPath+"grid", grid, coach.ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT grid returned %v: %s", rr.Code, rr.Body)
+	}
+	week := teamPath + "availability?week=2025-05-05&tz=Europe/Berlin"
+	both := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Friday", Time: "19:00"}, {Day: "Friday", Time: "20:00"}}}
+	do(t, h, http.MethodPost, week, both, coach.ID)
+	one := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Friday", Time: "19:00"}}}
+	do(t, h, http.MethodPost, week, one, player.ID)
+
+	rr := do(t, h, http.MethodGet, teamPath+"availability/summary?week=2025-05-05&min_duration=120", nil, coach.ID)
+	var sum api.AvailabilitySummary
+	if err := json.NewDecoder(rr.Body).Decode(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if len(sum.Windows) == 0 {
+		t.Fatalf("no suggested windows: %+v", sum)
+	}
+	best := sum.Windows[0]
+
+	body := api.EventRequest{Type: "practice", StartTime: best.StartsAt, EndTime: best.EndsAt}
+	rr = do(t, h, http.MethodPost, teamPath+"events/from-summary", body, coach.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST from-summary returned %v: %s", rr.Code, rr.Body)
+	}
+	var created api.Event
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Title != "Practice" || len(created.RSVPs) != 1 || created.RSVPs[0].UserID != coach.ID || created.RSVPs[0].Status != "yes" {
+		t.Errorf("unexpected event: %+v", created)
+	}
+
+	body.StartTime = best.StartsAt.Add(30 * time.Minute)
+	if rr := do(t, h, http.MethodPost, teamPath+"events/from-summary", body, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("misaligned window returned %v, want 400", rr.Code)
+	}
+}
+
+func TestRecurringEvents(t *testing.T) {
+	_, h, team, members := newTeam(t, "coach")
+	coach := members[0]
+	teamPath := "/teams/" + strconv.Itoa(team.ID) + "/"
+
+	grid := api.Grid{SlotMinutes: 60, Timezone: "America/New_York", Days: []api.GridDay{{Weekday: "Tuesday", Start: "19:00", End: "21:00"}}}
+	if rr := do(t, h, http.MethodPut, teamPath+"grid", grid, coach.ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT grid returned %v: %s", rr.Code, rr.Body)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	start := time.Date(2026, 3, 3, 19, 0, 0, 0, ny)
+
+	// Tuesdays and Thursdays in the team's timezone, across the DST change
+	body := api.EventRequest{Type: "practice", StartTime: start, EndTime: start.Add(2 * time.Hour), Recurrence: "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=6"}
+	rr := do(t, h, http.MethodPost, teamPath+"events", body, coach.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST returned %v: %s", rr.Code, rr.Body)
+	}
+	var created api.Event
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Timezone != "America/New_York" || created.Recurrence != "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=6" {
+		t.Errorf("unexpected series: %+v", created)
+	}
+	eventPath := teamPath + "events/" + strconv.Itoa(created.ID)
+
+	list := func(from, to string) []api.Event {
+		t.Helper()
+		rr := do(t, h, http.MethodGet, teamPath+"events?tz=UTC&from="+from+"&to="+to, nil, coach.ID)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET returned %v: %s", rr.Code, rr.Body)
+		}
+		var events []api.Event
+		if err := json.NewDecoder(rr.Body).Decode(&events); err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+	got := list("2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z")
+	if len(got) != 6 {
+		t.Fatalf("got %d occurrences, want 6: %+v", len(got), got)
+	}
+	if got[0].StartTime.Hour() != 0 || got[2].StartTime.Hour() != 23 || got[2].OriginalStart == nil {
+		t.Errorf("occurrences do not keep 19:00 New York time: %v, %v", got[0].StartTime, got[2].StartTime)
+	}
+
+	// A lone ?to= lists the 90 days before it; wider or inverted ranges
+	// are rejected
+	for _, q := range []string{
+		"from=2026-03-01T00:00:00Z&to=2200-01-01T00:00:00Z",
+		"from=2026-04-01T00:00:00Z&to=2026-03-01T00:00:00Z",
+	} {
+		if rr := do(t, h, http.MethodGet, teamPath+"events?"+q, nil, coach.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("GET events?%s returned %v, want 400", q, rr.Code)
+		}
+	}
+	if got := list("", "2026-04-01T00:00:00Z"); len(got) != 6 {
+		t.Errorf("got %d occurrences with only ?to=, want 6", len(got))
+	}
+
+	// Move the March 12 practice to Friday and cancel March 17
+	moved := time.Date(2026, 3, 13, 20, 0, 0, 0, ny)
+	override := api.OccurrenceRequest{OriginalStart: *got[3].OriginalStart, StartTime: moved, Title: "Makeup practice"}
+	if rr := do(t, h, http.MethodPut, eventPath+"/occurrences", override, coach.ID); rr.Code != http.StatusOK {
+		t.Fatalf("override returned %v: %s", rr.Code, rr.Body)
+	}
+	cancel := api.OccurrenceRequest{OriginalStart: *got[4].OriginalStart, Cancelled: true}
+	if rr := do(t, h, http.MethodPut, eventPath+"/occurrences", cancel, coach.ID); rr.Code != http.StatusOK {
+		t.Fatalf("cancel returned %v: %s", rr.Code, rr.Body)
+	}
+	notAnOccurrence := api.OccurrenceRequest{OriginalStart: moved, Cancelled: true}
+	if rr := do(t, h, http.MethodPut, eventPath+"/occurrences", notAnOccurrence, coach.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("override of a missing occurrence returned %v, want 404", rr.Code)
+	}
+
+	got = list("2026-03-13T00:00:00Z", "2026-03-19T00:00:00Z")
+	if len(got) != 2 {
+		t.Fatalf("got %d occurrences, want the moved and the cancelled one: %+v", len(got), got)
+	}
+	if got[0].Title != "Makeup practice" || !got[0].StartTime.Equal(moved) || !got[0].EndTime.Equal(moved.Add(2*time.Hour)) || !got[0].Overridden {
+		t.Errorf("unexpected moved occurrence: %+v", got[0])
+	}
+	if !got[1].Cancelled || got[0].Cancelled {
+		t.Errorf("wrong occurrence cancelled: %+v", got)
+	}
+
+	rr = do(t, h, http.MethodDelete, eventPath+"/occurrences?original_start="+got[1].OriginalStart.UTC().Format(time.RFC3339), nil, coach.ID)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("restore returned %v: %s", rr.Code, rr.Body)
+	}
+	if got = list("2026-03-17T00:00:00Z", "2026-03-18T00:00:00Z"); len(got) != 1 || got[0].Cancelled {
+		t.Errorf("restored occurrence: %+v", got)
+	}
+
+	body.StartTime = start.Add(24 * time.Hour)
+	if rr := do(t, h, http.MethodPost, teamPath+"events", body, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("start outside the rule returned %v, want 400", rr.Code)
+	}
+	body.StartTime, body.Recurrence = start, "FREQ=MONTHLY"
+	if rr := do(t, h, http.MethodPost, teamPath+"events", body, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("unsupported rule returned %v, want 400", rr.Code)
+	}
+}
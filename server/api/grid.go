@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Grid is a team's weekly scheduling grid
+type Grid struct {
+	SlotMinutes int       `json:"slot_minutes"`
+	Timezone    string    `json:"timezone"`
+	Days        []GridDay `json:"days"`
+}
+
+// GridDay is the span of one weekday covered by a grid
+type GridDay struct {
+	Weekday string `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// Validate checks the timezone and that each day names a weekday with HH:MM
+// start and end times, where the end may be 24:00. Whether the days fit
+// together is checked by the store.
+func (g Grid) Validate() []response.FieldError {
+	var f response.Fields
+	f.Timezone("timezone", g.Timezone)
+	for i, d := range g.Days {
+		field := fmt.Sprintf("days[%d]", i)
+		if f.Required(field+".weekday", d.Weekday) {
+			f.Weekday(field+".weekday", d.Weekday)
+		}
+		if f.Required(field+".start", d.Start) {
+			f.ClockTime(field+".start", d.Start)
+		}
+		if f.Required(field+".end", d.End) && d.End != "24:00" {
+			f.ClockTime(field+".end", d.End)
+		}
+	}
+	return f
+}
+
+func newGrid(g store.Grid) Grid {
+	resp := Grid{SlotMinutes: g.SlotMinutes, Timezone: g.Timezone, Days: make([]GridDay, 0, len(g.Days))}
+	for _, d := range g.Days {
+		resp.Days = append(resp.Days, GridDay{Weekday: d.Weekday, Start: d.Start, End: d.End})
+	}
+	return resp
+}
+
+// GridHandler reads and replaces a team's weekly grid. Replacing the grid
+// regenerates the team's time slots and responds with them.
+func GridHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			grid, err := s.Grids.GetGrid(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, newGrid(grid))
+
+		case http.MethodPut:
+			var req Grid
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			grid := store.Grid{TeamID: teamID, SlotMinutes: req.SlotMinutes, Timezone: req.Timezone}
+			if grid.Timezone == "" {
+				grid.Timezone = "UTC"
+			}
+			for _, d := range req.Days {
+				grid.Days = append(grid.Days, store.GridDay{Weekday: d.Weekday, Start: d.Start, End: d.End})
+			}
+			if err := grid.Validate(); err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			slots, err := s.Grids.SetGrid(r.Context(), grid)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			resp := make([]TimeSlot, 0, len(slots))
+			for _, slot := range slots {
+				resp = append(resp, newTimeSlot(slot))
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func TestGridHandler(t *testing.T) {
+	_, h, team, members := newTeam(t, "coach")
+	coach := members[0]
+	teamPath := "/teams/" + strconv.Itoa(team.ID) + "/"
+
+	rr := do(t, h, http.MethodGet, teamPath+"grid", nil, coach.ID)
+	var grid api.Grid
+	if err := json.NewDecoder(rr.Body).Decode(&grid); err != nil {
+		t.Fatal(err)
+	}
+	if grid.SlotMinutes != 120 || grid.Timezone != "UTC" || len(grid.Days) != 7 {
+		t.Errorf("unexpected default grid: %+v", grid)
+	}
+
+	grid = api.Grid{SlotMinutes: 30, Timezone: "America/New_York", Days: []api.GridDay{{Weekday: "Saturday", Start: "10:00", End: "12:00"}}}
+	rr = do(t, h, http.MethodPut, teamPath+"grid", grid, coach.ID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr = do(t, h, http.MethodGet, teamPath+"schedule", nil, coach.ID)
+	var slots []api.TimeSlot
+	if err := json.NewDecoder(rr.Body).Decode(&slots); err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 4 || slots[0].Weekday != "Saturday" || slots[0].Time != "10:00" || slots[3].Time != "11:30" {
+		t.Errorf("unexpected schedule: %+v", slots)
+	}
+
+	// Slots are shown in the caller's UTC profile zone unless ?tz= overrides it
+	body := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Saturday", Time: "10:30"}}}
+	if rr := do(t, h, http.MethodPost, teamPath+"availability?tz=America/New_York", body, coach.ID); rr.Code != http.StatusOK {
+		t.Errorf("availability on the new grid returned %v: %s", rr.Code, rr.Body)
+	}
+
+	grid.Timezone = "Nowhere/Special"
+	if rr := do(t, h, http.MethodPut, teamPath+"grid", grid, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid timezone returned %v, want 400", rr.Code)
+	}
+}
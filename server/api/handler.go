@@ -1,648 +1,733 @@
 package api
 
 import (
-	"database/sql"
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
+	"time"
 
-	"github.com/go-chi/chi"
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// AvailabilitySlot selects one slot of the week being edited, either by ID
+// or by its day and time as shown in the request's timezone.
 type AvailabilitySlot struct {
-	Day  string `json:"day"`
-	Time string `json:"time"`
+	SlotID int    `json:"slot_id,omitempty"`
+	Day    string `json:"day,omitempty"`
+	Time   string `json:"time,omitempty"`
 }
 
 // Represents the entire JSON object from the frontend
 type AvailabilityRequest struct {
 	SelectedSlots []AvailabilitySlot `json:"selected_slots"`
+	// Weeks repeats the selection over this many consecutive weeks,
+	// starting with the requested one. Defaults to 1.
+	Weeks int `json:"weeks,omitempty"`
+}
+
+// Validate checks that each selected slot names a slot ID or a weekday and
+// HH:MM time, and that weeks is in range.
+func (req AvailabilityRequest) Validate() []response.FieldError {
+	var f response.Fields
+	for i, sel := range req.SelectedSlots {
+		if sel.SlotID != 0 {
+			continue
+		}
+		field := fmt.Sprintf("selected_slots[%d]", i)
+		if f.Required(field+".day", sel.Day) {
+			f.Weekday(field+".day", sel.Day)
+		}
+		if f.Required(field+".time", sel.Time) {
+			f.ClockTime(field+".time", sel.Time)
+		}
+	}
+	if req.Weeks < 0 || req.Weeks > maxAvailabilityWeeks {
+		f.Add("weeks", "must be between 1 and %d", maxAvailabilityWeeks)
+	}
+	return f
+}
+
+// TeamRequest creates or renames a team.
+type TeamRequest struct {
+	Name string `json:"name"`
+}
+
+// Validate checks the team name.
+func (req TeamRequest) Validate() []response.FieldError {
+	var f response.Fields
+	if f.Required("name", req.Name) {
+		f.MaxLength("name", req.Name, maxNameLength)
+	}
+	return f
+}
+
+// PlayerRequest updates a player's battletag and timezone. Omitted fields
+// are left alone.
+type PlayerRequest struct {
+	Username string `json:"username"`
+	Timezone string `json:"timezone"`
+}
+
+// Validate checks that at least one field is set and each is well formed.
+func (req PlayerRequest) Validate() []response.FieldError {
+	var f response.Fields
+	if req.Username == "" && req.Timezone == "" {
+		f.Add("username", "or timezone is required")
+	}
+	f.Battletag("username", req.Username)
+	f.Timezone("timezone", req.Timezone)
+	return f
+}
+
+// AddMemberRequest adds a player to a team.
+type AddMemberRequest struct {
+	UserID   int    `json:"user_id"`
+	Role     string `json:"role"`
+	GameRole string `json:"game_role"`
+}
+
+// UpdateMemberRequest changes a member's team role, in-game role or both.
+type UpdateMemberRequest struct {
+	UserID   int     `json:"user_id"`
+	Role     string  `json:"role"`
+	GameRole *string `json:"game_role"` // "" clears it
 }
 
-// AvailabilityHandler handles GET and POST requests for user availability.
-func AvailabilityHandler(db *sql.DB) http.HandlerFunc {
+// RemoveMemberRequest removes a player from a team.
+type RemoveMemberRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// TimeSlotRequest creates or moves a global time slot.
+type TimeSlotRequest struct {
+	Day  string `json:"day"`
+	Time string `json:"time"`
+}
+
+// Validate checks the weekday and HH:MM time.
+func (req TimeSlotRequest) Validate() []response.FieldError {
+	var f response.Fields
+	if f.Required("day", req.Day) {
+		f.Weekday("day", req.Day)
+	}
+	if f.Required("time", req.Time) {
+		f.ClockTime("time", req.Time)
+	}
+	return f
+}
+
+// maxNameLength caps names and other short free-text fields.
+const maxNameLength = 255
+
+// SlotAvailability is one week's occurrence of a time slot, shown in the
+// requester's timezone, along with whether the player is available.
+type SlotAvailability struct {
+	SlotID    int       `json:"slot_id"`
+	Day       string    `json:"day"`
+	Time      string    `json:"time"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Available bool      `json:"available"`
+}
+
+// maxAvailabilityWeeks caps how far ahead one request can fill availability.
+const maxAvailabilityWeeks = 12
+
+// urlParamInt reads an integer URL parameter. The router has already
+// validated it, so a parse error means the parameter is absent.
+func urlParamInt(r *http.Request, name string) (int, bool) {
+	v, err := strconv.Atoi(chi.URLParam(r, name))
+	return v, err == nil
+}
+
+// teamSlots returns the slots of the team's grid, falling back to the global
+// defaults when the team has not configured a grid.
+func teamSlots(ctx context.Context, s *store.Store, teamID int) ([]store.TimeSlot, error) {
+	slots, err := s.TimeSlots.ListTimeSlots(ctx, teamID)
+	if err != nil || len(slots) > 0 {
+		return slots, err
+	}
+	return s.TimeSlots.ListTimeSlots(ctx, 0)
+}
+
+// requestZone returns the timezone availability is shown in: the ?tz=
+// override, else the caller's profile timezone.
+func requestZone(r *http.Request, caller *middleware.Principal) (*time.Location, error) {
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		return store.LoadTimezone(tz)
+	}
+	if loc, err := store.LoadTimezone(caller.Timezone); err == nil {
+		return loc, nil
+	}
+	return time.UTC, nil
+}
+
+// requestWeek returns the start of the week named by ?week=YYYY-MM-DD, or of
+// the current week, in the team's timezone.
+func requestWeek(r *http.Request, grid store.Grid) (time.Time, error) {
+	loc, err := store.LoadTimezone(grid.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day := time.Now()
+	if v := r.URL.Query().Get("week"); v != "" {
+		if day, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			return time.Time{}, errors.New("week must be a date like 2025-05-05")
+		}
+	}
+	return store.WeekStart(day, loc), nil
+}
+
+// weekSlots returns each slot's occurrence in the week starting at
+// weekStart, shown in loc and checked against the merged availability.
+func weekSlots(grid store.Grid, slots []store.TimeSlot, weekStart time.Time, loc *time.Location, available []store.Interval) ([]SlotAvailability, error) {
+	resp := make([]SlotAvailability, 0, len(slots))
+	for _, slot := range slots {
+		iv, err := grid.SlotInterval(slot, weekStart)
+		if err != nil {
+			return nil, err
+		}
+		local := iv.Start.In(loc)
+		resp = append(resp, SlotAvailability{
+			SlotID:    slot.ID,
+			Day:       local.Weekday().String(),
+			Time:      local.Format("15:04"),
+			StartsAt:  local,
+			EndsAt:    iv.End.In(loc),
+			Available: store.Covers(available, iv.Start, iv.End),
+		})
+	}
+	return resp, nil
+}
+
+// AvailabilityHandler handles GET and POST requests for the caller's
+// availability during one week of the team's grid. Slots are shown in the
+// caller's timezone unless ?tz= overrides it, and ?week= picks the week.
+func AvailabilityHandler(s *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		// Error Checking
+		if _, ok := caller.Membership(teamID); !ok {
+			response.Error(w, r, "User is not a member of this team", http.StatusNotFound)
+			return
+		}
+		userID := caller.UserID
+
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		grid, err := s.Grids.GetGrid(r.Context(), teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		weekStart, err := requestWeek(r, grid)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slots, err := teamSlots(r.Context(), s, teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
-			userID := r.URL.Query().Get("user_id")
-			teamID := r.URL.Query().Get("team_id")
-			var count int
-			// Error Checking
-			err := db.QueryRow("SELECT COUNT(*) FROM team_members WHERE user_id = $1 AND team_id = $2", userID, teamID).Scan(&count)
-			if err != nil || count == 0 {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			// This query is just wrong
-			rows, err := db.Query(`
-				SELECT t.weekday, t.time, a.available
-				FROM time_slots t
-				LEFT JOIN availability a ON t.slot_id = a.slot_id AND a.user_id = $1
-			`, userID)
+			weekEnd := weekStart.AddDate(0, 0, 7)
+			available, err := s.Availability.ListAvailability(r.Context(), teamID, userID, weekStart, weekEnd)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			defer rows.Close()
-			var slots []map[string]any
-			for rows.Next() {
-				var day, time string
-				var available sql.NullBool
-				err := rows.Scan(&day, &time, &available)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				slots = append(slots, map[string]any{
-					"day":       day,
-					"time":      time,
-					"available": available.Valid && available.Bool,
-				})
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(slots)
-		case http.MethodPost:
-			var req struct {
-				AvailabilityRequest
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			resp, err := weekSlots(grid, slots, weekStart, loc, available)
+			if err != nil {
+				log.Printf("Error placing slots for team %d: %v", teamID, err)
+				response.Error(w, r, "Invalid team grid", http.StatusInternalServerError)
 				return
 			}
-			if len(req.SelectedSlots) == 0 {
-				http.Error(w, "User ID and selected slots are required", http.StatusBadRequest)
+			response.JSON(w, http.StatusOK, resp)
+		case http.MethodPost:
+			var req AvailabilityRequest
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			// Check if the user is a member of the team
-			var count int
-			err := db.QueryRow("SELECT COUNT(*) FROM team_members WHERE user_id = $1 AND team_id = $2", r.Context().Value("user_id"), r.Context().Value("team_id")).Scan(&count)
-			if err != nil || count == 0 {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
+			if req.Weeks == 0 {
+				req.Weeks = 1
 			}
 
-			// Clear existing availability for the user
-			_, err = db.Exec("DELETE FROM availability WHERE user_id = $1", r.Context().Value("user_id"))
+			// Resolve the selection against the slots as the caller sees them
+			shown, err := weekSlots(grid, slots, weekStart, loc, nil)
 			if err != nil {
-				http.Error(w, "Failed to clear existing availability", http.StatusInternalServerError)
+				log.Printf("Error placing slots for team %d: %v", teamID, err)
+				response.Error(w, r, "Invalid team grid", http.StatusInternalServerError)
 				return
 			}
-
-			// Insert new availability slots
-			for _, slot := range req.SelectedSlots {
-				_, err = db.Exec("INSERT INTO availability (user_id, slot_id) VALUES ($1, (SELECT id FROM time_slots WHERE weekday = $2 AND time = $3 LIMIT 1))",
-					r.Context().Value("user_id"), slot.Day, slot.Time)
-				if err != nil {
-					http.Error(w, "Failed to insert new availability slot", http.StatusInternalServerError)
+			var selected []store.TimeSlot
+			for _, sel := range req.SelectedSlots {
+				i := slices.IndexFunc(shown, func(slot SlotAvailability) bool {
+					if sel.SlotID != 0 {
+						return slot.SlotID == sel.SlotID
+					}
+					return slot.Day == sel.Day && slot.Time == sel.Time
+				})
+				if i < 0 {
+					response.Error(w, r, "Unknown time slot: "+sel.Day+" "+sel.Time, http.StatusBadRequest)
 					return
 				}
+				selected = append(selected, slots[i])
+			}
+
+			// Each week is placed separately so DST changes are respected
+			var intervals []store.Interval
+			week := weekStart
+			for n := 0; n < req.Weeks; n++ {
+				for _, slot := range selected {
+					iv, err := grid.SlotInterval(slot, week)
+					if err != nil {
+						response.Error(w, r, "Invalid team grid", http.StatusInternalServerError)
+						return
+					}
+					intervals = append(intervals, iv)
+				}
+				week = week.AddDate(0, 0, 7)
 			}
 
-			w.WriteHeader(http.StatusOK)
+			// Replace the user's availability for these weeks
+			if err := s.Availability.SetAvailability(r.Context(), teamID, userID, weekStart, week, intervals); err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, map[string]int{"selected": len(selected)})
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-func TeamHandler(db *sql.DB) http.HandlerFunc {
+func TeamHandler(s *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, hasTeamID := urlParamInt(r, "team_id")
+
 		switch r.Method {
 		case http.MethodGet:
-			// Extract the team ID from the URL path, e.g., "/api/teams/1" (May not be a good idea)
-			teamIDStr := strings.TrimPrefix(r.URL.Path, "/api/teams/")
-			teamID, err := strconv.Atoi(teamIDStr)
-			if err != nil {
-				http.Error(w, "Invalid team ID provided in URL", http.StatusBadRequest)
+			if !hasTeamID {
+				// List every team
+				teams, err := s.Teams.ListTeams(r.Context())
+				if err != nil {
+					response.StoreError(w, r, err, "Team not found")
+					return
+				}
+				resp := make([]Team, 0, len(teams))
+				for _, t := range teams {
+					resp = append(resp, newTeam(t))
+				}
+				response.JSON(w, http.StatusOK, resp)
 				return
 			}
 
 			// --- Step 1: Fetch the team's basic details ---
-			var team Team
-			err = db.QueryRow("SELECT id, name FROM teams WHERE id = $1", teamID).Scan(&team.ID, &team.Name)
+			team, err := s.Teams.GetTeam(r.Context(), teamID)
 			if err != nil {
-				// If no team is found, return a 404
-				if err == sql.ErrNoRows {
-					http.Error(w, "Team not found", http.StatusNotFound)
-					return
-				}
-				// For any other database error, return a 500
-				http.Error(w, "Database error fetching team", http.StatusInternalServerError)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
 
 			// --- Step 2: Fetch the list of members for that team ---
-			rows, err := db.Query(`
-            SELECT u.id, u.username, tm.role
-            FROM users u
-            JOIN team_members tm ON u.id = tm.user_id
-            WHERE tm.team_id = $1`, teamID)
+			members, err := s.Memberships.ListMembers(r.Context(), teamID)
 			if err != nil {
-				http.Error(w, "Database error fetching team members", http.StatusInternalServerError)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			defer rows.Close()
-
-			var members []TeamMember
-			for rows.Next() {
-				var member TeamMember
-				if err := rows.Scan(&member.ID, &member.Username, &member.Role); err != nil {
-					http.Error(w, "Error scanning team member data", http.StatusInternalServerError)
-					return
-				}
-				members = append(members, member)
-			}
 
 			// --- Step 3: Combine team details and members into a single response ---
-			fullTeamProfile := struct {
-				Team
-				Members []TeamMember `json:"members"`
-			}{
-				Team:    team,
-				Members: members,
+			fullTeamProfile := TeamDetail{
+				Team:    newTeam(team),
+				Members: make([]TeamMember, 0, len(members)),
+			}
+			for _, m := range members {
+				fullTeamProfile.Members = append(fullTeamProfile.Members, newTeamMember(m))
 			}
 
 			// --- Step 4: Send the JSON response ---
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(fullTeamProfile)
+			response.JSON(w, http.StatusOK, fullTeamProfile)
 		case http.MethodPost:
 			// Create a new team
-			var req struct {
-				Name string `json:"name"`
-			}
-			// ADD SOME ADMIN CHECKS FOR THIS POST
-			// Decode the request
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			var req TeamRequest
+			// Decode and validate the request
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			if req.Name == "" {
-				// Error Handling
-				http.Error(w, "Team Name must be provided", http.StatusBadRequest)
-				return
-			}
-			// Insert the new team into the database
-			_, err := db.Exec("INSERT INTO teams (name) VALUES ($1)", req.Name)
+			team, err := s.Teams.CreateTeam(r.Context(), req.Name)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			w.WriteHeader(http.StatusCreated)
+			response.JSON(w, http.StatusCreated, newTeam(team))
 		case http.MethodDelete:
-			var req struct {
-				TeamID int `json:"team_id"`
-			}
-			// ADD ADMIN CHECKS TO THIS DELETE
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
-				return
-			}
-			if req.TeamID == 0 {
-				http.Error(w, "Team ID must be provided", http.StatusBadRequest)
-				return
-			}
-			_, err := db.Exec("DELETE FROM teams WHERE id = $1", req.TeamID)
-			if err != nil {
-				if err == sql.ErrNoRows {
-					http.Error(w, "Team not found", http.StatusNotFound)
-					return
-				}
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if err := s.Teams.DeleteTeam(r.Context(), teamID); err != nil {
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
 			w.WriteHeader(http.StatusNoContent)
 		case http.MethodPut:
 			// Update an existing team
-			var req struct {
-				TeamID   int    `json:"team_id"`
-				TeamName string `json:"team_name"`
-			}
-
-			// ADD ADMIN CHECKS TO THIS PUT
-			
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			var req TeamRequest
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			if req.TeamName == "" {
-				// Error Handling
-				http.Error(w, "Team Name must be provided", http.StatusBadRequest)
-				return
-			}
-			_, err := db.Exec("UPDATE teams SET name = $1 WHERE id = $2", req.TeamName, req.TeamID)
-			if err != nil {
-				if err == sql.ErrNoRows {
-					http.Error(w, "Team not found", http.StatusNotFound)
-					return
-				}
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if err := s.Teams.UpdateTeam(r.Context(), teamID, req.Name); err != nil {
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
 			w.WriteHeader(http.StatusOK)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-func PlayerHandler(db *sql.DB) http.HandlerFunc {
+func PlayerHandler(s *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, hasUserID := urlParamInt(r, "user_id")
+
 		switch r.Method {
 		case http.MethodGet:
-			// Retrieve user username based on UserID
-			userIDStr := r.URL.Query().Get("user_id")
-			if userIDStr == "" {
-				http.Error(w, "User ID is required", http.StatusBadRequest)
-				return
-			}
-
-			// Convert the user ID from string to integer
-			userID, err := strconv.Atoi(userIDStr)
-			if err != nil {
-				http.Error(w, "Invalid User ID", http.StatusBadRequest)
-				return
-			}
-			row := db.QueryRow("SELECT username FROM users WHERE user_id = $1", userID)
-			var username string
-			if err := row.Scan(&username); err != nil {
-				if err == sql.ErrNoRows {
-					http.Error(w, "User not found", http.StatusNotFound)
+			if !hasUserID {
+				// List every player
+				players, err := s.Players.ListPlayers(r.Context())
+				if err != nil {
+					response.StoreError(w, r, err, "User not found")
 					return
 				}
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				resp := make([]Player, 0, len(players))
+				for _, p := range players {
+					resp = append(resp, newPlayer(p))
+				}
+				response.JSON(w, http.StatusOK, resp)
 				return
 			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{"username": username})
 
-		case http.MethodDelete:
-			var req struct {
-				UserID int `json:"user_id"`
-			}
-			// Decode the request body
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
-				return
-			}
-			if req.UserID == 0 {
-				http.Error(w, "User ID is required", http.StatusBadRequest)
-				return
-			}
-			// Check if the user is a member of a team
-			var count int
-			err := db.QueryRow("SELECT COUNT(*) FROM team_members WHERE user_id = $1", req.UserID).Scan(&count)
+			player, err := s.Players.GetPlayer(r.Context(), userID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.StoreError(w, r, err, "User not found")
 				return
 			}
-			if count == 0 {
-				http.Error(w, "User is not a member of the team", http.StatusNotFound)
+			response.JSON(w, http.StatusOK, newPlayer(player))
+
+		case http.MethodPut:
+			var req PlayerRequest
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			// Delete the user from the team_members table
-			_, err = db.Exec("DELETE FROM team_members WHERE user_id = $1", req.UserID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+			if req.Username != "" {
+				if err := s.Players.UpdatePlayer(r.Context(), userID, req.Username); err != nil {
+					response.StoreError(w, r, err, "User not found")
+					return
+				}
 			}
-			// Delete the user from the users table
-			_, err = db.Exec("DELETE FROM users WHERE user_id = $1", req.UserID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if req.Timezone != "" {
+				if err := s.Players.SetPlayerTimezone(r.Context(), userID, req.Timezone); err != nil {
+					response.StoreError(w, r, err, "User not found")
+					return
+				}
+			}
+			w.WriteHeader(http.StatusOK)
+
+		case http.MethodDelete:
+			// Deleting the user cascades to their team memberships and availability
+			if err := s.Players.DeletePlayer(r.Context(), userID); err != nil {
+				response.StoreError(w, r, err, "User not found")
 				return
 			}
 			w.WriteHeader(http.StatusNoContent)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-func TeamMembersHandler(db *sql.DB) http.HandlerFunc {
+// grantableRole validates the team role the caller gives a member, by
+// adding them, changing their role, inviting them or approving their
+// request, defaulting to player. Captains can't hand out a role above their
+// own; organization admins can hand out any.
+func grantableRole(r *http.Request, role string) (string, error) {
+	if role == "" {
+		return string(authz.RolePlayer), nil
+	}
+	want, ok := authz.LookupRole(role)
+	if !ok {
+		return "", errors.New("role must be one of sub, player, coach, captain or manager")
+	}
+	if caller, ok := middleware.PrincipalFromContext(r.Context()); ok && caller.IsAdmin {
+		return string(want), nil
+	}
+	own, _ := middleware.GetRoleFromContext(r.Context())
+	if !authz.Role(own).AtLeast(want) {
+		return "", errors.New("you can't give a role above your own")
+	}
+	return string(want), nil
+}
+
+// outranksCaller reports whether member holds a higher team role than the
+// caller, who then can't change their role or remove them. Organization
+// admins are outranked by no one.
+func outranksCaller(r *http.Request, member store.Member) bool {
+	if caller, ok := middleware.PrincipalFromContext(r.Context()); ok && caller.IsAdmin {
+		return false
+	}
+	own, _ := middleware.GetRoleFromContext(r.Context())
+	return !authz.Role(own).AtLeast(authz.ParseRole(member.Role))
+}
+
+func TeamMembersHandler(s *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
 		switch r.Method {
 		case http.MethodGet:
-			teamIDStr := chi.URLParam(r, "team_id")
-			if teamIDStr == "" {
-				http.Error(w, "Team ID is required", http.StatusBadRequest)
+			// Grab the team members from the store
+			if _, err := s.Teams.GetTeam(r.Context(), teamID); err != nil {
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-
-			// Convert the team ID from string to integer
-			teamID, err := strconv.Atoi(teamIDStr)
+			members, err := s.Memberships.ListMembers(r.Context(), teamID)
 			if err != nil {
-				http.Error(w, "Invalid Team ID", http.StatusBadRequest)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-
-			// Grab the team members from the database
-			rows, err := db.Query(`
-				SELECT u.user_id, u.username, tm.role 
-				FROM users u
-				JOIN team_members tm ON u.id = tm.user_id
-				WHERE tm.team_id = $1`, teamID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+			resp := make([]TeamMember, 0, len(members))
+			for _, m := range members {
+				resp = append(resp, newTeamMember(m))
 			}
-			defer rows.Close()
-			var members []map[string]any
-			for rows.Next() {
-				var userID int
-				var username string
-				var role string
-				err := rows.Scan(&userID, &username, &role)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				members = append(members, map[string]any{
-					"user_id":  userID,
-					"username": username,
-					"role":     role,
-				})
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(members)
+			response.JSON(w, http.StatusOK, resp)
 
 		case http.MethodPost:
-			var req struct {
-				UserID int    `json:"user_id"`
-				TeamID int    `json:"team_id"`
-				Role   string `json:"role"`
-			}
-
+			var req AddMemberRequest
 			// Decode the request body
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			if req.UserID == 0 || req.TeamID == 0 {
-				http.Error(w, "User ID and Team ID are required", http.StatusBadRequest)
+			if req.UserID == 0 {
+				response.Error(w, r, "User ID is required", http.StatusBadRequest)
 				return
 			}
-			// Check if the user is already a member of the team
-			var count int
-			err := db.QueryRow("SELECT COUNT(*) FROM team_members WHERE user_id = $1 AND team_id = $2", req.UserID, req.TeamID).Scan(&count)
+			role, err := grantableRole(r, req.Role)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
 				return
 			}
-			if count > 0 {
-				http.Error(w, "User is already a member of this team", http.StatusBadRequest)
+			gameRole, ok := parseGameRole(req.GameRole)
+			if !ok {
+				response.Error(w, r, "game_role must be tank, dps or support", http.StatusBadRequest)
 				return
 			}
-			// Insert the user into the team_members table
-			_, err = db.Exec("INSERT INTO team_members (user_id, team_id, role) VALUES ($1, $2, $3)", req.UserID, req.TeamID, req.Role)
+			// Insert the user into the team; a duplicate membership is a conflict
+			err = s.Memberships.AddMember(r.Context(), store.Member{TeamID: teamID, UserID: req.UserID, Role: role, GameRole: gameRole})
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.StoreError(w, r, err, "User or team not found")
 				return
 			}
 			w.WriteHeader(http.StatusCreated)
 
 		case http.MethodDelete:
-			var req struct {
-				UserID int `json:"user_id"`
-				TeamID int `json:"team_id"`
-			}
+			var req RemoveMemberRequest
 			// Decode the request body
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			if req.UserID == 0 || req.TeamID == 0 {
-				http.Error(w, "User ID and Team ID are required", http.StatusBadRequest)
+			if req.UserID == 0 {
+				response.Error(w, r, "User ID is required", http.StatusBadRequest)
 				return
 			}
-			// Delete the user from the team_members table
-			_, err := db.Exec("DELETE FROM team_members WHERE user_id = $1 AND team_id = $2", req.UserID, req.TeamID)
+			// Captains can't remove someone who outranks them
+			member, err := s.Memberships.GetMember(r.Context(), teamID, req.UserID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.StoreError(w, r, err, "User is not a member of this team")
+				return
+			}
+			if outranksCaller(r, member) {
+				response.Error(w, r, "You can't remove a member above you", http.StatusForbidden)
+				return
+			}
+			if err := s.Memberships.RemoveMember(r.Context(), teamID, req.UserID); err != nil {
+				response.StoreError(w, r, err, "User is not a member of this team")
 				return
 			}
 			w.WriteHeader(http.StatusNoContent)
 
 		case http.MethodPut:
-			var req struct {
-				UserID int    `json:"user_id"`
-				TeamID int    `json:"team_id"`
-				Role   string `json:"role"`
-			}
+			var req UpdateMemberRequest
 			// Decode the request body
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			if req.UserID == 0 || req.TeamID == 0 {
-				http.Error(w, "User ID and Team ID are required", http.StatusBadRequest)
+			if req.UserID == 0 {
+				response.Error(w, r, "User ID is required", http.StatusBadRequest)
 				return
 			}
-			// Update the user's role in the team_members table
-			_, err := db.Exec("UPDATE team_members SET role = $1 WHERE user_id = $2 AND team_id = $3", req.Role, req.UserID, req.TeamID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if req.Role == "" && req.GameRole == nil {
+				response.Error(w, r, "Role or game_role is required", http.StatusBadRequest)
 				return
 			}
+			var role string
+			if req.Role != "" {
+				var err error
+				if role, err = grantableRole(r, req.Role); err != nil {
+					response.Error(w, r, err.Error(), http.StatusBadRequest)
+					return
+				}
+				// Nor can they change the role of someone who outranks them
+				member, err := s.Memberships.GetMember(r.Context(), teamID, req.UserID)
+				if err != nil {
+					response.StoreError(w, r, err, "User is not a member of this team")
+					return
+				}
+				if outranksCaller(r, member) {
+					response.Error(w, r, "You can't change the role of a member above you", http.StatusForbidden)
+					return
+				}
+			}
+			if req.GameRole != nil {
+				gameRole, ok := parseGameRole(*req.GameRole)
+				if !ok {
+					response.Error(w, r, "game_role must be tank, dps or support", http.StatusBadRequest)
+					return
+				}
+				if err := s.Memberships.SetMemberGameRole(r.Context(), teamID, req.UserID, gameRole); err != nil {
+					response.StoreError(w, r, err, "User is not a member of this team")
+					return
+				}
+			}
+			// Update the user's role in the team
+			if role != "" {
+				if err := s.Memberships.UpdateMemberRole(r.Context(), teamID, req.UserID, role); err != nil {
+					response.StoreError(w, r, err, "User is not a member of this team")
+					return
+				}
+			}
 			w.WriteHeader(http.StatusOK)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
-func TimeSlotsHandler(db *sql.DB) http.HandlerFunc {
+
+func TimeSlotsHandler(s *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		slotID, hasSlotID := urlParamInt(r, "timeSlotID")
+
 		switch r.Method {
 		case http.MethodGet:
-			var req struct {
-				TeamID int `json:"team_id"`
-			}
-			rows, err := db.Query("SELECT weekday, time FROM time_slots WHERE team_id = $1", req.TeamID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer rows.Close()
-			var slots []map[string]any
-			for rows.Next() {
-				var day, time string
-				err := rows.Scan(&day, &time)
+			if hasSlotID {
+				slot, err := s.TimeSlots.GetTimeSlot(r.Context(), slotID)
 				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
+					response.StoreError(w, r, err, "Time slot not found")
 					return
 				}
-				slots = append(slots, map[string]any{
-					"day":  day,
-					"time": time,
-				})
+				response.JSON(w, http.StatusOK, newTimeSlot(slot))
+				return
 			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(slots)
 
-		case http.MethodPost:
-			var req struct {
-				Day    string `json:"day"`
-				Time   string `json:"time"`
-				TeamID int    `json:"team_id"`
+			// ?team_id= selects a team's slots; without it the global slots are listed
+			var teamID int
+			if v := r.URL.Query().Get("team_id"); v != "" {
+				var err error
+				if teamID, err = strconv.Atoi(v); err != nil {
+					response.Error(w, r, "Invalid team ID", http.StatusBadRequest)
+					return
+				}
 			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			slots, err := s.TimeSlots.ListTimeSlots(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			if req.Day == "" || req.Time == "" {
-				http.Error(w, "Day and Time are required", http.StatusBadRequest)
+			resp := make([]TimeSlot, 0, len(slots))
+			for _, slot := range slots {
+				resp = append(resp, newTimeSlot(slot))
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodPost:
+			// Team slots come from each team's grid, so only global slots
+			// are created here
+			var req TimeSlotRequest
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			_, err := db.Exec("INSERT INTO time_slots (weekday, time, team_id) VALUES ($1, $2, $3)", req.Day, req.Time, req.TeamID)
+			slot, err := s.TimeSlots.CreateTimeSlot(r.Context(), store.TimeSlot{Weekday: req.Day, Time: req.Time})
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			w.WriteHeader(http.StatusCreated)
+			response.JSON(w, http.StatusCreated, newTimeSlot(slot))
 
 		case http.MethodDelete:
-			var req struct {
-				Day    string `json:"day"`
-				Time   string `json:"time"`
-				TeamID int    `json:"team_id"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
-				return
-			}
-			if req.Day == "" || req.Time == "" {
-				http.Error(w, "Day and Time are required", http.StatusBadRequest)
-				return
-			}
-			_, err := db.Exec("DELETE FROM time_slots WHERE weekday = $1 AND time = $2 AND team_id = $3", req.Day, req.Time, req.TeamID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if err := s.TimeSlots.DeleteTimeSlot(r.Context(), slotID); err != nil {
+				response.StoreError(w, r, err, "Time slot not found")
 				return
 			}
 			w.WriteHeader(http.StatusNoContent)
 
 		case http.MethodPut:
-			var req struct {
-				Day     string `json:"day"`
-				Time    string `json:"time"`
-				TeamID  int    `json:"team_id"`
-				NewDay  string `json:"new_day"`
-				NewTime string `json:"new_time"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+			var req TimeSlotRequest
+			if !response.Decode(w, r, &req) {
 				return
 			}
-			if req.Day == "" || req.Time == "" || req.NewDay == "" || req.NewTime == "" {
-				http.Error(w, "Day, Time, New Day and New Time are required", http.StatusBadRequest)
-				return
-			}
-			_, err := db.Exec("UPDATE time_slots SET weekday = $1, time = $2 WHERE weekday = $3 AND time = $4 AND team_id = $5", req.NewDay, req.NewTime, req.Day, req.Time, req.TeamID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if err := s.TimeSlots.UpdateTimeSlot(r.Context(), store.TimeSlot{ID: slotID, Weekday: req.Day, Time: req.Time}); err != nil {
+				response.StoreError(w, r, err, "Time slot not found")
 				return
 			}
 			w.WriteHeader(http.StatusOK)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-func ScheduleHandler(db *sql.DB) http.HandlerFunc {
+// ScheduleHandler lists the team's time slots. They are generated from the
+// team's grid, which is edited through GridHandler.
+func ScheduleHandler(s *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
 		switch r.Method {
 		case http.MethodGet:
-			// Fetch all time slots
-			rows, err := db.Query("SELECT slot_id, weekday, time FROM time_slots")
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer rows.Close()
-			var slots []map[string]any
-			for rows.Next() {
-				var slotID int
-				var weekday, time string
-				err := rows.Scan(&slotID, &weekday, &time)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				slots = append(slots, map[string]any{
-					"slot_id": slotID,
-					"weekday": weekday,
-					"time":    time,
-				})
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(slots)
-
-		case http.MethodPost:
-			// Create a new time slot
-			var req struct {
-				Weekday string `json:"weekday"`
-				Time    string `json:"time"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
-				return
-			}
-			if req.Weekday == "" || req.Time == "" {
-				http.Error(w, "Weekday and Time are required", http.StatusBadRequest)
+			// Fetch the team's time slots
+			if _, err := s.Teams.GetTeam(r.Context(), teamID); err != nil {
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			_, err := db.Exec("INSERT INTO time_slots (weekday, time) VALUES ($1, $2)", req.Weekday, req.Time)
+			slots, err := teamSlots(r.Context(), s, teamID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusCreated)
-
-		case http.MethodDelete:
-			// Delete a time slot
-			var req struct {
-				SlotID int `json:"slot_id"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
+				response.StoreError(w, r, err, "Team not found")
 				return
 			}
-			if req.SlotID == 0 {
-				http.Error(w, "Slot ID is required", http.StatusBadRequest)
-				return
+			resp := make([]TimeSlot, 0, len(slots))
+			for _, slot := range slots {
+				resp = append(resp, newTimeSlot(slot))
 			}
-			_, err := db.Exec("DELETE FROM time_slots WHERE slot_id = $1", req.SlotID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusNoContent)
-
-		case http.MethodPut:
-			// Update a time slot
-			var req struct {
-				SlotID  int    `json:"slot_id"`
-				Weekday string `json:"weekday"`
-				Time    string `json:"time"`
-			}
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid input", http.StatusBadRequest)
-				return
-			}
-			if req.SlotID == 0 || req.Weekday == "" || req.Time == "" {
-				http.Error(w, "Slot ID, Weekday and Time are required", http.StatusBadRequest)
-				return
-			}
-			_, err := db.Exec("UPDATE time_slots SET weekday = $1, time = $2 WHERE slot_id = $3", req.Weekday, req.Time, req.SlotID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
+			response.JSON(w, http.StatusOK, resp)
 
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
@@ -2,47 +2,422 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/KhrisKringle/Vivacity_website-main/server/api"
-
-	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
 )
 
-func TestTeamHandler(t *testing.T) {
-	db, mock, err := sqlmock.New()
+// testAuth trusts the X-Test-User header as the caller's user ID.
+func testAuth(s *store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := strconv.Atoi(r.Header.Get("X-Test-User"))
+			if err != nil {
+				middleware.Unauthorized(w, r, "no test user")
+				return
+			}
+			principal, err := middleware.LoadPrincipal(r.Context(), s, userID)
+			if err != nil {
+				middleware.Unauthorized(w, r, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(middleware.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func newRouter(s *store.Store) http.Handler {
+	return api.NewRouter(api.Options{Store: s, Authenticate: testAuth(s)})
+}
+
+// newAdmin creates an organization admin to act as the caller.
+func newAdmin(t *testing.T, s *store.Store) store.Player {
+	t.Helper()
+	ctx := context.Background()
+	admin, err := s.Players.UpsertBattleNetPlayer(ctx, 1, "Admin#0001")
 	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		t.Fatal(err)
+	}
+	if err := s.Players.SetPlayerAdmin(ctx, admin.ID, true); err != nil {
+		t.Fatal(err)
 	}
-	defer db.Close()
+	return admin
+}
+
+// newTeam is the setup most handler tests share: a memory store holding the
+// team Alpha with a member for each role, in order, and the API router over
+// it. Member i is the player with Battle.net ID 1001+i.
+func newTeam(t *testing.T, roles ...string) (*store.Store, http.Handler, store.Team, []store.Player) {
+	t.Helper()
+	ctx := context.Background()
+	s := store.NewMemory()
+	team, err := s.Teams.CreateTeam(ctx, "Alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members []store.Player
+	for i, role := range roles {
+		p, err := s.Players.UpsertBattleNetPlayer(ctx, int64(1001+i), fmt.Sprintf("Member%d#1234", i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: p.ID, Role: role}); err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, p)
+	}
+	return s, newRouter(s), team, members
+}
+
+// do sends a JSON request through the API router as userID (zero for an
+// anonymous request) and returns the recorder.
+func do(t *testing.T, h http.Handler, method, path string, body any, userID int) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if userID != 0 {
+		req.Header.Set("X-Test-User", strconv.Itoa(userID))
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestTeamHandler(t *testing.T) {
+	s := store.NewMemory()
+	h := newRouter(s)
+	admin := newAdmin(t, s)
 
 	// Test POST
-	teamName := "New Team"
-	body, _ := json.Marshal(map[string]string{"name": teamName})
+	rr := do(t, h, http.MethodPost, "/teams/", map[string]string{"name": "New Team"}, admin.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	var created api.Team
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Name != "New Team" {
+		t.Fatalf("unexpected team: %+v", created)
+	}
+	teamPath := "/teams/" + strconv.Itoa(created.ID) + "/"
 
-	// 1. Expect the INSERT query
-	mock.ExpectExec("INSERT INTO teams").
-		WithArgs(teamName).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Test PUT
+	rr = do(t, h, http.MethodPut, teamPath, map[string]string{"name": "Renamed"}, admin.ID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT returned %v: %s", rr.Code, rr.Body)
+	}
 
-	// 2. ADD THIS: Expect the SELECT query to get the new ID
-	mock.ExpectQuery("SELECT id FROM teams WHERE name = \\$1").
-		WithArgs(teamName).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	// Test GET
+	rr = do(t, h, http.MethodGet, teamPath, nil, admin.ID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET returned %v", rr.Code)
+	}
+	var got struct {
+		api.Team
+		Members []api.TeamMember `json:"members"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Renamed" || len(got.Members) != 0 {
+		t.Errorf("unexpected team profile: %+v", got)
+	}
 
-	req, err := http.NewRequest(http.MethodPost, "/api/teams", bytes.NewBuffer(body))
-	if err != nil {
+	// Test DELETE
+	rr = do(t, h, http.MethodDelete, teamPath, nil, admin.ID)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned %v", rr.Code)
+	}
+	rr = do(t, h, http.MethodGet, teamPath, nil, admin.ID)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE returned %v, want 404", rr.Code)
+	}
+}
+
+func TestTeamHandlerRequiresName(t *testing.T) {
+	s := store.NewMemory()
+	h := newRouter(s)
+
+	rr := do(t, h, http.MethodPost, "/teams/", map[string]string{"name": ""}, newAdmin(t, s).ID)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	s := store.NewMemory()
+	h := newRouter(s)
+	admin := newAdmin(t, s)
+	player, _ := s.Players.UpsertBattleNetPlayer(context.Background(), 2, "Player#1234")
+
+	tests := []struct {
+		method, path string
+		body         any
+		userID       int
+		status       int
+		code         string
+		fields       []string
+	}{
+		{http.MethodPost, "/teams/", map[string]string{"name": "Alpha", "tag": "A"}, admin.ID, http.StatusBadRequest, response.CodeInvalid, []string{"tag"}},
+		{http.MethodPost, "/teams/", map[string]any{"name": 7}, admin.ID, http.StatusBadRequest, response.CodeInvalid, []string{"name"}},
+		{http.MethodPost, "/teams/", map[string]string{"name": strings.Repeat("a", 256)}, admin.ID, http.StatusBadRequest, response.CodeInvalid, []string{"name"}},
+		{http.MethodPut, "/players/" + strconv.Itoa(player.ID) + "/", map[string]string{"username": "no-tag", "timezone": "Nowhere"}, player.ID, http.StatusBadRequest, response.CodeInvalid, []string{"username", "timezone"}},
+		{http.MethodPost, "/timeslots/", map[string]string{"day": "Someday", "time": "25:00"}, admin.ID, http.StatusBadRequest, response.CodeInvalid, []string{"day", "time"}},
+		{http.MethodPost, "/teams/", map[string]string{"name": "Alpha"}, player.ID, http.StatusForbidden, response.CodeForbidden, nil},
+		{http.MethodGet, "/teams/999", nil, admin.ID, http.StatusNotFound, response.CodeNotFound, nil},
+		{http.MethodGet, "/teams/", nil, 0, http.StatusUnauthorized, response.CodeUnauthorized, nil},
+	}
+	for _, tt := range tests {
+		rr := do(t, h, tt.method, tt.path, tt.body, tt.userID)
+		if rr.Code != tt.status {
+			t.Errorf("%s %s: got %d want %d: %s", tt.method, tt.path, rr.Code, tt.status, rr.Body)
+			continue
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: content type %q", tt.method, tt.path, ct)
+		}
+		var env response.Envelope
+		if err := json.NewDecoder(rr.Body).Decode(&env); err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		var fields []string
+		for _, f := range env.Error.Fields {
+			fields = append(fields, f.Field)
+		}
+		if env.Error.Code != tt.code || env.Error.Message == "" || strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s %s: got %+v", tt.method, tt.path, env.Error)
+		}
+	}
+}
+
+func TestTeamMembersHandler(t *testing.T) {
+	s, h, team, members := newTeam(t, "captain")
+	captain := members[0]
+	ctx := context.Background()
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "John#1234")
+	membersPath := "/teams/" + strconv.Itoa(team.ID) + "/members"
+
+	rr := do(t, h, http.MethodPost, membersPath, map[string]any{"user_id": player.ID, "role": "player"}, captain.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = do(t, h, http.MethodPost, membersPath, map[string]any{"user_id": player.ID, "role": "player"}, captain.ID)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("duplicate POST returned %v, want 409", rr.Code)
+	}
+
+	rr = do(t, h, http.MethodGet, membersPath, nil, player.ID)
+	var list []api.TeamMember
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
 		t.Fatal(err)
 	}
+	if len(list) != 2 || list[0].Username != "John#1234" || list[0].Role != "player" {
+		t.Errorf("unexpected members: %+v", list)
+	}
 
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(api.TeamHandler(db))
-	handler.ServeHTTP(rr, req)
+	// A captain can't promote anyone, themselves included, above captain,
+	// nor store a role the hierarchy doesn't know
+	for _, body := range []map[string]any{
+		{"user_id": captain.ID, "role": "manager"},
+		{"user_id": player.ID, "role": "manager"},
+		{"user_id": player.ID, "role": "overlord"},
+	} {
+		if rr := do(t, h, http.MethodPut, membersPath, body, captain.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("PUT %v returned %v, want 400", body, rr.Code)
+		}
+	}
+	if rr := do(t, h, http.MethodDelete, "/teams/"+strconv.Itoa(team.ID)+"/", nil, captain.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("captain DELETE team returned %v, want 403", rr.Code)
+	}
+	if m, _ := s.Memberships.GetMember(ctx, team.ID, captain.ID); m.Role != "captain" {
+		t.Errorf("captain's role is %q after rejected promotion", m.Role)
+	}
+	manager, _ := s.Players.UpsertBattleNetPlayer(ctx, 1003, "Boss#1234")
+	if err := s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: manager.ID, Role: "manager"}); err != nil {
+		t.Fatal(err)
+	}
+	if rr := do(t, h, http.MethodPut, membersPath, map[string]any{"user_id": manager.ID, "role": "sub"}, captain.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("captain demoting a manager returned %v, want 403", rr.Code)
+	}
+	if rr := do(t, h, http.MethodDelete, membersPath, map[string]any{"user_id": manager.ID}, captain.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("captain removing a manager returned %v, want 403", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPut, membersPath, map[string]any{"user_id": player.ID, "role": "coach"}, captain.ID); rr.Code != http.StatusOK {
+		t.Errorf("PUT coach returned %v, want 200", rr.Code)
+	}
+
+	// A plain player can't manage the roster
+	rr = do(t, h, http.MethodDelete, membersPath, map[string]any{"user_id": captain.ID}, player.ID)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("player DELETE returned %v, want 403", rr.Code)
+	}
+
+	rr = do(t, h, http.MethodDelete, membersPath, map[string]any{"user_id": player.ID}, captain.ID)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("DELETE returned %v", rr.Code)
+	}
+}
+
+func TestAvailabilityHandler(t *testing.T) {
+	_, h, team, members := newTeam(t, "player")
+	player := members[0]
+	path := "/teams/" + strconv.Itoa(team.ID) + "/availability"
+
+	body := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Monday", Time: "19:00"}}}
+	rr := do(t, h, http.MethodPost, path, body, player.ID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("POST returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr = do(t, h, http.MethodGet, path, nil, player.ID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET returned %v: %s", rr.Code, rr.Body)
+	}
+	var slots []api.SlotAvailability
+	if err := json.NewDecoder(rr.Body).Decode(&slots); err != nil {
+		t.Fatal(err)
+	}
+	var available int
+	for _, slot := range slots {
+		if slot.Available {
+			available++
+			if slot.Day != "Monday" || slot.Time != "19:00" {
+				t.Errorf("wrong slot marked available: %+v", slot)
+			}
+		}
+	}
+	if len(slots) != 14 || available != 1 {
+		t.Errorf("got %d slots with %d available, want 14 with 1", len(slots), available)
+	}
+
+	rr = do(t, h, http.MethodPost, path, api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Monday", Time: "03:00"}}}, player.ID)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown slot returned %v, want 400", rr.Code)
+	}
+}
+
+func TestMutatingRoutesRequireAuthorization(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	outsider, _ := s.Players.UpsertBattleNetPlayer(ctx, 2001, "Out#1234")
+	other, _ := s.Players.UpsertBattleNetPlayer(ctx, 2002, "Other#1234")
+	h := newRouter(s)
+	teamPath := "/teams/" + strconv.Itoa(team.ID) + "/"
+
+	cases := []struct {
+		method, path string
+		body         any
+		userID       int
+		want         int
+	}{
+		{http.MethodGet, "/teams/", nil, 0, http.StatusUnauthorized},
+		{http.MethodGet, "/timeslots", nil, 0, http.StatusUnauthorized},
+		{http.MethodPost, "/teams/", map[string]string{"name": "X"}, 0, http.StatusUnauthorized},
+		{http.MethodGet, teamPath + "availability", nil, outsider.ID, http.StatusNotFound},
+		{http.MethodPost, "/teams/", map[string]string{"name": "X"}, outsider.ID, http.StatusForbidden},
+		{http.MethodPut, teamPath, map[string]string{"name": "X"}, outsider.ID, http.StatusForbidden},
+		{http.MethodDelete, teamPath, nil, outsider.ID, http.StatusForbidden},
+		{http.MethodPut, teamPath + "grid", map[string]any{"slot_minutes": 60, "days": []any{}}, outsider.ID, http.StatusForbidden},
+		{http.MethodPut, "/players/" + strconv.Itoa(other.ID) + "/", map[string]string{"username": "X"}, outsider.ID, http.StatusForbidden},
+		{http.MethodPut, "/players/" + strconv.Itoa(outsider.ID) + "/", map[string]string{"username": "Mee#1234"}, outsider.ID, http.StatusOK},
+	}
+	for _, c := range cases {
+		rr := do(t, h, c.method, c.path, c.body, c.userID)
+		if rr.Code != c.want {
+			t.Errorf("%s %s as %d: got %v want %v", c.method, c.path, c.userID, rr.Code, c.want)
+		}
+		if rr.Code == http.StatusForbidden && rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s %s: 403 is not JSON", c.method, c.path)
+		}
+	}
+}
+
+func TestAvailabilityTimezones(t *testing.T) {
+	_, h, team, members := newTeam(t, "coach")
+	player := members[0]
+	teamPath := "/teams/" + strconv.Itoa(team.ID) + "/"
+
+	grid := api.Grid{SlotMinutes: 120, Timezone: "America/New_York", Days: []api.GridDay{{Weekday: "Monday", Start: "19:00", End: "23:00"}}}
+	if rr := do(t, h, http.MethodPut, teamPath+"grid", grid, player.ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT grid returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodPut, "/players/"+strconv.Itoa(player.ID)+"/", map[string]string{"timezone": "Europe/Berlin"}, player.ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT timezone returned %v: %s", rr.Code, rr.Body)
+	}
+
+	// Select Monday 19:00 New York time for the two weeks around the US
+	// switch to daylight saving time on 8 March 2026. Europe only switches
+	// on 29 March, so the Berlin wall-clock time moves by an hour.
+	week := teamPath + "availability?week=2026-03-02"
+	body := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Tuesday", Time: "01:00"}}, Weeks: 2}
+	if rr := do(t, h, http.MethodPost, week, body, player.ID); rr.Code != http.StatusOK {
+		t.Fatalf("POST returned %v: %s", rr.Code, rr.Body)
+	}
+
+	cases := []struct {
+		path, day, time, startsAt string
+	}{
+		{week, "Tuesday", "01:00", "2026-03-03T01:00:00+01:00"},
+		{teamPath + "availability?week=2026-03-09", "Tuesday", "00:00", "2026-03-10T00:00:00+01:00"},
+		{teamPath + "availability?week=2026-03-09&tz=America/New_York", "Monday", "19:00", "2026-03-09T19:00:00-04:00"},
+	}
+	for _, c := range cases {
+		rr := do(t, h, http.MethodGet, c.path, nil, player.ID)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s returned %v: %s", c.path, rr.Code, rr.Body)
+		}
+		var slots []api.SlotAvailability
+		if err := json.NewDecoder(rr.Body).Decode(&slots); err != nil {
+			t.Fatal(err)
+		}
+		if len(slots) != 2 {
+			t.Fatalf("GET %s: got %d slots, want 2", c.path, len(slots))
+		}
+		got := slots[0]
+		if got.Day != c.day || got.Time != c.time || got.StartsAt.Format(time.RFC3339) != c.startsAt || !got.Available || slots[1].Available {
+			t.Errorf("GET %s: unexpected slots %+v", c.path, slots)
+		}
+	}
+
+	// Shrinking the slots keeps the player available where they still fit
+	grid.SlotMinutes = 60
+	if rr := do(t, h, http.MethodPut, teamPath+"grid", grid, player.ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT grid returned %v: %s", rr.Code, rr.Body)
+	}
+	rr := do(t, h, http.MethodGet, week, nil, player.ID)
+	var slots []api.SlotAvailability
+	if err := json.NewDecoder(rr.Body).Decode(&slots); err != nil {
+		t.Fatal(err)
+	}
+	var available []string
+	for _, slot := range slots {
+		if slot.Available {
+			available = append(available, slot.Time)
+		}
+	}
+	if len(available) != 2 || available[0] != "01:00" || available[1] != "02:00" {
+		t.Errorf("available after grid change = %v, want [01:00 02:00]", available)
+	}
 
-	if status := rr.Code; status != http.StatusCreated {
-		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	if rr := do(t, h, http.MethodGet, week+"&tz=Not/AZone", nil, player.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown tz returned %v, want 400", rr.Code)
 	}
 }
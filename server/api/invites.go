@@ -0,0 +1,505 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// InviteLink is a shareable link to join a team. Token is only shown when
+// the link is created.
+type InviteLink struct {
+	ID        int        `json:"id"`
+	Token     string     `json:"token,omitempty"`
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   int        `json:"max_uses"` // 0 is unlimited
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// JoinRequest is a pending invite to, or request to join, a team
+type JoinRequest struct {
+	ID        int       `json:"id"`
+	TeamID    int       `json:"team_id"`
+	TeamName  string    `json:"team_name"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InviteLinkRequest creates an invite link.
+type InviteLinkRequest struct {
+	Role           string `json:"role"`
+	ExpiresInHours *int   `json:"expires_in_hours"` // default 168
+	MaxUses        int    `json:"max_uses"`         // 0 is unlimited
+}
+
+// RedeemInviteRequest joins a team with an invite link's token.
+type RedeemInviteRequest struct {
+	Token string `json:"token"`
+}
+
+// InviteRequest invites a player to a team by battletag.
+type InviteRequest struct {
+	Battletag string `json:"battletag"`
+	Role      string `json:"role"`
+	Message   string `json:"message"`
+}
+
+// JoinTeamRequest asks to join a team. Both fields are optional.
+type JoinTeamRequest struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
+
+// ApproveRequest approves a request to join, optionally with another role.
+type ApproveRequest struct {
+	Role string `json:"role"`
+}
+
+const (
+	// defaultInviteHours is how long an invite link lasts unless the
+	// captain says otherwise, and maxInviteHours is the longest allowed.
+	defaultInviteHours = 7 * 24
+	maxInviteHours     = 30 * 24
+	// maxJoinMessage caps the note sent with a join request or invite.
+	maxJoinMessage = 500
+)
+
+func newInviteLink(l store.InviteLink) InviteLink {
+	resp := InviteLink{ID: l.ID, Role: l.Role, MaxUses: l.MaxUses, Uses: l.Uses, CreatedAt: l.CreatedAt}
+	if !l.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &l.ExpiresAt
+	}
+	return resp
+}
+
+func newJoinRequest(jr store.JoinRequest) JoinRequest {
+	return JoinRequest{
+		ID:        jr.ID,
+		TeamID:    jr.TeamID,
+		TeamName:  jr.TeamName,
+		UserID:    jr.UserID,
+		Username:  jr.Username,
+		Role:      jr.Role,
+		Message:   jr.Message,
+		CreatedAt: jr.CreatedAt,
+	}
+}
+
+// joinRequests converts the requests of one kind: invites when invited is
+// true, requests to join otherwise.
+func joinRequests(all []store.JoinRequest, invited bool) []JoinRequest {
+	resp := make([]JoinRequest, 0, len(all))
+	for _, jr := range all {
+		if jr.Invited == invited {
+			resp = append(resp, newJoinRequest(jr))
+		}
+	}
+	return resp
+}
+
+// joinConflict explains a 409 from creating or accepting a join request.
+const joinConflict = "Player is already a member or has a pending invite or join request"
+
+// InviteLinksHandler lists (GET) and creates (POST) a team's invite links.
+// A new link's token is only shown in the POST response.
+func InviteLinksHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			links, err := s.Invites.ListInviteLinks(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			resp := make([]InviteLink, 0, len(links))
+			for _, l := range links {
+				resp = append(resp, newInviteLink(l))
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodPost:
+			var req InviteLinkRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			role, err := grantableRole(r, req.Role)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			hours := defaultInviteHours
+			if req.ExpiresInHours != nil {
+				hours = *req.ExpiresInHours
+			}
+			if hours < 1 || hours > maxInviteHours {
+				response.Error(w, r, "expires_in_hours must be between 1 and "+strconv.Itoa(maxInviteHours), http.StatusBadRequest)
+				return
+			}
+			if req.MaxUses < 0 {
+				response.Error(w, r, "max_uses must not be negative", http.StatusBadRequest)
+				return
+			}
+			caller, _ := middleware.PrincipalFromContext(r.Context())
+
+			token, hash, err := middleware.NewToken()
+			if err != nil {
+				log.Printf("Error generating invite token: %v", err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			link, err := s.Invites.CreateInviteLink(r.Context(), store.InviteLink{
+				TeamID:    teamID,
+				Role:      role,
+				CreatedBy: caller.UserID,
+				ExpiresAt: time.Now().Add(time.Duration(hours) * time.Hour).UTC().Truncate(time.Second),
+				MaxUses:   req.MaxUses,
+			}, hash)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			resp := newInviteLink(link)
+			resp.Token = token
+			response.JSON(w, http.StatusCreated, resp)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// InviteLinkHandler revokes (DELETE) one of a team's invite links.
+func InviteLinkHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		inviteID, _ := urlParamInt(r, "invite_id")
+		if r.Method != http.MethodDelete {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.Invites.DeleteInviteLink(r.Context(), teamID, inviteID); err != nil {
+			response.StoreError(w, r, err, "Invite link not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RedeemInviteLinkHandler adds the caller to the team of the invite link
+// whose token is posted, with the link's role.
+func RedeemInviteLinkHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		var req RedeemInviteRequest
+		if !response.Decode(w, r, &req) {
+			return
+		}
+		token := strings.TrimSpace(req.Token)
+		if token == "" {
+			response.Error(w, r, "token is required", http.StatusBadRequest)
+			return
+		}
+		m, err := s.Invites.RedeemInviteLink(r.Context(), middleware.HashToken(token), caller.UserID, time.Now())
+		if errors.Is(err, store.ErrConflict) {
+			response.Error(w, r, "You are already a member of this team", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			response.StoreError(w, r, err, "Invite link is invalid, expired or used up")
+			return
+		}
+		response.JSON(w, http.StatusCreated, newMembership(m))
+	}
+}
+
+// TeamInvitesHandler lists (GET) the team's pending invites, or invites a
+// player by battletag (POST). The player joins once they accept.
+func TeamInvitesHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			all, err := s.Invites.ListTeamJoinRequests(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, joinRequests(all, true))
+
+		case http.MethodPost:
+			var req InviteRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			battletag := strings.TrimSpace(req.Battletag)
+			var fields response.Fields
+			if fields.Required("battletag", battletag) {
+				fields.Battletag("battletag", battletag)
+			}
+			if len(fields) > 0 {
+				response.Invalid(w, r, fields)
+				return
+			}
+			if len(req.Message) > maxJoinMessage {
+				response.Error(w, r, "message must be at most "+strconv.Itoa(maxJoinMessage)+" bytes", http.StatusBadRequest)
+				return
+			}
+			role, err := grantableRole(r, req.Role)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			player, err := s.Players.GetPlayerByUsername(r.Context(), battletag)
+			if err != nil {
+				response.StoreError(w, r, err, "No player with that battletag")
+				return
+			}
+			caller, _ := middleware.PrincipalFromContext(r.Context())
+			jr, err := s.Invites.CreateJoinRequest(r.Context(), store.JoinRequest{
+				TeamID:    teamID,
+				UserID:    player.ID,
+				Invited:   true,
+				Role:      role,
+				Message:   strings.TrimSpace(req.Message),
+				CreatedBy: caller.UserID,
+			})
+			if errors.Is(err, store.ErrConflict) {
+				response.Error(w, r, joinConflict, http.StatusConflict)
+				return
+			}
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusCreated, newJoinRequest(jr))
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// JoinRequestsHandler lists a team's pending requests to join (GET, for
+// captains), or asks to join the team as the caller (POST). The requested
+// role is a suggestion; the captain who approves picks the final one.
+func JoinRequestsHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			all, err := s.Invites.ListTeamJoinRequests(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, joinRequests(all, false))
+
+		case http.MethodPost:
+			caller, ok := middleware.PrincipalFromContext(r.Context())
+			if !ok {
+				middleware.Unauthorized(w, r, "authentication required")
+				return
+			}
+			var req JoinTeamRequest
+			if r.ContentLength != 0 {
+				if !response.Decode(w, r, &req) {
+					return
+				}
+			}
+			role := authz.RolePlayer
+			if req.Role != "" {
+				var ok bool
+				if role, ok = authz.LookupRole(req.Role); !ok {
+					response.Error(w, r, "role must be one of sub, player, coach, captain or manager", http.StatusBadRequest)
+					return
+				}
+			}
+			if len(req.Message) > maxJoinMessage {
+				response.Error(w, r, "message must be at most "+strconv.Itoa(maxJoinMessage)+" bytes", http.StatusBadRequest)
+				return
+			}
+			jr, err := s.Invites.CreateJoinRequest(r.Context(), store.JoinRequest{
+				TeamID:    teamID,
+				UserID:    caller.UserID,
+				Role:      string(role),
+				Message:   strings.TrimSpace(req.Message),
+				CreatedBy: caller.UserID,
+			})
+			if errors.Is(err, store.ErrConflict) {
+				response.Error(w, r, "You are already a member, have already asked to join or have a pending invite from this team", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusCreated, newJoinRequest(jr))
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// teamJoinRequest loads the {request_id} request of the {team_id} team, of
+// the given kind, writing a 404 when there is none.
+func teamJoinRequest(w http.ResponseWriter, r *http.Request, s *store.Store, invited bool) (store.JoinRequest, bool) {
+	teamID, _ := urlParamInt(r, "team_id")
+	requestID, _ := urlParamInt(r, "request_id")
+	jr, err := s.Invites.GetJoinRequest(r.Context(), requestID)
+	if err == nil && (jr.TeamID != teamID || jr.Invited != invited) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		response.StoreError(w, r, err, "Not found")
+		return store.JoinRequest{}, false
+	}
+	return jr, true
+}
+
+// playerJoinRequest is teamJoinRequest for the {user_id} player's requests.
+func playerJoinRequest(w http.ResponseWriter, r *http.Request, s *store.Store, invited bool) (store.JoinRequest, bool) {
+	userID, _ := urlParamInt(r, "user_id")
+	requestID, _ := urlParamInt(r, "request_id")
+	jr, err := s.Invites.GetJoinRequest(r.Context(), requestID)
+	if err == nil && (jr.UserID != userID || jr.Invited != invited) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		response.StoreError(w, r, err, "Not found")
+		return store.JoinRequest{}, false
+	}
+	return jr, true
+}
+
+// acceptJoinRequest turns jr into a membership with role and writes it.
+func acceptJoinRequest(w http.ResponseWriter, r *http.Request, s *store.Store, jr store.JoinRequest, role string) {
+	m, err := s.Invites.AcceptJoinRequest(r.Context(), jr.ID, role)
+	if errors.Is(err, store.ErrConflict) {
+		response.Error(w, r, "Player is already a member of this team", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		response.StoreError(w, r, err, "Not found")
+		return
+	}
+	response.JSON(w, http.StatusCreated, newMembership(m))
+}
+
+// ApproveJoinRequestHandler adds the player who asked to join to the team,
+// with the role in the body or else the one they asked for.
+func ApproveJoinRequestHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ApproveRequest
+		if r.ContentLength != 0 {
+			if !response.Decode(w, r, &req) {
+				return
+			}
+		}
+		jr, ok := teamJoinRequest(w, r, s, false)
+		if !ok {
+			return
+		}
+		if req.Role == "" {
+			req.Role = jr.Role
+		}
+		role, err := grantableRole(r, req.Role)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		acceptJoinRequest(w, r, s, jr, role)
+	}
+}
+
+// TeamJoinRequestHandler deletes (DELETE) a pending invite or request to
+// join of the team: withdrawing the invite, or denying the request.
+func TeamJoinRequestHandler(s *store.Store, invited bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		jr, ok := teamJoinRequest(w, r, s, invited)
+		if !ok {
+			return
+		}
+		if err := s.Invites.DeleteJoinRequest(r.Context(), jr.ID); err != nil {
+			response.StoreError(w, r, err, "Not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PlayerJoinRequestsHandler lists (GET) the player's pending invites when
+// invited is true, or their pending requests to join otherwise.
+func PlayerJoinRequestsHandler(s *store.Store, invited bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+		if r.Method != http.MethodGet {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		all, err := s.Invites.ListPlayerJoinRequests(r.Context(), userID)
+		if err != nil {
+			response.StoreError(w, r, err, "Player not found")
+			return
+		}
+		response.JSON(w, http.StatusOK, joinRequests(all, invited))
+	}
+}
+
+// PlayerJoinRequestHandler deletes (DELETE) one of the player's pending
+// invites or requests to join: declining the invite, or withdrawing the
+// request.
+func PlayerJoinRequestHandler(s *store.Store, invited bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		jr, ok := playerJoinRequest(w, r, s, invited)
+		if !ok {
+			return
+		}
+		if err := s.Invites.DeleteJoinRequest(r.Context(), jr.ID); err != nil {
+			response.StoreError(w, r, err, "Not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AcceptInviteHandler accepts one of the player's invites, joining the team
+// with the role the invite gives.
+func AcceptInviteHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jr, ok := playerJoinRequest(w, r, s, true)
+		if !ok {
+			return
+		}
+		acceptJoinRequest(w, r, s, jr, "")
+	}
+}
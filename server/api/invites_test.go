@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func TestInvitesAndJoinRequests(t *testing.T) {
+	s, h, team, members := newTeam(t, "captain")
+	captain := members[0]
+	ctx := context.Background()
+	ana, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Ana#2222")
+	ben, _ := s.Players.UpsertBattleNetPlayer(ctx, 1003, "Ben#3333")
+	cam, _ := s.Players.UpsertBattleNetPlayer(ctx, 1004, "Cam#4444")
+	teamPath := "/teams/" + strconv.Itoa(team.ID)
+
+	role := func(userID int) string {
+		m, err := s.Memberships.GetMember(ctx, team.ID, userID)
+		if err != nil {
+			return ""
+		}
+		return m.Role
+	}
+
+	// Invite links
+	if rr := do(t, h, http.MethodPost, teamPath+"/invite-links", map[string]any{}, ana.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("link created by a non-member: %v", rr.Code)
+	}
+	for _, bad := range []map[string]any{{"role": "manager"}, {"role": "healer"}, {"expires_in_hours": 0}, {"max_uses": -1}} {
+		if rr := do(t, h, http.MethodPost, teamPath+"/invite-links", bad, captain.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("POST invite link %v returned %v, want 400", bad, rr.Code)
+		}
+	}
+	rr := do(t, h, http.MethodPost, teamPath+"/invite-links", map[string]any{"role": "sub", "max_uses": 1}, captain.ID)
+	var link api.InviteLink
+	if rr.Code != http.StatusCreated || json.NewDecoder(rr.Body).Decode(&link) != nil || link.Token == "" || link.ExpiresAt == nil {
+		t.Fatalf("POST invite link returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodPost, "/invite-links/redeem", map[string]string{"token": "nope"}, ana.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown token returned %v, want 404", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPost, "/invite-links/redeem", map[string]string{"token": link.Token}, captain.ID); rr.Code != http.StatusConflict {
+		t.Errorf("redeemed by a member: %v, want 409", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPost, "/invite-links/redeem", map[string]string{"token": link.Token}, ana.ID); rr.Code != http.StatusCreated || role(ana.ID) != "sub" {
+		t.Fatalf("redeem returned %v (%s), role %q", rr.Code, rr.Body, role(ana.ID))
+	}
+	if rr := do(t, h, http.MethodPost, "/invite-links/redeem", map[string]string{"token": link.Token}, ben.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("used up link returned %v, want 404", rr.Code)
+	}
+	rr = do(t, h, http.MethodGet, teamPath+"/invite-links", nil, captain.ID)
+	var links []api.InviteLink
+	if json.NewDecoder(rr.Body).Decode(&links); len(links) != 1 || links[0].Uses != 1 || links[0].Token != "" {
+		t.Errorf("unexpected links: %+v", links)
+	}
+	if rr := do(t, h, http.MethodDelete, teamPath+"/invite-links/"+strconv.Itoa(link.ID), nil, captain.ID); rr.Code != http.StatusNoContent {
+		t.Errorf("DELETE invite link returned %v", rr.Code)
+	}
+
+	// Invites by battletag
+	if rr := do(t, h, http.MethodPost, teamPath+"/invites", map[string]string{"battletag": "nobody#0000"}, captain.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("invite of an unknown battletag returned %v, want 404", rr.Code)
+	}
+	rr = do(t, h, http.MethodPost, teamPath+"/invites", map[string]string{"battletag": "ben#3333", "role": "coach"}, captain.ID)
+	var invite api.JoinRequest
+	if rr.Code != http.StatusCreated || json.NewDecoder(rr.Body).Decode(&invite) != nil || invite.UserID != ben.ID {
+		t.Fatalf("POST invite returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodPost, teamPath+"/invites", map[string]string{"battletag": "Ben#3333"}, captain.ID); rr.Code != http.StatusConflict {
+		t.Errorf("second invite returned %v, want 409", rr.Code)
+	}
+	benPath := "/players/" + strconv.Itoa(ben.ID)
+	rr = do(t, h, http.MethodGet, benPath+"/invites", nil, ben.ID)
+	var invites []api.JoinRequest
+	if json.NewDecoder(rr.Body).Decode(&invites); len(invites) != 1 || invites[0].TeamName != "Alpha" {
+		t.Errorf("unexpected invites: %+v", invites)
+	}
+	acceptPath := benPath + "/invites/" + strconv.Itoa(invite.ID) + "/accept"
+	if rr := do(t, h, http.MethodPost, acceptPath, nil, ana.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("invite accepted by someone else: %v", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPost, acceptPath, nil, ben.ID); rr.Code != http.StatusCreated || role(ben.ID) != "coach" {
+		t.Fatalf("accept returned %v (%s), role %q", rr.Code, rr.Body, role(ben.ID))
+	}
+
+	// Requests to join
+	camPath := "/players/" + strconv.Itoa(cam.ID)
+	rr = do(t, h, http.MethodPost, teamPath+"/join-requests", map[string]string{"message": "I main Lucio"}, cam.ID)
+	var jr api.JoinRequest
+	if rr.Code != http.StatusCreated || json.NewDecoder(rr.Body).Decode(&jr) != nil || jr.Role != "player" {
+		t.Fatalf("POST join request returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodPost, teamPath+"/join-requests", nil, ana.ID); rr.Code != http.StatusConflict {
+		t.Errorf("join request by a member returned %v, want 409", rr.Code)
+	}
+	if rr := do(t, h, http.MethodGet, teamPath+"/join-requests", nil, cam.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("join requests listed by a non-member: %v", rr.Code)
+	}
+	rr = do(t, h, http.MethodGet, teamPath+"/join-requests", nil, captain.ID)
+	var requests []api.JoinRequest
+	if json.NewDecoder(rr.Body).Decode(&requests); len(requests) != 1 || requests[0].Message != "I main Lucio" {
+		t.Errorf("unexpected join requests: %+v", requests)
+	}
+	requestPath := teamPath + "/join-requests/" + strconv.Itoa(jr.ID)
+	if rr := do(t, h, http.MethodPost, requestPath+"/approve", map[string]string{"role": "manager"}, captain.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("approval above the captain's role returned %v, want 400", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPost, teamPath+"/join-requests/"+strconv.Itoa(invite.ID)+"/approve", nil, captain.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("approving an invite returned %v, want 404", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPost, requestPath+"/approve", map[string]string{"role": "sub"}, captain.ID); rr.Code != http.StatusCreated || role(cam.ID) != "sub" {
+		t.Fatalf("approve returned %v (%s), role %q", rr.Code, rr.Body, role(cam.ID))
+	}
+
+	// Denied and withdrawn requests leave no membership
+	s.Memberships.RemoveMember(ctx, team.ID, cam.ID)
+	rr = do(t, h, http.MethodPost, teamPath+"/join-requests", nil, cam.ID)
+	json.NewDecoder(rr.Body).Decode(&jr)
+	if rr := do(t, h, http.MethodDelete, teamPath+"/join-requests/"+strconv.Itoa(jr.ID), nil, captain.ID); rr.Code != http.StatusNoContent {
+		t.Errorf("deny returned %v", rr.Code)
+	}
+	rr = do(t, h, http.MethodPost, teamPath+"/join-requests", nil, cam.ID)
+	json.NewDecoder(rr.Body).Decode(&jr)
+	if rr := do(t, h, http.MethodDelete, camPath+"/join-requests/"+strconv.Itoa(jr.ID), nil, cam.ID); rr.Code != http.StatusNoContent {
+		t.Errorf("withdraw returned %v", rr.Code)
+	}
+	if role(cam.ID) != "" {
+		t.Errorf("denied player is a member with role %q", role(cam.ID))
+	}
+}
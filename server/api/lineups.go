@@ -0,0 +1,147 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/roster"
+	"github.com/KhrisKringle/Vivacity_website-main/server/schedule"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// LineupPick is a member placed in a suggested lineup. GameRole is the role
+// they play in it; OffRole is set when that isn't their roster role.
+type LineupPick struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	GameRole string `json:"game_role"`
+	Sub      bool   `json:"sub"`
+	OffRole  bool   `json:"off_role"`
+}
+
+// RoleShortfall is how many players a lineup is missing on a role
+type RoleShortfall struct {
+	Role    string `json:"role"`
+	Missing int    `json:"missing"`
+}
+
+// SlotLineup is the lineup suggested for one slot from the members
+// available for it
+type SlotLineup struct {
+	SlotID   int             `json:"slot_id"`
+	Day      string          `json:"day"`
+	Time     string          `json:"time"`
+	StartsAt time.Time       `json:"starts_at"`
+	EndsAt   time.Time       `json:"ends_at"`
+	Full     bool            `json:"full"`
+	Lineup   []LineupPick    `json:"lineup"`
+	Short    []RoleShortfall `json:"short"`
+	Bench    []TeamMember    `json:"bench"`
+}
+
+// LineupSuggestions lists a suggested lineup for every slot of a week
+type LineupSuggestions struct {
+	WeekStart time.Time    `json:"week_start"`
+	Slots     []SlotLineup `json:"slots"`
+}
+
+// LineupsHandler suggests a starting lineup for each slot of a week from the
+// members marked available for it. It accepts the same week and tz
+// parameters as the availability summary, and full_only=true to list only
+// the slots a full lineup can make.
+func LineupsHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		grid, err := s.Grids.GetGrid(r.Context(), teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		weekStart, err := requestWeek(r, grid)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fullOnly := r.URL.Query().Get("full_only") == "true"
+
+		sum, members, err := summarizeWeek(r.Context(), s, teamID, grid, weekStart, schedule.Options{})
+		if errors.Is(err, errInvalidGrid) {
+			log.Printf("Error placing slots for team %d: %v", teamID, err)
+			response.Error(w, r, "Invalid team grid", http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		byID := make(map[int]store.Member, len(members))
+		ids := make([]int, len(members))
+		for i, m := range members {
+			byID[m.UserID] = m
+			ids[i] = m.UserID
+		}
+		profiles, err := s.Profiles.ListGameProfiles(r.Context(), ids)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+
+		resp := LineupSuggestions{WeekStart: weekStart.In(loc), Slots: []SlotLineup{}}
+		for _, slot := range sum.Slots {
+			var available []store.Member
+			for _, m := range slot.Available {
+				available = append(available, byID[m.UserID])
+			}
+			lineup := roster.Suggest(available, profiles)
+			if fullOnly && !lineup.Full() {
+				continue
+			}
+			start := slot.Start.In(loc)
+			sl := SlotLineup{
+				SlotID:   slot.ID,
+				Day:      start.Weekday().String(),
+				Time:     start.Format("15:04"),
+				StartsAt: start,
+				EndsAt:   slot.End.In(loc),
+				Full:     lineup.Full(),
+				Lineup:   []LineupPick{},
+				Short:    []RoleShortfall{},
+				Bench:    []TeamMember{},
+			}
+			for _, p := range lineup.Picks {
+				sl.Lineup = append(sl.Lineup, LineupPick{
+					UserID:   p.Member.UserID,
+					Username: p.Member.Username,
+					Role:     p.Member.Role,
+					GameRole: p.Role,
+					Sub:      p.Sub,
+					OffRole:  p.OffRole,
+				})
+			}
+			for _, short := range lineup.Short {
+				sl.Short = append(sl.Short, RoleShortfall(short))
+			}
+			for _, m := range lineup.Bench {
+				sl.Bench = append(sl.Bench, newTeamMember(m))
+			}
+			resp.Slots = append(resp.Slots, sl)
+		}
+		response.JSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestLineups(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	h := newRouter(s)
+	teamPath := "/teams/" + strconv.Itoa(team.ID)
+
+	roles := []struct{ role, gameRole string }{
+		{"captain", "tank"}, {"player", "dps"}, {"player", "dps"}, {"player", "support"}, {"sub", "support"}, {"coach", ""},
+	}
+	var players []store.Player
+	for i, r := range roles {
+		p, _ := s.Players.UpsertBattleNetPlayer(ctx, int64(3000+i), "Lineup"+strconv.Itoa(i)+"#1234")
+		if err := s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: p.ID, Role: r.role, GameRole: r.gameRole}); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+	grid := api.Grid{SlotMinutes: 60, Timezone: "UTC", Days: []api.GridDay{{Weekday: "Friday", Start: "19:00", End: "21:00"}}}
+	if rr := do(t, h, http.MethodPut, teamPath+"/grid", grid, players[0].ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT grid returned %v: %s", rr.Code, rr.Body)
+	}
+
+	// Everyone can make 19:00; the support sub can't make 20:00
+	week := time.Date(2025, 5, 5, 0, 0, 0, 0, time.UTC)
+	friday := time.Date(2025, 5, 9, 19, 0, 0, 0, time.UTC)
+	for i, p := range players {
+		end := friday.Add(2 * time.Hour)
+		if i == 4 {
+			end = friday.Add(time.Hour)
+		}
+		if err := s.Availability.SetAvailability(ctx, team.ID, p.ID, week, week.AddDate(0, 0, 7), []store.Interval{{Start: friday, End: end}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(query string) api.LineupSuggestions {
+		t.Helper()
+		rr := do(t, h, http.MethodGet, teamPath+"/lineups?week=2025-05-05&tz=UTC"+query, nil, players[1].ID)
+		var got api.LineupSuggestions
+		if rr.Code != http.StatusOK || json.NewDecoder(rr.Body).Decode(&got) != nil {
+			t.Fatalf("lineups returned %v: %s", rr.Code, rr.Body)
+		}
+		return got
+	}
+	got := get("")
+	if len(got.Slots) != 2 {
+		t.Fatalf("want 2 slots, got %+v", got.Slots)
+	}
+	first, second := got.Slots[0], got.Slots[1]
+	if !first.Full || len(first.Lineup) != 5 || len(first.Bench) != 1 || first.Bench[0].ID != players[5].ID {
+		t.Errorf("19:00 lineup: %+v", first)
+	}
+	if last := first.Lineup[4]; last.UserID != players[4].ID || !last.Sub || last.GameRole != "support" {
+		t.Errorf("the support sub should start at 19:00: %+v", first.Lineup)
+	}
+	if second.Full || len(second.Short) != 1 || second.Short[0] != (api.RoleShortfall{Role: "support", Missing: 1}) {
+		t.Errorf("20:00 lineup: %+v", second)
+	}
+
+	if got := get("&full_only=true"); len(got.Slots) != 1 || got.Slots[0].Time != "19:00" {
+		t.Errorf("full_only returned %+v", got.Slots)
+	}
+	outsider, _ := s.Players.UpsertBattleNetPlayer(ctx, 999, "Out#1234")
+	if rr := do(t, h, http.MethodGet, teamPath+"/lineups", nil, outsider.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("non-member got %v, want 403", rr.Code)
+	}
+}
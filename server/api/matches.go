@@ -0,0 +1,530 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/results"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// MapResult is the result of one map of a match. Outcome is set in
+// responses.
+type MapResult struct {
+	Number        int    `json:"number"`
+	Map           string `json:"map"`
+	Mode          string `json:"mode"`
+	TeamScore     int    `json:"team_score"`
+	OpponentScore int    `json:"opponent_score"`
+	Outcome       string `json:"outcome,omitempty"`
+	VODURL        string `json:"vod_url,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+}
+
+// Match is a series the team played. Outcome is empty until a map is
+// recorded.
+type Match struct {
+	ID          int         `json:"match_id"`
+	TeamID      int         `json:"team_id"`
+	EventID     int         `json:"event_id,omitempty"`
+	EventStart  *time.Time  `json:"event_start,omitempty"`
+	Opponent    string      `json:"opponent"`
+	Competition string      `json:"competition,omitempty"`
+	Format      string      `json:"format"`
+	PlayedAt    time.Time   `json:"played_at"`
+	MapsWon     int         `json:"maps_won"`
+	MapsLost    int         `json:"maps_lost"`
+	Outcome     string      `json:"outcome,omitempty"`
+	VODURL      string      `json:"vod_url,omitempty"`
+	Notes       string      `json:"notes,omitempty"`
+	Maps        []MapResult `json:"maps"`
+}
+
+// MatchRequest records or replaces a match result. Maps are numbered in
+// the order given.
+type MatchRequest struct {
+	Opponent    string      `json:"opponent"`
+	Competition string      `json:"competition"`
+	Format      string      `json:"format"` // bo1, bo3, bo5 or bo7
+	PlayedAt    time.Time   `json:"played_at"`
+	VODURL      string      `json:"vod_url"`
+	Notes       string      `json:"notes"`
+	Maps        []MapResult `json:"maps"`
+	// OriginalStart picks the occurrence of a recurring event whose result
+	// is recorded.
+	OriginalStart time.Time `json:"original_start"`
+}
+
+// Record is a win/loss record
+type Record struct {
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Draws   int     `json:"draws"`
+	Played  int     `json:"played"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// MapStats is the team's record on one map
+type MapStats struct {
+	Map    string `json:"map"`
+	Mode   string `json:"mode"`
+	Record Record `json:"record"`
+}
+
+// ModeStats is the team's record on one game mode
+type ModeStats struct {
+	Mode   string `json:"mode"`
+	Record Record `json:"record"`
+}
+
+// OpponentStats is the team's record against one opponent
+type OpponentStats struct {
+	Opponent string `json:"opponent"`
+	Matches  Record `json:"matches"`
+	Maps     Record `json:"maps"`
+}
+
+// PeriodStats is the team's record over one week or month
+type PeriodStats struct {
+	Start   time.Time `json:"start"`
+	Matches Record    `json:"matches"`
+	Maps    Record    `json:"maps"`
+}
+
+// MatchStats is the team's record over its match history
+type MatchStats struct {
+	Period     string          `json:"period"`
+	Matches    Record          `json:"matches"`
+	Maps       Record          `json:"maps"`
+	ByMap      []MapStats      `json:"by_map"`
+	ByMode     []ModeStats     `json:"by_mode"`
+	ByOpponent []OpponentStats `json:"by_opponent"`
+	Over       []PeriodStats   `json:"over_time"`
+}
+
+func newMatch(m store.Match, loc *time.Location) Match {
+	resp := Match{
+		ID:          m.ID,
+		TeamID:      m.TeamID,
+		EventID:     m.EventID,
+		Opponent:    m.Opponent,
+		Competition: m.Competition,
+		Format:      m.Format,
+		PlayedAt:    m.PlayedAt.In(loc),
+		Outcome:     m.Outcome(),
+		VODURL:      m.VODURL,
+		Notes:       m.Notes,
+		Maps:        []MapResult{},
+	}
+	resp.MapsWon, resp.MapsLost = m.Score()
+	if !m.EventStart.IsZero() {
+		start := m.EventStart.In(loc)
+		resp.EventStart = &start
+	}
+	for _, r := range m.Maps {
+		resp.Maps = append(resp.Maps, MapResult{
+			Number:        r.Number,
+			Map:           r.Map,
+			Mode:          r.Mode,
+			TeamScore:     r.TeamScore,
+			OpponentScore: r.OpponentScore,
+			Outcome:       r.Outcome(),
+			VODURL:        r.VODURL,
+			Notes:         r.Notes,
+		})
+	}
+	return resp
+}
+
+func newRecord(r results.Record) Record {
+	return Record{Wins: r.Wins, Losses: r.Losses, Draws: r.Draws, Played: r.Played(), WinRate: r.WinRate()}
+}
+
+// match validates the request and returns the match it describes.
+func (req MatchRequest) match(teamID int) (store.Match, error) {
+	m := store.Match{
+		TeamID:      teamID,
+		Opponent:    strings.TrimSpace(req.Opponent),
+		Competition: strings.TrimSpace(req.Competition),
+		Format:      strings.ToLower(strings.TrimSpace(req.Format)),
+		PlayedAt:    req.PlayedAt,
+		VODURL:      strings.TrimSpace(req.VODURL),
+		Notes:       strings.TrimSpace(req.Notes),
+	}
+	for i, r := range req.Maps {
+		m.Maps = append(m.Maps, store.MapResult{
+			Number:        i + 1,
+			Map:           strings.TrimSpace(r.Map),
+			Mode:          strings.ToLower(strings.TrimSpace(r.Mode)),
+			TeamScore:     r.TeamScore,
+			OpponentScore: r.OpponentScore,
+			VODURL:        strings.TrimSpace(r.VODURL),
+			Notes:         strings.TrimSpace(r.Notes),
+		})
+	}
+	return m, m.Validate()
+}
+
+// teamMatch loads the {match_id} match, writing a 404 unless it belongs to
+// the {team_id} team.
+func teamMatch(w http.ResponseWriter, r *http.Request, s *store.Store) (store.Match, bool) {
+	teamID, _ := urlParamInt(r, "team_id")
+	matchID, _ := urlParamInt(r, "match_id")
+	m, err := s.Matches.GetMatch(r.Context(), matchID)
+	if err != nil {
+		response.StoreError(w, r, err, "Match not found")
+		return store.Match{}, false
+	}
+	if m.TeamID != teamID {
+		response.Error(w, r, "Match not found", http.StatusNotFound)
+		return store.Match{}, false
+	}
+	return m, true
+}
+
+// MatchesHandler lists a team's match history, most recent first, and
+// records matches that weren't scheduled as events. GET accepts ?from= and
+// ?to= (RFC 3339) and ?opponent= to pick one opponent, ignoring case.
+func MatchesHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			from, err := parseTimeParam(r, "from")
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			to, err := parseTimeParam(r, "to")
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			matches, err := s.Matches.ListMatches(r.Context(), teamID, from, to)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			opponent := strings.TrimSpace(r.URL.Query().Get("opponent"))
+			resp := make([]Match, 0, len(matches))
+			for _, m := range matches {
+				if opponent == "" || strings.EqualFold(m.Opponent, opponent) {
+					resp = append(resp, newMatch(m, loc))
+				}
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodPost:
+			var req MatchRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			m, err := req.match(teamID)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			m.CreatedBy = caller.UserID
+			if m, err = s.Matches.CreateMatch(r.Context(), m); err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusCreated, newMatch(m, loc))
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// MatchHandler reads, replaces and deletes a single match. Replacing keeps
+// the match's link to its event.
+func MatchHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, ok := teamMatch(w, r, s)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			response.JSON(w, http.StatusOK, newMatch(m, loc))
+
+		case http.MethodPut:
+			var req MatchRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			updated, err := req.match(m.TeamID)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			updated.ID, updated.EventID, updated.EventStart = m.ID, m.EventID, m.EventStart
+			if err := s.Matches.UpdateMatch(r.Context(), updated); err != nil {
+				response.StoreError(w, r, err, "Match not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, newMatch(updated, loc))
+
+		case http.MethodDelete:
+			if err := s.Matches.DeleteMatch(r.Context(), m.ID); err != nil {
+				response.StoreError(w, r, err, "Match not found")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// MatchStatsHandler reports the team's win/loss record overall, by map, by
+// mode, by opponent and over time. It accepts ?from= and ?to= (RFC 3339),
+// ?period=week or month (the default) and ?tz= for where periods start.
+func MatchStatsHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, err := parseTimeParam(r, "from")
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := parseTimeParam(r, "to")
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		period := r.URL.Query().Get("period")
+		if period == "" {
+			period = results.Month
+		}
+		if period != results.Week && period != results.Month {
+			response.Error(w, r, "period must be week or month", http.StatusBadRequest)
+			return
+		}
+
+		matches, err := s.Matches.ListMatches(r.Context(), teamID, from, to)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		stats := results.Summarize(matches, period, loc)
+		resp := MatchStats{
+			Period:     period,
+			Matches:    newRecord(stats.Matches),
+			Maps:       newRecord(stats.Maps),
+			ByMap:      []MapStats{},
+			ByMode:     []ModeStats{},
+			ByOpponent: []OpponentStats{},
+			Over:       []PeriodStats{},
+		}
+		for _, g := range stats.ByMap {
+			resp.ByMap = append(resp.ByMap, MapStats{Map: g.Key, Mode: g.Mode, Record: newRecord(g.Maps)})
+		}
+		for _, g := range stats.ByMode {
+			resp.ByMode = append(resp.ByMode, ModeStats{Mode: g.Key, Record: newRecord(g.Maps)})
+		}
+		for _, g := range stats.ByOpponent {
+			resp.ByOpponent = append(resp.ByOpponent, OpponentStats{Opponent: g.Key, Matches: newRecord(g.Matches), Maps: newRecord(g.Maps)})
+		}
+		for _, p := range stats.Over {
+			resp.Over = append(resp.Over, PeriodStats{Start: p.Start, Matches: newRecord(p.Matches), Maps: newRecord(p.Maps)})
+		}
+		response.JSON(w, http.StatusOK, resp)
+	}
+}
+
+var (
+	errOccurrenceNotFound    = errors.New("occurrence not found")
+	errOriginalStartRequired = errors.New("original_start is required for recurring events")
+)
+
+// eventOccurrence returns the occurrence of e whose result is recorded:
+// the event itself when it is one-off, or the one the rule starts at
+// original for a recurring event.
+func eventOccurrence(ctx context.Context, s *store.Store, e store.Event, original time.Time) (store.Occurrence, error) {
+	rule, loc, err := e.Rule()
+	if err != nil {
+		return store.Occurrence{}, err
+	}
+	if loc == nil {
+		if !original.IsZero() && !original.Equal(e.Start) {
+			return store.Occurrence{}, errOccurrenceNotFound
+		}
+		return firstOccurrence(e), nil
+	}
+	if original.IsZero() {
+		return store.Occurrence{}, errOriginalStartRequired
+	}
+	if !rule.Includes(e.Start, loc, original) {
+		return store.Occurrence{}, errOccurrenceNotFound
+	}
+	exceptions, err := s.Events.ListEventExceptions(ctx, e.ID)
+	if err != nil {
+		return store.Occurrence{}, err
+	}
+	for _, x := range exceptions {
+		if x.OriginalStart.Equal(original) {
+			return applyException(e, x), nil
+		}
+	}
+	o := store.Occurrence{Event: e, OriginalStart: original.UTC()}
+	o.Start, o.End = o.OriginalStart, o.OriginalStart.Add(e.End.Sub(e.Start))
+	return o, nil
+}
+
+// occurrenceError writes the HTTP error matching an eventOccurrence error.
+func occurrenceError(w http.ResponseWriter, r *http.Request, e store.Event, err error) {
+	switch {
+	case errors.Is(err, errOccurrenceNotFound):
+		response.Error(w, r, "Occurrence not found", http.StatusNotFound)
+	case errors.Is(err, errOriginalStartRequired):
+		response.Error(w, r, "original_start is required for recurring events", http.StatusBadRequest)
+	default:
+		log.Printf("Error finding occurrence of event %d: %v", e.ID, err)
+		response.Error(w, r, "Invalid recurrence rule", http.StatusInternalServerError)
+	}
+}
+
+// EventResultHandler records the result of a match or scrim once it has
+// been played. For recurring events it works on one occurrence, picked by
+// original_start (in the body for PUT, the query otherwise). PUT creates or
+// replaces the result; the opponent defaults to the event's and played_at
+// to when the occurrence started.
+func EventResultHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, ok := teamEvent(w, r, s)
+		if !ok {
+			return
+		}
+		if e.Type != store.EventMatch && e.Type != store.EventScrim {
+			response.Error(w, r, "Only matches and scrims have results", http.StatusBadRequest)
+			return
+		}
+
+		var req MatchRequest
+		if r.Method == http.MethodPut {
+			if !response.Decode(w, r, &req) {
+				return
+			}
+		} else if req.OriginalStart, err = parseTimeParam(r, "original_start"); err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		o, err := eventOccurrence(r.Context(), s, e, req.OriginalStart)
+		if err != nil {
+			occurrenceError(w, r, e, err)
+			return
+		}
+		var eventStart time.Time
+		if e.Recurrence != "" {
+			eventStart = o.OriginalStart
+		}
+		existing, err := s.Matches.GetEventMatch(r.Context(), e.ID, eventStart)
+		if err != nil && (r.Method != http.MethodPut || !errors.Is(err, store.ErrNotFound)) {
+			response.StoreError(w, r, err, "No result recorded")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			response.JSON(w, http.StatusOK, newMatch(existing, loc))
+
+		case http.MethodPut:
+			if o.Cancelled {
+				response.Error(w, r, "Event has been cancelled", http.StatusConflict)
+				return
+			}
+			if o.End.After(time.Now()) {
+				response.Error(w, r, "Event hasn't finished yet", http.StatusConflict)
+				return
+			}
+			if strings.TrimSpace(req.Opponent) == "" {
+				req.Opponent = o.Opponent
+			}
+			if req.PlayedAt.IsZero() {
+				req.PlayedAt = o.Start
+			}
+			m, err := req.match(e.TeamID)
+			if err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			m.EventID, m.EventStart = e.ID, eventStart
+
+			status := http.StatusOK
+			if existing.ID != 0 {
+				m.ID = existing.ID
+				err = s.Matches.UpdateMatch(r.Context(), m)
+			} else {
+				m.CreatedBy = caller.UserID
+				m, err = s.Matches.CreateMatch(r.Context(), m)
+				status = http.StatusCreated
+			}
+			if err != nil {
+				response.StoreError(w, r, err, "Event not found")
+				return
+			}
+			response.JSON(w, status, newMatch(m, loc))
+
+		case http.MethodDelete:
+			if err := s.Matches.DeleteMatch(r.Context(), existing.ID); err != nil {
+				response.StoreError(w, r, err, "No result recorded")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestMatches(t *testing.T) {
+	s, h, team, members := newTeam(t, "coach", "player")
+	coach, player := members[0], members[1]
+	ctx := context.Background()
+	teamPath := "/teams/" + strconv.Itoa(team.ID)
+
+	// A finished league match, recorded from its event
+	start := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Hour)
+	e, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventMatch, Title: "Week 3", Opponent: "Team Bravo", Start: start, End: start.Add(2 * time.Hour)})
+	resultPath := teamPath + "/events/" + strconv.Itoa(e.ID) + "/result"
+	if rr := do(t, h, http.MethodGet, resultPath, nil, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("GET before a result returned %v, want 404", rr.Code)
+	}
+	result := api.MatchRequest{Competition: "Open Division", Format: "Bo3", Maps: []api.MapResult{
+		{Map: "Lijiang Tower", Mode: "Control", TeamScore: 2, OpponentScore: 1, VODURL: "https://youtu.be/abc"},
+		{Map: "Dorado", Mode: "escort", TeamScore: 2, OpponentScore: 3},
+		{Map: "King's Row", Mode: "hybrid", TeamScore: 3, OpponentScore: 2, Notes: "Held last point"},
+	}}
+	if rr := do(t, h, http.MethodPut, resultPath, result, player.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("player PUT returned %v, want 403", rr.Code)
+	}
+	rr := do(t, h, http.MethodPut, resultPath, result, coach.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("PUT result returned %v: %s", rr.Code, rr.Body)
+	}
+	var recorded api.Match
+	if err := json.NewDecoder(rr.Body).Decode(&recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.EventID != e.ID || recorded.Opponent != "Team Bravo" || !recorded.PlayedAt.Equal(start) || recorded.Format != "bo3" ||
+		recorded.Outcome != "win" || recorded.MapsWon != 2 || recorded.MapsLost != 1 || recorded.Maps[1].Outcome != "loss" {
+		t.Errorf("unexpected result: %+v", recorded)
+	}
+
+	// Recording again replaces the result
+	result.Maps = result.Maps[:2]
+	result.Maps = append(result.Maps, api.MapResult{Map: "Colosseo", Mode: "push", TeamScore: 0, OpponentScore: 1})
+	if rr := do(t, h, http.MethodPut, resultPath, result, coach.ID); rr.Code != http.StatusOK {
+		t.Fatalf("second PUT returned %v: %s", rr.Code, rr.Body)
+	}
+
+	// Maps after the series was decided are rejected
+	result.Maps = append(result.Maps, api.MapResult{Map: "Ilios", Mode: "control", TeamScore: 2, OpponentScore: 0})
+	if rr := do(t, h, http.MethodPut, resultPath, result, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("fourth map of a decided bo3 returned %v, want 400", rr.Code)
+	}
+
+	// Events that haven't finished, or aren't matches, can't have results
+	upcoming, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventMatch, Title: "Week 4", Opponent: "Charlie", Start: time.Now().Add(time.Hour), End: time.Now().Add(3 * time.Hour)})
+	if rr := do(t, h, http.MethodPut, teamPath+"/events/"+strconv.Itoa(upcoming.ID)+"/result", result, coach.ID); rr.Code != http.StatusConflict {
+		t.Errorf("result of an upcoming match returned %v, want 409", rr.Code)
+	}
+	practice, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventPractice, Title: "Practice", Start: start, End: start.Add(time.Hour)})
+	if rr := do(t, h, http.MethodPut, teamPath+"/events/"+strconv.Itoa(practice.ID)+"/result", result, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("result of a practice returned %v, want 400", rr.Code)
+	}
+
+	// Weekly scrims record a result per occurrence
+	weekly, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventScrim, Title: "Scrims", Opponent: "Delta",
+		Start: start.AddDate(0, 0, -14), End: start.AddDate(0, 0, -14).Add(2 * time.Hour), Recurrence: "FREQ=WEEKLY;COUNT=3", Timezone: "UTC"})
+	weeklyPath := teamPath + "/events/" + strconv.Itoa(weekly.ID) + "/result"
+	bo1 := api.MatchRequest{Format: "bo1", Maps: []api.MapResult{{Map: "Ilios", Mode: "control", TeamScore: 2, OpponentScore: 0}}}
+	if rr := do(t, h, http.MethodPut, weeklyPath, bo1, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("recurring result without original_start returned %v, want 400", rr.Code)
+	}
+	bo1.OriginalStart = start.AddDate(0, 0, -7)
+	if rr := do(t, h, http.MethodPut, weeklyPath, bo1, coach.ID); rr.Code != http.StatusCreated {
+		t.Errorf("occurrence result returned %v: %s", rr.Code, rr.Body)
+	}
+	if rr := do(t, h, http.MethodGet, weeklyPath+"?original_start="+start.AddDate(0, 0, -14).Format(time.RFC3339), nil, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("another occurrence's result returned %v, want 404", rr.Code)
+	}
+	bo1.OriginalStart = start.AddDate(0, 0, -6)
+	if rr := do(t, h, http.MethodPut, weeklyPath, bo1, coach.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("result of a day the rule skips returned %v, want 404", rr.Code)
+	}
+
+	// A scrim that wasn't on the calendar
+	scrim := api.MatchRequest{Opponent: "Team Bravo", Format: "bo1", PlayedAt: start.AddDate(0, 0, -40), Maps: []api.MapResult{
+		{Map: "Lijiang Tower", Mode: "control", TeamScore: 2, OpponentScore: 0},
+	}}
+	rr = do(t, h, http.MethodPost, teamPath+"/matches", scrim, coach.ID)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST match returned %v: %s", rr.Code, rr.Body)
+	}
+	var standalone api.Match
+	if err := json.NewDecoder(rr.Body).Decode(&standalone); err != nil {
+		t.Fatal(err)
+	}
+	scrim.Format = "bo2"
+	if rr := do(t, h, http.MethodPost, teamPath+"/matches", scrim, coach.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown format returned %v, want 400", rr.Code)
+	}
+
+	rr = do(t, h, http.MethodGet, teamPath+"/matches?opponent=team%20bravo", nil, player.ID)
+	var list []api.Match
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].EventID != e.ID || list[0].Outcome != "loss" || list[1].ID != standalone.ID {
+		t.Errorf("unexpected history: %+v", list)
+	}
+
+	rr = do(t, h, http.MethodGet, teamPath+"/matches/stats?period=month&tz=UTC", nil, player.ID)
+	var stats api.MatchStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Matches.Wins != 2 || stats.Matches.Losses != 1 || stats.Maps.Played != 5 || stats.Maps.WinRate != 0.6 {
+		t.Errorf("unexpected records: %+v %+v", stats.Matches, stats.Maps)
+	}
+	if len(stats.ByMap) == 0 || stats.ByMap[0].Map != "Lijiang Tower" || stats.ByMap[0].Record.Wins != 2 || stats.ByMap[0].Mode != "control" {
+		t.Errorf("unexpected map stats: %+v", stats.ByMap)
+	}
+	if len(stats.ByOpponent) != 2 || stats.ByOpponent[0].Matches.Played != 2 || len(stats.Over) < 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if rr := do(t, h, http.MethodGet, teamPath+"/matches/stats?period=year", nil, player.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown period returned %v, want 400", rr.Code)
+	}
+
+	matchPath := teamPath + "/matches/" + strconv.Itoa(recorded.ID)
+	if rr := do(t, h, http.MethodDelete, matchPath, nil, coach.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned %v", rr.Code)
+	}
+	if rr := do(t, h, http.MethodGet, resultPath, nil, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("GET deleted result returned %v, want 404", rr.Code)
+	}
+
+	// Another team's URL can't reach the match
+	other, _ := s.Teams.CreateTeam(ctx, "Beta")
+	s.Memberships.AddMember(ctx, store.Member{TeamID: other.ID, UserID: player.ID, Role: "player"})
+	if rr := do(t, h, http.MethodGet, "/teams/"+strconv.Itoa(other.ID)+"/matches/"+strconv.Itoa(standalone.ID), nil, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("cross-team GET returned %v, want 404", rr.Code)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/openapi"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// doc documents the route a handler is registered on. The request and
+// response are zero values of the types the handler decodes and encodes.
+type doc struct {
+	summary      string
+	auth         string   // security scheme; "" is the session cookie or an API token
+	query        []string // names from queryParams
+	request      any
+	status       int // of success; 0 is 200
+	response     any
+	content      string // of the response, when it isn't JSON
+	optionalBody bool   // the request body may be left out
+}
+
+// documented is a handler carrying the doc of its route, for
+// openAPIDocument to find when it walks the router.
+type documented struct {
+	http.Handler
+	doc doc
+}
+
+// describe attaches d to h. NewRouter registers every handler through it.
+func describe(h http.Handler, d doc) http.Handler {
+	return documented{Handler: h, doc: d}
+}
+
+// Security schemes
+const (
+	authSession = ""
+	authToken   = "apiToken"
+	authFeed    = "feedToken"
+	authDiscord = "discordSignature"
+	authNone    = "none"
+)
+
+// queryParams documents the query parameters routes accept.
+var queryParams = map[string]openapi.Parameter{
+	"tz":             {Description: "Timezone to show times in. Defaults to the caller's profile timezone.", Schema: &openapi.Schema{Type: "string"}},
+	"week":           {Description: "A date in the week to show, like 2025-05-05. Defaults to this week.", Schema: &openapi.Schema{Type: "string", Format: "date"}},
+	"from":           {Description: "Start of the range, as an RFC 3339 timestamp.", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+	"to":             {Description: "End of the range, as an RFC 3339 timestamp.", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+	"original_start": {Description: "Scheduled start of the occurrence of a recurring event.", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+	"min_duration":   {Description: "Shortest useful window in minutes. Defaults to one slot.", Schema: &openapi.Schema{Type: "integer"}},
+	"limit":          {Description: "Number of windows to return. Defaults to 5.", Schema: &openapi.Schema{Type: "integer"}},
+	"require":        {Description: "Role counts every window must meet, like player:5,coach:1.", Schema: &openapi.Schema{Type: "string"}},
+	"full_only":      {Description: "List only the slots a full lineup can make.", Schema: &openapi.Schema{Type: "boolean"}},
+	"opponent":       {Description: "Only matches against this opponent.", Schema: &openapi.Schema{Type: "string"}},
+	"period":         {Description: "Length of each period in over_time.", Schema: &openapi.Schema{Type: "string", Enum: []string{"week", "month"}}},
+	"team_id":        {Description: "List this team's slots instead of the global ones.", Schema: &openapi.Schema{Type: "integer"}},
+	"token":          {Description: "The player's calendar feed token.", Schema: &openapi.Schema{Type: "string"}, Required: true},
+}
+
+// loginRoutes are the routes the server serves outside /api to log in and
+// out, which the document also describes.
+var loginRoutes = []struct {
+	method, path string
+	doc          doc
+}{
+	{"GET", "/auth/{provider}", doc{summary: "Log in with Battle.net", auth: authNone, status: http.StatusFound}},
+	{"GET", "/auth/callback/{provider}", doc{summary: "Finish logging in and start a session", auth: authNone, status: http.StatusFound}},
+	{"GET", "/auth/status", doc{summary: "The logged in player and their teams", response: AuthStatus{}}},
+	{"GET", "/logout", doc{summary: "End the session", auth: authNone, status: http.StatusFound}},
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// openAPIDocument describes the routes served by api, the /api router, and
+// loginRoutes. Each route's policy is read from the guard mounted on it, and
+// everything else from its doc.
+func openAPIDocument(api chi.Routes) (*openapi.Document, error) {
+	schemas := openapi.NewSchemas(reflect.TypeOf(doc{}).PkgPath())
+	errorBody := schemas.For(response.Envelope{})
+	document := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Vivacity Schedule Manager API",
+			Version:     "1.0.0",
+			Description: "Teams, players, availability, events and match results. Errors are returned in the ResponseEnvelope schema.",
+		},
+		Paths: map[string]openapi.PathItem{},
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"session":   {Type: "apiKey", In: "cookie", Name: middleware.SessionName, Description: "The session cookie set by logging in."},
+				authToken:   {Type: "http", Scheme: "bearer", Description: "A personal API token, limited to the scopes listed on each operation. A write scope also allows reads in its area."},
+				authFeed:    {Type: "apiKey", In: "query", Name: "token", Description: "A calendar feed token."},
+				authDiscord: {Type: "apiKey", In: "header", Name: "X-Signature-Ed25519", Description: "Discord's signature of the request, checked against DISCORD_PUBLIC_KEY."},
+			},
+		},
+	}
+	add := func(method, path string, policy *authz.Policy, d doc) {
+		op := &openapi.Operation{
+			Summary:   d.summary,
+			Tags:      []string{routeTag(path)},
+			Responses: map[string]openapi.Response{"default": {Description: "Error", Content: openapi.JSON(errorBody)}},
+		}
+		if policy != nil {
+			op.Description = "Requires " + policy.Name + "."
+		}
+		switch d.auth {
+		case authSession:
+			op.Security = []map[string][]string{{"session": {}}}
+			if path, ok := strings.CutPrefix(path, "/api"); !ok {
+				op.Security = append(op.Security, map[string][]string{authToken: {}})
+			} else if scope, ok := authz.Scope(method, path); ok {
+				op.Security = append(op.Security, map[string][]string{authToken: {scope}})
+			}
+		case authNone:
+		default:
+			op.Security = []map[string][]string{{d.auth: {}}}
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+			schema := &openapi.Schema{Type: "integer"}
+			if m[1] == "provider" {
+				schema = &openapi.Schema{Type: "string"}
+			}
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+		}
+		for _, name := range d.query {
+			p := queryParams[name]
+			p.Name, p.In = name, "query"
+			op.Parameters = append(op.Parameters, p)
+		}
+		if d.request != nil {
+			op.RequestBody = &openapi.RequestBody{Required: !d.optionalBody, Content: openapi.JSON(schemas.For(d.request))}
+		}
+		status := d.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := openapi.Response{Description: http.StatusText(status)}
+		switch {
+		case d.content != "":
+			resp.Content = map[string]openapi.MediaType{d.content: {Schema: &openapi.Schema{Type: "string"}}}
+		case d.response != nil:
+			resp.Content = openapi.JSON(schemas.For(d.response))
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+
+		item := document.Paths[path]
+		if item == nil {
+			item = openapi.PathItem{}
+			document.Paths[path] = item
+		}
+		item[strings.ToLower(method)] = op
+	}
+
+	for _, rt := range loginRoutes {
+		add(rt.method, rt.path, nil, rt.doc)
+	}
+	err := chi.Walk(api, func(method, route string, h http.Handler, mws ...func(http.Handler) http.Handler) error {
+		d, ok := h.(documented)
+		if !ok {
+			return fmt.Errorf("%s %s has no doc", method, route)
+		}
+		var policy *authz.Policy
+		for _, mw := range mws {
+			if p, ok := authz.Required(mw); ok {
+				policy = &p
+			}
+		}
+		add(method, "/api"+strings.TrimSuffix(route, "/"), policy, d.doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	document.Components.Schemas = schemas.Components()
+	return document, nil
+}
+
+// routeTag groups a route by the resource it acts on: the first segment
+// after a team or player, else the first segment, without any extension.
+func routeTag(path string) string {
+	segments := strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, "/api"), "/"), "/")
+	tag := segments[0]
+	if len(segments) > 2 && strings.HasPrefix(segments[1], "{") && !strings.HasPrefix(segments[2], "{") {
+		tag = segments[2]
+	}
+	tag, _, _ = strings.Cut(tag, ".")
+	return tag
+}
+
+// OpenAPIHandler serves the OpenAPI document describing the routes served
+// by api. It is built on the first request, once they are all registered.
+func OpenAPIHandler(api chi.Routes) http.HandlerFunc {
+	var once sync.Once
+	var document *openapi.Document
+	var err error
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { document, err = openAPIDocument(api) })
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, http.StatusOK, document)
+	}
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := store.NewMemory()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Serve the optional routes too
+	h := api.NewRouter(api.Options{Store: s, Authenticate: testAuth(s), DiscordPublicKey: pub, Profiles: &profiles.Syncer{Store: s}})
+
+	rr := do(t, h, http.MethodGet, "/openapi.json", nil, 0)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("openapi.json: got %d", rr.Code)
+	}
+	raw := rr.Body.String()
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.1") {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+
+	// Every route the router serves is documented, with the policy of the
+	// guard mounted on it
+	served := map[string]bool{}
+	err = chi.Walk(h, func(method, route string, _ http.Handler, mws ...func(http.Handler) http.Handler) error {
+		path := "/api" + strings.TrimSuffix(route, "/")
+		served[method+" "+path] = true
+		raw, ok := doc.Paths[path][strings.ToLower(method)]
+		if !ok {
+			t.Errorf("%s %s is not in the OpenAPI document", method, path)
+			return nil
+		}
+		var op struct {
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal(raw, &op); err != nil {
+			t.Fatal(err)
+		}
+		want := ""
+		for _, mw := range mws {
+			if p, ok := authz.Required(mw); ok {
+				want = "Requires " + p.Name + "."
+			}
+		}
+		if op.Description != want {
+			t.Errorf("%s %s: documented as %q, guarded by %q", method, path, op.Description, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// and every documented /api route is served
+	for path, item := range doc.Paths {
+		if !strings.HasPrefix(path, "/api/") {
+			continue
+		}
+		for method := range item {
+			if !served[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is documented but not served", method, path)
+			}
+		}
+	}
+
+	// Every schema reference resolves
+	for _, m := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(raw, -1) {
+		if _, ok := doc.Components.Schemas[m[1]]; !ok {
+			t.Errorf("schema %s is referenced but not defined", m[1])
+		}
+	}
+	for _, name := range []string{"ResponseEnvelope", "Event", "Match", "TeamDetail", "DiscordResponse"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s is missing", name)
+		}
+	}
+}
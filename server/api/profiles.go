@@ -0,0 +1,153 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// RoleRank is a player's rank on one role. Source is "player" for ranks the
+// player entered and "synced" for ranks fetched from their career profile.
+type RoleRank struct {
+	Role     string `json:"role"`
+	Division string `json:"division,omitempty"`
+	Tier     int    `json:"tier,omitempty"`
+	SR       int    `json:"sr,omitempty"`
+	Source   string `json:"source,omitempty"`
+}
+
+// GameProfile is a player's Overwatch profile
+type GameProfile struct {
+	Region         string     `json:"region"`
+	PreferredRoles []string   `json:"preferred_roles"`
+	Ranks          []RoleRank `json:"ranks"`
+	MainHeroes     []string   `json:"main_heroes"`
+	SyncedAt       *time.Time `json:"synced_at,omitempty"`
+	SyncError      string     `json:"sync_error,omitempty"`
+}
+
+func newRoleRank(r store.RoleRank) RoleRank {
+	source := "player"
+	if r.Synced {
+		source = "synced"
+	}
+	return RoleRank{Role: r.Role, Division: r.Division, Tier: r.Tier, SR: r.SR, Source: source}
+}
+
+func newGameProfile(p store.GameProfile) GameProfile {
+	resp := GameProfile{
+		Region:         p.Region,
+		PreferredRoles: append([]string{}, p.PreferredRoles...),
+		Ranks:          []RoleRank{},
+		MainHeroes:     append([]string{}, p.MainHeroes...),
+		SyncError:      p.SyncError,
+	}
+	for _, r := range p.Ranks {
+		resp.Ranks = append(resp.Ranks, newRoleRank(r))
+	}
+	if !p.SyncedAt.IsZero() {
+		resp.SyncedAt = &p.SyncedAt
+	}
+	return resp
+}
+
+// minRefreshInterval is how often a player can ask for their ranks to be
+// fetched again.
+const minRefreshInterval = 5 * time.Minute
+
+// GameProfileHandler reads and replaces a player's Overwatch profile.
+// Ranks sent back with source "synced" are left to the stats sync; the rest
+// replace the ranks the player entered before.
+func GameProfileHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			p, err := s.Profiles.GetGameProfile(r.Context(), userID)
+			if err != nil {
+				response.StoreError(w, r, err, "User not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, newGameProfile(p))
+
+		case http.MethodPut:
+			var req GameProfile
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			p := store.GameProfile{UserID: userID, Region: strings.ToLower(strings.TrimSpace(req.Region))}
+			for _, role := range req.PreferredRoles {
+				p.PreferredRoles = append(p.PreferredRoles, strings.ToLower(role))
+			}
+			for _, rank := range req.Ranks {
+				if rank.Source == "synced" {
+					continue
+				}
+				p.Ranks = append(p.Ranks, store.RoleRank{
+					Role:     strings.ToLower(rank.Role),
+					Division: strings.ToLower(rank.Division),
+					Tier:     rank.Tier,
+					SR:       rank.SR,
+				})
+			}
+			for _, hero := range req.MainHeroes {
+				p.MainHeroes = append(p.MainHeroes, strings.TrimSpace(hero))
+			}
+			if err := p.Validate(); err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := s.Profiles.SetGameProfile(r.Context(), p); err != nil {
+				response.StoreError(w, r, err, "User not found")
+				return
+			}
+			p, err := s.Profiles.GetGameProfile(r.Context(), userID)
+			if err != nil {
+				response.StoreError(w, r, err, "User not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, newGameProfile(p))
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RefreshGameProfileHandler fetches the player's ranks from their career
+// profile now instead of waiting for the next sync. Asking again within
+// minRefreshInterval returns the profile unchanged.
+func RefreshGameProfileHandler(s *store.Store, syncer *profiles.Syncer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		p, err := s.Profiles.GetGameProfile(r.Context(), userID)
+		if err != nil {
+			response.StoreError(w, r, err, "User not found")
+			return
+		}
+		if p.Region == "" {
+			response.Error(w, r, "Set a region before syncing ranks", http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		if now.Sub(p.SyncedAt) >= minRefreshInterval {
+			if err := syncer.Sync(r.Context(), userID, now); err != nil {
+				log.Printf("Error syncing profile of player %d: %v", userID, err)
+				response.Error(w, r, "Stats service unavailable", http.StatusBadGateway)
+				return
+			}
+			if p, err = s.Profiles.GetGameProfile(r.Context(), userID); err != nil {
+				response.StoreError(w, r, err, "User not found")
+				return
+			}
+		}
+		response.JSON(w, http.StatusOK, newGameProfile(p))
+	}
+}
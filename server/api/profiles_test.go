@@ -0,0 +1,86 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// stubStats returns the same ranks for every player.
+type stubStats []store.RoleRank
+
+func (s stubStats) FetchRanks(ctx context.Context, battletag, region string) ([]store.RoleRank, error) {
+	return s, nil
+}
+
+func TestGameProfile(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 1001, "John#1234")
+	other, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Jane#5678")
+	syncer := &profiles.Syncer{Store: s, Stats: stubStats{{Role: "dps", Division: "platinum", Tier: 1}}}
+	h := api.NewRouter(api.Options{Store: s, Authenticate: testAuth(s), Profiles: syncer})
+	path := "/players/" + strconv.Itoa(player.ID) + "/profile"
+
+	decode := func(rr *httptest.ResponseRecorder) api.GameProfile {
+		t.Helper()
+		var got api.GameProfile
+		if rr.Code != http.StatusOK || json.NewDecoder(rr.Body).Decode(&got) != nil {
+			t.Fatalf("profile returned %v: %s", rr.Code, rr.Body)
+		}
+		return got
+	}
+
+	if got := decode(do(t, h, http.MethodGet, path, nil, other.ID)); got.Region != "" || len(got.Ranks) != 0 || got.PreferredRoles == nil {
+		t.Errorf("empty profile: %+v", got)
+	}
+	if rr := do(t, h, http.MethodPost, path+"/refresh", nil, player.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("refresh without a region returned %v, want 400", rr.Code)
+	}
+
+	for _, bad := range []api.GameProfile{
+		{Region: "mars"},
+		{PreferredRoles: []string{"tank", "tank"}},
+		{PreferredRoles: []string{"healer"}},
+		{Ranks: []api.RoleRank{{Role: "tank"}}},
+		{Ranks: []api.RoleRank{{Role: "tank", Division: "diamond", Tier: 6}}},
+		{MainHeroes: []string{"Ana", "Kiriko", "Lucio", "Mercy", "Moira", "Zen"}},
+	} {
+		if rr := do(t, h, http.MethodPut, path, bad, player.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("PUT %+v returned %v, want 400", bad, rr.Code)
+		}
+	}
+	req := api.GameProfile{
+		Region:         "US",
+		PreferredRoles: []string{"support", "dps"},
+		Ranks:          []api.RoleRank{{Role: "support", Division: "Diamond", Tier: 2}, {Role: "dps", SR: 2800}},
+		MainHeroes:     []string{"Ana", "Kiriko"},
+	}
+	if rr := do(t, h, http.MethodPut, path, req, other.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("PUT by another player returned %v, want 403", rr.Code)
+	}
+	got := decode(do(t, h, http.MethodPut, path, req, player.ID))
+	if got.Region != "us" || len(got.Ranks) != 2 || got.Ranks[0] != (api.RoleRank{Role: "dps", SR: 2800, Source: "player"}) ||
+		got.Ranks[1] != (api.RoleRank{Role: "support", Division: "diamond", Tier: 2, Source: "player"}) || len(got.MainHeroes) != 2 {
+		t.Errorf("saved profile: %+v", got)
+	}
+
+	// Refreshing replaces the entered dps rank with the synced one
+	got = decode(do(t, h, http.MethodPost, path+"/refresh", nil, player.ID))
+	if got.SyncedAt == nil || len(got.Ranks) != 2 || got.Ranks[0] != (api.RoleRank{Role: "dps", Division: "platinum", Tier: 1, Source: "synced"}) {
+		t.Errorf("refreshed profile: %+v", got)
+	}
+
+	// Saving the profile as returned keeps the synced rank synced
+	got = decode(do(t, h, http.MethodPut, path, got, player.ID))
+	if len(got.Ranks) != 2 || got.Ranks[0].Source != "synced" || got.Ranks[1].Source != "player" {
+		t.Errorf("profile saved back: %+v", got.Ranks)
+	}
+}
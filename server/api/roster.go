@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/roster"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// RosterMember is a member as shown on the roster, with their rank on the
+// in-game role they fill.
+type RosterMember struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	GameRole string    `json:"game_role,omitempty"`
+	Rank     *RoleRank `json:"rank,omitempty"`
+}
+
+// RosterRole is the members filling one in-game role. Limit is zero when
+// the role is uncapped.
+type RosterRole struct {
+	Role     string         `json:"role"`
+	Needed   int            `json:"needed"`
+	Limit    int            `json:"limit"`
+	Starters []RosterMember `json:"starters"`
+	Subs     []RosterMember `json:"subs"`
+}
+
+// RosterWarning is a problem with the team's composition
+type RosterWarning struct {
+	Code    string `json:"code"`
+	Role    string `json:"role,omitempty"`
+	UserID  int    `json:"user_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// Roster is a team's members arranged by in-game role
+type Roster struct {
+	TeamID     int             `json:"team_id"`
+	CanField   bool            `json:"can_field_lineup"`
+	Roles      []RosterRole    `json:"roles"`
+	Unassigned []RosterMember  `json:"unassigned"`
+	Staff      []RosterMember  `json:"staff"`
+	Warnings   []RosterWarning `json:"warnings"`
+}
+
+// RosterLimits caps how many members can hold each in-game role; zero
+// leaves a role uncapped
+type RosterLimits struct {
+	Tank    int `json:"tank"`
+	DPS     int `json:"dps"`
+	Support int `json:"support"`
+}
+
+// parseGameRole normalizes an in-game role from a request. The empty role
+// clears it.
+func parseGameRole(s string) (string, bool) {
+	role := strings.ToLower(strings.TrimSpace(s))
+	return role, role == "" || slices.Contains(store.GameRoles, role)
+}
+
+func newRosterMembers(members []store.Member, profiles map[int]store.GameProfile) []RosterMember {
+	resp := make([]RosterMember, 0, len(members))
+	for _, m := range members {
+		rm := RosterMember{UserID: m.UserID, Username: m.Username, Role: m.Role, GameRole: m.GameRole}
+		if rank, ok := profiles[m.UserID].Rank(m.GameRole); ok {
+			rr := newRoleRank(rank)
+			rm.Rank = &rr
+		}
+		resp = append(resp, rm)
+	}
+	return resp
+}
+
+// loadRoster builds the team's roster from its members, limits and their
+// game profiles.
+func loadRoster(ctx context.Context, s *store.Store, teamID int) (roster.Roster, map[int]store.GameProfile, error) {
+	limits, err := s.Rosters.GetRosterLimits(ctx, teamID)
+	if err != nil {
+		return roster.Roster{}, nil, err
+	}
+	members, err := s.Memberships.ListMembers(ctx, teamID)
+	if err != nil {
+		return roster.Roster{}, nil, err
+	}
+	ids := make([]int, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	profiles, err := s.Profiles.ListGameProfiles(ctx, ids)
+	if err != nil {
+		return roster.Roster{}, nil, err
+	}
+	return roster.Build(teamID, members, limits, profiles), profiles, nil
+}
+
+// RosterHandler shows the team's members by in-game role, whether they can
+// field a full lineup, and what is wrong with the composition.
+func RosterHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		ros, profiles, err := loadRoster(r.Context(), s, teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		resp := Roster{
+			TeamID:     ros.TeamID,
+			CanField:   ros.CanField,
+			Roles:      []RosterRole{},
+			Unassigned: newRosterMembers(ros.Unassigned, profiles),
+			Staff:      newRosterMembers(ros.Staff, profiles),
+			Warnings:   []RosterWarning{},
+		}
+		for _, g := range ros.Roles {
+			resp.Roles = append(resp.Roles, RosterRole{
+				Role:     g.Role,
+				Needed:   g.Needed,
+				Limit:    g.Limit,
+				Starters: newRosterMembers(g.Starters, profiles),
+				Subs:     newRosterMembers(g.Subs, profiles),
+			})
+		}
+		for _, warn := range ros.Warnings {
+			resp.Warnings = append(resp.Warnings, RosterWarning(warn))
+		}
+		response.JSON(w, http.StatusOK, resp)
+	}
+}
+
+// RosterLimitsHandler reads and replaces the team's roster limits.
+func RosterLimitsHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			l, err := s.Rosters.GetRosterLimits(r.Context(), teamID)
+			if err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			response.JSON(w, http.StatusOK, RosterLimits{Tank: l.Tank, DPS: l.DPS, Support: l.Support})
+
+		case http.MethodPut:
+			var req RosterLimits
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			l := store.RosterLimits{TeamID: teamID, Tank: req.Tank, DPS: req.DPS, Support: req.Support}
+			if err := l.Validate(); err != nil {
+				response.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := s.Rosters.SetRosterLimits(r.Context(), l); err != nil {
+				response.StoreError(w, r, err, "Team not found")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
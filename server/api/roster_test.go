@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestRoster(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	admin := newAdmin(t, s)
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	h := newRouter(s)
+	teamPath := "/teams/" + strconv.Itoa(team.ID)
+
+	var players []store.Player
+	for i := 0; i < 4; i++ {
+		p, _ := s.Players.UpsertBattleNetPlayer(ctx, int64(2000+i), "Player#"+strconv.Itoa(2000+i))
+		players = append(players, p)
+	}
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{
+		UserID: players[0].ID,
+		Ranks:  []store.RoleRank{{Role: "tank", Division: "master", Tier: 4}},
+	})
+
+	add := func(p store.Player, role, gameRole string) int {
+		t.Helper()
+		return do(t, h, http.MethodPost, teamPath+"/members", map[string]any{"user_id": p.ID, "role": role, "game_role": gameRole}, admin.ID).Code
+	}
+	if code := add(players[0], "captain", "Tank"); code != http.StatusCreated {
+		t.Fatalf("adding a tank returned %v", code)
+	}
+	if code := add(players[1], "player", "healer"); code != http.StatusBadRequest {
+		t.Errorf("unknown game role returned %v, want 400", code)
+	}
+
+	// Only one tank fits once the limit is lowered
+	if rr := do(t, h, http.MethodPut, teamPath+"/roster/limits", api.RosterLimits{Tank: 0, DPS: 1, Support: 4}, admin.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("dps limit below a lineup returned %v, want 400", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPut, teamPath+"/roster/limits", api.RosterLimits{Tank: 1, DPS: 4, Support: 4}, players[0].ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("captain setting limits returned %v: %s", rr.Code, rr.Body)
+	}
+	if code := add(players[1], "sub", "tank"); code != http.StatusConflict {
+		t.Errorf("adding a second tank returned %v, want 409", code)
+	}
+	if code := add(players[1], "sub", "dps"); code != http.StatusCreated {
+		t.Fatalf("adding a dps sub returned %v", code)
+	}
+	if code := add(players[2], "coach", ""); code != http.StatusCreated {
+		t.Fatalf("adding a coach returned %v", code)
+	}
+	if code := add(players[3], "player", ""); code != http.StatusCreated {
+		t.Fatalf("adding an unassigned player returned %v", code)
+	}
+	rr := do(t, h, http.MethodPut, teamPath+"/members", map[string]any{"user_id": players[3].ID, "game_role": "tank"}, admin.ID)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("moving a player onto the full tank role returned %v, want 409", rr.Code)
+	}
+	rr = do(t, h, http.MethodPut, teamPath+"/members", map[string]any{"user_id": players[3].ID, "game_role": "support"}, admin.ID)
+	if rr.Code != http.StatusOK {
+		t.Errorf("moving a player onto support returned %v", rr.Code)
+	}
+
+	rr = do(t, h, http.MethodGet, teamPath+"/roster", nil, players[3].ID)
+	var got api.Roster
+	if rr.Code != http.StatusOK || json.NewDecoder(rr.Body).Decode(&got) != nil {
+		t.Fatalf("roster returned %v: %s", rr.Code, rr.Body)
+	}
+	if got.CanField || len(got.Roles) != 3 || len(got.Staff) != 1 || len(got.Unassigned) != 0 {
+		t.Errorf("unexpected roster: %+v", got)
+	}
+	tank := got.Roles[0]
+	if tank.Role != "tank" || tank.Limit != 1 || len(tank.Starters) != 1 || tank.Starters[0].Rank == nil || tank.Starters[0].Rank.Division != "master" {
+		t.Errorf("tank role: %+v", tank)
+	}
+	if dps := got.Roles[1]; len(dps.Starters) != 0 || len(dps.Subs) != 1 {
+		t.Errorf("dps role: %+v", dps)
+	}
+	warnings := map[string]bool{}
+	for _, w := range got.Warnings {
+		warnings[w.Code+":"+w.Role] = true
+	}
+	if !warnings["no_backup:tank"] || !warnings["short:dps"] || !warnings["short:support"] {
+		t.Errorf("roster warnings: %+v", got.Warnings)
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// requireIntParam rejects requests whose URL parameter name is not an integer.
+func requireIntParam(name, message string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := strconv.Atoi(chi.URLParam(r, name)); err != nil {
+				response.Error(w, r, message, http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Options configures the API router.
+type Options struct {
+	Store *store.Store
+	// Authenticate identifies the caller and stores their Principal in the
+	// request context, rejecting anonymous requests. It runs on every route
+	// except the calendar feeds, which take a feed token instead, and the
+	// Discord interactions endpoint, which Discord signs.
+	Authenticate func(http.Handler) http.Handler
+	// DiscordPublicKey verifies requests to /discord/interactions. The
+	// endpoint is only served when it is set.
+	DiscordPublicKey ed25519.PublicKey
+	// Profiles fetches ranks on demand for POST
+	// /players/{user_id}/profile/refresh, which is only served when it is
+	// set.
+	Profiles *profiles.Syncer
+	// SessionIdleTimeout is how long login sessions last unused, so
+	// sessions idle longer aren't listed. Zero means they don't go idle.
+	SessionIdleTimeout time.Duration
+}
+
+// NewRouter returns the /api routes. It is mounted under /api by the server
+// and used directly by tests.
+func NewRouter(opts Options) chi.Router {
+	s := opts.Store
+	root := chi.NewRouter()
+
+	// guard enforces the route's policy against the authenticated caller
+	guard := authz.Require
+
+	// Calendar feeds. Calendar clients can't send the session cookie, so
+	// these routes authenticate with the player's feed token instead.
+	root.Group(func(r chi.Router) {
+		r.Use(middleware.FeedTokenAuth(s))
+		r.With(requireIntParam("team_id", "Invalid team ID"), guard(authz.TeamMember)).Method(http.MethodGet, "/teams/{team_id}/calendar.ics", describe(TeamCalendarHandler(s), doc{summary: "A team's events as an iCalendar feed", auth: authFeed, query: []string{"token"}, content: "text/calendar"}))
+		r.With(requireIntParam("user_id", "Invalid user ID")).Method(http.MethodGet, "/players/{user_id}/calendar.ics", describe(PlayerCalendarHandler(s), doc{summary: "Events of all the player's teams as an iCalendar feed", auth: authFeed, query: []string{"token"}, content: "text/calendar"}))
+	})
+
+	// The OpenAPI document describing these routes, read off the router
+	root.Method(http.MethodGet, "/openapi.json", describe(OpenAPIHandler(root), doc{summary: "This document", auth: authNone, response: map[string]any{}}))
+
+	// Discord slash commands, signed by Discord and run as the linked player
+	if opts.DiscordPublicKey != nil {
+		root.Method(http.MethodPost, "/discord/interactions", describe(DiscordInteractionsHandler(s, opts.DiscordPublicKey), doc{summary: "Run a Discord slash command", auth: authDiscord, request: discord.Interaction{}, response: discord.Response{}}))
+	}
+
+	// Personal API tokens are limited to their scopes on every route below
+	r := root.With(opts.Authenticate, authz.RequireScope)
+
+	// Teams API
+	r.Route("/teams", func(r chi.Router) {
+		r.Method(http.MethodGet, "/", describe(TeamHandler(s), doc{summary: "List all teams", response: []Team{}}))
+		r.With(guard(authz.OrgAdmin)).Method(http.MethodPost, "/", describe(TeamHandler(s), doc{summary: "Create a team", request: TeamRequest{}, status: http.StatusCreated, response: Team{}}))
+		// Team-specific routes
+		r.Route("/{team_id}", func(r chi.Router) {
+			// Ensure teamID is an integer
+			r.Use(requireIntParam("team_id", "Invalid team ID"))
+			// Team-specific handlers
+			r.Method(http.MethodGet, "/", describe(TeamHandler(s), doc{summary: "Get a team and its members", response: TeamDetail{}}))
+			r.With(guard(authz.TeamManager)).Method(http.MethodDelete, "/", describe(TeamHandler(s), doc{summary: "Delete a team", status: http.StatusNoContent}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPut, "/", describe(TeamHandler(s), doc{summary: "Rename a team", request: TeamRequest{}}))
+
+			r.Method(http.MethodGet, "/members", describe(TeamMembersHandler(s), doc{summary: "List a team's members", response: []TeamMember{}}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPost, "/members", describe(TeamMembersHandler(s), doc{summary: "Add a member to a team", request: AddMemberRequest{}, status: http.StatusCreated}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodDelete, "/members", describe(TeamMembersHandler(s), doc{summary: "Remove a member from a team", request: RemoveMemberRequest{}, status: http.StatusNoContent}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPut, "/members", describe(TeamMembersHandler(s), doc{summary: "Change a member's team or in-game role", request: UpdateMemberRequest{}}))
+
+			r.Method(http.MethodGet, "/roster", describe(RosterHandler(s), doc{summary: "Members by in-game role, with composition warnings", response: Roster{}}))
+			r.Method(http.MethodGet, "/roster/limits", describe(RosterLimitsHandler(s), doc{summary: "How many members each in-game role can hold", response: RosterLimits{}}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPut, "/roster/limits", describe(RosterLimitsHandler(s), doc{summary: "Set the roster limits", request: RosterLimits{}, status: http.StatusNoContent}))
+
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodGet, "/invite-links", describe(InviteLinksHandler(s), doc{summary: "List a team's invite links", response: []InviteLink{}}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPost, "/invite-links", describe(InviteLinksHandler(s), doc{summary: "Create an invite link", request: InviteLinkRequest{}, status: http.StatusCreated, response: InviteLink{}}))
+			r.Route("/invite-links/{invite_id}", func(r chi.Router) {
+				r.Use(requireIntParam("invite_id", "Invalid invite ID"), guard(authz.TeamCaptain))
+				r.Method(http.MethodDelete, "/", describe(InviteLinkHandler(s), doc{summary: "Revoke an invite link", status: http.StatusNoContent}))
+			})
+
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodGet, "/invites", describe(TeamInvitesHandler(s), doc{summary: "List a team's pending invites", response: []JoinRequest{}}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPost, "/invites", describe(TeamInvitesHandler(s), doc{summary: "Invite a player by battletag", request: InviteRequest{}, status: http.StatusCreated, response: JoinRequest{}}))
+			r.Route("/invites/{request_id}", func(r chi.Router) {
+				r.Use(requireIntParam("request_id", "Invalid invite ID"), guard(authz.TeamCaptain))
+				r.Method(http.MethodDelete, "/", describe(TeamJoinRequestHandler(s, true), doc{summary: "Withdraw an invite", status: http.StatusNoContent}))
+			})
+
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodGet, "/join-requests", describe(JoinRequestsHandler(s), doc{summary: "List requests to join a team", response: []JoinRequest{}}))
+			r.Method(http.MethodPost, "/join-requests", describe(JoinRequestsHandler(s), doc{summary: "Ask to join a team", request: JoinTeamRequest{}, optionalBody: true, status: http.StatusCreated, response: JoinRequest{}}))
+			r.Route("/join-requests/{request_id}", func(r chi.Router) {
+				// Ensure requestID is an integer
+				r.Use(requireIntParam("request_id", "Invalid request ID"))
+				r.With(guard(authz.TeamCaptain)).Method(http.MethodPost, "/approve", describe(ApproveJoinRequestHandler(s), doc{summary: "Approve a request to join", request: ApproveRequest{}, optionalBody: true, status: http.StatusCreated, response: Membership{}}))
+				r.With(guard(authz.TeamCaptain)).Method(http.MethodDelete, "/", describe(TeamJoinRequestHandler(s, false), doc{summary: "Deny a request to join", status: http.StatusNoContent}))
+			})
+
+			r.Method(http.MethodGet, "/schedule", describe(ScheduleHandler(s), doc{summary: "The time slots of a team's grid", response: []TimeSlot{}}))
+			r.Method(http.MethodGet, "/grid", describe(GridHandler(s), doc{summary: "Get a team's weekly grid", response: Grid{}}))
+			r.With(guard(authz.TeamCoach)).Method(http.MethodPut, "/grid", describe(GridHandler(s), doc{summary: "Replace a team's weekly grid and regenerate its time slots", request: Grid{}, response: []TimeSlot{}}))
+
+			r.Method(http.MethodGet, "/availability", describe(AvailabilityHandler(s), doc{summary: "The caller's availability for a week", query: []string{"tz", "week"}, response: []SlotAvailability{}}))
+			r.With(guard(authz.TeamMember)).Method(http.MethodPost, "/availability", describe(AvailabilityHandler(s), doc{summary: "Set the caller's availability for one or more weeks", query: []string{"tz", "week"}, request: AvailabilityRequest{}, response: map[string]int{}}))
+			r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/availability/summary", describe(AvailabilitySummaryHandler(s), doc{summary: "Members available for each slot, and the best windows to meet", query: []string{"tz", "week", "min_duration", "limit", "require"}, response: AvailabilitySummary{}}))
+			r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/lineups", describe(LineupsHandler(s), doc{summary: "Suggest a starting lineup for each slot", query: []string{"tz", "week", "full_only"}, response: LineupSuggestions{}}))
+
+			r.With(guard(authz.TeamCoach)).Method(http.MethodGet, "/discord", describe(DiscordSettingsHandler(s), doc{summary: "Get a team's Discord notification settings", response: DiscordSettings{}}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodPut, "/discord", describe(DiscordSettingsHandler(s), doc{summary: "Set up Discord notifications", request: DiscordSettings{}, status: http.StatusNoContent}))
+			r.With(guard(authz.TeamCaptain)).Method(http.MethodDelete, "/discord", describe(DiscordSettingsHandler(s), doc{summary: "Turn Discord notifications off", status: http.StatusNoContent}))
+
+			r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/events", describe(EventsHandler(s), doc{summary: "List a team's events, expanding recurring ones", query: []string{"tz", "from", "to"}, response: []Event{}}))
+			r.With(guard(authz.TeamCoach)).Method(http.MethodPost, "/events", describe(EventsHandler(s), doc{summary: "Create an event", query: []string{"tz"}, request: EventRequest{}, status: http.StatusCreated, response: Event{}}))
+			r.With(guard(authz.TeamCoach)).Method(http.MethodPost, "/events/from-summary", describe(EventFromSummaryHandler(s), doc{summary: "Create an event from a suggested window, RSVPing everyone available", query: []string{"tz"}, request: EventRequest{}, status: http.StatusCreated, response: Event{}}))
+			r.Route("/events/{event_id}", func(r chi.Router) {
+				// Ensure eventID is an integer
+				r.Use(requireIntParam("event_id", "Invalid event ID"))
+				r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/", describe(EventHandler(s), doc{summary: "Get an event and its RSVPs", query: []string{"tz"}, response: Event{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodPut, "/", describe(EventHandler(s), doc{summary: "Update an event", query: []string{"tz"}, request: EventRequest{}, response: Event{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodDelete, "/", describe(EventHandler(s), doc{summary: "Cancel an event", status: http.StatusNoContent}))
+				r.With(guard(authz.TeamMember)).Method(http.MethodPut, "/rsvp", describe(RSVPHandler(s), doc{summary: "RSVP to an event", request: RSVPRequest{}, response: RSVP{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodPut, "/occurrences", describe(OccurrencesHandler(s), doc{summary: "Override or cancel one occurrence of a recurring event", query: []string{"tz"}, request: OccurrenceRequest{}, response: Event{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodDelete, "/occurrences", describe(OccurrencesHandler(s), doc{summary: "Restore an occurrence", query: []string{"original_start"}, status: http.StatusNoContent}))
+				r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/result", describe(EventResultHandler(s), doc{summary: "Get the result of a played match or scrim", query: []string{"tz", "original_start"}, response: Match{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodPut, "/result", describe(EventResultHandler(s), doc{summary: "Record or replace the result of a match or scrim", query: []string{"tz"}, request: MatchRequest{}, response: Match{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodDelete, "/result", describe(EventResultHandler(s), doc{summary: "Remove the result of a match or scrim", query: []string{"original_start"}, status: http.StatusNoContent}))
+			})
+
+			r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/matches", describe(MatchesHandler(s), doc{summary: "List a team's match history, most recent first", query: []string{"tz", "from", "to", "opponent"}, response: []Match{}}))
+			r.With(guard(authz.TeamCoach)).Method(http.MethodPost, "/matches", describe(MatchesHandler(s), doc{summary: "Record a match that wasn't an event", query: []string{"tz"}, request: MatchRequest{}, status: http.StatusCreated, response: Match{}}))
+			r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/matches/stats", describe(MatchStatsHandler(s), doc{summary: "Win/loss record by map, mode, opponent and over time", query: []string{"tz", "from", "to", "period"}, response: MatchStats{}}))
+			r.Route("/matches/{match_id}", func(r chi.Router) {
+				// Ensure matchID is an integer
+				r.Use(requireIntParam("match_id", "Invalid match ID"))
+				r.With(guard(authz.TeamMember)).Method(http.MethodGet, "/", describe(MatchHandler(s), doc{summary: "Get a match and its maps", query: []string{"tz"}, response: Match{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodPut, "/", describe(MatchHandler(s), doc{summary: "Replace a match's result", query: []string{"tz"}, request: MatchRequest{}, response: Match{}}))
+				r.With(guard(authz.TeamCoach)).Method(http.MethodDelete, "/", describe(MatchHandler(s), doc{summary: "Delete a match", status: http.StatusNoContent}))
+			})
+		})
+	})
+
+	// Players API
+	r.Route("/players", func(r chi.Router) {
+		r.Method(http.MethodGet, "/", describe(PlayerHandler(s), doc{summary: "List all players", response: []Player{}}))
+		r.Route("/{user_id}", func(r chi.Router) {
+			// Ensure userID is an integer
+			r.Use(requireIntParam("user_id", "Invalid user ID"))
+			// Player-specific handlers
+			r.Method(http.MethodGet, "/", describe(PlayerHandler(s), doc{summary: "Get a player", response: Player{}}))
+			r.With(guard(authz.Self)).Method(http.MethodDelete, "/", describe(PlayerHandler(s), doc{summary: "Delete a player and their memberships", status: http.StatusNoContent}))
+			r.With(guard(authz.Self)).Method(http.MethodPut, "/", describe(PlayerHandler(s), doc{summary: "Update a player's battletag or timezone", request: PlayerRequest{}}))
+			r.With(guard(authz.Self)).Method(http.MethodPut, "/active-team", describe(ActiveTeamHandler(s), doc{summary: "Switch the team the player works in", request: ActiveTeamRequest{}, status: http.StatusNoContent}))
+			r.Method(http.MethodGet, "/profile", describe(GameProfileHandler(s), doc{summary: "Get the player's Overwatch profile", response: GameProfile{}}))
+			r.With(guard(authz.Self)).Method(http.MethodPut, "/profile", describe(GameProfileHandler(s), doc{summary: "Set roles, ranks, main heroes and region", request: GameProfile{}, response: GameProfile{}}))
+			r.With(guard(authz.Self)).Method(http.MethodPost, "/calendar-token", describe(CalendarTokenHandler(s), doc{summary: "Issue a calendar feed token", status: http.StatusCreated, response: CalendarToken{}}))
+			r.With(guard(authz.Self)).Method(http.MethodDelete, "/calendar-token", describe(CalendarTokenHandler(s), doc{summary: "Revoke the calendar feed token", status: http.StatusNoContent}))
+			r.With(guard(authz.Self)).Method(http.MethodGet, "/discord-link", describe(DiscordLinkHandler(s), doc{summary: "Show the linked Discord account", response: DiscordLink{}}))
+			r.With(guard(authz.Self)).Method(http.MethodPost, "/discord-link", describe(DiscordLinkHandler(s), doc{summary: "Issue a code for the bot's /link command", status: http.StatusCreated, response: DiscordLinkCode{}}))
+			r.With(guard(authz.Self)).Method(http.MethodDelete, "/discord-link", describe(DiscordLinkHandler(s), doc{summary: "Unlink the Discord account", status: http.StatusNoContent}))
+			r.With(guard(authz.Self)).Method(http.MethodGet, "/email-preferences", describe(EmailPreferencesHandler(s), doc{summary: "Get the player's email preferences", response: EmailPreferences{}}))
+			r.With(guard(authz.Self)).Method(http.MethodPut, "/email-preferences", describe(EmailPreferencesHandler(s), doc{summary: "Opt in to or out of emails", request: EmailPreferences{}, status: http.StatusNoContent}))
+			r.With(guard(authz.Self)).Method(http.MethodDelete, "/email-preferences", describe(EmailPreferencesHandler(s), doc{summary: "Stop all email", status: http.StatusNoContent}))
+
+			r.With(guard(authz.Self)).Method(http.MethodGet, "/invites", describe(PlayerJoinRequestsHandler(s, true), doc{summary: "List the player's pending invites", response: []JoinRequest{}}))
+			r.With(guard(authz.Self)).Method(http.MethodGet, "/join-requests", describe(PlayerJoinRequestsHandler(s, false), doc{summary: "List the player's requests to join", response: []JoinRequest{}}))
+			r.Route("/invites/{request_id}", func(r chi.Router) {
+				r.Use(requireIntParam("request_id", "Invalid invite ID"), guard(authz.Self))
+				r.Method(http.MethodPost, "/accept", describe(AcceptInviteHandler(s), doc{summary: "Accept an invite", status: http.StatusCreated, response: Membership{}}))
+				r.Method(http.MethodDelete, "/", describe(PlayerJoinRequestHandler(s, true), doc{summary: "Decline an invite", status: http.StatusNoContent}))
+			})
+			r.Route("/join-requests/{request_id}", func(r chi.Router) {
+				r.Use(requireIntParam("request_id", "Invalid request ID"), guard(authz.Self))
+				r.Method(http.MethodDelete, "/", describe(PlayerJoinRequestHandler(s, false), doc{summary: "Withdraw a request to join", status: http.StatusNoContent}))
+			})
+			r.With(guard(authz.Self)).Method(http.MethodGet, "/api-tokens", describe(APITokensHandler(s), doc{summary: "List the player's personal API tokens", response: []APIToken{}}))
+			r.With(guard(authz.Self)).Method(http.MethodPost, "/api-tokens", describe(APITokensHandler(s), doc{summary: "Create a personal API token", request: APITokenRequest{}, status: http.StatusCreated, response: APIToken{}}))
+			r.Route("/api-tokens/{token_id}", func(r chi.Router) {
+				r.Use(requireIntParam("token_id", "Invalid token ID"), guard(authz.Self))
+				r.Method(http.MethodDelete, "/", describe(APITokenHandler(s), doc{summary: "Revoke a personal API token", status: http.StatusNoContent}))
+			})
+			r.With(guard(authz.Self)).Method(http.MethodGet, "/sessions", describe(SessionsHandler(s, opts.SessionIdleTimeout), doc{summary: "List the player's login sessions", response: []LoginSession{}}))
+			r.With(guard(authz.Self)).Method(http.MethodDelete, "/sessions", describe(SessionsHandler(s, opts.SessionIdleTimeout), doc{summary: "Log out everywhere, ending all the player's sessions", status: http.StatusNoContent}))
+			r.Route("/sessions/{session_id}", func(r chi.Router) {
+				r.Use(requireIntParam("session_id", "Invalid session ID"), guard(authz.Self))
+				r.Method(http.MethodDelete, "/", describe(SessionHandler(s), doc{summary: "End one of the player's login sessions", status: http.StatusNoContent}))
+			})
+			if opts.Profiles != nil {
+				r.With(guard(authz.Self)).Method(http.MethodPost, "/profile/refresh", describe(RefreshGameProfileHandler(s, opts.Profiles), doc{summary: "Fetch ranks from the career profile now", response: GameProfile{}}))
+			}
+		})
+	})
+
+	// Join a team through an invite link
+	r.Method(http.MethodPost, "/invite-links/redeem", describe(RedeemInviteLinkHandler(s), doc{summary: "Join a team through an invite link", request: RedeemInviteRequest{}, status: http.StatusCreated, response: Membership{}}))
+
+	// TimeSlots API
+	r.Route("/timeslots", func(r chi.Router) {
+		r.Method(http.MethodGet, "/", describe(TimeSlotsHandler(s), doc{summary: "List the global time slots, or a team's", query: []string{"team_id"}, response: []TimeSlot{}}))
+		r.With(guard(authz.OrgAdmin)).Method(http.MethodPost, "/", describe(TimeSlotsHandler(s), doc{summary: "Create a global time slot", request: TimeSlotRequest{}, status: http.StatusCreated, response: TimeSlot{}}))
+		r.Route("/{timeSlotID}", func(r chi.Router) {
+			// Ensure timeSlotID is an integer
+			r.Use(requireIntParam("timeSlotID", "Invalid time slot ID"))
+			// TimeSlot-specific handlers
+			r.Method(http.MethodGet, "/", describe(TimeSlotsHandler(s), doc{summary: "Get a time slot", response: TimeSlot{}}))
+			r.With(guard(authz.OrgAdmin)).Method(http.MethodDelete, "/", describe(TimeSlotsHandler(s), doc{summary: "Delete a time slot", status: http.StatusNoContent}))
+			r.With(guard(authz.OrgAdmin)).Method(http.MethodPut, "/", describe(TimeSlotsHandler(s), doc{summary: "Move a global time slot", request: TimeSlotRequest{}}))
+		})
+	})
+
+	return root
+}
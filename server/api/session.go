@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Membership is a team a player belongs to and their role on it
+type Membership struct {
+	TeamID   int    `json:"team_id"`
+	TeamName string `json:"team_name"`
+	Role     string `json:"role"`
+}
+
+// AuthStatus describes the logged in player. UserID and battletag keep the
+// names the pages have always read.
+type AuthStatus struct {
+	UserID       int          `json:"UserID"`
+	Battletag    string       `json:"battletag"`
+	ActiveTeamID int          `json:"active_team_id"` // 0 when on no team
+	Memberships  []Membership `json:"memberships"`
+}
+
+// ActiveTeamRequest picks the team a player works in.
+type ActiveTeamRequest struct {
+	TeamID int `json:"team_id"`
+}
+
+func newMembership(m store.Member) Membership {
+	return Membership{TeamID: m.TeamID, TeamName: m.TeamName, Role: m.Role}
+}
+
+// AuthStatusHandler returns the caller's AuthStatus, listing every team they
+// belong to. It is served at /auth/status, behind the session middleware.
+func AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
+	caller, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		middleware.Unauthorized(w, r, "authentication required")
+		return
+	}
+	resp := AuthStatus{
+		UserID:       caller.UserID,
+		Battletag:    caller.Battletag,
+		ActiveTeamID: caller.ActiveTeamID,
+		Memberships:  make([]Membership, 0, len(caller.Memberships)),
+	}
+	for _, m := range caller.Memberships {
+		resp.Memberships = append(resp.Memberships, newMembership(m))
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// ActiveTeamHandler switches (PUT) the team the player works in. The
+// schedule and team pages open it when no team is named in the URL.
+func ActiveTeamHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+		if r.Method != http.MethodPut {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req ActiveTeamRequest
+		if !response.Decode(w, r, &req) {
+			return
+		}
+		if req.TeamID == 0 {
+			response.Error(w, r, "team_id is required", http.StatusBadRequest)
+			return
+		}
+		if err := s.Players.SetActiveTeam(r.Context(), userID, req.TeamID); err != nil {
+			response.StoreError(w, r, err, "Not a member of that team")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
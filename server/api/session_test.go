@@ -0,0 +1,59 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestActiveTeam(t *testing.T) {
+	s, h, alpha, members := newTeam(t, "player")
+	player := members[0]
+	ctx := context.Background()
+	bravo, _ := s.Teams.CreateTeam(ctx, "Bravo")
+	other, _ := s.Teams.CreateTeam(ctx, "Other")
+	s.Memberships.AddMember(ctx, store.Member{TeamID: bravo.ID, UserID: player.ID, Role: "coach"})
+	status := testAuth(s)(http.HandlerFunc(api.AuthStatusHandler))
+	path := "/players/" + strconv.Itoa(player.ID) + "/active-team"
+
+	getStatus := func() api.AuthStatus {
+		t.Helper()
+		rr := do(t, status, http.MethodGet, "/auth/status", nil, player.ID)
+		var got api.AuthStatus
+		if rr.Code != http.StatusOK || json.NewDecoder(rr.Body).Decode(&got) != nil {
+			t.Fatalf("status returned %v: %s", rr.Code, rr.Body)
+		}
+		return got
+	}
+
+	// The first team is active until the player picks one
+	got := getStatus()
+	want := []api.Membership{{TeamID: alpha.ID, TeamName: "Alpha", Role: "player"}, {TeamID: bravo.ID, TeamName: "Bravo", Role: "coach"}}
+	if got.UserID != player.ID || got.ActiveTeamID != alpha.ID || len(got.Memberships) != 2 || got.Memberships[0] != want[0] || got.Memberships[1] != want[1] {
+		t.Errorf("unexpected status: %+v", got)
+	}
+
+	if rr := do(t, h, http.MethodPut, path, map[string]int{"team_id": other.ID}, player.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("switch to a team the player isn't on returned %v, want 404", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPut, path, map[string]int{}, player.ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("switch without team_id returned %v, want 400", rr.Code)
+	}
+	if rr := do(t, h, http.MethodPut, path, map[string]int{"team_id": bravo.ID}, player.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("switch returned %v: %s", rr.Code, rr.Body)
+	}
+	if got := getStatus(); got.ActiveTeamID != bravo.ID {
+		t.Errorf("active team %d, want %d", got.ActiveTeamID, bravo.ID)
+	}
+
+	// Leaving the active team falls back to the first remaining one
+	s.Memberships.RemoveMember(ctx, bravo.ID, player.ID)
+	if got := getStatus(); got.ActiveTeamID != alpha.ID || len(got.Memberships) != 1 {
+		t.Errorf("after leaving: %+v", got)
+	}
+}
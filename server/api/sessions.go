@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// LoginSession is one of a player's active login sessions.
+type LoginSession struct {
+	ID         int       `json:"session_id"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // the latest it can last, however active
+}
+
+func newLoginSession(s store.Session) LoginSession {
+	return LoginSession{ID: s.ID, UserAgent: s.UserAgent, CreatedAt: s.CreatedAt, LastSeenAt: s.LastSeenAt, ExpiresAt: s.ExpiresAt}
+}
+
+// SessionsHandler lists (GET) the player's active login sessions, or logs
+// them out everywhere (DELETE), ending every session including the
+// caller's. Sessions unused for idleTimeout have ended; zero means sessions
+// don't go idle.
+func SessionsHandler(s *store.Store, idleTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			now := time.Now().UTC()
+			idleSince := time.Time{}
+			if idleTimeout > 0 {
+				idleSince = now.Add(-idleTimeout)
+			}
+			list, err := s.Sessions.ListSessions(r.Context(), userID, now, idleSince)
+			if err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			resp := make([]LoginSession, 0, len(list))
+			for _, sess := range list {
+				resp = append(resp, newLoginSession(sess))
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodDelete:
+			if _, err := s.Sessions.RevokeSessions(r.Context(), userID); err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// SessionHandler ends (DELETE) one of the player's login sessions, logging
+// out the browser that holds it.
+func SessionHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+		sessionID, _ := urlParamInt(r, "session_id")
+		if r.Method != http.MethodDelete {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.Sessions.RevokeSession(r.Context(), userID, sessionID); err != nil {
+			response.StoreError(w, r, err, "Session not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestSessions(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 1001, "John#1234")
+	other, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Jane#1234")
+	sessionStore := middleware.NewServerStore(s.Sessions, time.Hour, 24*time.Hour)
+	h := api.NewRouter(api.Options{Store: s, Authenticate: middleware.SessionAuth(sessionStore, s), SessionIdleTimeout: time.Hour})
+
+	// login saves a session the way the login callback does, and returns
+	// its cookie
+	login := func(userID int, userAgent string) *http.Cookie {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback/battlenet", nil)
+		req.Header.Set("User-Agent", userAgent)
+		rr := httptest.NewRecorder()
+		session, err := sessionStore.Get(req, middleware.SessionName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.Values["UserID"] = strconv.Itoa(userID)
+		if err := session.Save(req, rr); err != nil {
+			t.Fatal(err)
+		}
+		cookies := rr.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Value == "" || cookies[0].MaxAge != 24*60*60 {
+			t.Fatalf("unexpected session cookies: %+v", cookies)
+		}
+		return cookies[0]
+	}
+	with := func(cookie *http.Cookie, method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	laptop, phone := login(player.ID, "laptop"), login(player.ID, "phone")
+	janes := login(other.ID, "tablet")
+	path := "/players/" + strconv.Itoa(player.ID) + "/sessions"
+	rr := with(laptop, http.MethodGet, path)
+	var list []api.LoginSession
+	if json.NewDecoder(rr.Body).Decode(&list); rr.Code != http.StatusOK || len(list) != 2 {
+		t.Fatalf("GET returned %v: %+v", rr.Code, list)
+	}
+	if rr := with(janes, http.MethodGet, path); rr.Code != http.StatusForbidden {
+		t.Errorf("GET by another player returned %v, want 403", rr.Code)
+	}
+
+	// Ending one session logs out only the browser holding it
+	var phoneID int
+	for _, sess := range list {
+		if sess.UserAgent == "phone" {
+			phoneID = sess.ID
+		}
+	}
+	if rr := with(laptop, http.MethodDelete, path+"/"+strconv.Itoa(phoneID)); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE one returned %v", rr.Code)
+	}
+	if rr := with(phone, http.MethodGet, path); rr.Code != http.StatusUnauthorized {
+		t.Errorf("ended session returned %v, want 401", rr.Code)
+	}
+	if rr := with(laptop, http.MethodDelete, path+"/"+strconv.Itoa(phoneID)); rr.Code != http.StatusNotFound {
+		t.Errorf("DELETE of an ended session returned %v, want 404", rr.Code)
+	}
+
+	// Logging out everywhere ends the caller's own session too, and leaves
+	// other players logged in
+	if rr := with(laptop, http.MethodDelete, path); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE all returned %v", rr.Code)
+	}
+	if rr := with(laptop, http.MethodGet, path); rr.Code != http.StatusUnauthorized {
+		t.Errorf("session after logging out everywhere returned %v, want 401", rr.Code)
+	}
+	if rr := with(janes, http.MethodGet, "/players/"+strconv.Itoa(other.ID)+"/sessions"); rr.Code != http.StatusOK {
+		t.Errorf("another player's session returned %v, want 200", rr.Code)
+	}
+
+	// Logging out deletes the session
+	req := httptest.NewRequest(http.MethodGet, "/logout", nil)
+	req.AddCookie(janes)
+	session, _ := sessionStore.Get(req, middleware.SessionName)
+	session.Options.MaxAge = -1
+	if err := session.Save(req, httptest.NewRecorder()); err != nil {
+		t.Fatal(err)
+	}
+	if rr := with(janes, http.MethodGet, "/players/"+strconv.Itoa(other.ID)+"/sessions"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("session after logout returned %v, want 401", rr.Code)
+	}
+}
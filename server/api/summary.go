@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/schedule"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// SlotSummary is how many members can make one slot, and who can't
+type SlotSummary struct {
+	SlotID    int          `json:"slot_id"`
+	Day       string       `json:"day"`
+	Time      string       `json:"time"`
+	StartsAt  time.Time    `json:"starts_at"`
+	EndsAt    time.Time    `json:"ends_at"`
+	Available int          `json:"available"`
+	Missing   []TeamMember `json:"missing"`
+}
+
+// MeetingWindow is a run of back-to-back slots the team could meet in
+type MeetingWindow struct {
+	StartsAt  time.Time    `json:"starts_at"`
+	EndsAt    time.Time    `json:"ends_at"`
+	Minutes   int          `json:"minutes"`
+	SlotIDs   []int        `json:"slot_ids"`
+	Available int          `json:"available"`
+	Attendees []TeamMember `json:"attendees"`
+	Missing   []TeamMember `json:"missing"`
+}
+
+// AvailabilitySummary aggregates the team's availability for one week
+type AvailabilitySummary struct {
+	WeekStart time.Time       `json:"week_start"`
+	Members   int             `json:"members"`
+	Slots     []SlotSummary   `json:"slots"`
+	Windows   []MeetingWindow `json:"windows"`
+}
+
+// maxSummaryWindows caps the ?limit= of AvailabilitySummaryHandler.
+const maxSummaryWindows = 50
+
+func newTeamMembers(members []schedule.Member) []TeamMember {
+	resp := make([]TeamMember, 0, len(members))
+	for _, m := range members {
+		resp = append(resp, TeamMember{ID: m.UserID, Username: m.Username, Role: m.Role})
+	}
+	return resp
+}
+
+// errInvalidGrid means a team's grid has slots that can't be placed in a week.
+var errInvalidGrid = errors.New("invalid team grid")
+
+// summarizeWeek summarizes the team's availability for the week starting at
+// weekStart, and returns it with the team's members.
+func summarizeWeek(ctx context.Context, s *store.Store, teamID int, grid store.Grid, weekStart time.Time, opts schedule.Options) (schedule.Summary, []store.Member, error) {
+	teamSlotList, err := teamSlots(ctx, s, teamID)
+	if err != nil {
+		return schedule.Summary{}, nil, err
+	}
+	var slots []schedule.Slot
+	for _, slot := range teamSlotList {
+		iv, err := grid.SlotInterval(slot, weekStart)
+		if err != nil {
+			return schedule.Summary{}, nil, fmt.Errorf("%w: %v", errInvalidGrid, err)
+		}
+		slots = append(slots, schedule.Slot{ID: slot.ID, Start: iv.Start, End: iv.End})
+	}
+
+	members, err := s.Memberships.ListMembers(ctx, teamID)
+	if err != nil {
+		return schedule.Summary{}, nil, err
+	}
+	availability, err := s.Availability.ListTeamAvailability(ctx, teamID, weekStart, weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return schedule.Summary{}, nil, err
+	}
+	var team []schedule.Member
+	for _, m := range members {
+		team = append(team, schedule.Member{
+			UserID:    m.UserID,
+			Username:  m.Username,
+			Role:      m.Role,
+			Available: store.MergeIntervals(availability[m.UserID]),
+		})
+	}
+	return schedule.Summarize(slots, team, opts), members, nil
+}
+
+// AvailabilitySummaryHandler counts the members available for each slot of a
+// week and ranks the best windows to meet. Query parameters:
+//
+//	week          YYYY-MM-DD in the week to summarize (default: this week)
+//	tz            timezone to show days and times in (default: caller's)
+//	min_duration  shortest useful window in minutes (default: one slot)
+//	limit         number of windows to return (default 5)
+//	require       role counts every window must meet, e.g. player:5,coach:1
+func AvailabilitySummaryHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teamID, _ := urlParamInt(r, "team_id")
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok {
+			middleware.Unauthorized(w, r, "authentication required")
+			return
+		}
+		query := r.URL.Query()
+
+		loc, err := requestZone(r, caller)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		grid, err := s.Grids.GetGrid(r.Context(), teamID)
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		weekStart, err := requestWeek(r, grid)
+		if err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		opts := schedule.Options{MinDuration: time.Duration(grid.SlotMinutes) * time.Minute, Limit: 5}
+		if v := query.Get("min_duration"); v != "" {
+			minutes, err := strconv.Atoi(v)
+			if err != nil || minutes < 1 {
+				response.Error(w, r, "min_duration must be a positive number of minutes", http.StatusBadRequest)
+				return
+			}
+			opts.MinDuration = time.Duration(minutes) * time.Minute
+		}
+		if v := query.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxSummaryWindows {
+				response.Error(w, r, "limit must be between 1 and "+strconv.Itoa(maxSummaryWindows), http.StatusBadRequest)
+				return
+			}
+			opts.Limit = limit
+		}
+		if opts.Require, err = schedule.ParseRequirements(query.Get("require")); err != nil {
+			response.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sum, members, err := summarizeWeek(r.Context(), s, teamID, grid, weekStart, opts)
+		if errors.Is(err, errInvalidGrid) {
+			log.Printf("Error placing slots for team %d: %v", teamID, err)
+			response.Error(w, r, "Invalid team grid", http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			response.StoreError(w, r, err, "Team not found")
+			return
+		}
+		resp := AvailabilitySummary{
+			WeekStart: weekStart.In(loc),
+			Members:   len(members),
+			Slots:     make([]SlotSummary, 0, len(sum.Slots)),
+			Windows:   make([]MeetingWindow, 0, len(sum.Windows)),
+		}
+		for _, slot := range sum.Slots {
+			start := slot.Start.In(loc)
+			resp.Slots = append(resp.Slots, SlotSummary{
+				SlotID:    slot.ID,
+				Day:       start.Weekday().String(),
+				Time:      start.Format("15:04"),
+				StartsAt:  start,
+				EndsAt:    slot.End.In(loc),
+				Available: len(slot.Available),
+				Missing:   newTeamMembers(slot.Missing),
+			})
+		}
+		for _, win := range sum.Windows {
+			mw := MeetingWindow{
+				StartsAt:  win.Start.In(loc),
+				EndsAt:    win.End.In(loc),
+				Minutes:   int(win.Duration() / time.Minute),
+				Available: len(win.Attendees),
+				Attendees: newTeamMembers(win.Attendees),
+				Missing:   newTeamMembers(win.Missing),
+			}
+			for _, slot := range win.Slots {
+				mw.SlotIDs = append(mw.SlotIDs, slot.ID)
+			}
+			resp.Windows = append(resp.Windows, mw)
+		}
+		response.JSON(w, http.StatusOK, resp)
+	}
+}
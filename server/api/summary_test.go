@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func TestAvailabilitySummaryHandler(t *testing.T) {
+	s, h, team, players := newTeam(t, "coach", "player", "player")
+	outsider, _ := s.Players.UpsertBattleNetPlayer(context.Background(), 999, "Out#1234")
+	teamPath := "/teams/" + strconv.Itoa(team.ID) + "/"
+
+	grid := api.Grid{SlotMinutes: 60, Timezone: "UTC", Days: []api.GridDay{{Weekday: "Friday", Start: "18:00", End: "22:00"}}}
+	if rr := do(t, h, http.MethodPut, teamPath+"grid", grid, players[0].ID); rr.Code != http.StatusOK {
+		t.Fatalf("PUT grid returned %v: %s", rr.Code, rr.Body)
+	}
+	picks := [][]string{{"19:00", "20:00"}, {"18:00", "19:00", "20:00"}, {"20:00", "21:00"}}
+	for i, times := range picks {
+		var body api.AvailabilityRequest
+		for _, tm := range times {
+			body.SelectedSlots = append(body.SelectedSlots, api.AvailabilitySlot{Day: "Friday", Time: tm})
+		}
+		if rr := do(t, h, http.MethodPost, teamPath+"availability?week=2025-05-05", body, players[i].ID); rr.Code != http.StatusOK {
+			t.Fatalf("POST availability returned %v: %s", rr.Code, rr.Body)
+		}
+	}
+
+	path := teamPath + "availability/summary?week=2025-05-05&min_duration=120&require=player:1,coach:1"
+	rr := do(t, h, http.MethodGet, path, nil, players[1].ID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET summary returned %v: %s", rr.Code, rr.Body)
+	}
+	var sum api.AvailabilitySummary
+	if err := json.NewDecoder(rr.Body).Decode(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Members != 3 || len(sum.Slots) != 4 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	counts := []int{1, 2, 3, 1}
+	for i, slot := range sum.Slots {
+		if slot.Available != counts[i] || len(slot.Missing) != 3-counts[i] {
+			t.Errorf("slot %s: %d available, %d missing, want %d", slot.Time, slot.Available, len(slot.Missing), counts[i])
+		}
+	}
+	if len(sum.Windows) != 1 || sum.Windows[0].StartsAt.Hour() != 19 || sum.Windows[0].Minutes != 120 || sum.Windows[0].Available != 2 {
+		t.Errorf("unexpected windows: %+v", sum.Windows)
+	}
+
+	if rr := do(t, h, http.MethodGet, teamPath+"availability/summary?require=tank:2", nil, players[1].ID); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad requirement returned %v, want 400", rr.Code)
+	}
+	if rr := do(t, h, http.MethodGet, teamPath+"availability/summary", nil, outsider.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("non-member got %v, want 403", rr.Code)
+	}
+}
@@ -1,14 +1,55 @@
 package api
 
+import "github.com/KhrisKringle/Vivacity_website-main/server/store"
+
 // Team represents the structure of a team
 type Team struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
 }
 
+// TeamDetail is a team along with its members
+type TeamDetail struct {
+	Team
+	Members []TeamMember `json:"members"`
+}
+
 // TeamMember represents a user within a team context
 type TeamMember struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	GameRole string `json:"game_role,omitempty"`
+}
+
+// Player represents a registered user
+type Player struct {
+	ID          int    `json:"id"`
+	BattleNetID int64  `json:"battlenet_id"`
+	Username    string `json:"username"`
+	Timezone    string `json:"timezone"`
+}
+
+// TimeSlot represents a weekly slot on a schedule
+type TimeSlot struct {
+	ID      int    `json:"slot_id"`
+	TeamID  int    `json:"team_id,omitempty"`
+	Weekday string `json:"weekday"`
+	Time    string `json:"time"`
+}
+
+func newTeam(t store.Team) Team {
+	return Team{ID: t.ID, Name: t.Name}
+}
+
+func newTeamMember(m store.Member) TeamMember {
+	return TeamMember{ID: m.UserID, Username: m.Username, Role: m.Role, GameRole: m.GameRole}
+}
+
+func newPlayer(p store.Player) Player {
+	return Player{ID: p.ID, BattleNetID: p.BattleNetID, Username: p.Username, Timezone: p.Timezone}
+}
+
+func newTimeSlot(s store.TimeSlot) TimeSlot {
+	return TimeSlot{ID: s.ID, TeamID: s.TeamID, Weekday: s.Weekday, Time: s.Time}
 }
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// APIToken is a personal API token for bots and scripts, sent as
+// Authorization: Bearer. Token is only shown when the token is created.
+type APIToken struct {
+	ID         int        `json:"token_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APITokenRequest creates a personal API token.
+type APITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"` // default 90
+}
+
+const (
+	// defaultTokenDays is how long an API token lasts unless the player
+	// says otherwise, and maxTokenDays is the longest allowed.
+	defaultTokenDays = 90
+	maxTokenDays     = 365
+	// maxAPITokens caps how many tokens, expired ones included, a player
+	// can hold.
+	maxAPITokens = 25
+)
+
+// Validate checks the name, that each scope is known and listed once, and
+// that the lifetime is in range.
+func (req APITokenRequest) Validate() []response.FieldError {
+	var f response.Fields
+	if f.Required("name", req.Name) {
+		f.MaxLength("name", req.Name, maxNameLength)
+	}
+	if len(req.Scopes) == 0 {
+		f.Add("scopes", "is required")
+	}
+	for i, scope := range req.Scopes {
+		field := fmt.Sprintf("scopes[%d]", i)
+		if f.Required(field, scope) && f.OneOf(field, scope, store.APIScopes) && slices.Contains(req.Scopes[:i], scope) {
+			f.Add(field, "is listed more than once")
+		}
+	}
+	if req.ExpiresInDays != nil && (*req.ExpiresInDays < 1 || *req.ExpiresInDays > maxTokenDays) {
+		f.Add("expires_in_days", "must be between 1 and %d", maxTokenDays)
+	}
+	return f
+}
+
+func newAPIToken(t store.APIToken) APIToken {
+	resp := APIToken{ID: t.ID, Name: t.Name, Scopes: t.Scopes, ExpiresAt: t.ExpiresAt, CreatedAt: t.CreatedAt}
+	if resp.Scopes == nil {
+		resp.Scopes = []string{}
+	}
+	if !t.LastUsedAt.IsZero() {
+		resp.LastUsedAt = &t.LastUsedAt
+	}
+	return resp
+}
+
+// APITokensHandler lists (GET) and creates (POST) the player's personal API
+// tokens. A new token is only shown in the POST response.
+func APITokensHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			tokens, err := s.APITokens.ListAPITokens(r.Context(), userID)
+			if err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			resp := make([]APIToken, 0, len(tokens))
+			for _, t := range tokens {
+				resp = append(resp, newAPIToken(t))
+			}
+			response.JSON(w, http.StatusOK, resp)
+
+		case http.MethodPost:
+			var req APITokenRequest
+			if !response.Decode(w, r, &req) {
+				return
+			}
+			days := defaultTokenDays
+			if req.ExpiresInDays != nil {
+				days = *req.ExpiresInDays
+			}
+			existing, err := s.APITokens.ListAPITokens(r.Context(), userID)
+			if err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			if len(existing) >= maxAPITokens {
+				response.Error(w, r, fmt.Sprintf("A player can hold at most %d API tokens; revoke one first", maxAPITokens), http.StatusConflict)
+				return
+			}
+
+			token, hash, err := middleware.NewAPIToken()
+			if err != nil {
+				log.Printf("Error generating API token: %v", err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			created, err := s.APITokens.CreateAPIToken(r.Context(), store.APIToken{
+				UserID:    userID,
+				Name:      req.Name,
+				Scopes:    req.Scopes,
+				ExpiresAt: time.Now().AddDate(0, 0, days).UTC().Truncate(time.Second),
+			}, hash)
+			if err != nil {
+				response.StoreError(w, r, err, "Player not found")
+				return
+			}
+			resp := newAPIToken(created)
+			resp.Token = token
+			response.JSON(w, http.StatusCreated, resp)
+
+		default:
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APITokenHandler revokes (DELETE) one of the player's personal API tokens.
+func APITokenHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := urlParamInt(r, "user_id")
+		tokenID, _ := urlParamInt(r, "token_id")
+		if r.Method != http.MethodDelete {
+			response.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.APITokens.DeleteAPIToken(r.Context(), userID, tokenID); err != nil {
+			response.StoreError(w, r, err, "API token not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,120 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	"github.com/gorilla/sessions"
+)
+
+func TestAPITokens(t *testing.T) {
+	s, h, team, members := newTeam(t, "captain")
+	player := members[0]
+	ctx := context.Background()
+	other, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Jane#1234")
+	path := "/players/" + strconv.Itoa(player.ID) + "/api-tokens"
+
+	// Bearer tokens go through the same middleware as sessions
+	bearer := api.NewRouter(api.Options{Store: s, Authenticate: middleware.SessionAuth(sessions.NewCookieStore([]byte("test-session-key")), s)})
+	with := func(token, method, path string, body any) int {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		bearer.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for _, bad := range []map[string]any{
+		{"scopes": []string{"teams:read"}},
+		{"name": "bot"},
+		{"name": "bot", "scopes": []string{"teams:admin"}},
+		{"name": "bot", "scopes": []string{"teams:read", "teams:read"}},
+		{"name": "bot", "scopes": []string{"teams:read"}, "expires_in_days": 400},
+	} {
+		if rr := do(t, h, http.MethodPost, path, bad, player.ID); rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %v returned %v, want 400", bad, rr.Code)
+		}
+	}
+	req := api.APITokenRequest{Name: "Scheduler bot", Scopes: []string{"teams:read", "availability:write"}}
+	if rr := do(t, h, http.MethodPost, path, req, other.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("token for another player returned %v, want 403", rr.Code)
+	}
+	rr := do(t, h, http.MethodPost, path, req, player.ID)
+	var created api.APIToken
+	if rr.Code != http.StatusCreated || json.NewDecoder(rr.Body).Decode(&created) != nil {
+		t.Fatalf("POST returned %v: %s", rr.Code, rr.Body)
+	}
+	if !strings.HasPrefix(created.Token, "viv_") || created.ExpiresAt.Sub(time.Now()) < 89*24*time.Hour || created.LastUsedAt != nil {
+		t.Errorf("unexpected token: %+v", created)
+	}
+
+	// The token acts as the player, within its scopes
+	teamPath := "/teams/" + strconv.Itoa(team.ID)
+	availability := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Monday", Time: "19:00"}}}
+	for _, c := range []struct {
+		method, path string
+		body         any
+		want         int
+	}{
+		{http.MethodGet, teamPath, nil, http.StatusOK},
+		{http.MethodPost, teamPath + "/availability", availability, http.StatusOK},
+		{http.MethodGet, teamPath + "/availability", nil, http.StatusOK},                    // write allows read
+		{http.MethodPut, teamPath, map[string]string{"name": "Beta"}, http.StatusForbidden}, // captain, but no teams:write
+		{http.MethodGet, teamPath + "/events", nil, http.StatusForbidden},
+		{http.MethodGet, path, nil, http.StatusForbidden}, // tokens can't manage tokens
+		{http.MethodDelete, "/players/" + strconv.Itoa(player.ID), nil, http.StatusForbidden},
+	} {
+		if got := with(created.Token, c.method, c.path, c.body); got != c.want {
+			t.Errorf("%s %s with the token returned %v, want %v", c.method, c.path, got, c.want)
+		}
+	}
+	if got := with(created.Token+"x", http.MethodGet, teamPath, nil); got != http.StatusUnauthorized {
+		t.Errorf("wrong token returned %v, want 401", got)
+	}
+	if got := with("", http.MethodGet, teamPath, nil); got != http.StatusUnauthorized {
+		t.Errorf("no token or session returned %v, want 401", got)
+	}
+
+	rr = do(t, h, http.MethodGet, path, nil, player.ID)
+	var list []api.APIToken
+	if json.NewDecoder(rr.Body).Decode(&list); len(list) != 1 || list[0].Token != "" || list[0].LastUsedAt == nil || list[0].Name != "Scheduler bot" {
+		t.Errorf("unexpected tokens: %+v", list)
+	}
+
+	// Expired and revoked tokens stop working
+	expired, _ := s.APITokens.CreateAPIToken(ctx, store.APIToken{UserID: player.ID, Name: "old", Scopes: []string{"teams:read"}, ExpiresAt: time.Now().Add(-time.Minute)}, middleware.HashToken("viv_expired"))
+	if got := with("viv_expired", http.MethodGet, teamPath, nil); got != http.StatusUnauthorized {
+		t.Errorf("expired token returned %v, want 401", got)
+	}
+	tokenPath := path + "/" + strconv.Itoa(created.ID)
+	if rr := do(t, h, http.MethodDelete, tokenPath, nil, other.ID); rr.Code != http.StatusForbidden {
+		t.Errorf("DELETE by another player returned %v, want 403", rr.Code)
+	}
+	if rr := do(t, h, http.MethodDelete, "/players/"+strconv.Itoa(other.ID)+"/api-tokens/"+strconv.Itoa(expired.ID), nil, other.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("DELETE of another player's token returned %v, want 404", rr.Code)
+	}
+	if rr := do(t, h, http.MethodDelete, tokenPath, nil, player.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned %v", rr.Code)
+	}
+	if got := with(created.Token, http.MethodGet, teamPath, nil); got != http.StatusUnauthorized {
+		t.Errorf("revoked token returned %v, want 401", got)
+	}
+}
@@ -0,0 +1,154 @@
+// Package authz decides whether the caller may perform an action on a team or
+// player, based on their role in team_members and the organization-wide admin
+// flag on users, both carried by the request's Principal.
+package authz
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Role is a member's role within a team.
+type Role string
+
+const (
+	RoleSub     Role = "sub"
+	RolePlayer  Role = "player"
+	RoleCoach   Role = "coach"
+	RoleCaptain Role = "captain"
+	RoleManager Role = "manager"
+)
+
+// rank orders roles from least to most privileged.
+var rank = map[Role]int{
+	RoleSub:     1,
+	RolePlayer:  2,
+	RoleCoach:   3,
+	RoleCaptain: 4,
+	RoleManager: 5,
+}
+
+// ParseRole maps a team_members.role value onto a Role. Values that are not
+// a known team role, such as the in-game roles "Tank" or "DPS", count as
+// RolePlayer.
+func ParseRole(s string) Role {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := rank[r]; ok {
+		return r
+	}
+	return RolePlayer
+}
+
+// LookupRole is ParseRole for input that must name a team role, such as
+// the role a member is given. It reports false for anything else.
+func LookupRole(s string) (Role, bool) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	_, ok := rank[r]
+	return r, ok
+}
+
+// AtLeast reports whether r is as privileged as min.
+func (r Role) AtLeast(min Role) bool {
+	return rank[r] >= rank[min]
+}
+
+// Policy declares who may use a route. Organization admins always pass.
+type Policy struct {
+	// Name describes the policy in 403 responses.
+	Name string
+	// MinTeamRole, when set, admits members of the {team_id} team holding at
+	// least this role.
+	MinTeamRole Role
+	// AllowSelf admits the caller when the {user_id} URL parameter is their
+	// own user ID.
+	AllowSelf bool
+}
+
+// The policies used by the API routes.
+var (
+	OrgAdmin    = Policy{Name: "organization admin"}
+	TeamMember  = Policy{Name: "team member", MinTeamRole: RoleSub}
+	TeamCoach   = Policy{Name: "team coach", MinTeamRole: RoleCoach}
+	TeamCaptain = Policy{Name: "team captain", MinTeamRole: RoleCaptain}
+	TeamManager = Policy{Name: "team manager", MinTeamRole: RoleManager}
+	Self        = Policy{Name: "account owner", AllowSelf: true}
+)
+
+// Decision is the outcome of evaluating a policy.
+type Decision struct {
+	Allowed bool
+	// Role is the caller's role on the team, when the request names one and
+	// the caller is a member.
+	Role Role
+}
+
+// Evaluate checks p for the caller acting on teamID and targetUserID. Zero
+// IDs mean the request does not name a team or user.
+func Evaluate(p Policy, caller *middleware.Principal, teamID, targetUserID int) Decision {
+	var d Decision
+	if m, ok := caller.Membership(teamID); ok && teamID != 0 {
+		d.Role = ParseRole(m.Role)
+	}
+	switch {
+	case caller.IsAdmin:
+		d.Allowed = true
+	case p.AllowSelf && targetUserID != 0 && targetUserID == caller.UserID:
+		d.Allowed = true
+	case p.MinTeamRole != "" && d.Role != "" && d.Role.AtLeast(p.MinTeamRole):
+		d.Allowed = true
+	}
+	return d
+}
+
+// Require returns middleware that rejects callers who do not satisfy p with a
+// JSON 403. The caller's Principal must already be in the request context.
+func Require(p Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return guard{policy: p, next: next}
+	}
+}
+
+// Required reports the policy mw enforces, if mw was returned by Require.
+// It lets the OpenAPI document read each route's policy off the router.
+func Required(mw func(http.Handler) http.Handler) (Policy, bool) {
+	g, ok := mw(http.NotFoundHandler()).(guard)
+	return g.policy, ok
+}
+
+// guard is the handler Require wraps around a route.
+type guard struct {
+	policy Policy
+	next   http.Handler
+}
+
+func (g guard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	caller, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		middleware.Unauthorized(w, r, "authentication required")
+		return
+	}
+	teamID, _ := strconv.Atoi(chi.URLParam(r, "team_id"))
+	targetUserID, _ := strconv.Atoi(chi.URLParam(r, "user_id"))
+
+	d := Evaluate(g.policy, caller, teamID, targetUserID)
+	if !d.Allowed {
+		response.Error(w, r, "Requires "+g.policy.Name, http.StatusForbidden)
+		return
+	}
+
+	ctx := r.Context()
+	if teamID != 0 {
+		ctx = context.WithValue(ctx, middleware.TeamIDKey, int64(teamID))
+	}
+	if d.Role != "" {
+		ctx = context.WithValue(ctx, middleware.RoleKey, string(d.Role))
+	}
+	g.next.ServeHTTP(w, r.WithContext(ctx))
+}
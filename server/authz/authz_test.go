@@ -0,0 +1,104 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestParseRole(t *testing.T) {
+	cases := map[string]authz.Role{
+		"Captain": authz.RoleCaptain,
+		" coach ": authz.RoleCoach,
+		"Tank":    authz.RolePlayer,
+		"":        authz.RolePlayer,
+		"sub":     authz.RoleSub,
+	}
+	for in, want := range cases {
+		if got := authz.ParseRole(in); got != want {
+			t.Errorf("ParseRole(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if !authz.RoleManager.AtLeast(authz.RoleCaptain) || authz.RoleSub.AtLeast(authz.RolePlayer) {
+		t.Error("role ordering is wrong")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	coach, _ := s.Players.UpsertBattleNetPlayer(ctx, 1, "Coach#1")
+	admin, _ := s.Players.UpsertBattleNetPlayer(ctx, 2, "Admin#1")
+	s.Players.SetPlayerAdmin(ctx, admin.ID, true)
+	s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: coach.ID, Role: "Coach"})
+
+	cases := []struct {
+		name           string
+		policy         authz.Policy
+		userID, teamID int
+		target         int
+		want           bool
+	}{
+		{"coach meets coach policy", authz.TeamCoach, coach.ID, team.ID, 0, true},
+		{"coach below captain policy", authz.TeamCaptain, coach.ID, team.ID, 0, false},
+		{"admin bypasses team policy", authz.TeamManager, admin.ID, team.ID, 0, true},
+		{"non-member of another team", authz.TeamMember, coach.ID, team.ID + 1, 0, false},
+		{"self policy on own account", authz.Self, coach.ID, 0, coach.ID, true},
+		{"self policy on other account", authz.Self, coach.ID, 0, admin.ID, false},
+		{"org admin policy for member", authz.OrgAdmin, coach.ID, team.ID, 0, false},
+	}
+	for _, c := range cases {
+		caller, err := middleware.LoadPrincipal(ctx, s, c.userID)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		d := authz.Evaluate(c.policy, caller, c.teamID, c.target)
+		if d.Allowed != c.want {
+			t.Errorf("%s: allowed = %v, want %v", c.name, d.Allowed, c.want)
+		}
+	}
+}
+
+func TestScope(t *testing.T) {
+	cases := []struct {
+		method, path string
+		want         string
+		ok           bool
+	}{
+		{"GET", "/teams", "teams:read", true},
+		{"POST", "/teams/1/members", "teams:write", true},
+		{"POST", "/invite-links/redeem", "teams:write", true},
+		{"GET", "/teams/1/availability/summary", "availability:read", true},
+		{"PUT", "/teams/{team_id}/grid", "availability:write", true},
+		{"DELETE", "/timeslots/3", "availability:write", true},
+		{"PUT", "/teams/1/events/2/rsvp", "events:write", true},
+		{"GET", "/teams/1/matches/stats", "events:read", true},
+		{"PUT", "/players/1/profile", "profile:write", true},
+		{"PUT", "/players/1", "profile:write", true},
+		{"DELETE", "/players/1", "", false},
+		{"POST", "/players/1/api-tokens", "", false},
+		{"DELETE", "/players/1/api-tokens/2", "", false},
+		{"GET", "/players/1/sessions", "", false},
+		{"DELETE", "/players/1/sessions/2", "", false},
+		{"GET", "/unknown", "", false},
+	}
+	for _, c := range cases {
+		if got, ok := authz.Scope(c.method, c.path); got != c.want || ok != c.ok {
+			t.Errorf("Scope(%s %s) = %q, %v, want %q, %v", c.method, c.path, got, ok, c.want, c.ok)
+		}
+	}
+
+	p := &middleware.Principal{TokenID: 1, Scopes: []string{"teams:write", "events:read"}}
+	for scope, want := range map[string]bool{"teams:read": true, "teams:write": true, "events:read": true, "events:write": false, "profile:read": false} {
+		if got := p.HasScope(scope); got != want {
+			t.Errorf("HasScope(%s) = %v, want %v", scope, got, want)
+		}
+	}
+	if !(&middleware.Principal{}).HasScope("events:write") {
+		t.Error("a session should hold every scope")
+	}
+}
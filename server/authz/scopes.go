@@ -0,0 +1,74 @@
+package authz
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Scope returns the personal API token scope a request needs, from its
+// method and its path below /api. GET requests need the area's read scope
+// and everything else its write scope. It reports false for routes tokens
+// can't use at all: managing tokens or login sessions and deleting the
+// account, which need a browser session.
+func Scope(method, path string) (string, bool) {
+	seg := strings.Split(strings.Trim(path, "/"), "/")
+	var area string
+	switch seg[0] {
+	case "teams", "invite-links":
+		area = "teams"
+		if len(seg) > 2 {
+			switch seg[2] {
+			case "schedule", "grid", "availability", "lineups":
+				area = "availability"
+			case "events", "matches":
+				area = "events"
+			}
+		}
+	case "timeslots":
+		area = "availability"
+	case "players":
+		if len(seg) > 2 && (seg[2] == "api-tokens" || seg[2] == "sessions") || len(seg) == 2 && method == http.MethodDelete {
+			return "", false
+		}
+		area = "profile"
+	default:
+		return "", false
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return area + ":read", true
+	}
+	return area + ":write", true
+}
+
+// RequireScope rejects requests made with a personal API token that lacks
+// the scope the route needs. Requests from browser sessions pass. It runs
+// after authentication, before the request is routed further, so it reads
+// the path the router has left to match.
+func RequireScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := middleware.PrincipalFromContext(r.Context())
+		if !ok || caller.TokenID == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		path := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+			path = rctx.RoutePath
+		}
+		scope, ok := Scope(r.Method, path)
+		if !ok {
+			response.Error(w, r, "API tokens can't be used here; log in instead", http.StatusForbidden)
+			return
+		}
+		if !caller.HasScope(scope) {
+			response.Error(w, r, "Requires the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,268 @@
+// Package client is a typed Go client for the Vivacity API, for bots and
+// scripts. It speaks the api package's request and response types, so it
+// can't drift from the server, and reports failures as *Error, decoded from
+// the server's error envelope.
+//
+// Every API route has a method except the browser login routes and the
+// Discord interactions endpoint, which only Discord can call.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+)
+
+// Defaults for New.
+const (
+	DefaultRetries   = 2
+	DefaultRetryWait = 250 * time.Millisecond
+)
+
+// Client calls the API of the server at BaseURL. Its methods are safe for
+// concurrent use.
+type Client struct {
+	// BaseURL is the server's root URL, such as https://vivacity.example;
+	// API paths are appended to it with their /api prefix.
+	BaseURL string
+	// HTTPClient sends the requests. http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+	// Token, when set, is a personal API token, sent as a bearer token.
+	Token string
+	// Session, when set and Token isn't, is sent as the session cookie of a
+	// logged in browser.
+	Session string
+	// Retries is how many times a GET, PUT or DELETE is retried after a
+	// network error or a 5xx or 429 response, waiting RetryWait and then
+	// twice as long each time. POSTs create things, so they are never
+	// retried.
+	Retries   int
+	RetryWait time.Duration
+}
+
+// New returns a client for the server at baseURL with the default retries.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, Retries: DefaultRetries, RetryWait: DefaultRetryWait}
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	Code       string // see the Code constants of the response package
+	Message    string
+	Fields     []response.FieldError
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("vivacity: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same code, so the
+// sentinels below work with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Sentinels for errors.Is.
+var (
+	ErrInvalid      = &Error{Code: response.CodeInvalid}
+	ErrUnauthorized = &Error{Code: response.CodeUnauthorized}
+	ErrForbidden    = &Error{Code: response.CodeForbidden}
+	ErrNotFound     = &Error{Code: response.CodeNotFound}
+	ErrConflict     = &Error{Code: response.CodeConflict}
+	ErrRoleFull     = &Error{Code: response.CodeRoleFull}
+)
+
+// get, put, post and del call an API path, encoding body and decoding the
+// response into out when they aren't nil.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+func (c *Client) put(ctx context.Context, path string, query url.Values, body, out any) error {
+	return c.do(ctx, http.MethodPut, path, query, body, out)
+}
+
+func (c *Client) post(ctx context.Context, path string, query url.Values, body, out any) error {
+	return c.do(ctx, http.MethodPost, path, query, body, out)
+}
+
+func (c *Client) del(ctx context.Context, path string, query url.Values, body any) error {
+	return c.do(ctx, http.MethodDelete, path, query, body, nil)
+}
+
+// do sends the request, retrying it as described on Client. A *[]byte out
+// receives the raw body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	retries := c.Retries
+	if method == http.MethodPost {
+		retries = 0
+	}
+	wait := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u, payload)
+		if err == nil && !retryable(resp.StatusCode) || attempt >= retries || ctx.Err() != nil {
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decode(resp, out)
+		}
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Session != "":
+		req.AddCookie(&http.Cookie{Name: middleware.SessionName, Value: c.Session})
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+func retryable(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// decode reads a successful response into out, or the error envelope of a
+// failed one.
+func decode(resp *http.Response, out any) error {
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var env response.Envelope
+		if err := json.Unmarshal(body, &env); err != nil || env.Error.Code == "" {
+			// Not from the API, such as a proxy's error page
+			return &Error{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode), Message: strings.TrimSpace(string(body))}
+		}
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       env.Error.Code,
+			Message:    env.Error.Message,
+			Fields:     env.Error.Fields,
+			RequestID:  env.Error.RequestID,
+		}
+	}
+	if raw, ok := out.(*[]byte); ok {
+		var err error
+		*raw, err = io.ReadAll(resp.Body)
+		return err
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("vivacity: decoding %s response: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
+
+// Week picks the week and timezone availability is shown in. The zero
+// value is the current week in the caller's timezone.
+type Week struct {
+	Date     time.Time // any day of the week
+	Timezone string
+}
+
+func (w Week) query() url.Values {
+	q := url.Values{}
+	if !w.Date.IsZero() {
+		q.Set("week", w.Date.Format("2006-01-02"))
+	}
+	if w.Timezone != "" {
+		q.Set("tz", w.Timezone)
+	}
+	return q
+}
+
+// Range picks the time range of a listing and the timezone times are shown
+// in. Zero values use the server's defaults.
+type Range struct {
+	From, To time.Time
+	Timezone string
+}
+
+func (r Range) query() url.Values {
+	q := url.Values{}
+	if !r.From.IsZero() {
+		q.Set("from", r.From.Format(time.RFC3339))
+	}
+	if !r.To.IsZero() {
+		q.Set("to", r.To.Format(time.RFC3339))
+	}
+	if r.Timezone != "" {
+		q.Set("tz", r.Timezone)
+	}
+	return q
+}
+
+// zone returns the query showing times in timezone, if set.
+func zone(timezone string) url.Values {
+	if timezone == "" {
+		return nil
+	}
+	return url.Values{"tz": {timezone}}
+}
+
+// occurrence returns the query naming the occurrence of a recurring event
+// that started at originalStart, if set.
+func occurrence(originalStart time.Time) url.Values {
+	if originalStart.IsZero() {
+		return nil
+	}
+	return url.Values{"original_start": {originalStart.Format(time.RFC3339)}}
+}
@@ -0,0 +1,493 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+	"github.com/KhrisKringle/Vivacity_website-main/server/client"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// testAuth trusts a bearer token or session cookie holding the caller's
+// user ID.
+func testAuth(s *store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if c, err := r.Cookie(middleware.SessionName); err == nil {
+				id = c.Value
+			}
+			userID, err := strconv.Atoi(id)
+			if err != nil {
+				middleware.Unauthorized(w, r, "no test user")
+				return
+			}
+			principal, err := middleware.LoadPrincipal(r.Context(), s, userID)
+			if err != nil {
+				middleware.Unauthorized(w, r, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(middleware.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// stubStats returns the same ranks for every player.
+type stubStats []store.RoleRank
+
+func (s stubStats) FetchRanks(ctx context.Context, battletag, region string) ([]store.RoleRank, error) {
+	return s, nil
+}
+
+// newServer serves the API with every optional route the way main mounts
+// it, recording the route patterns it was called on.
+func newServer(t *testing.T, s *store.Store) (*httptest.Server, chi.Router, map[string]bool) {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiRouter := api.NewRouter(api.Options{
+		Store:            s,
+		Authenticate:     testAuth(s),
+		DiscordPublicKey: pub,
+		Profiles:         &profiles.Syncer{Store: s, Stats: stubStats{{Role: "dps", Division: "gold", Tier: 3}}},
+	})
+
+	var mu sync.Mutex
+	called := map[string]bool{}
+	root := chi.NewRouter()
+	root.Use(chimw.RequestID, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			mu.Lock()
+			called[r.Method+" "+strings.TrimSuffix(chi.RouteContext(r.Context()).RoutePattern(), "/")] = true
+			mu.Unlock()
+		})
+	})
+	root.Mount("/api", apiRouter)
+	root.With(testAuth(s)).Get("/auth/status", api.AuthStatusHandler)
+
+	srv := httptest.NewServer(root)
+	t.Cleanup(srv.Close)
+	return srv, apiRouter, called
+}
+
+func TestClient(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	admin, _ := s.Players.UpsertBattleNetPlayer(ctx, 1001, "Admin#0001")
+	s.Players.SetPlayerAdmin(ctx, admin.ID, true)
+	john, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "John#1234")
+	dee, _ := s.Players.UpsertBattleNetPlayer(ctx, 1003, "Dee#1234")
+	eve, _ := s.Players.UpsertBattleNetPlayer(ctx, 1004, "Eve#1234")
+	srv, apiRouter, called := newServer(t, s)
+
+	as := func(userID int) *client.Client {
+		c := client.New(srv.URL)
+		c.Token = strconv.Itoa(userID)
+		return c
+	}
+	c := as(admin.ID)
+	johnC := client.New(srv.URL)
+	johnC.Session = strconv.Itoa(john.ID)
+	deeC, eveC := as(dee.ID), as(eve.ID)
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Typed errors
+	if _, err := client.New(srv.URL).ListTeams(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("anonymous ListTeams: %v", err)
+	}
+	if _, err := johnC.CreateTeam(ctx, "Alpha"); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("CreateTeam by a player: %v", err)
+	}
+	_, err := c.GetTeam(ctx, 999)
+	var apiErr *client.Error
+	if !errors.Is(err, client.ErrNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.RequestID == "" {
+		t.Errorf("GetTeam of a missing team: %#v", err)
+	}
+	err = johnC.UpdatePlayer(ctx, john.ID, api.PlayerRequest{Username: "not a battletag"})
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrInvalid) || len(apiErr.Fields) != 1 || apiErr.Fields[0].Field != "username" {
+		t.Errorf("UpdatePlayer with a bad battletag: %#v", err)
+	}
+
+	// Teams, members and the roster
+	team, err := c.CreateTeam(ctx, "Alpha")
+	must(err)
+	must(c.RenameTeam(ctx, team.ID, "Vivacity"))
+	teams, err := c.ListTeams(ctx)
+	must(err)
+	if len(teams) != 1 || teams[0].Name != "Vivacity" {
+		t.Errorf("ListTeams: %+v", teams)
+	}
+	must(c.AddMember(ctx, team.ID, api.AddMemberRequest{UserID: john.ID, Role: "captain", GameRole: "dps"}))
+	support := "support"
+	must(johnC.UpdateMember(ctx, team.ID, api.UpdateMemberRequest{UserID: john.ID, GameRole: &support}))
+	detail, err := johnC.GetTeam(ctx, team.ID)
+	must(err)
+	if len(detail.Members) != 1 || detail.Members[0].GameRole != "support" {
+		t.Errorf("GetTeam: %+v", detail)
+	}
+	must(johnC.SetRosterLimits(ctx, team.ID, api.RosterLimits{Tank: 1, DPS: 3, Support: 3}))
+	limits, err := johnC.GetRosterLimits(ctx, team.ID)
+	must(err)
+	if limits.DPS != 3 {
+		t.Errorf("GetRosterLimits: %+v", limits)
+	}
+	if roster, err := johnC.GetRoster(ctx, team.ID); err != nil || roster.CanField {
+		t.Errorf("GetRoster: %+v, %v", roster, err)
+	}
+
+	// Invite links, invites and requests to join
+	link, err := johnC.CreateInviteLink(ctx, team.ID, api.InviteLinkRequest{Role: "sub", MaxUses: 1})
+	must(err)
+	if m, err := deeC.RedeemInviteLink(ctx, link.Token); err != nil || m.Role != "sub" {
+		t.Errorf("RedeemInviteLink: %+v, %v", m, err)
+	}
+	if links, err := johnC.ListInviteLinks(ctx, team.ID); err != nil || len(links) != 1 || links[0].Uses != 1 {
+		t.Errorf("ListInviteLinks: %+v, %v", links, err)
+	}
+	must(johnC.RevokeInviteLink(ctx, team.ID, link.ID))
+	must(johnC.RemoveMember(ctx, team.ID, dee.ID))
+	if members, err := johnC.ListMembers(ctx, team.ID); err != nil || len(members) != 1 {
+		t.Errorf("ListMembers: %+v, %v", members, err)
+	}
+
+	invite, err := johnC.InvitePlayer(ctx, team.ID, api.InviteRequest{Battletag: "Eve#1234"})
+	must(err)
+	if invites, err := johnC.ListTeamInvites(ctx, team.ID); err != nil || len(invites) != 1 {
+		t.Errorf("ListTeamInvites: %+v, %v", invites, err)
+	}
+	must(johnC.WithdrawInvite(ctx, team.ID, invite.ID))
+	invite, err = johnC.InvitePlayer(ctx, team.ID, api.InviteRequest{Battletag: "Eve#1234"})
+	must(err)
+	must(eveC.DeclineInvite(ctx, eve.ID, invite.ID))
+	invite, err = johnC.InvitePlayer(ctx, team.ID, api.InviteRequest{Battletag: "Eve#1234", Role: "coach"})
+	must(err)
+	if invites, err := eveC.ListPlayerInvites(ctx, eve.ID); err != nil || len(invites) != 1 || invites[0].TeamName != "Vivacity" {
+		t.Errorf("ListPlayerInvites: %+v, %v", invites, err)
+	}
+	if m, err := eveC.AcceptInvite(ctx, eve.ID, invite.ID); err != nil || m.Role != "coach" {
+		t.Errorf("AcceptInvite: %+v, %v", m, err)
+	}
+
+	jr, err := deeC.RequestToJoin(ctx, team.ID, api.JoinTeamRequest{Message: "I main Lucio"})
+	must(err)
+	must(johnC.DenyJoinRequest(ctx, team.ID, jr.ID))
+	jr, err = deeC.RequestToJoin(ctx, team.ID, api.JoinTeamRequest{})
+	must(err)
+	must(deeC.WithdrawJoinRequest(ctx, dee.ID, jr.ID))
+	jr, err = deeC.RequestToJoin(ctx, team.ID, api.JoinTeamRequest{})
+	must(err)
+	if requests, err := deeC.ListPlayerJoinRequests(ctx, dee.ID); err != nil || len(requests) != 1 {
+		t.Errorf("ListPlayerJoinRequests: %+v, %v", requests, err)
+	}
+	if requests, err := johnC.ListJoinRequests(ctx, team.ID); err != nil || len(requests) != 1 {
+		t.Errorf("ListJoinRequests: %+v, %v", requests, err)
+	}
+	if m, err := johnC.ApproveJoinRequest(ctx, team.ID, jr.ID, "sub"); err != nil || m.Role != "sub" {
+		t.Errorf("ApproveJoinRequest: %+v, %v", m, err)
+	}
+
+	// The grid, availability and what it suggests
+	slots, err := eveC.SetGrid(ctx, team.ID, api.Grid{SlotMinutes: 60, Timezone: "UTC", Days: []api.GridDay{{Weekday: "Monday", Start: "18:00", End: "20:00"}}})
+	must(err)
+	if len(slots) != 2 {
+		t.Errorf("SetGrid: %+v", slots)
+	}
+	if grid, err := johnC.GetGrid(ctx, team.ID); err != nil || len(grid.Days) != 1 {
+		t.Errorf("GetGrid: %+v, %v", grid, err)
+	}
+	if schedule, err := johnC.GetSchedule(ctx, team.ID); err != nil || len(schedule) != 2 {
+		t.Errorf("GetSchedule: %+v, %v", schedule, err)
+	}
+	week := client.Week{Date: store.WeekStart(time.Now(), time.UTC), Timezone: "UTC"}
+	both := api.AvailabilityRequest{SelectedSlots: []api.AvailabilitySlot{{Day: "Monday", Time: "18:00"}, {Day: "Monday", Time: "19:00"}}}
+	for _, pc := range []*client.Client{johnC, deeC} {
+		if n, err := pc.SetAvailability(ctx, team.ID, week, both); err != nil || n != 2 {
+			t.Errorf("SetAvailability: %d, %v", n, err)
+		}
+	}
+	if got, err := johnC.GetAvailability(ctx, team.ID, week); err != nil || len(got) != 2 || !got[0].Available {
+		t.Errorf("GetAvailability: %+v, %v", got, err)
+	}
+	sum, err := eveC.GetAvailabilitySummary(ctx, team.ID, client.SummaryOptions{Week: week, MinDuration: 2 * time.Hour, Limit: 1})
+	must(err)
+	if len(sum.Windows) != 1 {
+		t.Fatalf("GetAvailabilitySummary: %+v", sum)
+	}
+	if lineups, err := johnC.GetLineups(ctx, team.ID, week, true); err != nil || len(lineups.Slots) != 0 {
+		t.Errorf("GetLineups with two players: %+v, %v", lineups, err)
+	}
+	practice, err := eveC.CreateEventFromSummary(ctx, team.ID, api.EventRequest{Type: "practice", StartTime: sum.Windows[0].StartsAt, EndTime: sum.Windows[0].EndsAt}, "")
+	must(err)
+	if len(practice.RSVPs) != 2 {
+		t.Errorf("CreateEventFromSummary RSVPs: %+v", practice.RSVPs)
+	}
+
+	// Global time slots
+	slot, err := c.CreateTimeSlot(ctx, api.TimeSlotRequest{Day: "Tuesday", Time: "10:00"})
+	must(err)
+	must(c.UpdateTimeSlot(ctx, slot.ID, api.TimeSlotRequest{Day: "Tuesday", Time: "11:00"}))
+	if got, err := c.GetTimeSlot(ctx, slot.ID); err != nil || got.Time != "11:00" {
+		t.Errorf("GetTimeSlot: %+v, %v", got, err)
+	}
+	global, err := c.ListTimeSlots(ctx, 0)
+	must(err)
+	if !slices.Contains(global, api.TimeSlot{ID: slot.ID, Weekday: "Tuesday", Time: "11:00"}) {
+		t.Errorf("ListTimeSlots is missing slot %d: %+v", slot.ID, global)
+	}
+	if teamSlots, err := c.ListTimeSlots(ctx, team.ID); err != nil || len(teamSlots) != 2 {
+		t.Errorf("ListTimeSlots for the team: %+v, %v", teamSlots, err)
+	}
+	must(c.DeleteTimeSlot(ctx, slot.ID))
+
+	// Discord settings
+	must(johnC.SetDiscordSettings(ctx, team.ID, api.DiscordSettings{WebhookURL: "https://discord.com/api/webhooks/123/secret"}))
+	if settings, err := eveC.GetDiscordSettings(ctx, team.ID); err != nil || settings.ReminderMinutes == nil {
+		t.Errorf("GetDiscordSettings: %+v, %v", settings, err)
+	}
+	must(johnC.DeleteDiscordSettings(ctx, team.ID))
+
+	// Events
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	scrim, err := eveC.CreateEvent(ctx, team.ID, api.EventRequest{Type: "scrim", Opponent: "Bravo", StartTime: start, EndTime: start.Add(2 * time.Hour)}, "Europe/Berlin")
+	must(err)
+	if scrim.Title != "Scrim vs Bravo" || scrim.StartTime.Location().String() == "UTC" {
+		t.Errorf("CreateEvent: %+v", scrim)
+	}
+	_, err = eveC.UpdateEvent(ctx, team.ID, scrim.ID, api.EventRequest{Type: "scrim", Title: "Bravo scrim", StartTime: start, EndTime: start.Add(3 * time.Hour)}, "")
+	must(err)
+	if rsvp, err := johnC.RSVP(ctx, team.ID, scrim.ID, "yes"); err != nil || rsvp.Status != "yes" {
+		t.Errorf("RSVP: %+v, %v", rsvp, err)
+	}
+	if got, err := johnC.GetEvent(ctx, team.ID, scrim.ID, ""); err != nil || got.Title != "Bravo scrim" || len(got.RSVPs) != 1 {
+		t.Errorf("GetEvent: %+v, %v", got, err)
+	}
+	must(eveC.CancelEvent(ctx, team.ID, scrim.ID))
+	events, err := johnC.ListEvents(ctx, team.ID, client.Range{From: start.Add(-time.Hour), To: start.Add(time.Hour), Timezone: "UTC"})
+	must(err)
+	if len(events) != 1 || !events[0].Cancelled {
+		t.Errorf("ListEvents: %+v", events)
+	}
+
+	// A weekly scrim that has been played twice, and its results
+	first := week.Date.AddDate(0, 0, -14).Add(10 * time.Hour)
+	second := first.AddDate(0, 0, 7)
+	weekly, err := eveC.CreateEvent(ctx, team.ID, api.EventRequest{Type: "scrim", Opponent: "Delta", StartTime: first, EndTime: first.Add(2 * time.Hour), Recurrence: "FREQ=WEEKLY;COUNT=2", Timezone: "UTC"}, "")
+	must(err)
+	moved, err := eveC.OverrideOccurrence(ctx, team.ID, weekly.ID, api.OccurrenceRequest{OriginalStart: second, StartTime: second.Add(time.Hour), Title: "Late scrims"}, "")
+	must(err)
+	if moved.Title != "Late scrims" || !moved.Overridden {
+		t.Errorf("OverrideOccurrence: %+v", moved)
+	}
+	must(eveC.RestoreOccurrence(ctx, team.ID, weekly.ID, second))
+	bo1 := api.MatchRequest{Format: "bo1", OriginalStart: first, Maps: []api.MapResult{{Map: "Ilios", Mode: "control", TeamScore: 2, OpponentScore: 0}}}
+	if result, err := eveC.SetEventResult(ctx, team.ID, weekly.ID, bo1); err != nil || result.Outcome != "win" {
+		t.Errorf("SetEventResult: %+v, %v", result, err)
+	}
+	if result, err := johnC.GetEventResult(ctx, team.ID, weekly.ID, first); err != nil || result.Opponent != "Delta" {
+		t.Errorf("GetEventResult: %+v, %v", result, err)
+	}
+	must(eveC.DeleteEventResult(ctx, team.ID, weekly.ID, first))
+	if _, err := johnC.GetEventResult(ctx, team.ID, weekly.ID, first); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetEventResult after delete: %v", err)
+	}
+
+	// Matches that weren't events
+	played := first.AddDate(0, 0, -7)
+	match, err := eveC.RecordMatch(ctx, team.ID, api.MatchRequest{Opponent: "Team Bravo", Format: "bo1", PlayedAt: played, Maps: []api.MapResult{{Map: "Dorado", Mode: "escort", TeamScore: 1, OpponentScore: 2}}})
+	must(err)
+	match, err = eveC.UpdateMatch(ctx, team.ID, match.ID, api.MatchRequest{Opponent: "Team Bravo", Format: "bo1", PlayedAt: played, Maps: []api.MapResult{{Map: "Dorado", Mode: "escort", TeamScore: 3, OpponentScore: 2}}})
+	must(err)
+	if got, err := johnC.GetMatch(ctx, team.ID, match.ID); err != nil || got.Outcome != "win" {
+		t.Errorf("GetMatch: %+v, %v", got, err)
+	}
+	if matches, err := johnC.ListMatches(ctx, team.ID, client.MatchFilter{Opponent: "team bravo"}); err != nil || len(matches) != 1 {
+		t.Errorf("ListMatches: %+v, %v", matches, err)
+	}
+	if stats, err := johnC.GetMatchStats(ctx, team.ID, client.Range{Timezone: "UTC"}, "month"); err != nil || stats.Matches.Wins != 1 {
+		t.Errorf("GetMatchStats: %+v, %v", stats, err)
+	}
+	must(eveC.DeleteMatch(ctx, team.ID, match.ID))
+
+	// The player's own settings
+	if players, err := johnC.ListPlayers(ctx); err != nil || len(players) != 4 {
+		t.Errorf("ListPlayers: %+v, %v", players, err)
+	}
+	must(johnC.UpdatePlayer(ctx, john.ID, api.PlayerRequest{Timezone: "Europe/Berlin"}))
+	if p, err := johnC.GetPlayer(ctx, john.ID); err != nil || p.Timezone != "Europe/Berlin" {
+		t.Errorf("GetPlayer: %+v, %v", p, err)
+	}
+	must(deeC.SetActiveTeam(ctx, dee.ID, team.ID))
+	if status, err := deeC.AuthStatus(ctx); err != nil || status.UserID != dee.ID || status.ActiveTeamID != team.ID {
+		t.Errorf("AuthStatus: %+v, %v", status, err)
+	}
+
+	if _, err := johnC.SetGameProfile(ctx, john.ID, api.GameProfile{Region: "eu", PreferredRoles: []string{"support"}}); err != nil {
+		t.Errorf("SetGameProfile: %v", err)
+	}
+	if p, err := johnC.RefreshGameProfile(ctx, john.ID); err != nil || len(p.Ranks) != 1 || p.Ranks[0].Division != "gold" {
+		t.Errorf("RefreshGameProfile: %+v, %v", p, err)
+	}
+	if p, err := deeC.GetGameProfile(ctx, john.ID); err != nil || p.Region != "eu" {
+		t.Errorf("GetGameProfile: %+v, %v", p, err)
+	}
+
+	token, err := johnC.CreateCalendarToken(ctx, john.ID)
+	must(err)
+	feeds := client.New(srv.URL)
+	if ics, err := feeds.PlayerCalendar(ctx, john.ID, token.Token); err != nil || !strings.Contains(string(ics), "BEGIN:VCALENDAR") {
+		t.Errorf("PlayerCalendar: %q, %v", ics, err)
+	}
+	if ics, err := feeds.TeamCalendar(ctx, team.ID, token.Token); err != nil || !strings.Contains(string(ics), "SUMMARY:Practice") {
+		t.Errorf("TeamCalendar: %q, %v", ics, err)
+	}
+	must(johnC.RevokeCalendarToken(ctx, john.ID))
+	if _, err := feeds.TeamCalendar(ctx, team.ID, token.Token); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("TeamCalendar with a revoked token: %v", err)
+	}
+
+	if _, err := johnC.GetDiscordLink(ctx, john.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetDiscordLink before linking: %v", err)
+	}
+	if code, err := johnC.CreateDiscordLinkCode(ctx, john.ID); err != nil || code.Code == "" {
+		t.Errorf("CreateDiscordLinkCode: %+v, %v", code, err)
+	}
+	if err := johnC.UnlinkDiscord(ctx, john.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("UnlinkDiscord before linking: %v", err)
+	}
+
+	must(johnC.SetEmailPreferences(ctx, john.ID, api.EmailPreferences{Email: "john@example.com", Digest: true}))
+	if prefs, err := johnC.GetEmailPreferences(ctx, john.ID); err != nil || !prefs.Digest {
+		t.Errorf("GetEmailPreferences: %+v, %v", prefs, err)
+	}
+	must(johnC.StopEmail(ctx, john.ID))
+
+	apiToken, err := johnC.CreateAPIToken(ctx, john.ID, api.APITokenRequest{Name: "Scheduler bot", Scopes: []string{"availability:write"}})
+	must(err)
+	if tokens, err := johnC.ListAPITokens(ctx, john.ID); err != nil || len(tokens) != 1 || tokens[0].Token != "" || tokens[0].Scopes[0] != "availability:write" {
+		t.Errorf("ListAPITokens: %+v, %v", tokens, err)
+	}
+	must(johnC.RevokeAPIToken(ctx, john.ID, apiToken.ID))
+
+	for _, hash := range []string{"laptop", "phone"} {
+		_, err := s.Sessions.CreateSession(ctx, store.Session{UserID: john.ID, ExpiresAt: time.Now().Add(time.Hour)}, hash)
+		must(err)
+	}
+	loginSessions, err := johnC.ListSessions(ctx, john.ID)
+	if err != nil || len(loginSessions) != 2 {
+		t.Fatalf("ListSessions: %+v, %v", loginSessions, err)
+	}
+	must(johnC.EndSession(ctx, john.ID, loginSessions[0].ID))
+	must(johnC.LogOutEverywhere(ctx, john.ID))
+	if loginSessions, err := johnC.ListSessions(ctx, john.ID); err != nil || len(loginSessions) != 0 {
+		t.Errorf("ListSessions after logging out everywhere: %+v, %v", loginSessions, err)
+	}
+
+	doc, err := johnC.OpenAPI(ctx)
+	must(err)
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if err := json.Unmarshal(doc, &spec); err != nil || spec.OpenAPI == "" {
+		t.Errorf("OpenAPI: %v", err)
+	}
+
+	must(deeC.DeletePlayer(ctx, dee.ID))
+	must(c.DeleteTeam(ctx, team.ID))
+	if _, err := c.GetTeam(ctx, team.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetTeam after delete: %v", err)
+	}
+
+	// Every API route has a method. Only Discord calls its interactions
+	// endpoint.
+	err = chi.Walk(apiRouter, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		pattern := method + " /api" + strings.TrimSuffix(route, "/")
+		if !called[pattern] && pattern != "POST /api/discord/interactions" {
+			t.Errorf("%s was not called", pattern)
+		}
+		return nil
+	})
+	must(err)
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch r.URL.Path {
+		case "/api/teams":
+			if r.Method == http.MethodGet && n < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`[{"team_id":1,"team_name":"Alpha"}]`))
+		case "/api/players":
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		default:
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	c := client.New(srv.URL)
+	c.RetryWait = time.Millisecond
+
+	// GETs are retried until they succeed
+	teams, err := c.ListTeams(ctx)
+	if err != nil || len(teams) != 1 || calls.Load() != 3 {
+		t.Errorf("ListTeams after two failures: %+v, %v, %d calls", teams, err, calls.Load())
+	}
+
+	// POSTs never are
+	calls.Store(0)
+	_, err = c.CreateTeam(ctx, "Alpha")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("CreateTeam: %v, %d calls", err, calls.Load())
+	}
+
+	// Errors that aren't from the API keep their status
+	calls.Store(0)
+	_, err = c.ListPlayers(ctx)
+	if !errors.As(err, &apiErr) || apiErr.Code != "Bad Gateway" || apiErr.Message != "upstream unavailable" || calls.Load() != 3 {
+		t.Errorf("ListPlayers: %#v, %d calls", err, calls.Load())
+	}
+
+	// Cancelling the context stops the retries
+	c.RetryWait = time.Hour
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := c.GetTeam(ctx, 1); !errors.Is(err, context.DeadlineExceeded) || time.Since(started) > 5*time.Second {
+		t.Errorf("GetTeam with a deadline: %v after %v", err, time.Since(started))
+	}
+}
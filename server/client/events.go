@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func eventPath(teamID, eventID int, rest string) string {
+	return teamPath(teamID, fmt.Sprintf("/events/%d%s", eventID, rest))
+}
+
+// ListEvents lists a team's events in a range, listing each occurrence of
+// recurring events.
+func (c *Client) ListEvents(ctx context.Context, teamID int, r Range) ([]api.Event, error) {
+	var events []api.Event
+	err := c.get(ctx, teamPath(teamID, "/events"), r.query(), &events)
+	return events, err
+}
+
+// CreateEvent creates an event. The returned event's times are shown in
+// timezone, or the caller's when it is empty.
+func (c *Client) CreateEvent(ctx context.Context, teamID int, req api.EventRequest, timezone string) (api.Event, error) {
+	var e api.Event
+	err := c.post(ctx, teamPath(teamID, "/events"), zone(timezone), req, &e)
+	return e, err
+}
+
+// CreateEventFromSummary creates an event for a window of back-to-back
+// slots, RSVPing yes for every member available for it.
+func (c *Client) CreateEventFromSummary(ctx context.Context, teamID int, req api.EventRequest, timezone string) (api.Event, error) {
+	var e api.Event
+	err := c.post(ctx, teamPath(teamID, "/events/from-summary"), zone(timezone), req, &e)
+	return e, err
+}
+
+// GetEvent returns an event with its RSVPs.
+func (c *Client) GetEvent(ctx context.Context, teamID, eventID int, timezone string) (api.Event, error) {
+	var e api.Event
+	err := c.get(ctx, eventPath(teamID, eventID, ""), zone(timezone), &e)
+	return e, err
+}
+
+// UpdateEvent replaces an event.
+func (c *Client) UpdateEvent(ctx context.Context, teamID, eventID int, req api.EventRequest, timezone string) (api.Event, error) {
+	var e api.Event
+	err := c.put(ctx, eventPath(teamID, eventID, ""), zone(timezone), req, &e)
+	return e, err
+}
+
+// CancelEvent cancels an event.
+func (c *Client) CancelEvent(ctx context.Context, teamID, eventID int) error {
+	return c.del(ctx, eventPath(teamID, eventID, ""), nil, nil)
+}
+
+// RSVP answers an event as the caller: yes, no or maybe.
+func (c *Client) RSVP(ctx context.Context, teamID, eventID int, status string) (api.RSVP, error) {
+	var rsvp api.RSVP
+	err := c.put(ctx, eventPath(teamID, eventID, "/rsvp"), nil, api.RSVPRequest{Status: status}, &rsvp)
+	return rsvp, err
+}
+
+// OverrideOccurrence changes or cancels one occurrence of a recurring
+// event.
+func (c *Client) OverrideOccurrence(ctx context.Context, teamID, eventID int, req api.OccurrenceRequest, timezone string) (api.Event, error) {
+	var e api.Event
+	err := c.put(ctx, eventPath(teamID, eventID, "/occurrences"), zone(timezone), req, &e)
+	return e, err
+}
+
+// RestoreOccurrence undoes the changes to the occurrence of a recurring
+// event that was scheduled to start at originalStart.
+func (c *Client) RestoreOccurrence(ctx context.Context, teamID, eventID int, originalStart time.Time) error {
+	return c.del(ctx, eventPath(teamID, eventID, "/occurrences"), occurrence(originalStart), nil)
+}
+
+// GetEventResult returns the result of a played match or scrim. For a
+// recurring event, originalStart picks the occurrence.
+func (c *Client) GetEventResult(ctx context.Context, teamID, eventID int, originalStart time.Time) (api.Match, error) {
+	var m api.Match
+	err := c.get(ctx, eventPath(teamID, eventID, "/result"), occurrence(originalStart), &m)
+	return m, err
+}
+
+// SetEventResult records or replaces the result of a match or scrim. For a
+// recurring event, req.OriginalStart picks the occurrence.
+func (c *Client) SetEventResult(ctx context.Context, teamID, eventID int, req api.MatchRequest) (api.Match, error) {
+	var m api.Match
+	err := c.put(ctx, eventPath(teamID, eventID, "/result"), nil, req, &m)
+	return m, err
+}
+
+// DeleteEventResult removes the result of a match or scrim.
+func (c *Client) DeleteEventResult(ctx context.Context, teamID, eventID int, originalStart time.Time) error {
+	return c.del(ctx, eventPath(teamID, eventID, "/result"), occurrence(originalStart), nil)
+}
+
+// MatchFilter picks the matches ListMatches returns.
+type MatchFilter struct {
+	Range
+	Opponent string
+}
+
+// ListMatches lists a team's matches, most recent first.
+func (c *Client) ListMatches(ctx context.Context, teamID int, filter MatchFilter) ([]api.Match, error) {
+	q := filter.Range.query()
+	if filter.Opponent != "" {
+		q.Set("opponent", filter.Opponent)
+	}
+	var matches []api.Match
+	err := c.get(ctx, teamPath(teamID, "/matches"), q, &matches)
+	return matches, err
+}
+
+// RecordMatch records a match that wasn't an event.
+func (c *Client) RecordMatch(ctx context.Context, teamID int, req api.MatchRequest) (api.Match, error) {
+	var m api.Match
+	err := c.post(ctx, teamPath(teamID, "/matches"), nil, req, &m)
+	return m, err
+}
+
+// GetMatchStats returns a team's win/loss record by map, mode and opponent,
+// and over periods of a week or month.
+func (c *Client) GetMatchStats(ctx context.Context, teamID int, r Range, period string) (api.MatchStats, error) {
+	q := r.query()
+	if period != "" {
+		q.Set("period", period)
+	}
+	var stats api.MatchStats
+	err := c.get(ctx, teamPath(teamID, "/matches/stats"), q, &stats)
+	return stats, err
+}
+
+// GetMatch returns a match and its maps.
+func (c *Client) GetMatch(ctx context.Context, teamID, matchID int) (api.Match, error) {
+	var m api.Match
+	err := c.get(ctx, teamPath(teamID, fmt.Sprintf("/matches/%d", matchID)), nil, &m)
+	return m, err
+}
+
+// UpdateMatch replaces a match's result.
+func (c *Client) UpdateMatch(ctx context.Context, teamID, matchID int, req api.MatchRequest) (api.Match, error) {
+	var m api.Match
+	err := c.put(ctx, teamPath(teamID, fmt.Sprintf("/matches/%d", matchID)), nil, req, &m)
+	return m, err
+}
+
+// DeleteMatch deletes a match.
+func (c *Client) DeleteMatch(ctx context.Context, teamID, matchID int) error {
+	return c.del(ctx, teamPath(teamID, fmt.Sprintf("/matches/%d", matchID)), nil, nil)
+}
+
+// TeamCalendar returns a team's events as an iCalendar feed, authenticated
+// by a player's feed token rather than the client's credentials.
+func (c *Client) TeamCalendar(ctx context.Context, teamID int, feedToken string) ([]byte, error) {
+	var ics []byte
+	err := c.get(ctx, teamPath(teamID, "/calendar.ics"), url.Values{"token": {feedToken}}, &ics)
+	return ics, err
+}
+
+// PlayerCalendar returns the events of all a player's teams as an
+// iCalendar feed, authenticated by their feed token.
+func (c *Client) PlayerCalendar(ctx context.Context, userID int, feedToken string) ([]byte, error) {
+	var ics []byte
+	err := c.get(ctx, playerPath(userID, "/calendar.ics"), url.Values{"token": {feedToken}}, &ics)
+	return ics, err
+}
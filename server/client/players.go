@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func playerPath(userID int, rest string) string {
+	return fmt.Sprintf("/api/players/%d%s", userID, rest)
+}
+
+// AuthStatus returns the caller and the teams they belong to.
+func (c *Client) AuthStatus(ctx context.Context) (api.AuthStatus, error) {
+	var status api.AuthStatus
+	err := c.get(ctx, "/auth/status", nil, &status)
+	return status, err
+}
+
+// OpenAPI returns the server's OpenAPI document.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var doc []byte
+	err := c.get(ctx, "/api/openapi.json", nil, &doc)
+	return doc, err
+}
+
+// ListPlayers lists every player.
+func (c *Client) ListPlayers(ctx context.Context) ([]api.Player, error) {
+	var players []api.Player
+	err := c.get(ctx, "/api/players", nil, &players)
+	return players, err
+}
+
+// GetPlayer returns a player.
+func (c *Client) GetPlayer(ctx context.Context, userID int) (api.Player, error) {
+	var p api.Player
+	err := c.get(ctx, playerPath(userID, ""), nil, &p)
+	return p, err
+}
+
+// UpdatePlayer changes a player's battletag, timezone or both.
+func (c *Client) UpdatePlayer(ctx context.Context, userID int, req api.PlayerRequest) error {
+	return c.put(ctx, playerPath(userID, ""), nil, req, nil)
+}
+
+// DeletePlayer deletes a player along with their memberships.
+func (c *Client) DeletePlayer(ctx context.Context, userID int) error {
+	return c.del(ctx, playerPath(userID, ""), nil, nil)
+}
+
+// SetActiveTeam switches the team the player works in.
+func (c *Client) SetActiveTeam(ctx context.Context, userID, teamID int) error {
+	return c.put(ctx, playerPath(userID, "/active-team"), nil, api.ActiveTeamRequest{TeamID: teamID}, nil)
+}
+
+// GetGameProfile returns a player's Overwatch profile.
+func (c *Client) GetGameProfile(ctx context.Context, userID int) (api.GameProfile, error) {
+	var p api.GameProfile
+	err := c.get(ctx, playerPath(userID, "/profile"), nil, &p)
+	return p, err
+}
+
+// SetGameProfile sets a player's roles, ranks, main heroes and region.
+func (c *Client) SetGameProfile(ctx context.Context, userID int, profile api.GameProfile) (api.GameProfile, error) {
+	var p api.GameProfile
+	err := c.put(ctx, playerPath(userID, "/profile"), nil, profile, &p)
+	return p, err
+}
+
+// RefreshGameProfile fetches a player's ranks from their career profile
+// now. The server only offers it when it has a stats service.
+func (c *Client) RefreshGameProfile(ctx context.Context, userID int) (api.GameProfile, error) {
+	var p api.GameProfile
+	err := c.post(ctx, playerPath(userID, "/profile/refresh"), nil, nil, &p)
+	return p, err
+}
+
+// CreateCalendarToken issues a player's calendar feed token, replacing any
+// earlier one.
+func (c *Client) CreateCalendarToken(ctx context.Context, userID int) (api.CalendarToken, error) {
+	var token api.CalendarToken
+	err := c.post(ctx, playerPath(userID, "/calendar-token"), nil, nil, &token)
+	return token, err
+}
+
+// RevokeCalendarToken revokes a player's calendar feed token.
+func (c *Client) RevokeCalendarToken(ctx context.Context, userID int) error {
+	return c.del(ctx, playerPath(userID, "/calendar-token"), nil, nil)
+}
+
+// GetDiscordLink returns the Discord account linked to a player.
+func (c *Client) GetDiscordLink(ctx context.Context, userID int) (api.DiscordLink, error) {
+	var link api.DiscordLink
+	err := c.get(ctx, playerPath(userID, "/discord-link"), nil, &link)
+	return link, err
+}
+
+// CreateDiscordLinkCode issues a code for the bot's /link command.
+func (c *Client) CreateDiscordLinkCode(ctx context.Context, userID int) (api.DiscordLinkCode, error) {
+	var code api.DiscordLinkCode
+	err := c.post(ctx, playerPath(userID, "/discord-link"), nil, nil, &code)
+	return code, err
+}
+
+// UnlinkDiscord unlinks a player's Discord account.
+func (c *Client) UnlinkDiscord(ctx context.Context, userID int) error {
+	return c.del(ctx, playerPath(userID, "/discord-link"), nil, nil)
+}
+
+// GetEmailPreferences returns a player's email preferences.
+func (c *Client) GetEmailPreferences(ctx context.Context, userID int) (api.EmailPreferences, error) {
+	var prefs api.EmailPreferences
+	err := c.get(ctx, playerPath(userID, "/email-preferences"), nil, &prefs)
+	return prefs, err
+}
+
+// SetEmailPreferences opts a player in to or out of emails.
+func (c *Client) SetEmailPreferences(ctx context.Context, userID int, prefs api.EmailPreferences) error {
+	return c.put(ctx, playerPath(userID, "/email-preferences"), nil, prefs, nil)
+}
+
+// StopEmail stops all email to a player.
+func (c *Client) StopEmail(ctx context.Context, userID int) error {
+	return c.del(ctx, playerPath(userID, "/email-preferences"), nil, nil)
+}
+
+// ListPlayerInvites lists a player's pending invites.
+func (c *Client) ListPlayerInvites(ctx context.Context, userID int) ([]api.JoinRequest, error) {
+	var invites []api.JoinRequest
+	err := c.get(ctx, playerPath(userID, "/invites"), nil, &invites)
+	return invites, err
+}
+
+// AcceptInvite accepts an invite, joining its team.
+func (c *Client) AcceptInvite(ctx context.Context, userID, inviteID int) (api.Membership, error) {
+	var m api.Membership
+	err := c.post(ctx, playerPath(userID, fmt.Sprintf("/invites/%d/accept", inviteID)), nil, nil, &m)
+	return m, err
+}
+
+// DeclineInvite declines an invite.
+func (c *Client) DeclineInvite(ctx context.Context, userID, inviteID int) error {
+	return c.del(ctx, playerPath(userID, fmt.Sprintf("/invites/%d", inviteID)), nil, nil)
+}
+
+// ListPlayerJoinRequests lists a player's requests to join teams.
+func (c *Client) ListPlayerJoinRequests(ctx context.Context, userID int) ([]api.JoinRequest, error) {
+	var requests []api.JoinRequest
+	err := c.get(ctx, playerPath(userID, "/join-requests"), nil, &requests)
+	return requests, err
+}
+
+// WithdrawJoinRequest withdraws a request to join a team.
+func (c *Client) WithdrawJoinRequest(ctx context.Context, userID, requestID int) error {
+	return c.del(ctx, playerPath(userID, fmt.Sprintf("/join-requests/%d", requestID)), nil, nil)
+}
+
+// ListAPITokens lists a player's personal API tokens. Their tokens aren't
+// shown.
+func (c *Client) ListAPITokens(ctx context.Context, userID int) ([]api.APIToken, error) {
+	var tokens []api.APIToken
+	err := c.get(ctx, playerPath(userID, "/api-tokens"), nil, &tokens)
+	return tokens, err
+}
+
+// CreateAPIToken creates a personal API token, returning it with its token.
+// Tokens can't manage tokens, so this needs a session.
+func (c *Client) CreateAPIToken(ctx context.Context, userID int, req api.APITokenRequest) (api.APIToken, error) {
+	var token api.APIToken
+	err := c.post(ctx, playerPath(userID, "/api-tokens"), nil, req, &token)
+	return token, err
+}
+
+// RevokeAPIToken revokes a personal API token.
+func (c *Client) RevokeAPIToken(ctx context.Context, userID, tokenID int) error {
+	return c.del(ctx, playerPath(userID, fmt.Sprintf("/api-tokens/%d", tokenID)), nil, nil)
+}
+
+// ListSessions lists a player's active login sessions.
+func (c *Client) ListSessions(ctx context.Context, userID int) ([]api.LoginSession, error) {
+	var list []api.LoginSession
+	err := c.get(ctx, playerPath(userID, "/sessions"), nil, &list)
+	return list, err
+}
+
+// LogOutEverywhere ends every login session of a player. Like the other
+// session routes it needs a session, not an API token.
+func (c *Client) LogOutEverywhere(ctx context.Context, userID int) error {
+	return c.del(ctx, playerPath(userID, "/sessions"), nil, nil)
+}
+
+// EndSession ends one of a player's login sessions.
+func (c *Client) EndSession(ctx context.Context, userID, sessionID int) error {
+	return c.del(ctx, playerPath(userID, fmt.Sprintf("/sessions/%d", sessionID)), nil, nil)
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+// GetSchedule lists the time slots of a team's grid.
+func (c *Client) GetSchedule(ctx context.Context, teamID int) ([]api.TimeSlot, error) {
+	var slots []api.TimeSlot
+	err := c.get(ctx, teamPath(teamID, "/schedule"), nil, &slots)
+	return slots, err
+}
+
+// GetGrid returns a team's weekly grid.
+func (c *Client) GetGrid(ctx context.Context, teamID int) (api.Grid, error) {
+	var grid api.Grid
+	err := c.get(ctx, teamPath(teamID, "/grid"), nil, &grid)
+	return grid, err
+}
+
+// SetGrid replaces a team's weekly grid, returning the time slots it
+// generates.
+func (c *Client) SetGrid(ctx context.Context, teamID int, grid api.Grid) ([]api.TimeSlot, error) {
+	var slots []api.TimeSlot
+	err := c.put(ctx, teamPath(teamID, "/grid"), nil, grid, &slots)
+	return slots, err
+}
+
+// GetAvailability returns the caller's availability for each slot of a
+// week.
+func (c *Client) GetAvailability(ctx context.Context, teamID int, week Week) ([]api.SlotAvailability, error) {
+	var slots []api.SlotAvailability
+	err := c.get(ctx, teamPath(teamID, "/availability"), week.query(), &slots)
+	return slots, err
+}
+
+// SetAvailability replaces the caller's availability for the week, and the
+// weeks after it when req.Weeks is set, returning how many slots were
+// selected. Slots chosen by day and time are read in week's timezone.
+func (c *Client) SetAvailability(ctx context.Context, teamID int, week Week, req api.AvailabilityRequest) (int, error) {
+	var resp struct {
+		Selected int `json:"selected"`
+	}
+	err := c.post(ctx, teamPath(teamID, "/availability"), week.query(), req, &resp)
+	return resp.Selected, err
+}
+
+// SummaryOptions tunes GetAvailabilitySummary. Zero values use the
+// server's defaults.
+type SummaryOptions struct {
+	Week
+	MinDuration time.Duration // shortest useful window
+	Limit       int           // number of windows
+	Require     string        // role counts, such as player:5,coach:1
+}
+
+// GetAvailabilitySummary counts the members available for each slot of a
+// week and ranks the best windows to meet.
+func (c *Client) GetAvailabilitySummary(ctx context.Context, teamID int, opts SummaryOptions) (api.AvailabilitySummary, error) {
+	q := opts.Week.query()
+	if opts.MinDuration > 0 {
+		q.Set("min_duration", strconv.Itoa(int(opts.MinDuration/time.Minute)))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Require != "" {
+		q.Set("require", opts.Require)
+	}
+	var sum api.AvailabilitySummary
+	err := c.get(ctx, teamPath(teamID, "/availability/summary"), q, &sum)
+	return sum, err
+}
+
+// GetLineups suggests a starting lineup for each slot of a week, only for
+// the slots a full lineup can make when fullOnly is set.
+func (c *Client) GetLineups(ctx context.Context, teamID int, week Week, fullOnly bool) (api.LineupSuggestions, error) {
+	q := week.query()
+	if fullOnly {
+		q.Set("full_only", "true")
+	}
+	var lineups api.LineupSuggestions
+	err := c.get(ctx, teamPath(teamID, "/lineups"), q, &lineups)
+	return lineups, err
+}
+
+// ListTimeSlots lists a team's time slots, or the global ones when teamID
+// is zero.
+func (c *Client) ListTimeSlots(ctx context.Context, teamID int) ([]api.TimeSlot, error) {
+	var q url.Values
+	if teamID != 0 {
+		q = url.Values{"team_id": {strconv.Itoa(teamID)}}
+	}
+	var slots []api.TimeSlot
+	err := c.get(ctx, "/api/timeslots", q, &slots)
+	return slots, err
+}
+
+// CreateTimeSlot creates a global time slot.
+func (c *Client) CreateTimeSlot(ctx context.Context, req api.TimeSlotRequest) (api.TimeSlot, error) {
+	var slot api.TimeSlot
+	err := c.post(ctx, "/api/timeslots", nil, req, &slot)
+	return slot, err
+}
+
+// GetTimeSlot returns a time slot.
+func (c *Client) GetTimeSlot(ctx context.Context, slotID int) (api.TimeSlot, error) {
+	var slot api.TimeSlot
+	err := c.get(ctx, fmt.Sprintf("/api/timeslots/%d", slotID), nil, &slot)
+	return slot, err
+}
+
+// UpdateTimeSlot moves a global time slot.
+func (c *Client) UpdateTimeSlot(ctx context.Context, slotID int, req api.TimeSlotRequest) error {
+	return c.put(ctx, fmt.Sprintf("/api/timeslots/%d", slotID), nil, req, nil)
+}
+
+// DeleteTimeSlot deletes a time slot.
+func (c *Client) DeleteTimeSlot(ctx context.Context, slotID int) error {
+	return c.del(ctx, fmt.Sprintf("/api/timeslots/%d", slotID), nil, nil)
+}
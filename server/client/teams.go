@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/api"
+)
+
+func teamPath(teamID int, rest string) string {
+	return fmt.Sprintf("/api/teams/%d%s", teamID, rest)
+}
+
+// ListTeams lists every team.
+func (c *Client) ListTeams(ctx context.Context) ([]api.Team, error) {
+	var teams []api.Team
+	err := c.get(ctx, "/api/teams", nil, &teams)
+	return teams, err
+}
+
+// CreateTeam creates a team. It requires an organization admin.
+func (c *Client) CreateTeam(ctx context.Context, name string) (api.Team, error) {
+	var team api.Team
+	err := c.post(ctx, "/api/teams", nil, api.TeamRequest{Name: name}, &team)
+	return team, err
+}
+
+// GetTeam returns a team and its members.
+func (c *Client) GetTeam(ctx context.Context, teamID int) (api.TeamDetail, error) {
+	var team api.TeamDetail
+	err := c.get(ctx, teamPath(teamID, ""), nil, &team)
+	return team, err
+}
+
+// RenameTeam renames a team.
+func (c *Client) RenameTeam(ctx context.Context, teamID int, name string) error {
+	return c.put(ctx, teamPath(teamID, ""), nil, api.TeamRequest{Name: name}, nil)
+}
+
+// DeleteTeam deletes a team.
+func (c *Client) DeleteTeam(ctx context.Context, teamID int) error {
+	return c.del(ctx, teamPath(teamID, ""), nil, nil)
+}
+
+// ListMembers lists a team's members.
+func (c *Client) ListMembers(ctx context.Context, teamID int) ([]api.TeamMember, error) {
+	var members []api.TeamMember
+	err := c.get(ctx, teamPath(teamID, "/members"), nil, &members)
+	return members, err
+}
+
+// AddMember adds a player to a team.
+func (c *Client) AddMember(ctx context.Context, teamID int, req api.AddMemberRequest) error {
+	return c.post(ctx, teamPath(teamID, "/members"), nil, req, nil)
+}
+
+// UpdateMember changes a member's team role, in-game role or both.
+func (c *Client) UpdateMember(ctx context.Context, teamID int, req api.UpdateMemberRequest) error {
+	return c.put(ctx, teamPath(teamID, "/members"), nil, req, nil)
+}
+
+// RemoveMember removes a player from a team.
+func (c *Client) RemoveMember(ctx context.Context, teamID, userID int) error {
+	return c.del(ctx, teamPath(teamID, "/members"), nil, api.RemoveMemberRequest{UserID: userID})
+}
+
+// GetRoster returns a team's members by in-game role, with composition
+// warnings.
+func (c *Client) GetRoster(ctx context.Context, teamID int) (api.Roster, error) {
+	var roster api.Roster
+	err := c.get(ctx, teamPath(teamID, "/roster"), nil, &roster)
+	return roster, err
+}
+
+// GetRosterLimits returns how many members each in-game role can hold.
+func (c *Client) GetRosterLimits(ctx context.Context, teamID int) (api.RosterLimits, error) {
+	var limits api.RosterLimits
+	err := c.get(ctx, teamPath(teamID, "/roster/limits"), nil, &limits)
+	return limits, err
+}
+
+// SetRosterLimits sets how many members each in-game role can hold.
+func (c *Client) SetRosterLimits(ctx context.Context, teamID int, limits api.RosterLimits) error {
+	return c.put(ctx, teamPath(teamID, "/roster/limits"), nil, limits, nil)
+}
+
+// ListInviteLinks lists a team's invite links. Their tokens aren't shown.
+func (c *Client) ListInviteLinks(ctx context.Context, teamID int) ([]api.InviteLink, error) {
+	var links []api.InviteLink
+	err := c.get(ctx, teamPath(teamID, "/invite-links"), nil, &links)
+	return links, err
+}
+
+// CreateInviteLink creates an invite link, returning it with its token.
+func (c *Client) CreateInviteLink(ctx context.Context, teamID int, req api.InviteLinkRequest) (api.InviteLink, error) {
+	var link api.InviteLink
+	err := c.post(ctx, teamPath(teamID, "/invite-links"), nil, req, &link)
+	return link, err
+}
+
+// RevokeInviteLink revokes an invite link.
+func (c *Client) RevokeInviteLink(ctx context.Context, teamID, inviteID int) error {
+	return c.del(ctx, teamPath(teamID, fmt.Sprintf("/invite-links/%d", inviteID)), nil, nil)
+}
+
+// RedeemInviteLink joins the team of the invite link with token.
+func (c *Client) RedeemInviteLink(ctx context.Context, token string) (api.Membership, error) {
+	var m api.Membership
+	err := c.post(ctx, "/api/invite-links/redeem", nil, api.RedeemInviteRequest{Token: token}, &m)
+	return m, err
+}
+
+// ListTeamInvites lists a team's pending invites.
+func (c *Client) ListTeamInvites(ctx context.Context, teamID int) ([]api.JoinRequest, error) {
+	var invites []api.JoinRequest
+	err := c.get(ctx, teamPath(teamID, "/invites"), nil, &invites)
+	return invites, err
+}
+
+// InvitePlayer invites a player to a team by battletag.
+func (c *Client) InvitePlayer(ctx context.Context, teamID int, req api.InviteRequest) (api.JoinRequest, error) {
+	var invite api.JoinRequest
+	err := c.post(ctx, teamPath(teamID, "/invites"), nil, req, &invite)
+	return invite, err
+}
+
+// WithdrawInvite withdraws a pending invite.
+func (c *Client) WithdrawInvite(ctx context.Context, teamID, inviteID int) error {
+	return c.del(ctx, teamPath(teamID, fmt.Sprintf("/invites/%d", inviteID)), nil, nil)
+}
+
+// ListJoinRequests lists the requests to join a team.
+func (c *Client) ListJoinRequests(ctx context.Context, teamID int) ([]api.JoinRequest, error) {
+	var requests []api.JoinRequest
+	err := c.get(ctx, teamPath(teamID, "/join-requests"), nil, &requests)
+	return requests, err
+}
+
+// RequestToJoin asks to join a team as the caller.
+func (c *Client) RequestToJoin(ctx context.Context, teamID int, req api.JoinTeamRequest) (api.JoinRequest, error) {
+	var jr api.JoinRequest
+	err := c.post(ctx, teamPath(teamID, "/join-requests"), nil, req, &jr)
+	return jr, err
+}
+
+// ApproveJoinRequest adds the player who asked to join to the team, with
+// role, or the role they asked for when role is empty.
+func (c *Client) ApproveJoinRequest(ctx context.Context, teamID, requestID int, role string) (api.Membership, error) {
+	var m api.Membership
+	err := c.post(ctx, teamPath(teamID, fmt.Sprintf("/join-requests/%d/approve", requestID)), nil, api.ApproveRequest{Role: role}, &m)
+	return m, err
+}
+
+// DenyJoinRequest denies a request to join.
+func (c *Client) DenyJoinRequest(ctx context.Context, teamID, requestID int) error {
+	return c.del(ctx, teamPath(teamID, fmt.Sprintf("/join-requests/%d", requestID)), nil, nil)
+}
+
+// GetDiscordSettings returns a team's Discord notification settings.
+func (c *Client) GetDiscordSettings(ctx context.Context, teamID int) (api.DiscordSettings, error) {
+	var settings api.DiscordSettings
+	err := c.get(ctx, teamPath(teamID, "/discord"), nil, &settings)
+	return settings, err
+}
+
+// SetDiscordSettings sets up a team's Discord notifications.
+func (c *Client) SetDiscordSettings(ctx context.Context, teamID int, settings api.DiscordSettings) error {
+	return c.put(ctx, teamPath(teamID, "/discord"), nil, settings, nil)
+}
+
+// DeleteDiscordSettings turns a team's Discord notifications off.
+func (c *Client) DeleteDiscordSettings(ctx context.Context, teamID int) error {
+	return c.del(ctx, teamPath(teamID, "/discord"), nil, nil)
+}
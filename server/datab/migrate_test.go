@@ -0,0 +1,92 @@
+package datab_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/datab"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testMigrations = fstest.MapFS{
+	"0002_add_column.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN c INT;")},
+	"0002_add_column.down.sql": {Data: []byte("ALTER TABLE t DROP COLUMN c;")},
+	"0001_create.up.sql":       {Data: []byte("CREATE TABLE t (id INT);")},
+	"0001_create.down.sql":     {Data: []byte("DROP TABLE t;")},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := datab.LoadMigrations(testMigrations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create" {
+		t.Errorf("first migration = %d_%s, want 1_create", migrations[0].Version, migrations[0].Name)
+	}
+	if migrations[1].Down != "ALTER TABLE t DROP COLUMN c;" {
+		t.Errorf("unexpected down SQL: %q", migrations[1].Down)
+	}
+}
+
+func TestLoadMigrationsRequiresDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create.up.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+	}
+	if _, err := datab.LoadMigrations(fsys); err == nil {
+		t.Fatal("expected an error for a migration without a down file")
+	}
+}
+
+func TestMigratorUpSkipsApplied(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	migrator, err := datab.NewMigratorFS(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE t ADD COLUMN c").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(2, "add_column").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ran, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ran) != 1 || ran[0].Version != 2 {
+		t.Fatalf("expected only migration 2 to run, got %+v", ran)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := datab.NewMigrator(db); err != nil {
+		t.Fatalf("embedded migrations failed to load: %v", err)
+	}
+}
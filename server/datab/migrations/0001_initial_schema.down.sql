@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS availability;
+DROP TABLE IF EXISTS time_slots;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS teams;
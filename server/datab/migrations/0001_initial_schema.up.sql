@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS teams (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	user_id INT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+	user_id INT NOT NULL,
+	team_id INT NOT NULL,
+	role VARCHAR(255) NOT NULL,
+	PRIMARY KEY (user_id, team_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS time_slots (
+	slot_id SERIAL PRIMARY KEY,
+	weekday VARCHAR(9) NOT NULL,
+	time TIME NOT NULL,
+	UNIQUE (weekday, time)
+);
+
+CREATE TABLE IF NOT EXISTS availability (
+	user_id INT NOT NULL,
+	team_id INT NOT NULL,
+	slot_id INT NOT NULL,
+	available BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (user_id, slot_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (slot_id) REFERENCES time_slots(slot_id) ON DELETE CASCADE
+);
+
+INSERT INTO time_slots (weekday, time)
+VALUES
+	('Monday', '19:00:00'), ('Monday', '21:00:00'),
+	('Tuesday', '19:00:00'), ('Tuesday', '21:00:00'),
+	('Wednesday', '19:00:00'), ('Wednesday', '21:00:00'),
+	('Thursday', '19:00:00'), ('Thursday', '21:00:00'),
+	('Friday', '19:00:00'), ('Friday', '21:00:00'),
+	('Saturday', '19:00:00'), ('Saturday', '21:00:00'),
+	('Sunday', '19:00:00'), ('Sunday', '21:00:00')
+ON CONFLICT (weekday, time) DO NOTHING;
@@ -0,0 +1,7 @@
+DELETE FROM time_slots WHERE team_id IS NOT NULL;
+
+DROP INDEX IF EXISTS time_slots_team_weekday_time_key;
+
+ALTER TABLE time_slots ADD CONSTRAINT time_slots_weekday_time_key UNIQUE (weekday, time);
+
+ALTER TABLE time_slots DROP COLUMN IF EXISTS team_id;
@@ -0,0 +1,8 @@
+-- Slots without a team_id are the global defaults; team-specific slots share
+-- the same weekday/time space but are unique per team.
+ALTER TABLE time_slots ADD COLUMN IF NOT EXISTS team_id INT REFERENCES teams(id) ON DELETE CASCADE;
+
+ALTER TABLE time_slots DROP CONSTRAINT IF EXISTS time_slots_weekday_time_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS time_slots_team_weekday_time_key
+	ON time_slots (COALESCE(team_id, 0), weekday, time);
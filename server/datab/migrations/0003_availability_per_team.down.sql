@@ -0,0 +1,8 @@
+ALTER TABLE availability DROP CONSTRAINT IF EXISTS availability_team_id_fkey;
+ALTER TABLE availability DROP CONSTRAINT IF EXISTS availability_pkey;
+
+DELETE FROM availability a
+USING availability b
+WHERE a.user_id = b.user_id AND a.slot_id = b.slot_id AND a.team_id > b.team_id;
+
+ALTER TABLE availability ADD PRIMARY KEY (user_id, slot_id);
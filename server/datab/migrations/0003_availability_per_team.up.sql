@@ -0,0 +1,7 @@
+-- A player on several teams keeps separate availability for each of them.
+DELETE FROM availability a WHERE NOT EXISTS (SELECT 1 FROM teams t WHERE t.id = a.team_id);
+
+ALTER TABLE availability DROP CONSTRAINT IF EXISTS availability_pkey;
+ALTER TABLE availability ADD PRIMARY KEY (user_id, team_id, slot_id);
+ALTER TABLE availability ADD CONSTRAINT availability_team_id_fkey
+	FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS team_grid_days;
+DROP TABLE IF EXISTS team_grids;
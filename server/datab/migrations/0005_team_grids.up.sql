@@ -0,0 +1,15 @@
+-- Each team may define its own weekly grid. Teams without a row keep using
+-- the global default slots.
+CREATE TABLE IF NOT EXISTS team_grids (
+	team_id INT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+	slot_minutes INT NOT NULL CHECK (slot_minutes BETWEEN 15 AND 240 AND slot_minutes % 15 = 0),
+	timezone TEXT NOT NULL DEFAULT 'UTC'
+);
+
+CREATE TABLE IF NOT EXISTS team_grid_days (
+	team_id INT NOT NULL REFERENCES team_grids(team_id) ON DELETE CASCADE,
+	weekday VARCHAR(9) NOT NULL,
+	start_time TIME NOT NULL,
+	end_time TIME NOT NULL CHECK (end_time > start_time),
+	PRIMARY KEY (team_id, weekday)
+);
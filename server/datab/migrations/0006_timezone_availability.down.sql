@@ -0,0 +1,13 @@
+-- Interval availability can't be mapped back onto weekly slots reliably, so
+-- it is dropped.
+DROP TABLE IF EXISTS availability;
+
+CREATE TABLE availability (
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	slot_id INT NOT NULL REFERENCES time_slots(slot_id) ON DELETE CASCADE,
+	available BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (user_id, team_id, slot_id)
+);
+
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
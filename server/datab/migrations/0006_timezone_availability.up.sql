@@ -0,0 +1,36 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
+
+-- Availability becomes concrete UTC intervals instead of references to
+-- weekly slots whose wall-clock time had no timezone.
+CREATE TABLE availability_intervals (
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+	PRIMARY KEY (user_id, team_id, starts_at)
+);
+
+-- Existing weekly availability is carried into the current and next three
+-- weeks, reading each slot in its team's timezone.
+INSERT INTO availability_intervals (user_id, team_id, starts_at, ends_at)
+SELECT a.user_id, a.team_id,
+	l.local_start AT TIME ZONE l.tz,
+	(l.local_start + make_interval(mins => l.slot_minutes)) AT TIME ZONE l.tz
+FROM availability a
+JOIN time_slots ts ON ts.slot_id = a.slot_id
+LEFT JOIN team_grids g ON g.team_id = a.team_id
+CROSS JOIN generate_series(0, 3) AS w(week)
+CROSS JOIN LATERAL (
+	SELECT COALESCE(g.timezone, 'UTC') AS tz,
+		COALESCE(g.slot_minutes, 120) AS slot_minutes,
+		date_trunc('week', now() AT TIME ZONE COALESCE(g.timezone, 'UTC'))
+			+ make_interval(days => array_position(ARRAY['Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday', 'Sunday']::TEXT[], ts.weekday::TEXT) - 1 + 7 * w.week)
+			+ ts.time AS local_start
+) l
+WHERE a.available
+ON CONFLICT DO NOTHING;
+
+DROP TABLE availability;
+ALTER TABLE availability_intervals RENAME TO availability;
+ALTER INDEX availability_intervals_pkey RENAME TO availability_pkey;
+CREATE INDEX availability_team_range_idx ON availability (team_id, starts_at, ends_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS events (
+	id SERIAL PRIMARY KEY,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	type VARCHAR(16) NOT NULL CHECK (type IN ('scrim', 'match', 'practice', 'meeting')),
+	title VARCHAR(255) NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+	opponent VARCHAR(255) NOT NULL DEFAULT '',
+	location VARCHAR(255) NOT NULL DEFAULT '',
+	cancelled_at TIMESTAMPTZ,
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS events_team_starts_idx ON events (team_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS event_rsvps (
+	event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	status VARCHAR(5) NOT NULL CHECK (status IN ('yes', 'no', 'maybe')),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (event_id, user_id)
+);
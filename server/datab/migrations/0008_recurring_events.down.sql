@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS event_exceptions;
+
+DELETE FROM events WHERE recurrence <> '';
+
+ALTER TABLE events
+	DROP COLUMN IF EXISTS last_starts_at,
+	DROP COLUMN IF EXISTS timezone,
+	DROP COLUMN IF EXISTS recurrence;
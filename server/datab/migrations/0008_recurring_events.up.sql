@@ -0,0 +1,20 @@
+ALTER TABLE events
+	ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS last_starts_at TIMESTAMPTZ;
+
+-- One-off events end with their only occurrence; series leave it NULL when
+-- they repeat forever.
+UPDATE events SET last_starts_at = starts_at WHERE recurrence = '';
+
+CREATE TABLE IF NOT EXISTS event_exceptions (
+	event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+	original_starts_at TIMESTAMPTZ NOT NULL,
+	cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+	title VARCHAR(255) NOT NULL,
+	opponent VARCHAR(255) NOT NULL DEFAULT '',
+	location VARCHAR(255) NOT NULL DEFAULT '',
+	PRIMARY KEY (event_id, original_starts_at)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE, -- hex SHA-256 of the token
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
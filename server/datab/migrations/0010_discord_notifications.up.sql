@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS team_discord_settings (
+	team_id INT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+	webhook_url TEXT NOT NULL,
+	reminder_minutes INT NOT NULL DEFAULT 60 CHECK (reminder_minutes >= 0),
+	quorum INT NOT NULL DEFAULT 0 CHECK (quorum >= 0),
+	quorum_minutes INT NOT NULL DEFAULT 1440 CHECK (quorum_minutes >= 0)
+);
+
+-- Sent and abandoned messages are kept (done_at set) so dedupe keys keep
+-- reminders from being queued twice.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+	id SERIAL PRIMARY KEY,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	dedupe_key VARCHAR(255) UNIQUE,
+	payload JSONB NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_error TEXT NOT NULL DEFAULT '',
+	done_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notification_outbox_due_idx ON notification_outbox (next_attempt_at) WHERE done_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS discord_links;
+DROP TABLE IF EXISTS discord_link_codes;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS discord_link_codes (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	code_hash CHAR(64) NOT NULL UNIQUE, -- hex SHA-256 of the normalized code
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS discord_links (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	discord_user_id TEXT NOT NULL UNIQUE,
+	discord_username TEXT NOT NULL DEFAULT '',
+	linked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
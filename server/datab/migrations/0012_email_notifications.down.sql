@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_deliveries;
+DROP TABLE IF EXISTS email_preferences;
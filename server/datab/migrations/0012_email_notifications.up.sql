@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS email_preferences (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	email TEXT NOT NULL,
+	reminders BOOLEAN NOT NULL DEFAULT FALSE,
+	reminder_minutes INT NOT NULL DEFAULT 60,
+	digest BOOLEAN NOT NULL DEFAULT FALSE,
+	availability_alerts BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One row per scheduled email sent, so each goes out once
+CREATE TABLE IF NOT EXISTS email_deliveries (
+	dedupe_key TEXT PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	sent_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS email_deliveries_sent_at_idx ON email_deliveries (sent_at);
@@ -0,0 +1,16 @@
+-- Background jobs. Finished and given-up jobs keep finished_at set until
+-- they are pruned, so dedupe keys keep periodic jobs from running twice.
+CREATE TABLE IF NOT EXISTS jobs (
+	id SERIAL PRIMARY KEY,
+	kind VARCHAR(100) NOT NULL,
+	dedupe_key VARCHAR(255) UNIQUE,
+	payload JSONB NOT NULL,
+	run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	attempts INT NOT NULL DEFAULT 0,
+	max_attempts INT NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+	last_error TEXT NOT NULL DEFAULT '',
+	finished_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS jobs_due_idx ON jobs (run_at) WHERE finished_at IS NULL;
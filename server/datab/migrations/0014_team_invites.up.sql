@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS invite_links (
+	id SERIAL PRIMARY KEY,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE, -- hex SHA-256 of the token
+	role VARCHAR(255) NOT NULL,
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	expires_at TIMESTAMPTZ, -- NULL: never
+	max_uses INT NOT NULL DEFAULT 0 CHECK (max_uses >= 0), -- 0: unlimited
+	uses INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS invite_links_team_id_idx ON invite_links (team_id);
+
+CREATE TABLE IF NOT EXISTS join_requests (
+	id SERIAL PRIMARY KEY,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	invited BOOLEAN NOT NULL, -- true: the team invited the player
+	role VARCHAR(255) NOT NULL,
+	message TEXT NOT NULL DEFAULT '',
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS join_requests_user_id_idx ON join_requests (user_id);
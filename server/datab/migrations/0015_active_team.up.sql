@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active_team_id INT REFERENCES teams(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS game_profile_ranks;
+DROP TABLE IF EXISTS game_profiles;
@@ -0,0 +1,22 @@
+-- Players' Overwatch metadata, filled in by the player and by the stats sync
+CREATE TABLE IF NOT EXISTS game_profiles (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	region VARCHAR(8) NOT NULL DEFAULT '',
+	preferred_roles TEXT[] NOT NULL DEFAULT '{}',
+	main_heroes TEXT[] NOT NULL DEFAULT '{}',
+	synced_at TIMESTAMPTZ,
+	sync_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS game_profiles_synced_at_idx ON game_profiles (synced_at NULLS FIRST) WHERE region <> '';
+
+-- One rank per role, entered by the player or synced from their career profile
+CREATE TABLE IF NOT EXISTS game_profile_ranks (
+	user_id INT NOT NULL REFERENCES game_profiles(user_id) ON DELETE CASCADE,
+	role VARCHAR(16) NOT NULL,
+	division VARCHAR(16) NOT NULL DEFAULT '',
+	tier INT NOT NULL DEFAULT 0,
+	sr INT NOT NULL DEFAULT 0,
+	synced BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (user_id, role)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS team_roster_limits;
+
+-- Before this migration a player's in-game role was kept in role; move it
+-- back for plain players. Members with a higher team role keep that, as
+-- they did then.
+UPDATE team_members SET role = game_role WHERE role = 'player' AND game_role <> '';
+
+ALTER TABLE team_members DROP COLUMN IF EXISTS game_role;
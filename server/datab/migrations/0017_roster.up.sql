@@ -0,0 +1,15 @@
+-- The in-game role a member fills, kept apart from their team role
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS game_role VARCHAR(16) NOT NULL DEFAULT '';
+
+-- Early rosters stored the in-game role in role; move it over
+UPDATE team_members
+SET game_role = CASE lower(role) WHEN 'damage' THEN 'dps' ELSE lower(role) END, role = 'player'
+WHERE lower(role) IN ('tank', 'dps', 'damage', 'support');
+
+-- Teams without a row use the default limits
+CREATE TABLE IF NOT EXISTS team_roster_limits (
+	team_id INT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+	tank INT NOT NULL CHECK (tank >= 0),
+	dps INT NOT NULL CHECK (dps >= 0),
+	support INT NOT NULL CHECK (support >= 0)
+);
@@ -0,0 +1,35 @@
+-- Match results, optionally linked to the event they were scheduled as.
+-- event_starts_at picks the occurrence of a recurring event and is NULL for
+-- one-off events.
+CREATE TABLE IF NOT EXISTS matches (
+	id SERIAL PRIMARY KEY,
+	team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	event_id INT REFERENCES events(id) ON DELETE SET NULL,
+	event_starts_at TIMESTAMPTZ,
+	opponent VARCHAR(255) NOT NULL,
+	competition VARCHAR(255) NOT NULL DEFAULT '',
+	format VARCHAR(8) NOT NULL CHECK (format IN ('bo1', 'bo3', 'bo5', 'bo7')),
+	played_at TIMESTAMPTZ NOT NULL,
+	vod_url TEXT NOT NULL DEFAULT '',
+	notes TEXT NOT NULL DEFAULT '',
+	created_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One result per event occurrence
+CREATE UNIQUE INDEX IF NOT EXISTS matches_event_idx ON matches (event_id) WHERE event_starts_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS matches_event_occurrence_idx ON matches (event_id, event_starts_at);
+
+CREATE INDEX IF NOT EXISTS matches_team_played_idx ON matches (team_id, played_at);
+
+CREATE TABLE IF NOT EXISTS match_maps (
+	match_id INT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+	number INT NOT NULL CHECK (number > 0),
+	map VARCHAR(64) NOT NULL,
+	mode VARCHAR(16) NOT NULL,
+	team_score INT NOT NULL CHECK (team_score >= 0),
+	opponent_score INT NOT NULL CHECK (opponent_score >= 0),
+	vod_url TEXT NOT NULL DEFAULT '',
+	notes TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (match_id, number)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE, -- hex SHA-256 of the token
+	name VARCHAR(255) NOT NULL,
+	scopes TEXT[] NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ, -- NULL: never used
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens (user_id);
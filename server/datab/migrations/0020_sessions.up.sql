@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id SERIAL PRIMARY KEY,
+	session_hash CHAR(64) NOT NULL UNIQUE, -- hex SHA-256 of the ID in the session cookie
+	user_id INT REFERENCES users(id) ON DELETE CASCADE, -- NULL: not logged in
+	data BYTEA NOT NULL, -- gob-encoded session values
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL -- absolute timeout
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions (expires_at);
@@ -0,0 +1,111 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OptionType is the type of a command option.
+type OptionType int
+
+const (
+	OptionString  OptionType = 3
+	OptionInteger OptionType = 4
+)
+
+// Command is a slash command definition.
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+// CommandOption is an option of a slash command.
+type CommandOption struct {
+	Type         OptionType `json:"type"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Required     bool       `json:"required,omitempty"`
+	Autocomplete bool       `json:"autocomplete,omitempty"`
+	Choices      []Choice   `json:"choices,omitempty"`
+}
+
+var (
+	teamOption = CommandOption{Type: OptionString, Name: "team", Description: "Team, if you are on more than one", Autocomplete: true}
+	weekOption = CommandOption{Type: OptionString, Name: "week", Description: "Which week (default: this week)", Choices: []Choice{
+		{Name: "This week", Value: "this"},
+		{Name: "Next week", Value: "next"},
+	}}
+)
+
+// Commands are the slash commands the bot answers.
+var Commands = []Command{
+	{
+		Name:        "link",
+		Description: "Link your Discord account to Vivacity with a code from your profile page",
+		Options: []CommandOption{
+			{Type: OptionString, Name: "code", Description: "The link code", Required: true},
+		},
+	},
+	{
+		Name:        "availability",
+		Description: "Show the times you've marked yourself available",
+		Options:     []CommandOption{teamOption, weekOption},
+	},
+	{
+		Name:        "schedule",
+		Description: "List your team's events for the next week",
+		Options:     []CommandOption{teamOption},
+	},
+	{
+		Name:        "rsvp",
+		Description: "Answer whether you're coming to an event",
+		Options: []CommandOption{
+			{Type: OptionInteger, Name: "event", Description: "The event", Required: true, Autocomplete: true},
+			{Type: OptionString, Name: "status", Description: "Your answer", Required: true, Choices: []Choice{
+				{Name: "Going", Value: "yes"},
+				{Name: "Maybe", Value: "maybe"},
+				{Name: "Not going", Value: "no"},
+			}},
+		},
+	},
+	{
+		Name:        "bestslot",
+		Description: "Find the time most of your team can make",
+		Options:     []CommandOption{teamOption, weekOption},
+	},
+}
+
+// APIBase is the Discord REST API the commands are registered with.
+var APIBase = "https://discord.com/api/v10"
+
+// RegisterCommands replaces the application's global slash commands with
+// Commands. It is safe to call on every start: Discord keeps commands that
+// have not changed.
+func RegisterCommands(ctx context.Context, client *http.Client, applicationID, botToken string) error {
+	body, err := json.Marshal(Commands)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, APIBase+"/applications/"+applicationID+"/commands", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+botToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("registering discord commands: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package discord
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timestamp renders t with Discord's timestamp markup, which every reader
+// sees in their own timezone. style is one of Discord's format letters,
+// such as F (full date and time), t (time) or R (relative).
+func Timestamp(t time.Time, style string) string {
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
+
+// FormatDuration renders d compactly, like "1h 30m".
+func FormatDuration(d time.Duration) string {
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	default:
+		return fmt.Sprintf("%dh %dm", h, m)
+	}
+}
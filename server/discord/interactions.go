@@ -0,0 +1,235 @@
+// Package discord implements the parts of Discord's HTTP interactions
+// protocol the Vivacity bot needs: request signature checks, the interaction
+// and response payloads, slash command registration and account link codes.
+package discord
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// InteractionType is what kind of interaction Discord is sending.
+type InteractionType int
+
+const (
+	InteractionPing         InteractionType = 1
+	InteractionCommand      InteractionType = 2
+	InteractionAutocomplete InteractionType = 4
+)
+
+// Interaction is the body of a request to the interactions endpoint.
+type Interaction struct {
+	ID      string          `json:"id"`
+	Type    InteractionType `json:"type"`
+	Data    *CommandData    `json:"data,omitempty"`
+	GuildID string          `json:"guild_id,omitempty"`
+	// Member is set for commands run in a server, User for direct messages.
+	Member *GuildMember `json:"member,omitempty"`
+	User   *User        `json:"user,omitempty"`
+}
+
+// Caller returns the Discord user who ran the command.
+func (in Interaction) Caller() *User {
+	if in.Member != nil && in.Member.User != nil {
+		return in.Member.User
+	}
+	return in.User
+}
+
+// User is a Discord account.
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
+}
+
+// GuildMember is a Discord account's membership in a server.
+type GuildMember struct {
+	User *User `json:"user"`
+}
+
+// CommandData is the slash command being run or autocompleted.
+type CommandData struct {
+	Name    string   `json:"name"`
+	Options []Option `json:"options,omitempty"`
+}
+
+// Option returns the value given for the named option, if any.
+func (d *CommandData) Option(name string) (Option, bool) {
+	if d == nil {
+		return Option{}, false
+	}
+	for _, o := range d.Options {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// Focused returns the option being autocompleted.
+func (d *CommandData) Focused() (Option, bool) {
+	if d == nil {
+		return Option{}, false
+	}
+	for _, o := range d.Options {
+		if o.Focused {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// Option is one option value of a command. While autocompleting, Value
+// holds whatever the user has typed so far, even for integer options.
+type Option struct {
+	Name    string          `json:"name"`
+	Type    OptionType      `json:"type"`
+	Value   json.RawMessage `json:"value"`
+	Focused bool            `json:"focused,omitempty"`
+}
+
+// String returns the option's value as text.
+func (o Option) String() string {
+	var s string
+	if json.Unmarshal(o.Value, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	return string(o.Value)
+}
+
+// Int returns the option's value as an integer.
+func (o Option) Int() (int, error) {
+	n, err := strconv.Atoi(o.String())
+	if err != nil {
+		return 0, errors.New(o.Name + " must be a number")
+	}
+	return n, nil
+}
+
+// ResponseType is how the bot answers an interaction.
+type ResponseType int
+
+const (
+	ResponsePong         ResponseType = 1
+	ResponseMessage      ResponseType = 4
+	ResponseAutocomplete ResponseType = 8
+)
+
+// FlagEphemeral shows a reply only to the user who ran the command.
+const FlagEphemeral = 1 << 6
+
+// MaxContent is the longest message Discord accepts.
+const MaxContent = 2000
+
+// MaxChoices is the most autocomplete choices Discord shows.
+const MaxChoices = 25
+
+// MaxChoiceName is the longest autocomplete choice name, in characters.
+const MaxChoiceName = 100
+
+// Response answers an interaction.
+type Response struct {
+	Type ResponseType  `json:"type"`
+	Data *ResponseData `json:"data,omitempty"`
+}
+
+// ResponseData is a reply message, or the choices for an autocomplete.
+type ResponseData struct {
+	Content string `json:"content,omitempty"`
+	Flags   int    `json:"flags,omitempty"`
+	// AllowedMentions keeps event titles from pinging anyone.
+	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"`
+	Choices         []Choice         `json:"choices,omitempty"`
+}
+
+// AllowedMentions restricts which mentions in a message notify users.
+type AllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// Choice is a suggested option value.
+type Choice struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// Reply returns a message response that mentions no one. Ephemeral replies
+// are only shown to the caller.
+func Reply(content string, ephemeral bool) Response {
+	if len(content) > MaxContent {
+		// Cut at the last whole line that fits, or mid-line if none does
+		cut := MaxContent - len("…")
+		if i := strings.LastIndex(content[:cut], "\n"); i > 0 {
+			cut = i + 1
+		}
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut] + "…"
+	}
+	data := &ResponseData{Content: content, AllowedMentions: &AllowedMentions{Parse: []string{}}}
+	if ephemeral {
+		data.Flags = FlagEphemeral
+	}
+	return Response{Type: ResponseMessage, Data: data}
+}
+
+// Suggest returns an autocomplete response, keeping the first MaxChoices
+// and cutting longer names to MaxChoiceName characters.
+func Suggest(choices []Choice) Response {
+	if len(choices) > MaxChoices {
+		choices = choices[:MaxChoices]
+	}
+	for i, c := range choices {
+		if name := []rune(c.Name); len(name) > MaxChoiceName {
+			choices[i].Name = string(name[:MaxChoiceName-1]) + "…"
+		}
+	}
+	if choices == nil {
+		choices = []Choice{}
+	}
+	return Response{Type: ResponseAutocomplete, Data: &ResponseData{Choices: choices}}
+}
+
+// ParsePublicKey decodes the hex public key shown on the application's page
+// of the Discord developer portal.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("discord public key must be 64 hex characters")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// MaxClockSkew is how far an interaction's timestamp may be from the
+// current time, allowing for clock drift and delivery delays. Requests
+// further off are refused so a captured one can't be replayed later.
+const MaxClockSkew = 5 * time.Minute
+
+// Verify reports whether body was signed by Discord within MaxClockSkew of
+// now. signature and timestamp are the X-Signature-Ed25519 and
+// X-Signature-Timestamp headers.
+func Verify(publicKey ed25519.PublicKey, signature, timestamp string, body []byte, now time.Time) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return false
+	}
+	msg := make([]byte, 0, len(timestamp)+len(body))
+	msg = append(msg, timestamp...)
+	msg = append(msg, body...)
+	return ed25519.Verify(publicKey, msg, sig)
+}
@@ -0,0 +1,90 @@
+package discord_test
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+)
+
+func TestVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := discord.ParsePublicKey(hex.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`{"type":1}`)
+	sig := hex.EncodeToString(ed25519.Sign(priv, append([]byte("1700000000"), body...)))
+	now := time.Unix(1700000002, 0)
+
+	if !discord.Verify(parsed, sig, "1700000000", body, now) {
+		t.Error("valid signature was rejected")
+	}
+	if discord.Verify(parsed, sig, "1700000001", body, now) {
+		t.Error("signature verified with a different timestamp")
+	}
+	if discord.Verify(parsed, sig, "1700000000", []byte(`{"type":2}`), now) {
+		t.Error("signature verified with a different body")
+	}
+	if discord.Verify(parsed, "zz", "1700000000", body, now) {
+		t.Error("malformed signature verified")
+	}
+	if !discord.Verify(parsed, sig, "1700000000", body, now.Add(time.Minute)) {
+		t.Error("signature delivered a minute late was rejected")
+	}
+	if discord.Verify(parsed, sig, "1700000000", body, now.Add(10*time.Minute)) {
+		t.Error("replayed signature verified ten minutes later")
+	}
+	if discord.Verify(parsed, sig, "1700000000", body, now.Add(-10*time.Minute)) {
+		t.Error("signature from the future verified")
+	}
+	if _, err := discord.ParsePublicKey("abc"); err == nil {
+		t.Error("short public key was accepted")
+	}
+}
+
+func TestLinkCodes(t *testing.T) {
+	code, hash, err := discord.NewLinkCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 9 || code[4] != '-' {
+		t.Errorf("unexpected code format %q", code)
+	}
+	if discord.HashLinkCode(" "+strings.ToLower(strings.ReplaceAll(code, "-", ""))) != hash {
+		t.Error("hash depends on case, spaces or dashes")
+	}
+}
+
+func TestReplyTruncates(t *testing.T) {
+	long := strings.Repeat("0123456789\n", 300)
+	r := discord.Reply(long, true)
+	if n := len(r.Data.Content); n > discord.MaxContent {
+		t.Errorf("content is %d bytes", n)
+	}
+	if !strings.HasSuffix(r.Data.Content, "\n…") {
+		t.Errorf("content was not cut at a line: %q", r.Data.Content[len(r.Data.Content)-20:])
+	}
+	if r.Data.Flags != discord.FlagEphemeral || r.Data.AllowedMentions == nil {
+		t.Errorf("unexpected reply data: %+v", r.Data)
+	}
+}
+
+func TestSuggestTruncatesNames(t *testing.T) {
+	long := strings.Repeat("é", 150)
+	r := discord.Suggest([]discord.Choice{{Name: long, Value: 1}, {Name: "short", Value: 2}})
+	name := r.Data.Choices[0].Name
+	if n := utf8.RuneCountInString(name); n != discord.MaxChoiceName || !utf8.ValidString(name) || !strings.HasSuffix(name, "…") {
+		t.Errorf("name is %d characters, valid UTF-8 %v: %q", n, utf8.ValidString(name), name)
+	}
+	if r.Data.Choices[1].Name != "short" {
+		t.Errorf("short name changed to %q", r.Data.Choices[1].Name)
+	}
+}
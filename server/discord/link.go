@@ -0,0 +1,38 @@
+package discord
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// linkAlphabet leaves out characters that are easy to mistake for others.
+const linkAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewLinkCode returns a random one-time code, formatted like "K7PQ-M2XD",
+// and the hash to store for it.
+func NewLinkCode() (code, hash string, err error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	for i := range b {
+		b[i] = linkAlphabet[int(b[i])%len(linkAlphabet)]
+	}
+	code = string(b[:4]) + "-" + string(b[4:])
+	return code, HashLinkCode(code), nil
+}
+
+// HashLinkCode returns the hex SHA-256 of a code as kept by the store. The
+// code is normalized first, so case, spaces and dashes don't matter.
+func HashLinkCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
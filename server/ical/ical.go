@@ -0,0 +1,257 @@
+// Package ical writes RFC 5545 calendars for subscription feeds. Times in a
+// named zone are written with a TZID and a matching VTIMEZONE, built from
+// Go's zone data, so recurring events keep their wall-clock time in calendar
+// clients across daylight saving changes.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Calendar is a VCALENDAR.
+type Calendar struct {
+	Name string
+	// Now stamps every event (DTSTAMP) and bounds the VTIMEZONE rules
+	// written for recurring events.
+	Now    time.Time
+	Events []Event
+}
+
+// Event is a VEVENT. An override of one occurrence of a recurring event
+// shares the series' UID and sets RecurrenceID to the start the rule gives
+// that occurrence.
+type Event struct {
+	UID   string
+	Start time.Time
+	End   time.Time
+	// Zone is the timezone Start and End are written in. Nil writes UTC.
+	Zone         *time.Location
+	RRule        string
+	RecurrenceID time.Time
+	Summary      string
+	Location     string
+	Description  string
+	Cancelled    bool
+	// Transparent events don't block time in the subscriber's calendar.
+	Transparent bool
+}
+
+// timezoneYears is how far past Now VTIMEZONE rules are written for series
+// that keep repeating.
+const timezoneYears = 3
+
+// Encode writes c to w.
+func (c Calendar) Encode(w io.Writer) error {
+	b := &builder{}
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//Vivacity//Schedule Manager//EN")
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		b.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	b.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	b.line("X-PUBLISHED-TTL:PT1H")
+
+	for _, z := range c.zones() {
+		writeTimezone(b, z.loc, z.from, z.to)
+	}
+	for _, e := range c.Events {
+		c.writeEvent(b, e)
+	}
+	b.line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c Calendar) writeEvent(b *builder, e Event) {
+	b.line("BEGIN:VEVENT")
+	b.line("UID:" + e.UID)
+	b.line("DTSTAMP:" + c.Now.UTC().Format(utcFormat))
+	if !e.RecurrenceID.IsZero() {
+		b.line("RECURRENCE-ID" + timeValue(e.RecurrenceID, e.Zone))
+	}
+	b.line("DTSTART" + timeValue(e.Start, e.Zone))
+	b.line("DTEND" + timeValue(e.End, e.Zone))
+	if e.RRule != "" {
+		b.line("RRULE:" + e.RRule)
+	}
+	b.line("SUMMARY:" + escape(e.Summary))
+	if e.Location != "" {
+		b.line("LOCATION:" + escape(e.Location))
+	}
+	if e.Description != "" {
+		b.line("DESCRIPTION:" + escape(e.Description))
+	}
+	if e.Cancelled {
+		b.line("STATUS:CANCELLED")
+	} else {
+		b.line("STATUS:CONFIRMED")
+	}
+	if e.Transparent {
+		b.line("TRANSP:TRANSPARENT")
+	} else {
+		b.line("TRANSP:OPAQUE")
+	}
+	b.line("END:VEVENT")
+}
+
+const (
+	utcFormat   = "20060102T150405Z"
+	localFormat = "20060102T150405"
+)
+
+// timeValue formats t as the parameters and value of a DTSTART-like
+// property, starting with ";" or ":".
+func timeValue(t time.Time, zone *time.Location) string {
+	if zone == nil || zone == time.UTC {
+		return ":" + t.UTC().Format(utcFormat)
+	}
+	return ";TZID=" + zone.String() + ":" + t.In(zone).Format(localFormat)
+}
+
+type zoneRange struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// zones returns every named zone the events use, with the span of time its
+// VTIMEZONE has to cover.
+func (c Calendar) zones() []zoneRange {
+	byName := map[string]*zoneRange{}
+	for _, e := range c.Events {
+		if e.Zone == nil || e.Zone == time.UTC {
+			continue
+		}
+		to := e.End
+		if e.RRule != "" {
+			to = maxTime(to, c.Now.AddDate(timezoneYears, 0, 0))
+		}
+		z, ok := byName[e.Zone.String()]
+		if !ok {
+			byName[e.Zone.String()] = &zoneRange{loc: e.Zone, from: e.Start, to: to}
+			continue
+		}
+		if e.Start.Before(z.from) {
+			z.from = e.Start
+		}
+		z.to = maxTime(z.to, to)
+	}
+
+	zones := make([]zoneRange, 0, len(byName))
+	for _, z := range byName {
+		zones = append(zones, *z)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].loc.String() < zones[j].loc.String() })
+	return zones
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// writeTimezone writes a VTIMEZONE for loc with one observance for the
+// offset in effect at from and one per transition up to to.
+func writeTimezone(b *builder, loc *time.Location, from, to time.Time) {
+	b.line("BEGIN:VTIMEZONE")
+	b.line("TZID:" + loc.String())
+
+	at := from.AddDate(0, 0, -1)
+	name, offset := at.In(loc).Zone()
+	writeObservance(b, at.In(loc).IsDST(), at, offset, offset, name)
+	for _, t := range transitions(loc, at, to) {
+		_, before := t.Add(-time.Second).In(loc).Zone()
+		name, after := t.In(loc).Zone()
+		writeObservance(b, t.In(loc).IsDST(), t, before, after, name)
+	}
+	b.line("END:VTIMEZONE")
+}
+
+// writeObservance writes a STANDARD or DAYLIGHT component starting at t,
+// written as local time in the offset that was in effect before it.
+func writeObservance(b *builder, daylight bool, t time.Time, from, to int, name string) {
+	kind := "STANDARD"
+	if daylight {
+		kind = "DAYLIGHT"
+	}
+	b.line("BEGIN:" + kind)
+	b.line("DTSTART:" + t.UTC().Add(time.Duration(from)*time.Second).Format(localFormat))
+	b.line("TZOFFSETFROM:" + formatOffset(from))
+	b.line("TZOFFSETTO:" + formatOffset(to))
+	b.line("TZNAME:" + escape(name))
+	b.line("END:" + kind)
+}
+
+// transitions returns the instants in (from, to] at which loc changes its
+// UTC offset or abbreviation.
+func transitions(loc *time.Location, from, to time.Time) []time.Time {
+	var found []time.Time
+	from = from.Truncate(time.Second)
+	zoneAt := func(t time.Time) string {
+		name, offset := t.In(loc).Zone()
+		return fmt.Sprint(name, offset)
+	}
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if zoneAt(day) == zoneAt(next) {
+			continue
+		}
+		// Narrow the change down to the second.
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+			if zoneAt(mid) == zoneAt(lo) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		found = append(found, hi)
+	}
+	return found
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// builder accumulates content lines, folding them at 75 octets without
+// splitting UTF-8 sequences.
+type builder struct {
+	strings.Builder
+}
+
+func (b *builder) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,77 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/ical"
+)
+
+func TestEncode(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 3, 19, 0, 0, 0, ny)
+	cal := ical.Calendar{
+		Name: "Alpha",
+		Now:  time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC),
+		Events: []ical.Event{
+			{
+				UID:         "event-1@vivacity",
+				Start:       start,
+				End:         start.Add(2 * time.Hour),
+				Zone:        ny,
+				RRule:       "FREQ=WEEKLY;BYDAY=TU,TH",
+				Summary:     "Practice; scrims, VODs",
+				Description: "RSVPs: 2 yes\nYour RSVP: " + strings.Repeat("very long answer ", 6),
+			},
+			{
+				UID:          "event-1@vivacity",
+				RecurrenceID: start.AddDate(0, 0, 7),
+				Start:        start.AddDate(0, 0, 8),
+				End:          start.AddDate(0, 0, 8).Add(time.Hour),
+				Zone:         ny,
+				Summary:      "Practice",
+				Cancelled:    true,
+			},
+			{
+				UID:     "event-2@vivacity",
+				Start:   time.Date(2026, 4, 1, 18, 0, 0, 0, time.UTC),
+				End:     time.Date(2026, 4, 1, 20, 0, 0, 0, time.UTC),
+				Summary: "Match",
+			},
+		},
+	}
+	var b strings.Builder
+	if err := cal.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"X-WR-CALNAME:Alpha\r\n",
+		"TZID:America/New_York\r\n",
+		// Daylight saving starts March 8, 2026 at 02:00 local time.
+		"BEGIN:DAYLIGHT\r\nDTSTART:20260308T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\n",
+		"DTSTART;TZID=America/New_York:20260303T190000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=TU,TH\r\n",
+		"SUMMARY:Practice\\; scrims\\, VODs\r\n",
+		"RECURRENCE-ID;TZID=America/New_York:20260310T190000\r\n",
+		"STATUS:CANCELLED\r\n",
+		"DTSTART:20260401T180000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q", want)
+		}
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	if !strings.Contains(out, "DESCRIPTION:RSVPs: 2 yes\\nYour RSVP: very long answer") {
+		t.Error("description is not escaped")
+	}
+}
@@ -0,0 +1,185 @@
+// Package jobs runs background work from a queue persisted in the store.
+// Jobs are claimed with a lease (FOR UPDATE SKIP LOCKED in Postgres), so any
+// number of replicas can run a Runner against the same database without
+// running a job twice.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a delayed job is tried before it
+	// is given up.
+	DefaultMaxAttempts = 5
+	// claimLease hides a claimed job from other runners while it runs. A
+	// handler that takes longer than this may run twice.
+	claimLease = 5 * time.Minute
+)
+
+// Handler runs one job. A returned error retries the job with backoff
+// until its attempts run out.
+type Handler func(ctx context.Context, job store.Job) error
+
+// Runner claims due jobs and runs them with the handler registered for
+// their kind, and queues the runs of periodic jobs.
+type Runner struct {
+	Store *store.Store
+	// Poll is how often Run checks for due jobs. It bounds how late a job
+	// can start.
+	Poll time.Duration
+	// Workers is how many jobs run at once.
+	Workers int
+
+	handlers map[string]Handler
+	periodic []periodic
+}
+
+type periodic struct {
+	kind     string
+	schedule Schedule
+}
+
+// NewRunner returns a Runner polling every five seconds with four workers.
+func NewRunner(s *store.Store) *Runner {
+	return &Runner{Store: s, Poll: 5 * time.Second, Workers: 4, handlers: map[string]Handler{}}
+}
+
+// Handle registers the handler for a kind of delayed job.
+func (r *Runner) Handle(kind string, h Handler) {
+	r.handlers[kind] = h
+}
+
+// Periodic registers a job that runs on a schedule (see ParseSchedule),
+// evaluated in UTC. A failed run is not retried; the next scheduled run
+// takes its place.
+func (r *Runner) Periodic(kind, spec string, h Handler) error {
+	sched, err := ParseSchedule(spec, time.UTC)
+	if err != nil {
+		return fmt.Errorf("periodic job %s: %w", kind, err)
+	}
+	r.handlers[kind] = h
+	r.periodic = append(r.periodic, periodic{kind: kind, schedule: sched})
+	return nil
+}
+
+// Enqueue queues a job of the given kind to run at runAt, or right away when
+// runAt is zero, with payload marshalled to JSON. A non-empty key makes the
+// job unique: Enqueue reports false when a job with the key was queued
+// before.
+func Enqueue(ctx context.Context, s *store.Store, kind string, payload any, runAt time.Time, key string) (bool, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	return s.Jobs.EnqueueJob(ctx, store.Job{Kind: kind, Key: key, Payload: data, RunAt: runAt, MaxAttempts: DefaultMaxAttempts})
+}
+
+// Run ticks every Poll until ctx is done. It then stops claiming jobs and
+// returns once the jobs already running have finished, so it can be
+// waited on during a graceful shutdown.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Poll)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Tick(ctx, time.Now()); err != nil {
+			log.Printf("Error running jobs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick queues the next run of every periodic job and runs the jobs due at
+// now, a batch of Workers at a time, until none are left. It returns how
+// many jobs ran. Jobs already claimed are finished even if ctx is done, so
+// that a shutdown doesn't leave them to wait out their lease.
+func (r *Runner) Tick(ctx context.Context, now time.Time) (int, error) {
+	for _, p := range r.periodic {
+		next := p.schedule.Next(now)
+		if next.IsZero() {
+			continue
+		}
+		key := fmt.Sprintf("cron:%s:%d", p.kind, next.Unix())
+		if _, err := r.Store.Jobs.EnqueueJob(ctx, store.Job{Kind: p.kind, Key: key, Payload: []byte("null"), RunAt: next, MaxAttempts: 1}); err != nil {
+			return 0, err
+		}
+	}
+
+	ran := 0
+	for ctx.Err() == nil {
+		claimed, err := r.Store.Jobs.ClaimJobs(ctx, now, claimLease, max(r.Workers, 1))
+		if err != nil {
+			return ran, err
+		}
+		if len(claimed) == 0 {
+			break
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, len(claimed))
+		for i, job := range claimed {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = r.run(context.WithoutCancel(ctx), job, now)
+			}()
+		}
+		wg.Wait()
+		ran += len(claimed)
+		for _, err := range errs {
+			if err != nil {
+				return ran, err
+			}
+		}
+	}
+	return ran, nil
+}
+
+// run runs one claimed job and records the outcome. It only returns an
+// error when the outcome can't be saved.
+func (r *Runner) run(ctx context.Context, job store.Job, now time.Time) error {
+	err := r.call(ctx, job)
+	if err == nil {
+		return r.Store.Jobs.CompleteJob(ctx, job.ID)
+	}
+	var next time.Time
+	if job.Attempts < job.MaxAttempts {
+		next = now.Add(Backoff(job.Attempts))
+	}
+	log.Printf("Job %d (%s) failed (attempt %d of %d): %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, err)
+	return r.Store.Jobs.FailJob(ctx, job.ID, next, err.Error())
+}
+
+// call runs the job's handler, turning a panic into an error.
+func (r *Runner) call(ctx context.Context, job store.Job) (err error) {
+	h, ok := r.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// Backoff is the wait after the given number of failed attempts: 30s,
+// doubling up to an hour.
+func Backoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, time.Hour)
+}
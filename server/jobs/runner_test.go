@@ -0,0 +1,210 @@
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/jobs"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestParseSchedule(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sunday 2025-03-09 is when New York springs forward
+	from := time.Date(2025, 3, 8, 12, 30, 0, 0, ny)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 3, 8, 12, 45, 0, 0, ny)},
+		{"0 4 * * 1", time.Date(2025, 3, 10, 4, 0, 0, 0, ny)},
+		{"30 2 * * *", time.Date(2025, 3, 10, 2, 30, 0, 0, ny)}, // 02:30 doesn't exist on the 9th
+		{"0 9 1,15 * *", time.Date(2025, 3, 15, 9, 0, 0, 0, ny)},
+		{"0 9 1 * 7", time.Date(2025, 3, 9, 9, 0, 0, 0, ny)}, // day of month or Sunday
+		{"0 0 1-3 4 *", time.Date(2025, 4, 1, 0, 0, 0, 0, ny)},
+		{"@daily", time.Date(2025, 3, 9, 0, 0, 0, 0, ny)},
+		{"@every 1h", from.Truncate(time.Hour).Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		sched, err := jobs.ParseSchedule(tt.spec, ny)
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if got := sched.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: next run %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	if sched, _ := jobs.ParseSchedule("0 0 30 2 *", time.UTC); !sched.Next(from).IsZero() {
+		t.Error("February 30th should never run")
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@every 1ms", "@yearly"} {
+		if _, err := jobs.ParseSchedule(spec, time.UTC); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestRunnerRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	runner := jobs.NewRunner(s)
+
+	now := time.Date(2025, 5, 5, 18, 0, 0, 0, time.UTC)
+	var calls []int
+	runner.Handle("remind", func(ctx context.Context, job store.Job) error {
+		var eventID int
+		if err := json.Unmarshal(job.Payload, &eventID); err != nil {
+			return err
+		}
+		calls = append(calls, eventID)
+		if len(calls) < 3 {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+
+	ok, err := jobs.Enqueue(ctx, s, "remind", 7, now.Add(time.Hour), "remind:7")
+	if err != nil || !ok {
+		t.Fatalf("enqueue: %v, %v", ok, err)
+	}
+	if ok, _ := jobs.Enqueue(ctx, s, "remind", 7, now.Add(time.Hour), "remind:7"); ok {
+		t.Error("a job with the same key was queued twice")
+	}
+
+	// Not due yet
+	if ran, err := runner.Tick(ctx, now); err != nil || ran != 0 {
+		t.Fatalf("before it is due: ran %d, %v", ran, err)
+	}
+	// Fails, then waits 30s and fails again, then waits 1m and succeeds
+	now = now.Add(time.Hour)
+	steps := []time.Duration{0, 29 * time.Second, time.Second, 59 * time.Second, time.Second}
+	wantRan := []int{1, 0, 1, 0, 1}
+	for i, step := range steps {
+		now = now.Add(step)
+		if ran, err := runner.Tick(ctx, now); err != nil || ran != wantRan[i] {
+			t.Fatalf("step %d: ran %d, %v, want %d", i, ran, err, wantRan[i])
+		}
+	}
+	if len(calls) != 3 || calls[0] != 7 {
+		t.Errorf("handler calls %v, want three with event 7", calls)
+	}
+	if ran, _ := runner.Tick(ctx, now.Add(24*time.Hour)); ran != 0 {
+		t.Errorf("completed job ran again")
+	}
+}
+
+func TestRunnerGivesUp(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	runner := jobs.NewRunner(s)
+	var calls int
+	runner.Handle("broken", func(ctx context.Context, job store.Job) error {
+		calls++
+		panic("boom")
+	})
+
+	now := time.Date(2025, 5, 5, 18, 0, 0, 0, time.UTC)
+	jobs.Enqueue(ctx, s, "broken", nil, now, "")
+	jobs.Enqueue(ctx, s, "unknown", nil, now, "")
+	for range 10 {
+		if _, err := runner.Tick(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(2 * time.Hour)
+	}
+	if calls != jobs.DefaultMaxAttempts {
+		t.Errorf("handler ran %d times, want %d", calls, jobs.DefaultMaxAttempts)
+	}
+}
+
+func TestRunnerPeriodicRunsOncePerSlot(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+
+	// Two replicas share one store
+	var calls atomic.Int32
+	var runners []*jobs.Runner
+	for range 2 {
+		r := jobs.NewRunner(s)
+		if err := r.Periodic("digest", "@every 1m", func(ctx context.Context, job store.Job) error {
+			calls.Add(1)
+			return errors.New("failed runs are not retried")
+		}); err != nil {
+			t.Fatal(err)
+		}
+		runners = append(runners, r)
+	}
+
+	now := time.Date(2025, 5, 5, 18, 0, 30, 0, time.UTC)
+	for range 3 {
+		for _, r := range runners {
+			if _, err := r.Tick(ctx, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		now = now.Add(time.Minute)
+	}
+	// 18:01 and 18:02 have passed; 18:03 is queued
+	if got := calls.Load(); got != 2 {
+		t.Errorf("periodic job ran %d times, want 2", got)
+	}
+
+	if err := jobs.NewRunner(s).Periodic("bad", "61 * * * *", nil); err == nil {
+		t.Error("an invalid schedule should be rejected")
+	}
+}
+
+func TestRunnerStopsGracefully(t *testing.T) {
+	s := store.NewMemory()
+	runner := jobs.NewRunner(s)
+	runner.Poll = 10 * time.Millisecond
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var handlerErr atomic.Value
+	runner.Handle("slow", func(ctx context.Context, job store.Job) error {
+		close(started)
+		<-release
+		// The handler's context outlives the runner's
+		if err := ctx.Err(); err != nil {
+			handlerErr.Store(err)
+		}
+		return nil
+	})
+	jobs.Enqueue(context.Background(), s, "slow", nil, time.Time{}, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Run returned before the running job finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after the job finished")
+	}
+	if err := handlerErr.Load(); err != nil {
+		t.Errorf("handler context was cancelled: %v", err)
+	}
+	if claimed, _ := s.Jobs.ClaimJobs(context.Background(), time.Now().Add(time.Hour), time.Minute, 10); len(claimed) != 0 {
+		t.Errorf("job wasn't completed: %+v", claimed)
+	}
+}
@@ -5,20 +5,31 @@ import (
 	//"encoding/json"
 
 	"context"
+	"crypto/ed25519"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
+	_ "time/tzdata" // team and player timezones must resolve on hosts without zoneinfo
 
 	"github.com/KhrisKringle/Vivacity_website-main/server/api"
 	"github.com/KhrisKringle/Vivacity_website-main/server/datab"
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/jobs"
+	authmw "github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/notify"
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
 	"github.com/KhrisKringle/Vivacity_website-main/server/user_account"
 
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/gorilla/sessions"
 
 	"github.com/markbates/goth"
@@ -28,7 +39,9 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var store *sessions.CookieStore
+// sessionStore keeps login sessions in Postgres. It is set up in main, once
+// the database is connected.
+var sessionStore *authmw.ServerStore
 
 func init() {
 	// Load .env file
@@ -48,9 +61,9 @@ func init() {
 		log.Fatal("SESSION_KEY must be 32 bytes (64 hex characters)")
 	}
 
-	// Initialize session store
-	store = sessions.NewCookieStore(sessionKey)
-	store.Options = &sessions.Options{
+	// The OAuth flow's short-lived state stays in a cookie
+	oauthStore := sessions.NewCookieStore(sessionKey)
+	oauthStore.Options = &sessions.Options{
 		Path:     "/",
 		MaxAge:   3600, // 1 hour for OAuth flow
 		HttpOnly: true,
@@ -71,7 +84,7 @@ func init() {
 	log.Printf("BLIZZARD_CLIENT_SECRET loaded: %t", os.Getenv("BLIZZARD_CLIENT_SECRET") != "")
 
 	// 3. Configure gothic to use your store and session name.
-	gothic.Store = store
+	gothic.Store = oauthStore
 
 	callbackURL := "http://192.168.1.234:8080/auth/callback/battlenet"
 
@@ -79,7 +92,7 @@ func init() {
 	goth.UseProviders(
 		battlenet.New(bliz_public, bliz_secret, callbackURL, "us"),
 	)
-	log.Println("Session store configured!")
+	log.Println("OAuth session store configured!")
 }
 
 func main() {
@@ -87,6 +100,7 @@ func main() {
 	r := chi.NewRouter()
 
 	// Add middleware
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -97,49 +111,110 @@ func main() {
 	}
 	defer db.Close()
 
-	// Call the SetupDB function from the db package
-	err = datab.SetupDB(db)
+	// "vivacity-app migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Bring the schema up to date before serving
+	migrator, err := datab.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+	applied, err := migrator.Up(context.Background())
 	if err != nil {
-		log.Fatalf("Error setting up database: %v", err)
+		log.Fatalf("Error migrating database: %v", err)
 	}
 
-	fmt.Println("Database setup completed successfully!")
+	fmt.Printf("Database migrated successfully (%d migrations applied)\n", len(applied))
 
-	r.Get("/auth/status", func(w http.ResponseWriter, r *http.Request) {
-		session, err := store.Get(r, "vivacity-session")
-		log.Printf("Auth status session values: %v", session.Values)
-		if err != nil {
-			log.Printf("Error getting session: %v", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+	st := store.NewPostgres(db)
+
+	// Background work runs from the jobs queue, so replicas share it
+	runner := jobs.NewRunner(st)
+	notifier := &notify.Service{Store: st, Client: &http.Client{Timeout: 10 * time.Second}}
+	mustPeriodic(runner, "notify.discord", "@every 30s", func(ctx context.Context, _ store.Job) error {
+		now := time.Now()
+		if err := notifier.Schedule(ctx, now); err != nil {
+			return err
 		}
-		if authenticated, ok := session.Values["authenticated"].(bool); !ok || !authenticated {
-			log.Printf("User is not authorized: %v", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+		return notifier.Deliver(ctx, now)
+	})
+	runner.Handle(notify.ReminderJob, notifier.Remind)
+	// Reminders are queued when events change; this covers events from
+	// before that, and is a no-op for reminders already queued
+	if err := queueReminders(context.Background(), st); err != nil {
+		log.Printf("Error queueing event reminders: %v", err)
+	}
+
+	// Email players who opted in, when an SMTP server is configured
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		emailer := &notify.EmailService{
+			Store: st,
+			Notifier: &notify.SMTPNotifier{
+				Addr:     addr,
+				From:     os.Getenv("SMTP_FROM"),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+			},
+			BaseURL: os.Getenv("APP_BASE_URL"),
 		}
-		user := map[string]string{
-			"UserID":    session.Values["UserID"].(string),
-			"TeamID": session.Values["TeamID"].(int8),
-			"battletag": session.Values["battletag"].(string),
-			"role": session.Values["role"].(int8),
+		mustPeriodic(runner, "notify.email", "@every 1m", func(ctx context.Context, _ store.Job) error {
+			return emailer.Send(ctx, time.Now())
+		})
+	}
+
+	// Keep players' ranks fresh from their career profiles, when a stats
+	// service is configured
+	var syncer *profiles.Syncer
+	if statsURL := os.Getenv("OVERWATCH_STATS_URL"); statsURL != "" {
+		syncer = &profiles.Syncer{
+			Store: st,
+			Stats: &profiles.OverFastClient{BaseURL: statsURL, Client: &http.Client{Timeout: 10 * time.Second}},
 		}
+		mustPeriodic(runner, "profiles.sync", "@hourly", func(ctx context.Context, _ store.Job) error {
+			n, err := syncer.Refresh(ctx, time.Now())
+			if n > 0 {
+				log.Printf("Synced %d player profiles", n)
+			}
+			return err
+		})
+	}
 
-		err = session.Save(r, w)
-		if err != nil {
-    		http.Error(w, "Failed to save session", http.StatusInternalServerError)
-    		return
+	// Reset availability weekly by dropping weeks long past, and forget
+	// finished jobs
+	mustPeriodic(runner, "availability.prune", "0 4 * * 1", func(ctx context.Context, _ store.Job) error {
+		n, err := st.Availability.PruneAvailability(ctx, time.Now().Add(-availabilityRetention))
+		if err == nil {
+			log.Printf("Pruned %d availability intervals", n)
 		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(user); err != nil {
-			log.Printf("Error encoding response: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
+		return err
+	})
+	mustPeriodic(runner, "jobs.prune", "@daily", func(ctx context.Context, _ store.Job) error {
+		_, err := st.Jobs.PruneJobs(ctx, time.Now().Add(-jobRetention))
+		return err
+	})
+
+	// Login sessions live server-side, so they can be listed and revoked.
+	// Expired ones are cleaned up hourly.
+	sessionStore = authmw.NewServerStore(st.Sessions, sessionIdleTimeout, sessionAbsoluteTimeout)
+	sessionStore.Options.Secure = false // False for local dev
+	mustPeriodic(runner, "sessions.prune", "@hourly", func(ctx context.Context, _ store.Job) error {
+		now := time.Now().UTC()
+		n, err := st.Sessions.PruneSessions(ctx, now, sessionStore.IdleSince(now))
+		if n > 0 {
+			log.Printf("Pruned %d expired sessions", n)
 		}
-		log.Printf("Auth status checked for user: %v", user)
+		return err
 	})
 
+	// The logged in player and every team they belong to
+	sessionAuth := authmw.SessionAuth(sessionStore, st)
+	r.With(sessionAuth).Get("/auth/status", api.AuthStatusHandler)
+
 	// Add authentication routes
 	r.Get("/auth/{provider}", func(w http.ResponseWriter, r *http.Request) {
 		// This is a small trick to tell Goth which provider to use.
@@ -164,39 +239,44 @@ func main() {
 		user, err := gothic.CompleteUserAuth(w, r)
 		if err != nil {
 			log.Printf("Error during callback for provider: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, "Login failed", http.StatusInternalServerError)
 			return
 		}
 
 		log.Printf("USER AUTHENTICATED: %+v", user)
 
 		// Process user data from Blizzard and create/update account and get back both the userID AND teamID
-		err = user_account.HandleBlizzardAuth(db, user)
+		player, err := user_account.HandleBlizzardAuth(r.Context(), st.Players, user)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to process user: %v", err), http.StatusInternalServerError)
+			log.Printf("Error processing user %s: %v", user.NickName, err)
+			http.Error(w, "Failed to process user", http.StatusInternalServerError)
 			return
 		}
 
 		log.Printf("User data processed for: %s", user.NickName)
 
 		// Get a new session for our application data
-		session, err := store.Get(r, "vivacity-session")
+		session, err := sessionStore.Get(r, authmw.SessionName)
 		if err != nil {
-			session, _ = store.New(r, "vivacity-session")
+			session, _ = sessionStore.New(r, authmw.SessionName)
+		}
+		// A fresh session ID at login, so one planted before it is useless
+		if err := sessionStore.Renew(r, session); err != nil {
+			log.Printf("Error renewing session: %v", err)
+			http.Error(w, "Failed to save session", http.StatusInternalServerError)
+			return
 		}
 
 		session.Values["battletag"] = user.NickName
-		session.Values["UserID"] = user.UserID
-		session.Values["TeamID"] = user.TeamID
+		session.Values["UserID"] = strconv.Itoa(player.ID)
 		session.Values["authenticated"] = true
-		session.Values["role"] = user.role
 
 		log.Printf("Authentication successful for user: %v", user)
 
 		err = session.Save(r, w)
 		if err != nil {
 			log.Printf("Error saving session: %v", err)
-			http.Error(w, fmt.Sprintf("Failed to save session: %v", err), http.StatusInternalServerError)
+			http.Error(w, "Failed to save session", http.StatusInternalServerError)
 			return
 		}
 		log.Printf("Session saved with values: %v", session.Values)
@@ -213,7 +293,7 @@ func main() {
 	// 	}
 
 	// 	// Verify session
-	// 	session, err := store.Get(r, "_gothic_session")
+	// 	session, err := sessionStore.Get(r, "_gothic_session")
 	// 	if err != nil {
 	// 		log.Printf("Error getting session: %v", err)
 	// 	}
@@ -229,16 +309,10 @@ func main() {
 	// })
 
 	r.Get("/logout", func(w http.ResponseWriter, r *http.Request) {
-		session, _ := store.Get(r, "vivacity-session")
+		session, _ := sessionStore.Get(r, authmw.SessionName)
 
-		// Clear the session values
-		session.Values["authenticated"] = false
-		session.Values["battletag"] = ""
-		session.Values["UserID"] = ""
-		session.Values["TeamID"] = 0
-		session.Options.MaxAge = -1 // Expire the cookie immediately
-
-		// Save the session to commit the changes
+		// Delete the stored session and expire the cookie immediately
+		session.Options.MaxAge = -1
 		err := session.Save(r, w)
 		if err != nil {
 			log.Printf("Error saving session during logout: %v", err)
@@ -250,87 +324,31 @@ func main() {
 		http.Redirect(w, r, "/", http.StatusFound)
 	})
 
-	// Teams API
-	r.Route("/api/teams", func(r chi.Router) {
-		r.Post("/", api.TeamHandler(db))
-		// Team-specific routes
-		r.Route("/{team_id}", func(r chi.Router) {
-			// Ensure teamID is an integer
-			r.Use(func(next http.Handler) http.Handler {
-				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					teamID := chi.URLParam(r, "team_id")
-					if _, err := strconv.Atoi(teamID); err != nil {
-						http.Error(w, "Invalid team ID", http.StatusBadRequest)
-						return
-					}
-					next.ServeHTTP(w, r)
-				})
-			})
-			// Team-specific handlers
-			r.Get("/", api.TeamHandler(db))    // Get team by ID
-			r.Delete("/", api.TeamHandler(db)) // Delete team by ID
-			r.Put("/", api.TeamHandler(db))    // Update team name by ID
-
-			r.Get("/members", api.TeamMembersHandler(db))    // Get members of a team
-			r.Post("/members", api.TeamMembersHandler(db))   // Add a member to a team
-			r.Delete("/members", api.TeamMembersHandler(db)) // Remove a member from a team
-			r.Put("/members", api.TeamMembersHandler(db))    // Update a member's role in a team
-
-			r.Get("/schedule", api.ScheduleHandler(db))    // Get schedule for a team
-			r.Post("/schedule", api.ScheduleHandler(db))   // Create schedule for a team
-			r.Delete("/schedule", api.ScheduleHandler(db)) // Delete schedule for a team
-			r.Put("/schedule", api.ScheduleHandler(db))    // Update schedule for a team
-
-			r.Get("/availability", api.AvailabilityHandler(db))  // Get availability for a team
-			r.Post("/availability", api.AvailabilityHandler(db)) // Set availability for a team
-		})
-	})
-
-	// Players API
-	r.Route("/api/players", func(r chi.Router) {
-		r.Get("/", api.PlayerHandler(db)) // Get all players
-		r.Route("/{user_id}", func(r chi.Router) {
-			// Ensure userID is an integer
-			r.Use(func(next http.Handler) http.Handler {
-				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					userID := chi.URLParam(r, "user_id")
-					if _, err := strconv.Atoi(userID); err != nil {
-						http.Error(w, "Invalid user ID", http.StatusBadRequest)
-						return
-					}
-					next.ServeHTTP(w, r)
-				})
-			})
-			// Player-specific handlers
-			r.Get("/", api.PlayerHandler(db))
-			r.Post("/", api.PlayerHandler(db))
-			r.Delete("/", api.PlayerHandler(db))
-			r.Put("/", api.PlayerHandler(db))
-		})
-	})
+	// The Discord bot is optional: its endpoint is served when the public key
+	// is set, and its commands are registered when the bot token is too
+	var discordKey ed25519.PublicKey
+	if key := os.Getenv("DISCORD_PUBLIC_KEY"); key != "" {
+		if discordKey, err = discord.ParsePublicKey(key); err != nil {
+			log.Fatal(err)
+		}
+		appID, botToken := os.Getenv("DISCORD_APPLICATION_ID"), os.Getenv("DISCORD_BOT_TOKEN")
+		if appID != "" && botToken != "" {
+			go func() {
+				if err := discord.RegisterCommands(context.Background(), &http.Client{Timeout: 10 * time.Second}, appID, botToken); err != nil {
+					log.Printf("Error registering Discord commands: %v", err)
+				}
+			}()
+		}
+	}
 
-	// TimeSlots API
-	r.Route("/api/timeslots", func(r chi.Router) {
-		r.Get("/", api.TimeSlotsHandler(db)) // Get all time slot
-		r.Route("/{timeSlotID}", func(r chi.Router) {
-			// Ensure timeSlotID is an integer
-			r.Use(func(next http.Handler) http.Handler {
-				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					timeSlotID := chi.URLParam(r, "timeSlotID")
-					if _, err := strconv.Atoi(timeSlotID); err != nil {
-						http.Error(w, "Invalid time slot ID", http.StatusBadRequest)
-						return
-					}
-					next.ServeHTTP(w, r)
-				})
-			})
-			// TimeSlot-specific handlers
-			r.Get("/", api.TimeSlotsHandler(db))
-			r.Post("/", api.TimeSlotsHandler(db))
-			r.Delete("/", api.TimeSlotsHandler(db))
-			r.Put("/", api.TimeSlotsHandler(db))
-		})
-	})
+	// API routes
+	r.Mount("/api", api.NewRouter(api.Options{
+		Store:              st,
+		Authenticate:       sessionAuth,
+		DiscordPublicKey:   discordKey,
+		Profiles:           syncer,
+		SessionIdleTimeout: sessionIdleTimeout,
+	}))
 
 	// Serve static files (CSS, JS, images)
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("../static"))))
@@ -340,15 +358,8 @@ func main() {
 		http.ServeFile(w, r, "../static/index.html")
 	})
 
-	r.Get("/teams", func(w http.ResponseWriter, r *http.Request) {
-		// Think about this it maybe wrong
-		sessions, err := store.Get(r, "vivacity_session")
-		if err != nil {
-			log.Printf("Error getting session: %v", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		log.Printf("Teams route session values: %v", sessions.Values)
+	// The teams page is only for logged in players
+	r.With(sessionAuth).Get("/teams", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "../static/Teams/teams.html")
 	})
 
@@ -363,12 +374,70 @@ func main() {
 
 	// Routes for Profile
 	r.Route("/profile", func(r chi.Router) {
-		r.Get("/{user_id}", func(w http.ResponseWriter, r *http.Request) {
-			user_account.ProfileHandler(w, r, store, db)
-		})
+		r.With(sessionAuth).Get("/{user_id}", user_account.ProfileHandler)
 	})
 
-	// Start server
+	// Serve until SIGTERM or Ctrl-C, then let requests and running jobs
+	// finish before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	jobsDone := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(jobsDone)
+	}()
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down server: %v", err)
+		}
+	}()
+
 	log.Println("Server starting on :8080")
-	http.ListenAndServe(":8080", r)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-jobsDone
+	log.Println("Server stopped")
+}
+
+const (
+	// availabilityRetention is how long availability is kept after it ends.
+	availabilityRetention = 8 * 7 * 24 * time.Hour
+	// jobRetention is how long finished jobs are kept.
+	jobRetention = 7 * 24 * time.Hour
+	// sessionIdleTimeout ends login sessions left unused this long, and
+	// sessionAbsoluteTimeout ends them this long after login.
+	sessionIdleTimeout     = 7 * 24 * time.Hour
+	sessionAbsoluteTimeout = 30 * 24 * time.Hour
+	// shutdownTimeout is how long in-flight requests get to finish.
+	shutdownTimeout = 30 * time.Second
+)
+
+// queueReminders queues the next reminder of every upcoming event of the
+// teams with Discord notifications.
+func queueReminders(ctx context.Context, st *store.Store) error {
+	settings, err := st.Notifications.ListDiscordSettings(ctx)
+	if err != nil {
+		return err
+	}
+	for _, set := range settings {
+		if err := notify.ScheduleReminders(ctx, st, set.TeamID, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mustPeriodic registers a periodic job, and fails when its schedule is
+// invalid.
+func mustPeriodic(runner *jobs.Runner, kind, spec string, h jobs.Handler) {
+	if err := runner.Periodic(kind, spec, h); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// APITokenPrefix starts every personal API token, so leaked tokens are easy
+// to recognize.
+const APITokenPrefix = "viv_"
+
+// NewAPIToken returns a random personal API token and the hash to store for
+// it.
+func NewAPIToken() (token, hash string, err error) {
+	token, _, err = NewToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + token
+	return token, HashToken(token), nil
+}
+
+// HasScope reports whether the caller may use scope. Sessions hold every
+// scope. A token holds its own, where a write scope also allows reads in
+// its area.
+func (p *Principal) HasScope(scope string) bool {
+	if p.TokenID == 0 {
+		return true
+	}
+	area, access, _ := strings.Cut(scope, ":")
+	for _, s := range p.Scopes {
+		if s == scope || access == "read" && s == area+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of an Authorization: Bearer header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// tokenPrincipal loads the Principal of the player who owns an API token,
+// limited to the token's scopes. It returns store.ErrNotFound for an
+// unknown, expired or revoked token.
+func tokenPrincipal(ctx context.Context, s *store.Store, token string) (*Principal, error) {
+	t, err := s.APITokens.UseAPIToken(ctx, HashToken(token), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	p, err := LoadPrincipal(ctx, s, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	p.TokenID = t.ID
+	p.Scopes = t.Scopes
+	return p, nil
+}
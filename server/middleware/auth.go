@@ -2,53 +2,155 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
 
 	"github.com/gorilla/sessions"
 )
 
-// Define a key for using context to pass the user ID.
+// SessionName is the name of the cookie session set at login.
+const SessionName = "vivacity-session"
+
+// Define a key type for values this package stores in the request context.
 type contextKey string
 
-const UserIDKey = contextKey("userID")
+const (
+	principalKey = contextKey("principal")
+	TeamIDKey    = contextKey("teamID")
+	RoleKey      = contextKey("role")
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID      int   // internal users.id
+	BattleNetID int64 // users.user_id
+	Battletag   string
+	IsAdmin     bool
+	Timezone    string // IANA name from the user's profile
+	Memberships []store.Member
+	// ActiveTeamID is the team the caller chose to work in, or their first
+	// team when they haven't chosen one or have since left it. It is zero
+	// for players on no team.
+	ActiveTeamID int
+	// TokenID is the personal API token the request was made with, limited
+	// to Scopes. It is zero for browser sessions, which aren't limited.
+	TokenID int
+	Scopes  []string
+}
+
+// Membership returns the caller's membership in teamID, if any.
+func (p *Principal) Membership(teamID int) (store.Member, bool) {
+	for _, m := range p.Memberships {
+		if m.TeamID == teamID {
+			return m, true
+		}
+	}
+	return store.Member{}, false
+}
+
+// LoadPrincipal builds the Principal for userID from the store.
+func LoadPrincipal(ctx context.Context, s *store.Store, userID int) (*Principal, error) {
+	player, err := s.Players.GetPlayer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.Memberships.ListMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	p := &Principal{
+		UserID:      player.ID,
+		BattleNetID: player.BattleNetID,
+		Battletag:   player.Username,
+		IsAdmin:     player.IsAdmin,
+		Timezone:    player.Timezone,
+		Memberships: memberships,
+	}
+	if _, ok := p.Membership(player.ActiveTeamID); ok {
+		p.ActiveTeamID = player.ActiveTeamID
+	} else if len(memberships) > 0 {
+		p.ActiveTeamID = memberships[0].TeamID
+	}
+	return p, nil
+}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
 
-// SessionAuthMiddleware checks for a valid user session and adds the UserID to the request context.
-func SessionAuth(store *sessions.CookieStore) func(http.Handler) http.Handler {
+// PrincipalFromContext returns the caller stored by the auth middleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// SessionAuth checks for a valid user session, or a personal API token sent
+// as Authorization: Bearer, and adds the caller's Principal to the request
+// context.
+func SessionAuth(sessionStore sessions.Store, s *store.Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get the session from the request.
-			session, err := store.Get(r, "vivacity-session")
-			if err != nil || session.IsNew {
-				http.Error(w, "Unauthorized: Please log in.", http.StatusUnauthorized)
-				return
-			}
+			var principal *Principal
+			var err error
+			if token, ok := bearerToken(r); ok {
+				principal, err = tokenPrincipal(r.Context(), s, token)
+				if errors.Is(err, store.ErrNotFound) {
+					Unauthorized(w, r, "Invalid, expired or revoked API token.")
+					return
+				}
+			} else {
+				// Get the session from the request.
+				session, sessionErr := sessionStore.Get(r, SessionName)
+				if sessionErr != nil || session.IsNew {
+					Unauthorized(w, r, "Please log in.")
+					return
+				}
 
-			// Get the UserID stored during login. It's a string in the session.
-			userID, ok := session.Values["UserID"].(string)
-			if !ok || userID == "" {
-				http.Error(w, "Unauthorized: Invalid session data.", http.StatusUnauthorized)
+				// Get the UserID stored during login. It's a string in the session.
+				userIDStr, _ := session.Values["UserID"].(string)
+				userID, convErr := strconv.Atoi(userIDStr)
+				if convErr != nil {
+					Unauthorized(w, r, "Invalid session data.")
+					return
+				}
+
+				principal, err = LoadPrincipal(r.Context(), s, userID)
+				if errors.Is(err, store.ErrNotFound) {
+					Unauthorized(w, r, "Account no longer exists.")
+					return
+				}
+			}
+			if err != nil {
+				log.Printf("Error authenticating request: %v", err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 
-			// Add the UserID to the request's context so the next handler can access it.
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			// Add the Principal to the request's context so the next handler can access it.
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
 		})
 	}
 }
 
-// GetUserIDFromContext is a helper to safely retrieve the UserID from a request's context.
-func GetUserIDFromContext(ctx context.Context) (string, bool) {
-	userID, ok := ctx.Value(UserIDKey).(string)
-	return userID, ok
+// Unauthorized writes a JSON 401 response.
+func Unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	response.Error(w, r, message, http.StatusUnauthorized)
 }
 
+// GetTeamIDFromContext returns the team the request was authorized against.
 func GetTeamIDFromContext(ctx context.Context) (int64, bool) {
-	teamID, ok := ctx.Value("TeamID").(int64)
+	teamID, ok := ctx.Value(TeamIDKey).(int64)
 	return teamID, ok
 }
 
+// GetRoleFromContext returns the caller's role on that team.
 func GetRoleFromContext(ctx context.Context) (string, bool) {
-	role, ok := ctx.Value("Role").(string)
+	role, ok := ctx.Value(RoleKey).(string)
 	return role, ok
 }
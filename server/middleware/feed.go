@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// FeedTokenAuth authenticates calendar feed requests by their ?token=
+// parameter, since calendar clients can't send the session cookie, and adds
+// the token owner's Principal to the request context.
+func FeedTokenAuth(s *store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				Unauthorized(w, r, "feed token required")
+				return
+			}
+			userID, err := s.FeedTokens.FeedTokenUser(r.Context(), HashToken(token))
+			if errors.Is(err, store.ErrNotFound) {
+				Unauthorized(w, r, "invalid or revoked feed token")
+				return
+			}
+			if err != nil {
+				log.Printf("Error looking up feed token: %v", err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			principal, err := LoadPrincipal(r.Context(), s, userID)
+			if errors.Is(err, store.ErrNotFound) {
+				Unauthorized(w, r, "Account no longer exists.")
+				return
+			}
+			if err != nil {
+				log.Printf("Error loading principal for user %d: %v", userID, err)
+				response.Error(w, r, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	"github.com/gorilla/sessions"
+)
+
+// ServerStore is a sessions.Store that keeps session values server-side in
+// a store.SessionStore, so sessions can be listed and revoked. The cookie
+// only carries a random session ID.
+type ServerStore struct {
+	Sessions store.SessionStore
+	// Options are the cookie options of new sessions. A session saved with
+	// a negative MaxAge is deleted.
+	Options *sessions.Options
+	// IdleTimeout ends sessions left unused this long, and AbsoluteTimeout
+	// ends them this long after login however active they are. Zero
+	// disables the idle timeout.
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+}
+
+// NewServerStore returns a ServerStore with HttpOnly, SameSite=Lax cookies
+// that last as long as the absolute timeout.
+func NewServerStore(s store.SessionStore, idle, absolute time.Duration) *ServerStore {
+	return &ServerStore{
+		Sessions: s,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(absolute.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		IdleTimeout:     idle,
+		AbsoluteTimeout: absolute,
+	}
+}
+
+// IdleSince returns the time at which a session last used then would have
+// gone idle by now.
+func (s *ServerStore) IdleSince(now time.Time) time.Time {
+	if s.IdleTimeout <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-s.IdleTimeout)
+}
+
+// Get returns the named session, loading it once per request.
+func (s *ServerStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request's cookie, and records its use.
+// A missing, expired or revoked session gives a new, empty one.
+func (s *ServerStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return session, nil
+	}
+	now := time.Now().UTC()
+	stored, err := s.Sessions.TouchSession(r.Context(), HashToken(cookie.Value), now, s.IdleSince(now))
+	if errors.Is(err, store.ErrNotFound) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(stored.Data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = cookie.Value
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session's values. A new session gets an ID and its
+// cookie; one saved with a negative MaxAge is deleted and its cookie
+// expired.
+func (s *ServerStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if err := s.delete(r, session); err != nil {
+			return err
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return err
+	}
+	userIDStr, _ := session.Values["UserID"].(string)
+	userID, _ := strconv.Atoi(userIDStr)
+	if session.ID != "" {
+		// The cookie already names the session and keeps its expiry
+		return s.Sessions.UpdateSession(r.Context(), HashToken(session.ID), userID, buf.Bytes())
+	}
+
+	id, hash, err := NewToken()
+	if err != nil {
+		return err
+	}
+	_, err = s.Sessions.CreateSession(r.Context(), store.Session{
+		UserID:    userID,
+		Data:      buf.Bytes(),
+		UserAgent: r.UserAgent(),
+		ExpiresAt: time.Now().Add(s.AbsoluteTimeout).UTC(),
+	}, hash)
+	if err != nil {
+		return err
+	}
+	session.ID = id
+	http.SetCookie(w, sessions.NewCookie(session.Name(), id, session.Options))
+	return nil
+}
+
+// Renew deletes the stored session, so the next Save starts a new one with
+// a fresh ID and the session's values. Logins renew the session, so an ID
+// planted before login is useless after it.
+func (s *ServerStore) Renew(r *http.Request, session *sessions.Session) error {
+	if err := s.delete(r, session); err != nil {
+		return err
+	}
+	session.ID = ""
+	session.IsNew = true
+	return nil
+}
+
+// delete removes the stored session, if there is one.
+func (s *ServerStore) delete(r *http.Request, session *sessions.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	err := s.Sessions.DeleteSession(r.Context(), HashToken(session.ID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	return err
+}
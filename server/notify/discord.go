@@ -0,0 +1,134 @@
+// Package notify posts team notifications to Discord webhooks. Messages are
+// written to an outbox first and delivered by a background Service with
+// retries, so a Discord outage never fails the API call that caused them.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+)
+
+// Message is the body of a Discord webhook call.
+type Message struct {
+	Content string  `json:"content,omitempty"`
+	Embeds  []Embed `json:"embeds,omitempty"`
+	// AllowedMentions keeps event titles from pinging anyone.
+	AllowedMentions *discord.AllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// Embed is a Discord rich embed.
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"` // RFC 3339
+	Fields      []EmbedField `json:"fields,omitempty"`
+	Footer      *EmbedFooter `json:"footer,omitempty"`
+}
+
+// EmbedField is a name/value pair shown in an embed.
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedFooter is the small text under an embed.
+type EmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// Embed colors by notification kind.
+const (
+	colorCreated   = 0x2ECC71
+	colorUpdated   = 0x3498DB
+	colorCancelled = 0xE74C3C
+	colorReminder  = 0xF1C40F
+	colorQuorum    = 0xE67E22
+)
+
+// ValidWebhookURL reports whether u looks like a Discord webhook URL.
+func ValidWebhookURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme != "https" {
+		return false
+	}
+	switch parsed.Host {
+	case "discord.com", "discordapp.com", "ptb.discord.com", "canary.discord.com":
+	default:
+		return false
+	}
+	return strings.HasPrefix(parsed.Path, "/api/webhooks/")
+}
+
+// DeliveryError is a failed webhook call. Permanent errors, such as a
+// deleted webhook, are not retried.
+type DeliveryError struct {
+	Status     int // zero for network errors
+	Message    string
+	Permanent  bool
+	RetryAfter time.Duration // set by Discord's rate limiter
+}
+
+func (e *DeliveryError) Error() string {
+	if e.Status == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("discord returned %d: %s", e.Status, e.Message)
+}
+
+// post sends a webhook payload.
+func post(ctx context.Context, client *http.Client, webhookURL string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return &DeliveryError{Message: err.Error(), Permanent: true}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return &DeliveryError{Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	derr := &DeliveryError{Status: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		derr.RetryAfter = retryAfter(resp.Header, body)
+	case resp.StatusCode >= 500:
+	default:
+		// 4xx: the webhook was deleted or the payload was rejected, so
+		// sending it again won't help.
+		derr.Permanent = true
+	}
+	return derr
+}
+
+// retryAfter reads how long Discord asks us to wait, from the Retry-After
+// header or the retry_after field (seconds) of the JSON body.
+func retryAfter(h http.Header, body []byte) time.Duration {
+	var rl struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &rl) == nil && rl.RetryAfter > 0 {
+		return time.Duration(rl.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(h.Get("Retry-After"), 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return 0
+}
@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+const (
+	// digestDays is how far ahead the weekly digest looks.
+	digestDays = 7
+	// availabilityAlertLead is how long before a week starts players who
+	// haven't filled it in are reminded to.
+	availabilityAlertLead = 72 * time.Hour
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var emailTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// EmailService sends each player the emails they opted in to: event
+// reminders, a weekly digest of their teams' schedule and alerts when their
+// availability for next week is empty. Each email is sent once; one that
+// fails is tried again on the next call to Send.
+type EmailService struct {
+	Store    *store.Store
+	Notifier Notifier
+	// BaseURL, when set, is linked to from every email.
+	BaseURL string
+}
+
+// emailEvent is an occurrence as shown in an email, in the reader's
+// timezone.
+type emailEvent struct {
+	Title, Team, Type    string
+	When, Time, Duration string
+	Opponent, Location   string
+	RSVP                 string
+}
+
+// recipientState is what Send needs to know about one player.
+type recipientState struct {
+	prefs    store.EmailPreferences
+	to       Recipient
+	loc      *time.Location
+	teams    map[int]store.Team
+	teamIDs  []int
+	rsvpByID map[int]store.RSVPStatus
+}
+
+// Send sends the emails due at now.
+func (sv *EmailService) Send(ctx context.Context, now time.Time) error {
+	prefs, err := sv.Store.Email.ListEmailPreferences(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range prefs {
+		r, err := sv.recipient(ctx, p)
+		if err != nil {
+			return err
+		}
+		if p.Reminders {
+			if err := sv.sendReminders(ctx, r, now); err != nil {
+				return err
+			}
+		}
+		if p.Digest {
+			if err := sv.sendDigest(ctx, r, now); err != nil {
+				return err
+			}
+		}
+		if p.AvailabilityAlerts {
+			if err := sv.sendAvailabilityAlerts(ctx, r, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sv *EmailService) recipient(ctx context.Context, p store.EmailPreferences) (*recipientState, error) {
+	player, err := sv.Store.Players.GetPlayer(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := store.LoadTimezone(player.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	memberships, err := sv.Store.Memberships.ListMemberships(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	r := &recipientState{
+		prefs:    p,
+		to:       Recipient{UserID: p.UserID, Name: player.Username, Email: p.Email},
+		loc:      loc,
+		teams:    map[int]store.Team{},
+		rsvpByID: map[int]store.RSVPStatus{},
+	}
+	for _, m := range memberships {
+		team, err := sv.Store.Teams.GetTeam(ctx, m.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		r.teams[m.TeamID] = team
+		r.teamIDs = append(r.teamIDs, m.TeamID)
+	}
+	return r, nil
+}
+
+// occurrences lists the player's upcoming occurrences across their teams,
+// leaving out cancelled ones and those they've said no to.
+func (sv *EmailService) occurrences(ctx context.Context, r *recipientState, from, to time.Time) ([]store.Occurrence, error) {
+	var all []store.Occurrence
+	for _, teamID := range r.teamIDs {
+		occurrences, err := store.ListOccurrences(ctx, sv.Store.Events, teamID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range occurrences {
+			if o.Cancelled {
+				continue
+			}
+			if _, ok := r.rsvpByID[o.ID]; !ok {
+				rsvps, err := sv.Store.Events.ListRSVPs(ctx, o.ID)
+				if err != nil {
+					return nil, err
+				}
+				r.rsvpByID[o.ID] = ""
+				for _, rsvp := range rsvps {
+					if rsvp.UserID == r.to.UserID {
+						r.rsvpByID[o.ID] = rsvp.Status
+					}
+				}
+			}
+			if r.rsvpByID[o.ID] != store.RSVPNo {
+				all = append(all, o)
+			}
+		}
+	}
+	slices.SortFunc(all, func(a, b store.Occurrence) int { return a.Start.Compare(b.Start) })
+	return all, nil
+}
+
+// newEmailEvent shows o to the player r.
+func newEmailEvent(r *recipientState, o store.Occurrence) emailEvent {
+	start := o.Start.In(r.loc)
+	return emailEvent{
+		Title:    o.Title,
+		Team:     r.teams[o.TeamID].Name,
+		Type:     string(o.Type),
+		When:     start.Format("Monday, January 2 at 15:04 MST"),
+		Time:     start.Format("15:04"),
+		Duration: discord.FormatDuration(o.End.Sub(o.Start)),
+		Opponent: o.Opponent,
+		Location: o.Location,
+		RSVP:     string(r.rsvpByID[o.ID]),
+	}
+}
+
+func (sv *EmailService) sendReminders(ctx context.Context, r *recipientState, now time.Time) error {
+	lead := time.Duration(r.prefs.ReminderMinutes) * time.Minute
+	occurrences, err := sv.occurrences(ctx, r, now, now.Add(lead))
+	if err != nil {
+		return err
+	}
+	for _, o := range occurrences {
+		e := newEmailEvent(r, o)
+		key := fmt.Sprintf("reminder:%d:%d:%d", r.to.UserID, o.ID, o.Start.Unix())
+		data := map[string]any{"Name": r.to.Name, "URL": sv.BaseURL, "Event": e}
+		if err := sv.send(ctx, r, key, "Starting soon: "+o.Title, "reminder", data, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendDigest sends the week's digest once per week, in the player's
+// timezone, as soon as they have something coming up.
+func (sv *EmailService) sendDigest(ctx context.Context, r *recipientState, now time.Time) error {
+	weekStart := store.WeekStart(now, r.loc)
+	key := fmt.Sprintf("digest:%d:%s", r.to.UserID, weekStart.Format("2006-01-02"))
+	occurrences, err := sv.occurrences(ctx, r, now, now.AddDate(0, 0, digestDays))
+	if err != nil || len(occurrences) == 0 {
+		return err
+	}
+
+	type day struct {
+		Day    string
+		Events []emailEvent
+	}
+	var days []day
+	for _, o := range occurrences {
+		name := o.Start.In(r.loc).Format("Monday, January 2")
+		if len(days) == 0 || days[len(days)-1].Day != name {
+			days = append(days, day{Day: name})
+		}
+		days[len(days)-1].Events = append(days[len(days)-1].Events, newEmailEvent(r, o))
+	}
+	data := map[string]any{"Name": r.to.Name, "URL": sv.BaseURL, "Zone": r.loc.String(), "Days": days}
+	subject := fmt.Sprintf("Your week: %d upcoming event", len(occurrences))
+	if len(occurrences) != 1 {
+		subject += "s"
+	}
+	return sv.send(ctx, r, key, subject, "digest", data, now)
+}
+
+// sendAvailabilityAlerts nudges the player about each team whose next week
+// they haven't marked any availability for, once that week is close.
+func (sv *EmailService) sendAvailabilityAlerts(ctx context.Context, r *recipientState, now time.Time) error {
+	for _, teamID := range r.teamIDs {
+		grid, err := sv.Store.Grids.GetGrid(ctx, teamID)
+		if err != nil {
+			return err
+		}
+		loc, err := store.LoadTimezone(grid.Timezone)
+		if err != nil {
+			continue
+		}
+		nextWeek := store.WeekStart(now, loc).AddDate(0, 0, 7)
+		if nextWeek.Sub(now) > availabilityAlertLead {
+			continue
+		}
+		available, err := sv.Store.Availability.ListAvailability(ctx, teamID, r.to.UserID, nextWeek, nextWeek.AddDate(0, 0, 7))
+		if err != nil {
+			return err
+		}
+		if len(available) > 0 {
+			continue
+		}
+		team := r.teams[teamID].Name
+		key := fmt.Sprintf("availability:%d:%d:%s", r.to.UserID, teamID, nextWeek.Format("2006-01-02"))
+		data := map[string]any{"Name": r.to.Name, "URL": sv.BaseURL, "Team": team, "WeekOf": nextWeek.Format("January 2")}
+		if err := sv.send(ctx, r, key, "Fill in your availability for "+team, "availability", data, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send renders and sends one email unless key was sent before. A delivery
+// failure is logged and the claim released so the next run tries again;
+// only store errors are returned.
+func (sv *EmailService) send(ctx context.Context, r *recipientState, key, subject, tmpl string, data any, now time.Time) error {
+	var body bytes.Buffer
+	if err := emailTemplates.ExecuteTemplate(&body, tmpl, data); err != nil {
+		return err
+	}
+	claimed, err := sv.Store.Email.ClaimEmail(ctx, key, r.to.UserID, now)
+	if err != nil || !claimed {
+		return err
+	}
+	n := Notification{Subject: subject, HTML: strings.TrimSpace(body.String())}
+	if err := sv.Notifier.Notify(ctx, r.to, n); err != nil {
+		log.Printf("Error emailing %s to user %d: %v", tmpl, r.to.UserID, err)
+		return sv.Store.Email.ReleaseEmail(ctx, key)
+	}
+	return nil
+}
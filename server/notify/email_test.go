@@ -0,0 +1,133 @@
+package notify_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/notify"
+	"github.com/KhrisKringle/Vivacity_website-main/server/notify/smtptest"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// received decodes the subject and HTML body of each message the server got.
+func received(t *testing.T, srv *smtptest.Server) (subjects, bodies []string) {
+	t.Helper()
+	var dec mime.WordDecoder
+	for _, m := range srv.Messages() {
+		msg, err := mail.ReadMessage(bytes.NewReader(m.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subjects = append(subjects, subject)
+		bodies = append(bodies, string(body))
+	}
+	return subjects, bodies
+}
+
+func TestEmailService(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 1, "Ana#1")
+	quiet, _ := s.Players.UpsertBattleNetPlayer(ctx, 2, "Ben#2")
+	s.Players.SetPlayerTimezone(ctx, player.ID, "Europe/Berlin")
+	for _, p := range []store.Player{player, quiet} {
+		s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: p.ID, Role: "player"})
+	}
+	s.Email.SetEmailPreferences(ctx, store.EmailPreferences{UserID: player.ID, Email: "ana@example.com", Reminders: true, ReminderMinutes: 60, Digest: true, AvailabilityAlerts: true})
+	s.Email.SetEmailPreferences(ctx, store.EmailPreferences{UserID: quiet.ID, Email: "ben@example.com"})
+
+	// Friday noon: next week starts in 60 hours
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC)
+	event := func(title string, start time.Time) store.Event {
+		e, err := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventScrim, Title: title, Opponent: "Bravo & Co", Start: start, End: start.Add(90 * time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	soon := event("Scrim vs Bravo", now.Add(30*time.Minute))
+	event("Weekend scrim", now.Add(48*time.Hour))
+	cancelled := event("Called off", now.Add(20*time.Minute))
+	s.Events.CancelEvent(ctx, cancelled.ID)
+	declined := event("Skipping this one", now.Add(40*time.Minute))
+	s.Events.SetRSVP(ctx, store.RSVP{EventID: declined.ID, UserID: player.ID, Status: store.RSVPNo})
+	s.Events.SetRSVP(ctx, store.RSVP{EventID: soon.ID, UserID: player.ID, Status: store.RSVPMaybe})
+
+	srv := smtptest.NewServer()
+	defer srv.Close()
+	sv := &notify.EmailService{
+		Store:    s,
+		Notifier: &notify.SMTPNotifier{Addr: srv.Addr, From: "Vivacity <noreply@example.com>"},
+		BaseURL:  "https://vivacity.example.com",
+	}
+
+	if err := sv.Send(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := sv.Send(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	subjects, bodies := received(t, srv)
+	want := []string{"Starting soon: Scrim vs Bravo", "Your week: 2 upcoming events", "Fill in your availability for Alpha"}
+	if strings.Join(subjects, "|") != strings.Join(want, "|") {
+		t.Fatalf("sent %q, want %q", subjects, want)
+	}
+	for _, m := range srv.Messages() {
+		if m.From != "noreply@example.com" || len(m.To) != 1 || m.To[0] != "ana@example.com" {
+			t.Errorf("unexpected envelope: %s -> %v", m.From, m.To)
+		}
+	}
+	for _, check := range []struct {
+		body, want string
+	}{
+		{bodies[0], "starts Friday, March 6 at 13:30 CET"},
+		{bodies[0], "Bravo &amp; Co"},
+		{bodies[0], "<td>maybe</td>"},
+		{bodies[1], "times in Europe/Berlin"},
+		{bodies[1], "Sunday, March 8"},
+		{bodies[1], `<a href="https://vivacity.example.com"`},
+		{bodies[2], "week of March 9"},
+	} {
+		if !strings.Contains(check.body, check.want) {
+			t.Errorf("email is missing %q:\n%s", check.want, check.body)
+		}
+	}
+	for _, b := range bodies[:2] {
+		if strings.Contains(b, "Called off") || strings.Contains(b, "Skipping this one") {
+			t.Errorf("email includes a cancelled or declined event:\n%s", b)
+		}
+	}
+
+	// A failed delivery is retried on the next run
+	event("Extra practice", now.Add(90*time.Minute))
+	srv.Reject(1)
+	later := now.Add(45 * time.Minute)
+	if err := sv.Send(ctx, later); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Messages()); n != 3 {
+		t.Fatalf("rejected email was counted as sent: %d messages", n)
+	}
+	if err := sv.Send(ctx, later.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if subjects, _ := received(t, srv); len(subjects) != 4 || subjects[3] != "Starting soon: Extra practice" {
+		t.Errorf("after retry sent %q", subjects)
+	}
+}
@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/discord"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Change is what happened to an event.
+type Change string
+
+const (
+	Created   Change = "created"
+	Updated   Change = "updated"
+	Cancelled Change = "cancelled"
+)
+
+// EventChanged queues a notification that an event was created, updated or
+// cancelled, and the reminder for its next occurrence. Pass the first
+// occurrence for a whole event or series, or an overridden occurrence when
+// only that one changed. Teams without a webhook are skipped, and failures
+// are logged rather than returned so that a notification problem never
+// fails the request that changed the event.
+func EventChanged(ctx context.Context, s *store.Store, change Change, o store.Occurrence) {
+	if _, err := s.Notifications.GetDiscordSettings(ctx, o.TeamID); err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Error loading Discord settings for team %d: %v", o.TeamID, err)
+		}
+		return
+	}
+	team, err := s.Teams.GetTeam(ctx, o.TeamID)
+	if err != nil {
+		log.Printf("Error loading team %d for a notification: %v", o.TeamID, err)
+		return
+	}
+
+	embed := eventEmbed(team, o)
+	switch change {
+	case Created:
+		embed.Title = "New " + string(o.Type) + ": " + o.Title
+		embed.Color = colorCreated
+	case Updated:
+		embed.Title = "Updated: " + o.Title
+		embed.Color = colorUpdated
+	case Cancelled:
+		embed.Title = "Cancelled: " + o.Title
+		embed.Color = colorCancelled
+	}
+	if o.Overridden {
+		embed.Description = "Only the occurrence on " + discord.Timestamp(o.OriginalStart, "F") + " is affected."
+	}
+	if err := enqueue(ctx, s, o.TeamID, "", Message{Embeds: []Embed{embed}}); err != nil {
+		log.Printf("Error queueing notification for event %d: %v", o.ID, err)
+	}
+	// o may carry an exception's start and title, so load the series itself
+	e, err := s.Events.GetEvent(ctx, o.ID)
+	if err == nil {
+		err = scheduleReminder(ctx, s, e, time.Now())
+	}
+	if err != nil {
+		log.Printf("Error queueing reminder for event %d: %v", o.ID, err)
+	}
+}
+
+// eventEmbed describes an occurrence, without a title or color.
+func eventEmbed(team store.Team, o store.Occurrence) Embed {
+	e := Embed{
+		Timestamp: o.Start.UTC().Format(time.RFC3339),
+		Footer:    &EmbedFooter{Text: team.Name},
+		Fields: []EmbedField{
+			{Name: "When", Value: discord.Timestamp(o.Start, "F") + " (" + discord.Timestamp(o.Start, "R") + ")"},
+			{Name: "Duration", Value: discord.FormatDuration(o.End.Sub(o.Start)), Inline: true},
+			{Name: "Type", Value: string(o.Type), Inline: true},
+		},
+	}
+	if o.Opponent != "" {
+		e.Fields = append(e.Fields, EmbedField{Name: "Opponent", Value: o.Opponent, Inline: true})
+	}
+	if o.Location != "" {
+		e.Fields = append(e.Fields, EmbedField{Name: "Location", Value: o.Location, Inline: true})
+	}
+	if rule, loc, err := o.Rule(); err == nil && loc != nil && !o.Overridden {
+		e.Fields = append(e.Fields, EmbedField{Name: "Repeats", Value: rule.Describe() + " (" + o.Timezone + ")"})
+	}
+	return e
+}
+
+// rsvpFields lists who answered what, and who hasn't answered.
+func rsvpFields(members []store.Member, rsvps []store.RSVP) []EmbedField {
+	answered := map[int]store.RSVPStatus{}
+	for _, r := range rsvps {
+		answered[r.UserID] = r.Status
+	}
+	groups := map[store.RSVPStatus][]string{}
+	var pending []string
+	for _, m := range members {
+		if status, ok := answered[m.UserID]; ok {
+			groups[status] = append(groups[status], m.Username)
+		} else {
+			pending = append(pending, m.Username)
+		}
+	}
+
+	var fields []EmbedField
+	for _, g := range []struct {
+		name   string
+		status store.RSVPStatus
+	}{{"Going", store.RSVPYes}, {"Maybe", store.RSVPMaybe}, {"Not going", store.RSVPNo}} {
+		if names := groups[g.status]; len(names) > 0 {
+			fields = append(fields, EmbedField{Name: fmt.Sprintf("%s (%d)", g.name, len(names)), Value: strings.Join(names, ", ")})
+		}
+	}
+	if len(pending) > 0 {
+		fields = append(fields, EmbedField{Name: fmt.Sprintf("No answer (%d)", len(pending)), Value: strings.Join(pending, ", ")})
+	}
+	return fields
+}
+
+// enqueue writes msg to the outbox for delivery as soon as possible. A
+// non-empty key is queued at most once; it reports nil when it was before.
+func enqueue(ctx context.Context, s *store.Store, teamID int, key string, msg Message) error {
+	msg.AllowedMentions = &discord.AllowedMentions{Parse: []string{}}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = s.Notifications.EnqueueOutbox(ctx, store.OutboxMessage{TeamID: teamID, Key: key, Payload: payload})
+	return err
+}
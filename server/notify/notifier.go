@@ -0,0 +1,23 @@
+package notify
+
+import "context"
+
+// Recipient is the player a Notification is for.
+type Recipient struct {
+	UserID int
+	Name   string
+	Email  string
+}
+
+// Notification is a rendered message for one player.
+type Notification struct {
+	Subject string
+	HTML    string
+}
+
+// Notifier delivers notifications to players over one channel, such as
+// email. EmailService sends through whichever Notifier it is given, so
+// tests and other channels can stand in for SMTP.
+type Notifier interface {
+	Notify(ctx context.Context, to Recipient, n Notification) error
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/jobs"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// ReminderJob is the kind of the delayed job that posts an event reminder.
+// Register Service.Remind as its handler.
+const ReminderJob = "notify.reminder"
+
+// reminderLookahead bounds the search for the next occurrence of a series
+// that repeats forever.
+const reminderLookahead = 366 * 24 * time.Hour
+
+// reminderPayload names the occurrence a reminder job is for, and the lead
+// time it was queued with.
+type reminderPayload struct {
+	EventID int       `json:"event_id"`
+	Start   time.Time `json:"start"`
+	Minutes int       `json:"minutes"`
+}
+
+// ScheduleReminders queues the reminder for the next occurrence of each of
+// the team's upcoming events, such as after its reminder lead time changes.
+// Reminders already queued are left alone.
+func ScheduleReminders(ctx context.Context, s *store.Store, teamID int, now time.Time) error {
+	events, err := s.Events.ListEvents(ctx, teamID, now, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := scheduleReminder(ctx, s, e, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleReminder queues a job to remind the team of e's first uncancelled
+// occurrence starting after after, ReminderMinutes before it starts. Teams
+// without a webhook or with reminders turned off are skipped. The job is
+// keyed by the occurrence's start and the lead time, so a moved occurrence
+// or a changed lead time queues a new one and the old one does nothing.
+func scheduleReminder(ctx context.Context, s *store.Store, e store.Event, after time.Time) error {
+	if e.Cancelled {
+		return nil
+	}
+	settings, err := s.Notifications.GetDiscordSettings(ctx, e.TeamID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && settings.ReminderMinutes == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	o, ok, err := nextOccurrence(ctx, s, e, after)
+	if err != nil || !ok {
+		return err
+	}
+	payload := reminderPayload{EventID: e.ID, Start: o.Start, Minutes: settings.ReminderMinutes}
+	runAt := o.Start.Add(-time.Duration(settings.ReminderMinutes) * time.Minute)
+	key := fmt.Sprintf("reminder:%d:%d:%d", e.ID, o.Start.Unix(), settings.ReminderMinutes)
+	_, err = jobs.Enqueue(ctx, s, ReminderJob, payload, runAt, key)
+	return err
+}
+
+// nextOccurrence returns e's first uncancelled occurrence starting after
+// after. Series that repeat forever are only searched reminderLookahead
+// ahead.
+func nextOccurrence(ctx context.Context, s *store.Store, e store.Event, after time.Time) (store.Occurrence, bool, error) {
+	var exceptions []store.EventException
+	var to time.Time
+	if e.Recurrence != "" {
+		var err error
+		if exceptions, err = s.Events.ListEventExceptions(ctx, e.ID); err != nil {
+			return store.Occurrence{}, false, err
+		}
+		if _, finite, err := e.LastStart(); err != nil {
+			return store.Occurrence{}, false, err
+		} else if !finite {
+			to = after.Add(reminderLookahead)
+		}
+	}
+	occurrences, err := e.Occurrences(exceptions, after.Add(time.Nanosecond), to)
+	if err != nil {
+		return store.Occurrence{}, false, err
+	}
+	for _, o := range occurrences {
+		if !o.Cancelled {
+			return o, true, nil
+		}
+	}
+	return store.Occurrence{}, false, nil
+}
+
+// Remind runs a ReminderJob: it posts a reminder with the RSVPs so far,
+// unless the occurrence has since moved, been cancelled or started, or the
+// team's lead time has changed, and then queues the reminder for the
+// event's next occurrence.
+func (sv *Service) Remind(ctx context.Context, job store.Job) error {
+	var p reminderPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+	e, err := sv.Store.Events.GetEvent(ctx, p.EventID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := sv.remind(ctx, e, p, time.Now()); err != nil {
+		return err
+	}
+	return scheduleReminder(ctx, sv.Store, e, p.Start)
+}
+
+// remind posts the reminder p describes, if it still applies at now.
+func (sv *Service) remind(ctx context.Context, e store.Event, p reminderPayload, now time.Time) error {
+	if e.Cancelled || !now.Before(p.Start) {
+		return nil
+	}
+	settings, err := sv.Store.Notifications.GetDiscordSettings(ctx, e.TeamID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && settings.ReminderMinutes != p.Minutes) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	o, ok, err := nextOccurrence(ctx, sv.Store, e, p.Start.Add(-time.Nanosecond))
+	if err != nil || !ok || !o.Start.Equal(p.Start) {
+		return err
+	}
+
+	team, err := sv.Store.Teams.GetTeam(ctx, e.TeamID)
+	if err != nil {
+		return err
+	}
+	members, err := sv.Store.Memberships.ListMembers(ctx, e.TeamID)
+	if err != nil {
+		return err
+	}
+	rsvps, err := sv.Store.Events.ListRSVPs(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	embed := eventEmbed(team, o)
+	embed.Title = "Starting soon: " + o.Title
+	embed.Color = colorReminder
+	embed.Fields = append(embed.Fields, rsvpFields(members, rsvps)...)
+	return enqueue(ctx, sv.Store, e.TeamID, occurrenceKey("reminder", o), Message{Embeds: []Embed{embed}})
+}
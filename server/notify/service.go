@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/jobs"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+const (
+	// maxAttempts is how many times a message is tried before it is given
+	// up.
+	maxAttempts = 8
+	// claimLease hides a claimed message from other workers while it is
+	// being delivered.
+	claimLease = 2 * time.Minute
+	// deliverBatch is how many messages one Deliver call claims.
+	deliverBatch = 20
+)
+
+// Service delivers the outbox to Discord and queues reminders and quorum
+// warnings for upcoming events.
+type Service struct {
+	Store  *store.Store
+	Client *http.Client
+}
+
+// Deliver posts the messages due at now. Failed messages are retried with
+// exponential backoff, or as late as Discord's rate limiter asks, and are
+// given up after maxAttempts or a permanent error.
+func (sv *Service) Deliver(ctx context.Context, now time.Time) error {
+	messages, err := sv.Store.Notifications.ClaimOutbox(ctx, now, claimLease, deliverBatch)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		settings, err := sv.Store.Notifications.GetDiscordSettings(ctx, m.TeamID)
+		if errors.Is(err, store.ErrNotFound) {
+			if err := sv.Store.Notifications.MarkOutboxFailed(ctx, m.ID, time.Time{}, "team has no Discord webhook"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		sendErr := post(ctx, sv.Client, settings.WebhookURL, m.Payload)
+		if sendErr == nil {
+			if err := sv.Store.Notifications.MarkOutboxSent(ctx, m.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var next time.Time
+		var derr *DeliveryError
+		permanent := errors.As(sendErr, &derr) && derr.Permanent
+		if !permanent && m.Attempts < maxAttempts {
+			wait := jobs.Backoff(m.Attempts)
+			if derr != nil && derr.RetryAfter > wait {
+				wait = derr.RetryAfter
+			}
+			next = now.Add(wait)
+		}
+		log.Printf("Discord notification %d for team %d failed (attempt %d): %v", m.ID, m.TeamID, m.Attempts, sendErr)
+		if err := sv.Store.Notifications.MarkOutboxFailed(ctx, m.ID, next, sendErr.Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schedule queues a warning for every event within its team's quorum lead
+// time that doesn't have enough yes RSVPs. Each is queued once per
+// occurrence start, so Schedule can run as often as needed. Reminders are
+// delayed jobs instead; see Remind.
+func (sv *Service) Schedule(ctx context.Context, now time.Time) error {
+	settings, err := sv.Store.Notifications.ListDiscordSettings(ctx)
+	if err != nil {
+		return err
+	}
+	for _, set := range settings {
+		if set.Quorum == 0 || set.QuorumMinutes == 0 {
+			continue
+		}
+		quorum := time.Duration(set.QuorumMinutes) * time.Minute
+		occurrences, err := store.ListOccurrences(ctx, sv.Store.Events, set.TeamID, now, now.Add(quorum))
+		if err != nil {
+			return err
+		}
+		if len(occurrences) == 0 {
+			continue
+		}
+		team, err := sv.Store.Teams.GetTeam(ctx, set.TeamID)
+		if err != nil {
+			return err
+		}
+		members, err := sv.Store.Memberships.ListMembers(ctx, set.TeamID)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range occurrences {
+			if o.Cancelled {
+				continue
+			}
+			rsvps, err := sv.Store.Events.ListRSVPs(ctx, o.ID)
+			if err != nil {
+				return err
+			}
+			yes := 0
+			for _, r := range rsvps {
+				if r.Status == store.RSVPYes {
+					yes++
+				}
+			}
+			if yes >= set.Quorum {
+				continue
+			}
+			embed := eventEmbed(team, o)
+			embed.Title = "Not enough players: " + o.Title
+			embed.Description = fmt.Sprintf("Only %d of the %d players needed have said yes.", yes, set.Quorum)
+			embed.Color = colorQuorum
+			embed.Fields = append(embed.Fields, rsvpFields(members, rsvps)...)
+			if err := enqueue(ctx, sv.Store, set.TeamID, occurrenceKey("quorum", o), Message{Embeds: []Embed{embed}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// occurrenceKey deduplicates a scheduled notification. It uses the
+// occurrence's current start so that a rescheduled event is announced again.
+func occurrenceKey(kind string, o store.Occurrence) string {
+	return fmt.Sprintf("%s:%d:%d", kind, o.ID, o.Start.Unix())
+}
@@ -0,0 +1,217 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/jobs"
+	"github.com/KhrisKringle/Vivacity_website-main/server/notify"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// fakeDiscord stands in for a Discord webhook. It answers with the queued
+// statuses in order, then 204, and records every message it accepts.
+type fakeDiscord struct {
+	mu       sync.Mutex
+	statuses []int
+	received []notify.Message
+}
+
+func (f *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.statuses) > 0 {
+		status := f.statuses[0]
+		f.statuses = f.statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 300}`))
+			return
+		}
+		w.WriteHeader(status)
+		return
+	}
+	var msg notify.Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.received = append(f.received, msg)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeDiscord) titles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var titles []string
+	for _, m := range f.received {
+		for _, e := range m.Embeds {
+			titles = append(titles, e.Title)
+		}
+	}
+	return titles
+}
+
+func (f *fakeDiscord) fail(statuses ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses = append(f.statuses, statuses...)
+}
+
+func setup(t *testing.T) (*store.Store, *notify.Service, *fakeDiscord, store.Team) {
+	t.Helper()
+	s := store.NewMemory()
+	ctx := context.Background()
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	for i, name := range []string{"Ana#1", "Ben#2", "Cam#3"} {
+		p, _ := s.Players.UpsertBattleNetPlayer(ctx, int64(i+1), name)
+		s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: p.ID, Role: "player"})
+	}
+
+	discord := &fakeDiscord{}
+	srv := httptest.NewServer(discord)
+	t.Cleanup(srv.Close)
+	settings := store.DiscordSettings{TeamID: team.ID, WebhookURL: srv.URL + "/api/webhooks/1/token", ReminderMinutes: 60, Quorum: 2, QuorumMinutes: 120}
+	if err := s.Notifications.SetDiscordSettings(ctx, settings); err != nil {
+		t.Fatal(err)
+	}
+	return s, &notify.Service{Store: s, Client: srv.Client()}, discord, team
+}
+
+func TestDeliverRetries(t *testing.T) {
+	s, sv, discord, team := setup(t)
+	ctx := context.Background()
+	now := time.Now()
+	start := now.Add(72 * time.Hour).Truncate(time.Hour)
+	e, err := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventScrim, Title: "Scrim vs Bravo", Opponent: "Bravo", Start: start, End: start.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := store.Occurrence{Event: e, OriginalStart: e.Start}
+
+	notify.EventChanged(ctx, s, notify.Created, o)
+	discord.fail(http.StatusBadGateway)
+	if err := sv.Deliver(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if got := discord.titles(); len(got) != 0 {
+		t.Fatalf("delivered through an outage: %v", got)
+	}
+	// Not due again until the backoff has passed
+	sv.Deliver(ctx, now.Add(10*time.Second))
+	if got := discord.titles(); len(got) != 0 {
+		t.Fatalf("retried before the backoff: %v", got)
+	}
+	sv.Deliver(ctx, now.Add(time.Minute))
+	if got := discord.titles(); len(got) != 1 || got[0] != "New scrim: Scrim vs Bravo" {
+		t.Fatalf("after retry got %v", got)
+	}
+
+	// Rate limits are honoured
+	notify.EventChanged(ctx, s, notify.Updated, o)
+	discord.fail(http.StatusTooManyRequests)
+	sv.Deliver(ctx, now)
+	sv.Deliver(ctx, now.Add(time.Minute))
+	if got := discord.titles(); len(got) != 1 {
+		t.Fatalf("retried before retry_after: %v", got)
+	}
+	sv.Deliver(ctx, now.Add(6*time.Minute))
+	if got := discord.titles(); len(got) != 2 || got[1] != "Updated: Scrim vs Bravo" {
+		t.Fatalf("after rate limit got %v", got)
+	}
+
+	// A deleted webhook is not retried
+	notify.EventChanged(ctx, s, notify.Cancelled, o)
+	discord.fail(http.StatusNotFound)
+	sv.Deliver(ctx, now)
+	sv.Deliver(ctx, now.Add(24*time.Hour))
+	if got := discord.titles(); len(got) != 2 {
+		t.Fatalf("permanent failure was retried: %v", got)
+	}
+}
+
+func TestScheduleQuorum(t *testing.T) {
+	s, sv, discord, team := setup(t)
+	ctx := context.Background()
+	now := time.Now()
+	start := now.Add(90 * time.Minute)
+	e, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventPractice, Title: "Practice", Start: start, End: start.Add(time.Hour)})
+	members, _ := s.Memberships.ListMembers(ctx, team.ID)
+	s.Events.SetRSVP(ctx, store.RSVP{EventID: e.ID, UserID: members[0].UserID, Status: store.RSVPYes})
+
+	// 90 minutes out is inside the quorum window; the warning is sent once
+	if err := sv.Schedule(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	sv.Schedule(ctx, now.Add(time.Minute))
+	sv.Deliver(ctx, now.Add(time.Minute))
+	if got := discord.titles(); len(got) != 1 || got[0] != "Not enough players: Practice" {
+		t.Fatalf("quorum check sent %v", got)
+	}
+}
+
+func TestReminderJobs(t *testing.T) {
+	s, sv, discord, team := setup(t)
+	ctx := context.Background()
+	now := time.Now()
+	runner := jobs.NewRunner(s)
+	runner.Handle(notify.ReminderJob, sv.Remind)
+	tick := func(at time.Time) {
+		t.Helper()
+		if _, err := runner.Tick(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+		if err := sv.Deliver(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A daily series of two practices, 90 minutes and a day and 90 minutes out
+	start := now.Add(90 * time.Minute).Truncate(time.Second)
+	e, _ := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventPractice, Title: "Practice", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=DAILY;COUNT=2", Timezone: "UTC"})
+	members, _ := s.Memberships.ListMembers(ctx, team.ID)
+	s.Events.SetRSVP(ctx, store.RSVP{EventID: e.ID, UserID: members[0].UserID, Status: store.RSVPYes})
+	notify.EventChanged(ctx, s, notify.Created, store.Occurrence{Event: e, OriginalStart: e.Start})
+	tick(now.Add(time.Minute))
+	if got := discord.titles(); len(got) != 1 || got[0] != "New practice: Practice" {
+		t.Fatalf("before the reminder got %v", got)
+	}
+
+	// The reminder is a delayed job, due 60 minutes before the start
+	tick(now.Add(31 * time.Minute))
+	got := discord.titles()
+	if len(got) != 2 || got[1] != "Starting soon: Practice" {
+		t.Fatalf("reminder sent %v", got)
+	}
+	reminder := discord.received[1].Embeds[0]
+	var fields []string
+	for _, f := range reminder.Fields {
+		fields = append(fields, f.Name)
+	}
+	if fields[len(fields)-2] != "Going (1)" || fields[len(fields)-1] != "No answer (2)" {
+		t.Errorf("reminder fields: %v", fields)
+	}
+
+	// Moving the second practice replaces its queued reminder
+	second := start.Add(24 * time.Hour)
+	moved := second.Add(2 * time.Hour)
+	x := store.EventException{EventID: e.ID, OriginalStart: second, Start: moved, End: moved.Add(time.Hour), Title: "Late practice"}
+	if err := s.Events.SetEventException(ctx, x); err != nil {
+		t.Fatal(err)
+	}
+	notify.EventChanged(ctx, s, notify.Updated, store.Occurrence{Event: e, OriginalStart: second, Overridden: true})
+	tick(now.Add(24*time.Hour + 31*time.Minute))
+	if got := discord.titles(); len(got) != 3 || got[2] != "Updated: Practice" {
+		t.Fatalf("old reminder time got %v", got)
+	}
+	tick(now.Add(26*time.Hour + 31*time.Minute))
+	if got := discord.titles(); len(got) != 4 || got[3] != "Starting soon: Late practice" {
+		t.Fatalf("moved reminder got %v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schemas derives schemas from Go types. Named struct types become
+// components, defined once and referred to by $ref, which also lets a type
+// refer to itself.
+type Schemas struct {
+	home  string
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+// NewSchemas returns an empty set of schemas. Components are named after
+// their types; types from packages other than the home package, given by
+// import path, are prefixed with their package name.
+func NewSchemas(home string) *Schemas {
+	return &Schemas{home: home, defs: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// For returns the schema of v's type, or nil when v is nil.
+func (s *Schemas) For(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+// Components returns the named schemas collected so far.
+func (s *Schemas) Components() map[string]*Schema {
+	return s.defs
+}
+
+func (s *Schemas) schema(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.schema(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.define(t)}
+	}
+	// Interfaces and anything else may hold any value
+	return &Schema{}
+}
+
+// define adds the component for the named struct type t, returning its
+// name.
+func (s *Schemas) define(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if t.PkgPath() != s.home {
+		pkg := []rune(path.Base(t.PkgPath()))
+		pkg[0] = unicode.ToUpper(pkg[0])
+		name = string(pkg) + name
+	}
+	def := &Schema{}
+	s.names[t] = name
+	s.defs[name] = def
+	*def = *s.object(t)
+	return name
+}
+
+// object returns the schema of struct type t, with the properties
+// encoding/json would write. Embedded structs without a JSON name are
+// flattened into t.
+func (s *Schemas) object(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range s.object(ft).Properties {
+					obj.Properties[k] = v
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		obj.Properties[name] = s.schema(f.Type)
+	}
+	return obj
+}
+
+// nullable allows null in place of schema.
+func nullable(schema *Schema) *Schema {
+	if typ, ok := schema.Type.(string); ok && schema.Ref == "" {
+		schema.Type = []string{typ, "null"}
+		return schema
+	}
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
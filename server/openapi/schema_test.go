@@ -0,0 +1,69 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/openapi"
+)
+
+type base struct {
+	ID int `json:"id"`
+}
+
+type node struct {
+	base
+	Name     string            `json:"name"`
+	Parent   *node             `json:"parent"`
+	Children []node            `json:"children,omitempty"`
+	Seen     *time.Time        `json:"seen"`
+	Labels   map[string]string `json:"labels"`
+	Secret   string            `json:"-"`
+	Untagged bool
+	hidden   int
+}
+
+func TestSchemas(t *testing.T) {
+	s := openapi.NewSchemas(reflect.TypeOf(node{}).PkgPath())
+	if got := s.For([]node{}); got.Type != "array" || got.Items.Ref != "#/components/schemas/node" {
+		t.Fatalf("[]node = %+v", got)
+	}
+	if got := s.For(time.Time{}); got.Type != "string" || got.Format != "date-time" {
+		t.Errorf("time.Time = %+v", got)
+	}
+
+	def := s.Components()["node"]
+	if def == nil {
+		t.Fatalf("components: %v", s.Components())
+	}
+	b, _ := json.Marshal(def)
+	var got struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	json.Unmarshal(b, &got)
+	want := map[string]string{
+		"id":       `{"type":"integer"}`,
+		"name":     `{"type":"string"}`,
+		"parent":   `{"anyOf":[{"$ref":"#/components/schemas/node"},{"type":"null"}]}`,
+		"children": `{"type":"array","items":{"$ref":"#/components/schemas/node"}}`,
+		"seen":     `{"type":["string","null"],"format":"date-time"}`,
+		"labels":   `{"type":"object","additionalProperties":{"type":"string"}}`,
+		"Untagged": `{"type":"boolean"}`,
+	}
+	if len(got.Properties) != len(want) {
+		t.Errorf("properties: %s", b)
+	}
+	for name, schema := range want {
+		if string(got.Properties[name]) != schema {
+			t.Errorf("%s = %s, want %s", name, got.Properties[name], schema)
+		}
+	}
+
+	// Types from other packages are prefixed with the package name
+	s.For(struct{ T openapi.Info }{})
+	if _, ok := s.Components()["OpenapiInfo"]; !ok {
+		t.Errorf("components: %v", s.Components())
+	}
+}
@@ -0,0 +1,120 @@
+// Package profiles keeps players' Overwatch profiles up to date. Ranks are
+// fetched through a StatsClient, so tests and other stats sources can stand
+// in for the public API.
+package profiles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+var (
+	// ErrProfileNotFound is returned when no career profile matches the
+	// battletag.
+	ErrProfileNotFound = errors.New("career profile not found")
+	// ErrPrivateProfile is returned when the player hides their career
+	// profile.
+	ErrPrivateProfile = errors.New("career profile is private")
+)
+
+// StatsClient fetches a player's current competitive ranks.
+type StatsClient interface {
+	// FetchRanks returns the player's ranks on the roles they are placed
+	// on, or ErrProfileNotFound or ErrPrivateProfile. Other errors are
+	// temporary.
+	FetchRanks(ctx context.Context, battletag, region string) ([]store.RoleRank, error)
+}
+
+// DefaultStatsURL is the public OverFast API.
+const DefaultStatsURL = "https://overfast-api.tekrop.fr"
+
+// OverFastClient reads ranks from an OverFast API server, which scrapes
+// the official career profiles. Career profiles are shared between
+// regions, so the region is not used.
+type OverFastClient struct {
+	BaseURL string       // DefaultStatsURL when empty
+	Client  *http.Client // http.DefaultClient when nil
+}
+
+// overFastRank is one role's rank in an OverFast player summary.
+type overFastRank struct {
+	Division string `json:"division"`
+	Tier     int    `json:"tier"`
+}
+
+// overFastSummary is the part of GET /players/{player_id}/summary we read.
+type overFastSummary struct {
+	Privacy     string `json:"privacy"`
+	Competitive *struct {
+		PC *struct {
+			Tank    *overFastRank `json:"tank"`
+			Damage  *overFastRank `json:"damage"`
+			Support *overFastRank `json:"support"`
+		} `json:"pc"`
+	} `json:"competitive"`
+}
+
+func (c *OverFastClient) FetchRanks(ctx context.Context, battletag, region string) ([]store.RoleRank, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultStatsURL
+	}
+	// OverFast spells battletags with a dash: Name-1234
+	playerID := strings.Replace(battletag, "#", "-", 1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(base, "/")+"/players/"+url.PathEscape(playerID)+"/summary", nil)
+	if err != nil {
+		return nil, err
+	}
+	hc := c.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrProfileNotFound
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("stats service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var summary overFastSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("decoding player summary: %w", err)
+	}
+	if summary.Privacy == "private" {
+		return nil, ErrPrivateProfile
+	}
+	var ranks []store.RoleRank
+	if summary.Competitive == nil || summary.Competitive.PC == nil {
+		return ranks, nil
+	}
+	pc := summary.Competitive.PC
+	for _, role := range []struct {
+		name string
+		rank *overFastRank
+	}{{"tank", pc.Tank}, {"dps", pc.Damage}, {"support", pc.Support}} {
+		if role.rank == nil {
+			continue
+		}
+		r := store.RoleRank{Role: role.name, Division: role.rank.Division, Tier: role.rank.Tier}
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("stats service returned an invalid rank: %w", err)
+		}
+		ranks = append(ranks, r)
+	}
+	return ranks, nil
+}
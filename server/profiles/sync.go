@@ -0,0 +1,79 @@
+package profiles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Defaults for Syncer.
+const (
+	DefaultMaxAge = 24 * time.Hour
+	DefaultBatch  = 50
+)
+
+// Syncer refreshes the ranks of players who set a region from their career
+// profiles.
+type Syncer struct {
+	Store *store.Store
+	Stats StatsClient
+	// MaxAge is how long ranks are kept before they are fetched again, and
+	// how long a profile that failed to sync waits before the next attempt.
+	MaxAge time.Duration
+	// Batch caps how many players one Refresh fetches.
+	Batch int
+}
+
+// Refresh syncs the players whose ranks are older than MaxAge, least
+// recently synced first, and returns how many it tried. Sync records each
+// failure on the player's profile, so a profile that keeps failing waits
+// its turn like the rest instead of blocking the batch. The errors Sync
+// returns are joined.
+func (s *Syncer) Refresh(ctx context.Context, now time.Time) (int, error) {
+	maxAge, batch := s.MaxAge, s.Batch
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	if batch == 0 {
+		batch = DefaultBatch
+	}
+	stale, err := s.Store.Profiles.ListStaleGameProfiles(ctx, now.Add(-maxAge), batch)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	for _, p := range stale {
+		if err := s.Sync(ctx, p.UserID, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(stale), errors.Join(errs...)
+}
+
+// Sync fetches one player's ranks now. A failed fetch is recorded on the
+// player's profile, and only returned when it is something other than a
+// missing or private career profile.
+func (s *Syncer) Sync(ctx context.Context, userID int, now time.Time) error {
+	player, err := s.Store.Players.GetPlayer(ctx, userID)
+	if err != nil {
+		return err
+	}
+	profile, err := s.Store.Profiles.GetGameProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	ranks, err := s.Stats.FetchRanks(ctx, player.Username, profile.Region)
+	switch {
+	case errors.Is(err, ErrProfileNotFound), errors.Is(err, ErrPrivateProfile):
+		return s.Store.Profiles.SetSyncError(ctx, userID, err.Error(), now)
+	case err != nil:
+		if serr := s.Store.Profiles.SetSyncError(ctx, userID, err.Error(), now); serr != nil {
+			return errors.Join(err, serr)
+		}
+		return fmt.Errorf("fetching ranks of %s: %w", player.Username, err)
+	}
+	return s.Store.Profiles.SetSyncedRanks(ctx, userID, ranks, now)
+}
@@ -0,0 +1,167 @@
+package profiles_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/profiles"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// fakeStats answers FetchRanks from a map of battletag to ranks or error,
+// and counts the calls.
+type fakeStats struct {
+	ranks map[string][]store.RoleRank
+	errs  map[string]error
+	calls int
+}
+
+func (f *fakeStats) FetchRanks(ctx context.Context, battletag, region string) ([]store.RoleRank, error) {
+	f.calls++
+	if err := f.errs[battletag]; err != nil {
+		return nil, err
+	}
+	return f.ranks[battletag], nil
+}
+
+func TestSyncerRefresh(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	john, _ := s.Players.UpsertBattleNetPlayer(ctx, 1001, "John#1234")
+	jane, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Jane#5678")
+	anon, _ := s.Players.UpsertBattleNetPlayer(ctx, 1003, "Anon#0001")
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{
+		UserID: john.ID,
+		Region: "us",
+		Ranks: []store.RoleRank{
+			{Role: "tank", SR: 3000},
+			{Role: "support", Division: "gold", Tier: 2},
+		},
+	})
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{UserID: jane.ID, Region: "eu"})
+	// Without a region there's nothing to sync
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{UserID: anon.ID, PreferredRoles: []string{"dps"}})
+
+	stats := &fakeStats{
+		ranks: map[string][]store.RoleRank{"John#1234": {{Role: "tank", Division: "diamond", Tier: 3}}},
+		errs:  map[string]error{"Jane#5678": profiles.ErrPrivateProfile},
+	}
+	syncer := &profiles.Syncer{Store: s, Stats: stats}
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	n, err := syncer.Refresh(ctx, now)
+	if err != nil || n != 2 || stats.calls != 2 {
+		t.Fatalf("Refresh = %d, %v after %d calls, want 2 players", n, err, stats.calls)
+	}
+
+	// The synced tank rank replaces the one John entered; support is kept
+	p, _ := s.Profiles.GetGameProfile(ctx, john.ID)
+	want := []store.RoleRank{
+		{Role: "tank", Division: "diamond", Tier: 3, Synced: true},
+		{Role: "support", Division: "gold", Tier: 2},
+	}
+	if len(p.Ranks) != 2 || p.Ranks[0] != want[0] || p.Ranks[1] != want[1] || !p.SyncedAt.Equal(now) || p.SyncError != "" {
+		t.Errorf("John's profile after sync: %+v", p)
+	}
+	p, _ = s.Profiles.GetGameProfile(ctx, jane.ID)
+	if p.SyncError != profiles.ErrPrivateProfile.Error() || !p.SyncedAt.Equal(now) {
+		t.Errorf("Jane's profile after a private sync: %+v", p)
+	}
+
+	// Nothing is stale again until MaxAge passes
+	if n, err := syncer.Refresh(ctx, now.Add(time.Hour)); err != nil || n != 0 {
+		t.Errorf("second Refresh = %d, %v, want nothing to sync", n, err)
+	}
+
+	// Entering a rank on a synced role takes it over
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{UserID: john.ID, Region: "us", Ranks: []store.RoleRank{{Role: "tank", SR: 3200}}})
+	p, _ = s.Profiles.GetGameProfile(ctx, john.ID)
+	if len(p.Ranks) != 1 || p.Ranks[0] != (store.RoleRank{Role: "tank", SR: 3200}) {
+		t.Errorf("ranks after the player's edit: %+v", p.Ranks)
+	}
+
+	// A stats outage is returned, and recorded so John waits his turn
+	stats.errs["John#1234"] = errors.New("connection refused")
+	later := now.Add(profiles.DefaultMaxAge + time.Hour)
+	if _, err := syncer.Refresh(ctx, later); err == nil {
+		t.Error("Refresh succeeded during an outage")
+	}
+	if p, _ := s.Profiles.GetGameProfile(ctx, john.ID); !p.SyncedAt.Equal(later) || p.SyncError == "" {
+		t.Errorf("outage was not recorded: %+v", p)
+	}
+}
+
+func TestSyncerRefreshContinuesAfterFailure(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	john, _ := s.Players.UpsertBattleNetPlayer(ctx, 1001, "John#1234")
+	jane, _ := s.Players.UpsertBattleNetPlayer(ctx, 1002, "Jane#5678")
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{UserID: john.ID, Region: "us"})
+	s.Profiles.SetGameProfile(ctx, store.GameProfile{UserID: jane.ID, Region: "eu"})
+	stats := &fakeStats{
+		ranks: map[string][]store.RoleRank{"Jane#5678": {{Role: "support", Division: "gold", Tier: 1}}},
+		errs:  map[string]error{"John#1234": errors.New("stats service returned 502")},
+	}
+	syncer := &profiles.Syncer{Store: s, Stats: stats}
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	// John comes first and fails; Jane is still synced
+	n, err := syncer.Refresh(ctx, now)
+	if err == nil || n != 2 || stats.calls != 2 {
+		t.Fatalf("Refresh = %d, %v after %d calls, want 2 players and John's error", n, err, stats.calls)
+	}
+	if p, _ := s.Profiles.GetGameProfile(ctx, jane.ID); len(p.Ranks) != 1 || !p.SyncedAt.Equal(now) {
+		t.Errorf("Jane was not synced after John failed: %+v", p)
+	}
+	if p, _ := s.Profiles.GetGameProfile(ctx, john.ID); p.SyncError != "stats service returned 502" || !p.SyncedAt.Equal(now) {
+		t.Errorf("John's failure was not recorded: %+v", p)
+	}
+
+	// Until MaxAge passes John isn't retried ahead of everyone else
+	if n, err := syncer.Refresh(ctx, now.Add(time.Hour)); err != nil || n != 0 {
+		t.Errorf("second Refresh = %d, %v, want nothing to sync", n, err)
+	}
+}
+
+func TestOverFastClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/players/John-1234/summary":
+			w.Write([]byte(`{"username": "John", "privacy": "public", "competitive": {"pc": {
+				"season": 12,
+				"tank": {"division": "diamond", "tier": 3},
+				"damage": null,
+				"support": {"division": "master", "tier": 5}
+			}, "console": null}}`))
+		case "/players/Hidden-1/summary":
+			w.Write([]byte(`{"username": "Hidden", "privacy": "private", "competitive": null}`))
+		case "/players/Broken-1/summary":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := &profiles.OverFastClient{BaseURL: srv.URL, Client: srv.Client()}
+	ctx := context.Background()
+
+	ranks, err := client.FetchRanks(ctx, "John#1234", "us")
+	want := []store.RoleRank{{Role: "tank", Division: "diamond", Tier: 3}, {Role: "support", Division: "master", Tier: 5}}
+	if err != nil || len(ranks) != 2 || ranks[0] != want[0] || ranks[1] != want[1] {
+		t.Errorf("FetchRanks = %+v, %v, want %+v", ranks, err, want)
+	}
+	if _, err := client.FetchRanks(ctx, "Hidden#1", "us"); !errors.Is(err, profiles.ErrPrivateProfile) {
+		t.Errorf("private profile returned %v", err)
+	}
+	if _, err := client.FetchRanks(ctx, "Nobody#1", "us"); !errors.Is(err, profiles.ErrProfileNotFound) {
+		t.Errorf("unknown battletag returned %v", err)
+	}
+	_, err = client.FetchRanks(ctx, "Broken#1", "us")
+	if err == nil || errors.Is(err, profiles.ErrProfileNotFound) {
+		t.Errorf("server error returned %v, want a temporary error", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package recur parses and expands the subset of RFC 5545 recurrence rules
+// teams use for regular practices: daily or weekly repetition with an
+// interval, weekdays, and a COUNT or UNTIL limit.
+package recur
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a rule repeats.
+type Frequency string
+
+const (
+	Daily  Frequency = "DAILY"
+	Weekly Frequency = "WEEKLY"
+)
+
+var dayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Rule is a parsed RRULE.
+type Rule struct {
+	Freq Frequency
+	// Interval repeats the rule every Interval days or weeks.
+	Interval int
+	// ByDay lists the weekdays of a weekly rule. When empty the rule repeats
+	// on the weekday of the first occurrence.
+	ByDay []time.Weekday
+	// WeekStart is the first day of the week (WKST), Monday by default. It
+	// matters for weekly rules with an interval above one.
+	WeekStart time.Weekday
+	// Count stops the rule after that many occurrences.
+	Count int
+	// Until is the latest time an occurrence may start. When UntilDate is
+	// set it holds a calendar date (at midnight UTC) and the whole day in the
+	// rule's timezone is included.
+	Until     time.Time
+	UntilDate bool
+}
+
+// Parse reads an RRULE value such as "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10". A
+// leading "RRULE:" is accepted.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, errors.New("recurrence rule is empty")
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || name == "" || value == "" {
+			return r, fmt.Errorf("recurrence rule part %q must look like NAME=value", part)
+		}
+		if seen[name] {
+			return r, fmt.Errorf("recurrence rule repeats %s", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			if r.Freq != Daily && r.Freq != Weekly {
+				return r, errors.New("FREQ must be DAILY or WEEKLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, errors.New("INTERVAL must be a positive number")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, errors.New("COUNT must be a positive number")
+			}
+			r.Count = n
+		case "UNTIL":
+			until, date, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until, r.UntilDate = until, date
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := parseDay(code)
+				if !ok {
+					return r, fmt.Errorf("BYDAY has unknown day %q", code)
+				}
+				if slices.Contains(r.ByDay, day) {
+					return r, fmt.Errorf("BYDAY repeats %s", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "WKST":
+			day, ok := parseDay(value)
+			if !ok {
+				return r, fmt.Errorf("WKST has unknown day %q", value)
+			}
+			r.WeekStart = day
+		default:
+			return r, fmt.Errorf("recurrence rule part %s is not supported", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, errors.New("recurrence rule needs a FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return r, errors.New("recurrence rule cannot have both COUNT and UNTIL")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return r, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func parseDay(code string) (time.Weekday, bool) {
+	for i, c := range dayCodes {
+		if c == code {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// parseUntil accepts a date (20260601) or a UTC date-time (20260601T190000Z).
+func parseUntil(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102", v); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, errors.New("UNTIL must be a date or a UTC date-time such as 20260601T000000Z")
+}
+
+// String formats the rule as an RRULE value, without the "RRULE:" prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, d := range r.ByDay {
+			codes = append(codes, dayCodes[d])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayCodes[r.WeekStart])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.UntilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe returns a short English description of the rule, such as
+// "weekly on Tue, Thu, 10 times".
+func (r Rule) Describe() string {
+	var d string
+	unit := map[Frequency]string{Daily: "day", Weekly: "week"}[r.Freq]
+	switch {
+	case r.Interval > 1:
+		d = fmt.Sprintf("every %d %ss", r.Interval, unit)
+	case r.Freq == Daily:
+		d = "daily"
+	default:
+		d = "weekly"
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			names = append(names, day.String()[:3])
+		}
+		d += " on " + strings.Join(names, ", ")
+	}
+	switch {
+	case r.Count == 1:
+		d += ", once"
+	case r.Count > 1:
+		d += fmt.Sprintf(", %d times", r.Count)
+	case r.UntilDate:
+		d += ", until " + r.Until.Format("Jan 2, 2006")
+	case !r.Until.IsZero():
+		d += ", until " + r.Until.UTC().Format("Jan 2, 2006")
+	}
+	return d
+}
+
+// UTCUntil returns r with a date-only UNTIL turned into the last UTC
+// instant of that day in loc. RFC 5545 requires that form when the first
+// occurrence has a timezone.
+func (r Rule) UTCUntil(loc *time.Location) Rule {
+	if !r.UntilDate {
+		return r
+	}
+	y, m, d := r.Until.Date()
+	r.Until = time.Date(y, m, d+1, 0, 0, 0, 0, loc).Add(-time.Second).UTC()
+	r.UntilDate = false
+	return r
+}
+
+// Bounded reports whether the rule has a last occurrence.
+func (r Rule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// Between returns the starts of the occurrences in [from, to) of a series
+// whose first occurrence starts at dtstart. Occurrences keep dtstart's
+// wall-clock time in loc, so they follow daylight saving changes. A zero to
+// only works for bounded rules; unbounded rules return nil.
+func (r Rule) Between(dtstart time.Time, loc *time.Location, from, to time.Time) []time.Time {
+	if to.IsZero() && !r.Bounded() {
+		return nil
+	}
+	var starts []time.Time
+	r.each(dtstart, loc, func(t time.Time) bool {
+		if !to.IsZero() && !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			starts = append(starts, t)
+		}
+		return true
+	})
+	return starts
+}
+
+// Includes reports whether t is the start of an occurrence.
+func (r Rule) Includes(dtstart time.Time, loc *time.Location, t time.Time) bool {
+	return len(r.Between(dtstart, loc, t, t.Add(time.Nanosecond))) > 0
+}
+
+// Last returns the start of the final occurrence, or false when the rule
+// repeats forever or has no occurrences at all.
+func (r Rule) Last(dtstart time.Time, loc *time.Location) (time.Time, bool) {
+	if !r.Bounded() {
+		return time.Time{}, false
+	}
+	var last time.Time
+	r.each(dtstart, loc, func(t time.Time) bool {
+		last = t
+		return true
+	})
+	return last, !last.IsZero()
+}
+
+// each calls yield with every occurrence in order until yield returns false
+// or the rule runs out. Dates are stepped in UTC so that adding days never
+// lands on a daylight saving boundary; only the final time uses loc.
+func (r Rule) each(dtstart time.Time, loc *time.Location, yield func(time.Time) bool) {
+	start := dtstart.In(loc)
+	hour, min, sec := start.Clock()
+	y, m, d := start.Date()
+	first := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	interval := max(r.Interval, 1)
+
+	n := 0
+	// emit reports whether expansion should continue past day.
+	emit := func(day time.Time) bool {
+		t := time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, start.Nanosecond(), loc)
+		if t.Before(start) {
+			return true
+		}
+		if r.past(t, loc) || !yield(t) {
+			return false
+		}
+		n++
+		return r.Count == 0 || n < r.Count
+	}
+
+	switch r.Freq {
+	case Daily:
+		for k := 0; ; k += interval {
+			if !emit(first.AddDate(0, 0, k)) {
+				return
+			}
+		}
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		offsets := make([]int, 0, len(days))
+		for _, day := range days {
+			offsets = append(offsets, r.weekOffset(day))
+		}
+		sort.Ints(offsets)
+		for week := first.AddDate(0, 0, -r.weekOffset(first.Weekday())); ; week = week.AddDate(0, 0, 7*interval) {
+			for _, off := range offsets {
+				if !emit(week.AddDate(0, 0, off)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// weekOffset is the number of days from WeekStart to day.
+func (r Rule) weekOffset(day time.Weekday) int {
+	return (int(day) - int(r.WeekStart) + 7) % 7
+}
+
+func (r Rule) past(t time.Time, loc *time.Location) bool {
+	switch {
+	case r.Until.IsZero():
+		return false
+	case r.UntilDate:
+		y, m, d := t.In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).After(r.Until)
+	default:
+		return t.After(r.Until)
+	}
+}
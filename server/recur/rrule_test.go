@@ -0,0 +1,95 @@
+package recur_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/recur"
+)
+
+func TestParse(t *testing.T) {
+	r, err := recur.Parse("RRULE:freq=weekly;byday=TU,TH;interval=2;until=20260601")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.String(); got != "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20260601" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := r.Describe(); got != "every 2 weeks on Tue, Thu, until Jun 1, 2026" {
+		t.Errorf("Describe() = %q", got)
+	}
+
+	for _, bad := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=MONTHLY",
+		"FREQ=WEEKLY;COUNT=3;UNTIL=20260601",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=MO,MO",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTH=1",
+		"FREQ=WEEKLY;INTERVAL=0",
+		"FREQ=WEEKLY;UNTIL=20260601T190000",
+	} {
+		if _, err := recur.Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestBetweenKeepsWallClockAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tuesdays and Thursdays at 19:00 in New York, across the March 8 change.
+	r, err := recur.Parse("FREQ=WEEKLY;BYDAY=TU,TH;COUNT=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 3, 19, 0, 0, 0, ny)
+	got := r.Between(start, ny, start, time.Time{})
+	want := []string{"2026-03-04T00:00:00Z", "2026-03-06T00:00:00Z", "2026-03-10T23:00:00Z", "2026-03-12T23:00:00Z", "2026-03-17T23:00:00Z"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if s := got[i].UTC().Format(time.RFC3339); s != want[i] {
+			t.Errorf("occurrence %d = %s, want %s", i, s, want[i])
+		}
+	}
+
+	if last, ok := r.Last(start, ny); !ok || !last.Equal(got[4]) {
+		t.Errorf("Last() = %v, %v; want %v", last, ok, got[4])
+	}
+	if !r.Includes(start, ny, got[2]) || r.Includes(start, ny, got[2].Add(time.Hour)) {
+		t.Error("Includes does not match the expanded occurrences")
+	}
+}
+
+func TestBetweenIntervalAndUntil(t *testing.T) {
+	r, err := recur.Parse("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;UNTIL=20250526")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first occurrence is a Friday, so the Monday of that week is skipped.
+	start := time.Date(2025, 5, 2, 18, 0, 0, 0, time.UTC)
+	got := r.Between(start, time.UTC, time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC), time.Time{})
+	want := []int{12, 16, 26}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want May %v", got, want)
+	}
+	for i, day := range want {
+		if got[i].Day() != day || got[i].Hour() != 18 {
+			t.Errorf("occurrence %d = %v, want May %d 18:00", i, got[i], day)
+		}
+	}
+
+	forever, _ := recur.Parse("FREQ=DAILY")
+	if got := forever.Between(start, time.UTC, start, time.Time{}); got != nil {
+		t.Errorf("unbounded rule without an end returned %v", got)
+	}
+	if _, ok := forever.Last(start, time.UTC); ok {
+		t.Error("unbounded rule reported a last occurrence")
+	}
+}
@@ -0,0 +1,86 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxBodyBytes caps the size of a JSON request body.
+const MaxBodyBytes = 1 << 20
+
+// Validator is implemented by request bodies that check their own fields
+// once decoded. Validate returns nil when the body is valid.
+type Validator interface {
+	Validate() []FieldError
+}
+
+// Decode reads the JSON request body into v, rejecting unknown fields,
+// trailing data and bodies over MaxBodyBytes, then validates v if it is a
+// Validator. On failure it writes the error and returns false.
+func Decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = errors.New("trailing data after the JSON body")
+		}
+	}
+	if err != nil {
+		decodeError(w, r, err)
+		return false
+	}
+	if val, ok := v.(Validator); ok {
+		if fields := val.Validate(); len(fields) > 0 {
+			Invalid(w, r, fields)
+			return false
+		}
+	}
+	return true
+}
+
+// decodeError reports why the body couldn't be decoded, naming the field
+// where encoding/json says which one it was.
+func decodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	var timeErr *time.ParseError
+	switch {
+	case errors.Is(err, io.EOF):
+		Error(w, r, "Request body is required", http.StatusBadRequest)
+	case errors.As(err, &tooLarge):
+		Error(w, r, "Request body is too large", http.StatusRequestEntityTooLarge)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		Invalid(w, r, []FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type.Kind().String())}})
+	case errors.As(err, &timeErr):
+		Error(w, r, "Times must be RFC 3339 timestamps", http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		Invalid(w, r, []FieldError{{Field: field, Message: "is not a known field"}})
+	default:
+		Error(w, r, "Request body must be valid JSON", http.StatusBadRequest)
+	}
+}
+
+// jsonType names a Go kind the way a JSON client would.
+func jsonType(kind string) string {
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "an integer"
+	case "float32", "float64":
+		return "a number"
+	case "bool":
+		return "true or false"
+	case "string":
+		return "a string"
+	case "slice", "array":
+		return "an array"
+	case "map", "struct":
+		return "an object"
+	}
+	return "a " + kind
+}
@@ -0,0 +1,153 @@
+// Package response writes API responses: JSON bodies, and errors in one
+// JSON envelope carrying a machine-readable code, a message, any field
+// errors and the request ID, so clients never see plain text or database
+// errors.
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/lib/pq"
+)
+
+// Error codes. Most follow from the status; the rest narrow it down.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalid          = "invalid_request" // fields failed validation
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeRoleFull         = "role_full"
+	CodeTooLarge         = "request_too_large"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+	CodeBadGateway       = "bad_gateway"
+	CodeUnavailable      = "unavailable"
+)
+
+// FieldError is a problem with one field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrorBody is the error reported to the client.
+type ErrorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// Envelope wraps every error response.
+type Envelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+// JSON encodes v as the response body.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// RequestID returns the ID the request was logged under, or the empty
+// string when the server isn't assigning them.
+func RequestID(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return chimw.GetReqID(r.Context())
+}
+
+// Error writes message with the code that goes with status. It takes the
+// same message and status as http.Error.
+func Error(w http.ResponseWriter, r *http.Request, message string, status int) {
+	ErrorCode(w, r, status, statusCode(status), message)
+}
+
+// ErrorCode writes message with an explicit code.
+func ErrorCode(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	write(w, r, status, ErrorBody{Code: code, Message: message})
+}
+
+// Invalid writes a 400 listing what is wrong with each field.
+func Invalid(w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	message := "Invalid input"
+	if len(fields) == 1 {
+		message = fields[0].Field + " " + fields[0].Message
+	}
+	write(w, r, http.StatusBadRequest, ErrorBody{Code: CodeInvalid, Message: message, Fields: fields})
+}
+
+func write(w http.ResponseWriter, r *http.Request, status int, body ErrorBody) {
+	body.RequestID = RequestID(r)
+	if body.RequestID != "" {
+		w.Header().Set("X-Request-Id", body.RequestID)
+	}
+	JSON(w, status, Envelope{Error: body})
+}
+
+// StoreError writes the error matching a store error. notFound is the
+// message for ErrNotFound. Postgres constraint violations that reach here
+// unmapped are treated like the store errors they stand for; anything else
+// is logged and reported without detail.
+func StoreError(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		Error(w, r, notFound, http.StatusNotFound)
+	case errors.Is(err, store.ErrConflict):
+		Error(w, r, "Conflicts with an existing record", http.StatusConflict)
+	case errors.Is(err, store.ErrRoleFull):
+		ErrorCode(w, r, http.StatusConflict, CodeRoleFull, "The team's roster limit for that in-game role is reached")
+	case errors.As(err, &pqErr) && pqErr.Code == "23505": // unique_violation
+		Error(w, r, "Conflicts with an existing record", http.StatusConflict)
+	case errors.As(err, &pqErr) && pqErr.Code == "23503": // foreign_key_violation
+		Error(w, r, notFound, http.StatusNotFound)
+	case errors.As(err, &pqErr) && (pqErr.Code == "23514" || pqErr.Code == "22001"): // check_violation, string_data_right_truncation
+		Error(w, r, "Invalid input", http.StatusBadRequest)
+	default:
+		log.Printf("Store error (request %s): %v", RequestID(r), err)
+		Error(w, r, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
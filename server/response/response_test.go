@@ -0,0 +1,145 @@
+package response_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/response"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/lib/pq"
+)
+
+type slotRequest struct {
+	Day   string `json:"day"`
+	Time  string `json:"time"`
+	Weeks int    `json:"weeks"`
+}
+
+func (req slotRequest) Validate() []response.FieldError {
+	var f response.Fields
+	if f.Required("day", req.Day) {
+		f.Weekday("day", req.Day)
+	}
+	f.ClockTime("time", req.Time)
+	return f
+}
+
+// decode runs Decode on body behind the request ID middleware and returns
+// the response along with whether decoding succeeded.
+func decode(t *testing.T, body string) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	var ok bool
+	h := chimw.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req slotRequest
+		ok = response.Decode(w, r, &req)
+	}))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return rr, ok
+}
+
+func envelope(t *testing.T, rr *httptest.ResponseRecorder) response.ErrorBody {
+	t.Helper()
+	var env response.Envelope
+	if err := json.NewDecoder(rr.Body).Decode(&env); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	return env.Error
+}
+
+func TestDecode(t *testing.T) {
+	if rr, ok := decode(t, `{"day":"Monday","time":"19:30","weeks":2}`); !ok {
+		t.Fatalf("valid body rejected: %d %s", rr.Code, rr.Body)
+	}
+
+	tests := []struct {
+		name, body, code, field string
+		status                  int
+	}{
+		{"empty", ``, response.CodeBadRequest, "", http.StatusBadRequest},
+		{"malformed", `{"day":`, response.CodeBadRequest, "", http.StatusBadRequest},
+		{"trailing data", `{"day":"Monday"} {}`, response.CodeBadRequest, "", http.StatusBadRequest},
+		{"unknown field", `{"day":"Monday","colour":"red"}`, response.CodeInvalid, "colour", http.StatusBadRequest},
+		{"wrong type", `{"day":"Monday","weeks":"two"}`, response.CodeInvalid, "weeks", http.StatusBadRequest},
+		{"missing", `{"time":"19:30"}`, response.CodeInvalid, "day", http.StatusBadRequest},
+		{"bad weekday", `{"day":"Funday"}`, response.CodeInvalid, "day", http.StatusBadRequest},
+		{"bad time", `{"day":"Monday","time":"7:30pm"}`, response.CodeInvalid, "time", http.StatusBadRequest},
+		{"too large", `{"day":"` + strings.Repeat("x", response.MaxBodyBytes) + `"}`, response.CodeTooLarge, "", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		rr, ok := decode(t, tt.body)
+		if ok {
+			t.Errorf("%s: accepted", tt.name)
+			continue
+		}
+		if rr.Code != tt.status {
+			t.Errorf("%s: status %d want %d", tt.name, rr.Code, tt.status)
+		}
+		body := envelope(t, rr)
+		if body.Code != tt.code || body.Message == "" {
+			t.Errorf("%s: error %+v want code %s", tt.name, body, tt.code)
+		}
+		if tt.field != "" && (len(body.Fields) != 1 || body.Fields[0].Field != tt.field) {
+			t.Errorf("%s: fields %+v want %s", tt.name, body.Fields, tt.field)
+		}
+		if body.RequestID == "" || rr.Header().Get("X-Request-Id") != body.RequestID {
+			t.Errorf("%s: request ID %q, header %q", tt.name, body.RequestID, rr.Header().Get("X-Request-Id"))
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	var f response.Fields
+	f.Battletag("ok", "Mercy#1234")
+	f.Battletag("short", "Me#1234")
+	f.Battletag("digits", "Mercy#12")
+	f.MaxLength("name", "héllo", 5)
+	f.MaxLength("long", "hello!", 5)
+	f.OneOf("format", "bo4", []string{"bo1", "bo3"})
+	f.Timezone("tz", "Europe/Berlin")
+	f.Timezone("bad_tz", "Mars/Olympus")
+	f.ClockTime("midnight", "24:00")
+
+	var got []string
+	for _, e := range f {
+		got = append(got, e.Field)
+	}
+	want := "short digits long format bad_tz midnight"
+	if strings.Join(got, " ") != want {
+		t.Errorf("fields with errors = %v, want %s", got, want)
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{store.ErrNotFound, http.StatusNotFound, response.CodeNotFound},
+		{fmt.Errorf("wrapped: %w", store.ErrConflict), http.StatusConflict, response.CodeConflict},
+		{store.ErrRoleFull, http.StatusConflict, response.CodeRoleFull},
+		{&pq.Error{Code: "23505"}, http.StatusConflict, response.CodeConflict},
+		{&pq.Error{Code: "23503"}, http.StatusNotFound, response.CodeNotFound},
+		{&pq.Error{Code: "23514"}, http.StatusBadRequest, response.CodeBadRequest},
+		{errors.New("connection refused"), http.StatusInternalServerError, response.CodeInternal},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		response.StoreError(rr, httptest.NewRequest(http.MethodGet, "/", nil), tt.err, "Team not found")
+		body := envelope(t, rr)
+		if rr.Code != tt.status || body.Code != tt.code {
+			t.Errorf("%v: got %d %s want %d %s", tt.err, rr.Code, body.Code, tt.status, tt.code)
+		}
+		if strings.Contains(body.Message, "connection") {
+			t.Errorf("%v: leaked %q", tt.err, body.Message)
+		}
+	}
+}
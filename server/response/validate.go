@@ -0,0 +1,97 @@
+package response
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// battletagPattern matches a Battle.net battletag: a 3-12 character name
+// starting with a letter, then # and the account's digits.
+var battletagPattern = regexp.MustCompile(`^\pL[\pL\pN]{2,11}#[0-9]{4,8}$`)
+
+// Fields collects field errors while a request validates itself.
+type Fields []FieldError
+
+// Add records a problem with field.
+func (f *Fields) Add(field, format string, args ...any) {
+	*f = append(*f, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Required checks that value isn't blank.
+func (f *Fields) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		f.Add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// MaxLength checks that value is at most max characters.
+func (f *Fields) MaxLength(field, value string, max int) bool {
+	if utf8.RuneCountInString(value) > max {
+		f.Add(field, "must be at most %d characters", max)
+		return false
+	}
+	return true
+}
+
+// OneOf checks that value is one of allowed, ignoring an empty value.
+func (f *Fields) OneOf(field, value string, allowed []string) bool {
+	if value != "" && !slices.Contains(allowed, value) {
+		f.Add(field, "must be one of %s", strings.Join(allowed, ", "))
+		return false
+	}
+	return true
+}
+
+// Battletag checks that value looks like Name#1234, ignoring an empty
+// value.
+func (f *Fields) Battletag(field, value string) bool {
+	if value != "" && !battletagPattern.MatchString(value) {
+		f.Add(field, "must be a battletag like Name#1234")
+		return false
+	}
+	return true
+}
+
+// Weekday checks that value is a weekday name such as Monday, ignoring an
+// empty value.
+func (f *Fields) Weekday(field, value string) bool {
+	if value != "" && store.WeekdayIndex(value) < 0 {
+		f.Add(field, "must be a weekday from Monday to Sunday")
+		return false
+	}
+	return true
+}
+
+// ClockTime checks that value is a 24-hour HH:MM time, ignoring an empty
+// value.
+func (f *Fields) ClockTime(field, value string) bool {
+	if value == "" {
+		return true
+	}
+	if _, err := time.Parse("15:04", value); err != nil || len(value) != 5 {
+		f.Add(field, "must be a time like 19:30")
+		return false
+	}
+	return true
+}
+
+// Timezone checks that value is an IANA timezone name, ignoring an empty
+// value.
+func (f *Fields) Timezone(field, value string) bool {
+	if value == "" {
+		return true
+	}
+	if _, err := store.LoadTimezone(value); err != nil {
+		f.Add(field, "must be an IANA timezone such as Europe/Berlin")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+// Package results works out a team's win/loss record from its match
+// history: overall, by map, by mode, by opponent and over time.
+package results
+
+import (
+	"sort"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Periods stats can be grouped by over time.
+const (
+	Week  = "week"
+	Month = "month"
+)
+
+// Record counts wins, losses and draws.
+type Record struct {
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+// Played returns how many results the record counts.
+func (r Record) Played() int {
+	return r.Wins + r.Losses + r.Draws
+}
+
+// WinRate returns the share of results that were wins, between 0 and 1, or
+// 0 when nothing was played.
+func (r Record) WinRate() float64 {
+	if r.Played() == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Played())
+}
+
+func (r *Record) add(outcome string) {
+	switch outcome {
+	case store.OutcomeWin:
+		r.Wins++
+	case store.OutcomeLoss:
+		r.Losses++
+	case store.OutcomeDraw:
+		r.Draws++
+	}
+}
+
+// Group is the record of the maps or matches sharing a key, such as a map
+// name or an opponent.
+type Group struct {
+	Key     string
+	Mode    string // set on groups by map
+	Matches Record // only counted for groups by opponent
+	Maps    Record
+}
+
+// Period is the record of the matches played in one week or month.
+type Period struct {
+	Start   time.Time
+	Matches Record
+	Maps    Record
+}
+
+// Stats is a team's record over a set of matches.
+type Stats struct {
+	Matches    Record
+	Maps       Record
+	ByMap      []Group // most played first
+	ByMode     []Group // in store.MapModes order
+	ByOpponent []Group // most played first
+	Over       []Period
+}
+
+// Summarize computes the team's record over matches. Matches without any
+// maps recorded are skipped. Periods of the given length (Week or Month)
+// start in loc and are listed oldest first, including the empty ones
+// between the first and last match.
+func Summarize(matches []store.Match, period string, loc *time.Location) Stats {
+	var s Stats
+	byMap := map[string]*Group{}
+	byMode := map[string]*Group{}
+	byOpponent := map[string]*Group{}
+	byPeriod := map[int64]*Period{} // keyed by start, in Unix seconds
+	group := func(groups map[string]*Group, key string) *Group {
+		g, ok := groups[key]
+		if !ok {
+			g = &Group{Key: key}
+			groups[key] = g
+		}
+		return g
+	}
+
+	for _, m := range matches {
+		outcome := m.Outcome()
+		if outcome == "" {
+			continue
+		}
+		start := periodStart(m.PlayedAt.In(loc), period)
+		p, ok := byPeriod[start.Unix()]
+		if !ok {
+			p = &Period{Start: start}
+			byPeriod[start.Unix()] = p
+		}
+		opponent := group(byOpponent, m.Opponent)
+
+		s.Matches.add(outcome)
+		p.Matches.add(outcome)
+		opponent.Matches.add(outcome)
+		for _, r := range m.Maps {
+			outcome := r.Outcome()
+			s.Maps.add(outcome)
+			p.Maps.add(outcome)
+			opponent.Maps.add(outcome)
+			g := group(byMap, r.Map)
+			g.Mode = r.Mode
+			g.Maps.add(outcome)
+			group(byMode, r.Mode).Maps.add(outcome)
+		}
+	}
+
+	s.ByMap = sortGroups(byMap)
+	s.ByOpponent = sortGroups(byOpponent)
+	for _, mode := range store.MapModes {
+		if g, ok := byMode[mode]; ok {
+			s.ByMode = append(s.ByMode, *g)
+		}
+	}
+	s.Over = periods(byPeriod, period)
+	return s
+}
+
+// sortGroups lists groups most played first, then by key.
+func sortGroups(groups map[string]*Group) []Group {
+	list := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		pi, pj := list[i].Matches.Played()+list[i].Maps.Played(), list[j].Matches.Played()+list[j].Maps.Played()
+		if pi != pj {
+			return pi > pj
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// periods lists byPeriod oldest first, filling the gaps with empty periods.
+func periods(byPeriod map[int64]*Period, period string) []Period {
+	if len(byPeriod) == 0 {
+		return nil
+	}
+	var first, last time.Time
+	for _, p := range byPeriod {
+		if first.IsZero() || p.Start.Before(first) {
+			first = p.Start
+		}
+		if p.Start.After(last) {
+			last = p.Start
+		}
+	}
+	var list []Period
+	for start := first; !start.After(last); start = nextPeriod(start, period) {
+		if p, ok := byPeriod[start.Unix()]; ok {
+			list = append(list, *p)
+		} else {
+			list = append(list, Period{Start: start})
+		}
+	}
+	return list
+}
+
+// periodStart returns the midnight starting t's week (on Monday) or month.
+func periodStart(t time.Time, period string) time.Time {
+	if period == Week {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func nextPeriod(start time.Time, period string) time.Time {
+	if period == Week {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 1, 0)
+}
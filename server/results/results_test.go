@@ -0,0 +1,62 @@
+package results_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/results"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func match(opponent string, playedAt time.Time, maps ...store.MapResult) store.Match {
+	for i := range maps {
+		maps[i].Number = i + 1
+	}
+	return store.Match{Opponent: opponent, Format: "bo3", PlayedAt: playedAt, Maps: maps}
+}
+
+func played(name, mode string, us, them int) store.MapResult {
+	return store.MapResult{Map: name, Mode: mode, TeamScore: us, OpponentScore: them}
+}
+
+func TestSummarize(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 19, 0, 0, 0, time.UTC) }
+	matches := []store.Match{
+		match("Rivals", day(5, 2), played("Lijiang Tower", "control", 2, 0), played("King's Row", "hybrid", 3, 2)),
+		match("Rivals", day(5, 9), played("Lijiang Tower", "control", 1, 2), played("Dorado", "escort", 2, 2), played("Ilios", "control", 0, 2)),
+		match("Underdogs", day(7, 1), played("Ilios", "control", 2, 1), played("Dorado", "escort", 3, 1)),
+		match("Pending", day(7, 3)),
+	}
+	s := results.Summarize(matches, results.Month, time.UTC)
+
+	if s.Matches != (results.Record{Wins: 2, Losses: 1}) {
+		t.Errorf("match record: %+v", s.Matches)
+	}
+	if s.Maps != (results.Record{Wins: 4, Losses: 2, Draws: 1}) || s.Maps.Played() != 7 {
+		t.Errorf("map record: %+v", s.Maps)
+	}
+	if got := s.Matches.WinRate(); got < 0.66 || got > 0.67 {
+		t.Errorf("win rate = %v", got)
+	}
+
+	if len(s.ByMap) != 4 || s.ByMap[0].Key != "Dorado" || s.ByMap[0].Mode != "escort" || s.ByMap[0].Maps != (results.Record{Wins: 1, Draws: 1}) {
+		t.Errorf("by map: %+v", s.ByMap)
+	}
+	if len(s.ByMode) != 3 || s.ByMode[0].Key != "control" || s.ByMode[0].Maps != (results.Record{Wins: 2, Losses: 2}) {
+		t.Errorf("by mode: %+v", s.ByMode)
+	}
+	if len(s.ByOpponent) != 2 || s.ByOpponent[0].Key != "Rivals" || s.ByOpponent[0].Matches != (results.Record{Wins: 1, Losses: 1}) {
+		t.Errorf("by opponent: %+v", s.ByOpponent)
+	}
+
+	// June had no matches but still gets a period
+	if len(s.Over) != 3 || !s.Over[0].Start.Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)) ||
+		s.Over[1].Matches.Played() != 0 || s.Over[2].Matches != (results.Record{Wins: 1}) {
+		t.Errorf("over time: %+v", s.Over)
+	}
+
+	weekly := results.Summarize(matches, results.Week, time.UTC)
+	if len(weekly.Over) == 0 || !weekly.Over[0].Start.Equal(time.Date(2025, 4, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("weeks should start on Monday: %+v", weekly.Over)
+	}
+}
@@ -0,0 +1,153 @@
+package roster
+
+import (
+	"slices"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Pick is a member placed in a lineup.
+type Pick struct {
+	Member store.Member
+	Role   string // the in-game role they play in this lineup
+	// Sub is set when the member is a substitute on the roster, and OffRole
+	// when they fill a role other than their roster role because it is one
+	// of their preferred roles.
+	Sub     bool
+	OffRole bool
+}
+
+// Shortfall is how many players a lineup is missing on a role.
+type Shortfall struct {
+	Role    string
+	Missing int
+}
+
+// Lineup is a proposed starting lineup.
+type Lineup struct {
+	Picks []Pick // in store.GameRoles order
+	Short []Shortfall
+	// Bench lists the available members who weren't picked.
+	Bench []store.Member
+}
+
+// Full reports whether the lineup has every slot filled.
+func (l Lineup) Full() bool {
+	return len(l.Short) == 0
+}
+
+// Suggest proposes a lineup from the members who are available. Members
+// are placed on their roster role or, when that is full, on a role they list
+// among their preferred roles in profiles. The lineup fills as many slots as
+// any assignment of the available members could, moving an earlier pick to
+// another of their roles when that makes room for one more. Members are
+// considered starters before subs, those with a roster role before those
+// without, and in each group in the order given, so a sub only starts when
+// no starter can take the slot. Coaches and managers without an in-game
+// role are never picked.
+func Suggest(available []store.Member, profiles map[int]store.GameProfile) Lineup {
+	type candidate struct {
+		member store.Member
+		sub    bool
+		roles  []string // roster role first, then preferred roles
+	}
+	var candidates []candidate
+	for _, m := range available {
+		teamRole := authz.ParseRole(m.Role)
+		c := candidate{member: m, sub: teamRole == authz.RoleSub}
+		if m.GameRole != "" {
+			c.roles = append(c.roles, m.GameRole)
+		}
+		staff := m.GameRole == "" && (teamRole == authz.RoleCoach || teamRole == authz.RoleManager)
+		for _, role := range profiles[m.UserID].PreferredRoles {
+			if !staff && store.LineupSize[role] > 0 && !slices.Contains(c.roles, role) {
+				c.roles = append(c.roles, role)
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	rank := func(c candidate) int {
+		r := 0
+		if c.sub {
+			r += 2
+		}
+		if c.member.GameRole == "" {
+			r++
+		}
+		return r
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return rank(a) - rank(b) })
+
+	// Place each candidate in turn along an augmenting path: a free slot on
+	// one of their roles, or one freed by moving its holder to another of
+	// theirs
+	holders := map[string][]int{} // role -> indexes into candidates
+	assigned := make([]string, len(candidates))
+	var place func(i int, tried map[string]bool) bool
+	place = func(i int, tried map[string]bool) bool {
+		for _, role := range candidates[i].roles {
+			if tried[role] {
+				continue
+			}
+			tried[role] = true
+			if len(holders[role]) < store.LineupSize[role] {
+				holders[role] = append(holders[role], i)
+				assigned[i] = role
+				return true
+			}
+			for k, j := range holders[role] {
+				if place(j, tried) {
+					holders[role][k] = i
+					assigned[i] = role
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for i := range candidates {
+		place(i, map[string]bool{})
+	}
+
+	var l Lineup
+	for _, role := range store.GameRoles {
+		var picks []Pick
+		for i, c := range candidates {
+			if assigned[i] == role {
+				picks = append(picks, Pick{Member: c.member, Role: role, Sub: c.sub, OffRole: c.member.GameRole != role})
+			}
+		}
+		// Members on their roster role come first, then subs
+		slices.SortStableFunc(picks, func(a, b Pick) int {
+			return pickRank(a) - pickRank(b)
+		})
+		l.Picks = append(l.Picks, picks...)
+		if n := store.LineupSize[role] - len(picks); n > 0 {
+			l.Short = append(l.Short, Shortfall{Role: role, Missing: n})
+		}
+	}
+	placed := map[int]bool{}
+	for _, p := range l.Picks {
+		placed[p.Member.UserID] = true
+	}
+	for _, m := range available {
+		if !placed[m.UserID] {
+			l.Bench = append(l.Bench, m)
+		}
+	}
+	return l
+}
+
+// pickRank orders the picks on a role: members on their roster role before
+// those filling in, and starters before subs.
+func pickRank(p Pick) int {
+	r := 0
+	if p.OffRole {
+		r += 2
+	}
+	if p.Sub {
+		r++
+	}
+	return r
+}
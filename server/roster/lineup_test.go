@@ -0,0 +1,90 @@
+package roster_test
+
+import (
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/roster"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// picked returns the user IDs in a lineup, by the role they play.
+func picked(l roster.Lineup) map[string][]int {
+	got := map[string][]int{}
+	for _, p := range l.Picks {
+		got[p.Role] = append(got[p.Role], p.Member.UserID)
+	}
+	return got
+}
+
+func TestSuggest(t *testing.T) {
+	available := []store.Member{
+		member(1, "sub", "tank"),
+		member(2, "captain", "tank"),
+		member(3, "player", "dps"),
+		member(4, "sub", "dps"),
+		member(5, "player", "dps"),
+		member(6, "player", "dps"),
+		member(7, "player", "support"),
+		member(8, "coach", ""),
+	}
+	profiles := map[int]store.GameProfile{
+		6: {UserID: 6, PreferredRoles: []string{"dps", "support"}},
+		8: {UserID: 8, PreferredRoles: []string{"support"}},
+	}
+
+	l := roster.Suggest(available, profiles)
+	got := picked(l)
+	// Starters win over subs, and a spare dps who also plays support fills
+	// the open support slot
+	if !l.Full() || len(got["tank"]) != 1 || got["tank"][0] != 2 {
+		t.Errorf("tank picks %v, short %+v", got["tank"], l.Short)
+	}
+	if len(got["dps"]) != 2 || got["dps"][0] != 3 || got["dps"][1] != 5 {
+		t.Errorf("dps picks %v", got["dps"])
+	}
+	if len(got["support"]) != 2 || got["support"][0] != 7 || got["support"][1] != 6 {
+		t.Errorf("support picks %v", got["support"])
+	}
+	for _, p := range l.Picks {
+		if p.OffRole != (p.Member.UserID == 6) {
+			t.Errorf("pick %+v has OffRole %v", p, p.OffRole)
+		}
+	}
+	if len(l.Bench) != 3 {
+		t.Errorf("bench %+v, want the tank sub, the dps sub and the coach", l.Bench)
+	}
+
+	// Without enough players the missing roles are reported
+	l = roster.Suggest([]store.Member{member(1, "sub", "tank"), member(3, "player", "dps")}, nil)
+	got = picked(l)
+	if l.Full() || len(got["tank"]) != 1 || !l.Picks[0].Sub {
+		t.Errorf("the tank sub should start: %+v", l.Picks)
+	}
+	want := []roster.Shortfall{{Role: "dps", Missing: 1}, {Role: "support", Missing: 2}}
+	if len(l.Short) != 2 || l.Short[0] != want[0] || l.Short[1] != want[1] {
+		t.Errorf("short %+v, want %+v", l.Short, want)
+	}
+}
+
+func TestSuggestMovesPicksToFillTheLineup(t *testing.T) {
+	// Taking tank for the first player would leave the second on the bench;
+	// playing the first on support fills both slots
+	available := []store.Member{member(1, "player", ""), member(2, "player", ""), member(3, "sub", "support")}
+	profiles := map[int]store.GameProfile{
+		1: {UserID: 1, PreferredRoles: []string{"tank", "support"}},
+		2: {UserID: 2, PreferredRoles: []string{"tank"}},
+	}
+	l := roster.Suggest(available, profiles)
+	got := picked(l)
+	if len(got["tank"]) != 1 || got["tank"][0] != 2 {
+		t.Errorf("tank picks %v, want the player who only plays tank", got["tank"])
+	}
+	// The support sub is on their roster role, so they're listed first
+	if len(got["support"]) != 2 || got["support"][0] != 3 || got["support"][1] != 1 {
+		t.Errorf("support picks %v", got["support"])
+	}
+	want := []roster.Shortfall{{Role: "dps", Missing: 2}}
+	if len(l.Short) != 1 || l.Short[0] != want[0] || len(l.Bench) != 0 {
+		t.Errorf("short %+v, bench %+v, want only dps short", l.Short, l.Bench)
+	}
+}
@@ -0,0 +1,134 @@
+// Package roster works out whether a team can field a full lineup from its
+// members' in-game roles, and what is missing when it can't.
+package roster
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Warning codes.
+const (
+	// WarnShort: there aren't enough members on a role to field a lineup,
+	// even with substitutes.
+	WarnShort = "short"
+	// WarnReliesOnSubs: the lineup can only be fielded by starting a
+	// substitute.
+	WarnReliesOnSubs = "relies_on_subs"
+	// WarnNoBackup: nobody can step in if a player on the role is missing.
+	WarnNoBackup = "no_backup"
+	// WarnOverLimit: more members hold the role than the team's limit, which
+	// was lowered after they were assigned.
+	WarnOverLimit = "over_limit"
+	// WarnUnassigned: a player has no in-game role.
+	WarnUnassigned = "unassigned"
+	// WarnOffRole: a player's in-game role isn't one of their preferred
+	// roles.
+	WarnOffRole = "off_role"
+)
+
+// Warning is a problem with a team's composition. Role and UserID are set
+// when it concerns one role or one member.
+type Warning struct {
+	Code    string
+	Role    string
+	UserID  int
+	Message string
+}
+
+// RoleGroup is the members holding one in-game role.
+type RoleGroup struct {
+	Role     string
+	Needed   int // players a lineup takes
+	Limit    int // zero when uncapped
+	Starters []store.Member
+	Subs     []store.Member
+}
+
+// Roster is a team's members arranged by in-game role.
+type Roster struct {
+	TeamID int
+	Limits store.RosterLimits
+	Roles  []RoleGroup // in store.GameRoles order
+	// Unassigned lists players and subs without an in-game role, and Staff
+	// coaches and managers without one.
+	Unassigned []store.Member
+	Staff      []store.Member
+	// CanField reports whether a full lineup can be fielded, counting subs.
+	CanField bool
+	Warnings []Warning
+}
+
+// Build arranges members into a roster. Members whose team role is sub are
+// substitutes; everyone else with an in-game role is a starter. profiles,
+// keyed by user ID, supply preferred roles and may leave players out.
+func Build(teamID int, members []store.Member, limits store.RosterLimits, profiles map[int]store.GameProfile) Roster {
+	r := Roster{TeamID: teamID, Limits: limits, CanField: true}
+	groups := map[string]*RoleGroup{}
+	for _, role := range store.GameRoles {
+		r.Roles = append(r.Roles, RoleGroup{Role: role, Needed: store.LineupSize[role], Limit: limits.Limit(role)})
+	}
+	for i := range r.Roles {
+		groups[r.Roles[i].Role] = &r.Roles[i]
+	}
+
+	for _, m := range members {
+		teamRole := authz.ParseRole(m.Role)
+		g, ok := groups[m.GameRole]
+		switch {
+		case !ok && (teamRole == authz.RoleCoach || teamRole == authz.RoleManager):
+			r.Staff = append(r.Staff, m)
+		case !ok:
+			r.Unassigned = append(r.Unassigned, m)
+			r.Warnings = append(r.Warnings, Warning{
+				Code: WarnUnassigned, UserID: m.UserID,
+				Message: fmt.Sprintf("%s has no in-game role", m.Username),
+			})
+		case teamRole == authz.RoleSub:
+			g.Subs = append(g.Subs, m)
+		default:
+			g.Starters = append(g.Starters, m)
+		}
+		if ok {
+			preferred := profiles[m.UserID].PreferredRoles
+			if len(preferred) > 0 && !slices.Contains(preferred, m.GameRole) {
+				r.Warnings = append(r.Warnings, Warning{
+					Code: WarnOffRole, Role: m.GameRole, UserID: m.UserID,
+					Message: fmt.Sprintf("%s plays %s but prefers %s", m.Username, m.GameRole, preferred[0]),
+				})
+			}
+		}
+	}
+
+	for _, g := range r.Roles {
+		starters, total := len(g.Starters), len(g.Starters)+len(g.Subs)
+		switch {
+		case total < g.Needed:
+			r.CanField = false
+			r.Warnings = append(r.Warnings, Warning{
+				Code: WarnShort, Role: g.Role,
+				Message: fmt.Sprintf("Needs %d more %s to field a full lineup", g.Needed-total, g.Role),
+			})
+		case starters < g.Needed:
+			r.Warnings = append(r.Warnings, Warning{
+				Code: WarnReliesOnSubs, Role: g.Role,
+				Message: fmt.Sprintf("Fields %d %s only by starting a substitute", g.Needed, g.Role),
+			})
+		case total == g.Needed:
+			r.Warnings = append(r.Warnings, Warning{
+				Code: WarnNoBackup, Role: g.Role,
+				Message: fmt.Sprintf("No substitute %s", g.Role),
+			})
+		}
+		if g.Limit > 0 && total > g.Limit {
+			r.Warnings = append(r.Warnings, Warning{
+				Code: WarnOverLimit, Role: g.Role,
+				Message: fmt.Sprintf("%d members play %s, over the limit of %d", total, g.Role, g.Limit),
+			})
+		}
+	}
+	return r
+}
@@ -0,0 +1,77 @@
+package roster_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/roster"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func member(id int, role, gameRole string) store.Member {
+	return store.Member{TeamID: 1, UserID: id, Username: fmt.Sprintf("Player%d", id), Role: role, GameRole: gameRole}
+}
+
+// codes returns each warning's code and role, such as "short:dps".
+func codes(r roster.Roster) map[string]bool {
+	got := map[string]bool{}
+	for _, w := range r.Warnings {
+		got[w.Code+":"+w.Role] = true
+	}
+	return got
+}
+
+func TestBuild(t *testing.T) {
+	limits := store.DefaultRosterLimits(1)
+
+	full := []store.Member{
+		member(1, "captain", "tank"),
+		member(2, "player", "tank"),
+		member(3, "player", "dps"),
+		member(4, "player", "dps"),
+		member(5, "sub", "dps"),
+		member(6, "player", "support"),
+		member(7, "player", "support"),
+		member(8, "sub", "support"),
+		member(9, "coach", ""),
+	}
+	r := roster.Build(1, full, limits, nil)
+	if !r.CanField || len(r.Warnings) != 0 {
+		t.Errorf("full roster: CanField %v, warnings %+v", r.CanField, r.Warnings)
+	}
+	if len(r.Staff) != 1 || len(r.Unassigned) != 0 || len(r.Roles[1].Starters) != 2 || len(r.Roles[1].Subs) != 1 {
+		t.Errorf("full roster grouped wrong: %+v", r)
+	}
+
+	thin := []store.Member{
+		member(1, "player", "tank"),
+		member(3, "player", "dps"),
+		member(4, "sub", "dps"),
+		member(6, "player", "support"),
+		member(7, "player", ""),
+	}
+	profiles := map[int]store.GameProfile{1: {UserID: 1, PreferredRoles: []string{"support"}}}
+	r = roster.Build(1, thin, limits, profiles)
+	want := map[string]bool{
+		"no_backup:tank":     true,
+		"relies_on_subs:dps": true,
+		"short:support":      true,
+		"unassigned:":        true,
+		"off_role:tank":      true,
+	}
+	got := codes(r)
+	if r.CanField || len(got) != len(want) {
+		t.Errorf("thin roster: CanField %v, warnings %+v", r.CanField, r.Warnings)
+	}
+	for code := range want {
+		if !got[code] {
+			t.Errorf("thin roster is missing warning %s: %+v", code, r.Warnings)
+		}
+	}
+
+	// Lowering a limit below the members already on the role is flagged
+	limits.Tank = 1
+	if got := codes(roster.Build(1, full, limits, nil)); !got["over_limit:tank"] {
+		t.Errorf("over the tank limit, warnings are %v", got)
+	}
+}
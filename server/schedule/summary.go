@@ -0,0 +1,223 @@
+// Package schedule aggregates a team's availability for one week into
+// per-slot counts and ranks the best windows for the team to meet.
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+// Slot is one occurrence of a team time slot.
+type Slot struct {
+	ID    int
+	Start time.Time
+	End   time.Time
+}
+
+// Member is a team member together with their merged availability.
+type Member struct {
+	UserID    int
+	Username  string
+	Role      string
+	Available []store.Interval
+}
+
+// Requirement asks for at least Count attending members with Role.
+type Requirement struct {
+	Role  authz.Role
+	Count int
+}
+
+func (r Requirement) String() string {
+	return fmt.Sprintf("%s:%d", r.Role, r.Count)
+}
+
+// ParseRequirements parses a comma-separated list such as "player:5,coach:1".
+func ParseRequirements(s string) ([]Requirement, error) {
+	var reqs []Requirement
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		role, count, ok := strings.Cut(part, ":")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n < 1 {
+			return nil, fmt.Errorf("requirement %q must look like role:count", part)
+		}
+		r := authz.Role(strings.ToLower(strings.TrimSpace(role)))
+		if authz.ParseRole(string(r)) != r {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		reqs = append(reqs, Requirement{Role: r, Count: n})
+	}
+	return reqs, nil
+}
+
+// Options tunes which windows Summarize ranks.
+type Options struct {
+	// MinDuration is the shortest window worth meeting for.
+	MinDuration time.Duration
+	// Limit caps the number of windows returned.
+	Limit int
+	// Require lists role counts a window's attendees must meet. Roles are
+	// matched exactly, so a captain does not count toward "player".
+	Require []Requirement
+}
+
+// SlotSummary reports who can make one slot.
+type SlotSummary struct {
+	Slot
+	Available []Member
+	Missing   []Member
+}
+
+// Window is a run of back-to-back slots and the members who can make all of
+// them.
+type Window struct {
+	Start     time.Time
+	End       time.Time
+	Slots     []Slot
+	Attendees []Member
+	Missing   []Member
+}
+
+// Duration is the window's length.
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// Summary is the aggregated availability of a team.
+type Summary struct {
+	Slots   []SlotSummary
+	Windows []Window
+}
+
+// Summarize counts who is available for each slot and ranks the best
+// non-overlapping windows of at least opts.MinDuration: most attendees first,
+// then longest, then earliest. Windows that miss a requirement or that nobody
+// can attend are left out. Slots must be in start order.
+func Summarize(slots []Slot, members []Member, opts Options) Summary {
+	var sum Summary
+	availability := make([][]bool, len(slots))
+	for i, slot := range slots {
+		s := SlotSummary{Slot: slot, Available: []Member{}, Missing: []Member{}}
+		availability[i] = make([]bool, len(members))
+		for j, m := range members {
+			if store.Covers(m.Available, slot.Start, slot.End) {
+				availability[i][j] = true
+				s.Available = append(s.Available, m)
+			} else {
+				s.Missing = append(s.Missing, m)
+			}
+		}
+		sum.Slots = append(sum.Slots, s)
+	}
+
+	// For every starting slot, grow the window until it is long enough, then
+	// keep growing while nobody else drops out.
+	var candidates []Window
+	for i := range slots {
+		attending := append([]bool(nil), availability[i]...)
+		end := i
+		for end+1 < len(slots) && slots[end].End.Sub(slots[i].Start) < opts.MinDuration && slots[end+1].Start.Equal(slots[end].End) {
+			end++
+			intersect(attending, availability[end])
+		}
+		if slots[end].End.Sub(slots[i].Start) < opts.MinDuration {
+			continue
+		}
+		for end+1 < len(slots) && slots[end+1].Start.Equal(slots[end].End) && covers(availability[end+1], attending) {
+			end++
+		}
+		w := newWindow(slots[i:end+1], members, attending)
+		if len(w.Attendees) > 0 && meets(w.Attendees, opts.Require) {
+			candidates = append(candidates, w)
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		wa, wb := candidates[a], candidates[b]
+		if len(wa.Attendees) != len(wb.Attendees) {
+			return len(wa.Attendees) > len(wb.Attendees)
+		}
+		if wa.Duration() != wb.Duration() {
+			return wa.Duration() > wb.Duration()
+		}
+		return wa.Start.Before(wb.Start)
+	})
+	for _, w := range candidates {
+		if opts.Limit > 0 && len(sum.Windows) >= opts.Limit {
+			break
+		}
+		if !overlapsAny(w, sum.Windows) {
+			sum.Windows = append(sum.Windows, w)
+		}
+	}
+	return sum
+}
+
+func newWindow(slots []Slot, members []Member, attending []bool) Window {
+	w := Window{
+		Start:     slots[0].Start,
+		End:       slots[len(slots)-1].End,
+		Slots:     slots,
+		Attendees: []Member{},
+		Missing:   []Member{},
+	}
+	for j, m := range members {
+		if attending[j] {
+			w.Attendees = append(w.Attendees, m)
+		} else {
+			w.Missing = append(w.Missing, m)
+		}
+	}
+	return w
+}
+
+// intersect clears every entry of set that is false in other.
+func intersect(set, other []bool) {
+	for j := range set {
+		set[j] = set[j] && other[j]
+	}
+}
+
+// covers reports whether every member in set is also in other.
+func covers(other, set []bool) bool {
+	for j := range set {
+		if set[j] && !other[j] {
+			return false
+		}
+	}
+	return true
+}
+
+func meets(attendees []Member, reqs []Requirement) bool {
+	for _, r := range reqs {
+		n := 0
+		for _, m := range attendees {
+			if authz.ParseRole(m.Role) == r.Role {
+				n++
+			}
+		}
+		if n < r.Count {
+			return false
+		}
+	}
+	return true
+}
+
+func overlapsAny(w Window, picked []Window) bool {
+	for _, p := range picked {
+		if w.Start.Before(p.End) && p.Start.Before(w.End) {
+			return true
+		}
+	}
+	return false
+}
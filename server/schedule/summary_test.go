@@ -0,0 +1,85 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/authz"
+	"github.com/KhrisKringle/Vivacity_website-main/server/schedule"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func at(hour int) time.Time {
+	return time.Date(2025, 5, 5, hour, 0, 0, 0, time.UTC)
+}
+
+func available(from, to int) []store.Interval {
+	return []store.Interval{{Start: at(from), End: at(to)}}
+}
+
+func TestSummarize(t *testing.T) {
+	var slots []schedule.Slot
+	for h := 18; h < 24; h++ {
+		slots = append(slots, schedule.Slot{ID: h, Start: at(h), End: at(h + 1)})
+	}
+	members := []schedule.Member{
+		{UserID: 1, Username: "Ana", Role: "player", Available: available(18, 22)},
+		{UserID: 2, Username: "Ben", Role: "player", Available: available(19, 23)},
+		{UserID: 3, Username: "Cam", Role: "coach", Available: available(19, 21)},
+		{UserID: 4, Username: "Dee", Role: "player"},
+	}
+
+	sum := schedule.Summarize(slots, members, schedule.Options{MinDuration: 2 * time.Hour})
+	if got := sum.Slots[1]; len(got.Available) != 3 || len(got.Missing) != 1 || got.Missing[0].Username != "Dee" {
+		t.Errorf("19:00 slot: %d available, missing %+v", len(got.Available), got.Missing)
+	}
+	want := []struct{ start, end, attendees int }{{19, 21, 3}, {21, 23, 1}}
+	if len(sum.Windows) != len(want) {
+		t.Fatalf("got %d windows, want %d: %+v", len(sum.Windows), len(want), sum.Windows)
+	}
+	for i, w := range want {
+		got := sum.Windows[i]
+		if !got.Start.Equal(at(w.start)) || !got.End.Equal(at(w.end)) || len(got.Attendees) != w.attendees {
+			t.Errorf("window %d: %v-%v with %d attendees, want %d:00-%d:00 with %d", i, got.Start, got.End, len(got.Attendees), w.start, w.end, w.attendees)
+		}
+	}
+
+	reqs, err := schedule.ParseRequirements("player:2, coach:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum = schedule.Summarize(slots, members, schedule.Options{MinDuration: 2 * time.Hour, Require: reqs})
+	if len(sum.Windows) != 1 || !sum.Windows[0].Start.Equal(at(19)) || len(sum.Windows[0].Slots) != 2 {
+		t.Errorf("with requirements got %+v, want only 19:00-21:00", sum.Windows)
+	}
+
+	sum = schedule.Summarize(slots, members, schedule.Options{MinDuration: 3 * time.Hour, Require: reqs})
+	if len(sum.Windows) != 0 {
+		t.Errorf("no three hour window has a coach, got %+v", sum.Windows)
+	}
+}
+
+func TestSummarizeSkipsGaps(t *testing.T) {
+	// Two slots with a break between them can't form one window
+	slots := []schedule.Slot{
+		{ID: 1, Start: at(18), End: at(19)},
+		{ID: 2, Start: at(20), End: at(21)},
+	}
+	members := []schedule.Member{{UserID: 1, Username: "Ana", Role: "player", Available: available(18, 21)}}
+	sum := schedule.Summarize(slots, members, schedule.Options{MinDuration: 2 * time.Hour})
+	if len(sum.Windows) != 0 {
+		t.Errorf("window spans a gap: %+v", sum.Windows)
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	reqs, err := schedule.ParseRequirements("player:5,coach:1")
+	if err != nil || len(reqs) != 2 || reqs[0] != (schedule.Requirement{Role: authz.RolePlayer, Count: 5}) {
+		t.Errorf("ParseRequirements = %v, %v", reqs, err)
+	}
+	for _, bad := range []string{"player", "player:x", "tank:2", "coach:0"} {
+		if _, err := schedule.ParseRequirements(bad); err == nil {
+			t.Errorf("ParseRequirements(%q) should fail", bad)
+		}
+	}
+}
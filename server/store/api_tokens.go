@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// APIScopes lists the scopes a personal API token can hold. Each area has a
+// read scope, for GET requests, and a write scope, which also allows reads.
+var APIScopes = []string{
+	"teams:read", "teams:write", // teams, members, roster, invites and Discord settings
+	"availability:read", "availability:write", // grids, time slots, availability and lineups
+	"events:read", "events:write", // events, RSVPs, results and matches
+	"profile:read", "profile:write", // players and their own settings
+}
+
+// APIToken is a personal access token a player created for a bot or script.
+// It acts as the player, limited to its scopes. Only a hash of the token is
+// stored.
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time // zero: never used
+	CreatedAt  time.Time
+}
+
+// APITokenStore persists personal API tokens.
+type APITokenStore interface {
+	// CreateAPIToken stores a token under its hash.
+	CreateAPIToken(ctx context.Context, t APIToken, hash string) (APIToken, error)
+	// ListAPITokens returns the player's tokens, expired ones included,
+	// oldest first.
+	ListAPITokens(ctx context.Context, userID int) ([]APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, id int) error
+	// UseAPIToken returns the token with hash and records now as its last
+	// use. It returns ErrNotFound for an unknown or expired token.
+	UseAPIToken(ctx context.Context, hash string, now time.Time) (APIToken, error)
+}
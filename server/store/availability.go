@@ -0,0 +1,79 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+// Interval is a half-open span of time [Start, End), stored in UTC.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MergeIntervals returns the union of intervals as sorted, non-overlapping
+// UTC intervals. Empty intervals are dropped and touching ones are joined.
+func MergeIntervals(intervals []Interval) []Interval {
+	var sorted []Interval
+	for _, iv := range intervals {
+		if iv.End.After(iv.Start) {
+			sorted = append(sorted, Interval{Start: iv.Start.UTC(), End: iv.End.UTC()})
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var merged []Interval
+	for _, iv := range sorted {
+		if n := len(merged); n > 0 && !iv.Start.After(merged[n-1].End) {
+			if iv.End.After(merged[n-1].End) {
+				merged[n-1].End = iv.End
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// Covers reports whether the merged intervals cover all of [start, end).
+func Covers(merged []Interval, start, end time.Time) bool {
+	for _, iv := range merged {
+		if !iv.Start.After(start) && !iv.End.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceWindow returns existing with [from, to) replaced by intervals
+// clipped to that window.
+func replaceWindow(existing []Interval, from, to time.Time, intervals []Interval) []Interval {
+	var out []Interval
+	for _, iv := range existing {
+		// Keep whatever lies outside the window
+		if iv.Start.Before(from) {
+			out = append(out, Interval{Start: iv.Start, End: minTime(iv.End, from)})
+		}
+		if iv.End.After(to) {
+			out = append(out, Interval{Start: maxTime(iv.Start, to), End: iv.End})
+		}
+	}
+	for _, iv := range intervals {
+		out = append(out, Interval{Start: maxTime(iv.Start, from), End: minTime(iv.End, to)})
+	}
+	return MergeIntervals(out)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// DiscordLink ties a Discord account to a player, so the Discord bot knows
+// who is running a slash command.
+type DiscordLink struct {
+	UserID          int
+	DiscordUserID   string // Discord snowflake ID
+	DiscordUsername string
+	LinkedAt        time.Time
+}
+
+// DiscordLinkStore persists Discord account links and the one-time codes
+// players redeem with the bot's /link command. Only a hash of each code is
+// stored, and each player has at most one pending code and one link.
+type DiscordLinkStore interface {
+	// SetDiscordLinkCode replaces the player's pending link code.
+	SetDiscordLinkCode(ctx context.Context, userID int, hash string, expires time.Time) error
+	// RedeemDiscordLinkCode consumes the code with hash if it has not
+	// expired by now, and links l's Discord account to the code's player,
+	// replacing any earlier link of either. It returns ErrNotFound for an
+	// unknown or expired code.
+	RedeemDiscordLinkCode(ctx context.Context, hash string, l DiscordLink, now time.Time) (DiscordLink, error)
+	GetDiscordLink(ctx context.Context, userID int) (DiscordLink, error)
+	// DiscordLinkUser returns the ID of the player linked to a Discord
+	// account.
+	DiscordLinkUser(ctx context.Context, discordUserID string) (int, error)
+	DeleteDiscordLink(ctx context.Context, userID int) error
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// EmailPreferences is where a player wants email and which emails they have
+// opted in to. Every kind is off until the player turns it on.
+type EmailPreferences struct {
+	UserID int
+	Email  string
+	// Reminders sends a reminder ReminderMinutes before each event on the
+	// player's teams, unless they RSVPed no.
+	Reminders       bool
+	ReminderMinutes int
+	// Digest sends a summary of the coming week's events once a week.
+	Digest bool
+	// AvailabilityAlerts sends a nudge when the player hasn't filled in
+	// their availability for next week.
+	AvailabilityAlerts bool
+}
+
+// Any reports whether the player wants any email at all.
+func (p EmailPreferences) Any() bool {
+	return p.Reminders || p.Digest || p.AvailabilityAlerts
+}
+
+// EmailStore persists email preferences and which emails have been sent.
+type EmailStore interface {
+	// GetEmailPreferences returns ErrNotFound when the player has not set
+	// any.
+	GetEmailPreferences(ctx context.Context, userID int) (EmailPreferences, error)
+	// ListEmailPreferences returns the preferences of every player who has
+	// opted in to at least one kind of email.
+	ListEmailPreferences(ctx context.Context) ([]EmailPreferences, error)
+	SetEmailPreferences(ctx context.Context, p EmailPreferences) error
+	DeleteEmailPreferences(ctx context.Context, userID int) error
+	// ClaimEmail records that the email identified by key is being sent to
+	// the player. It reports false when the key was already claimed, so each
+	// scheduled email goes out once.
+	ClaimEmail(ctx context.Context, key string, userID int, now time.Time) (bool, error)
+	// ReleaseEmail forgets a claim after a failed send so it is tried again.
+	ReleaseEmail(ctx context.Context, key string) error
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/recur"
+)
+
+// EventType classifies a team event.
+type EventType string
+
+const (
+	EventScrim    EventType = "scrim"
+	EventMatch    EventType = "match"
+	EventPractice EventType = "practice"
+	EventMeeting  EventType = "meeting"
+)
+
+// Valid reports whether t is one of the known event types.
+func (t EventType) Valid() bool {
+	switch t {
+	case EventScrim, EventMatch, EventPractice, EventMeeting:
+		return true
+	}
+	return false
+}
+
+// Event is a dated team activity. Cancelled events are kept, along with
+// their RSVPs, so members can see what was called off.
+//
+// A recurring event is a series: Start and End are its first occurrence and
+// Recurrence is an RFC 5545 RRULE expanded in Timezone, so occurrences keep
+// their wall-clock time across daylight saving changes.
+type Event struct {
+	ID         int
+	TeamID     int
+	Type       EventType
+	Title      string
+	Start      time.Time
+	End        time.Time
+	Opponent   string
+	Location   string // address or voice channel
+	Recurrence string // empty for one-off events
+	Timezone   string // IANA name; only used by recurring events
+	Cancelled  bool
+	CreatedBy  int // zero once the creator's account is deleted
+}
+
+// Rule parses the event's recurrence rule and timezone. It returns a nil
+// location for one-off events.
+func (e Event) Rule() (recur.Rule, *time.Location, error) {
+	if e.Recurrence == "" {
+		return recur.Rule{}, nil, nil
+	}
+	rule, err := recur.Parse(e.Recurrence)
+	if err != nil {
+		return rule, nil, err
+	}
+	loc, err := LoadTimezone(e.Timezone)
+	return rule, loc, err
+}
+
+// LastStart returns the start of the event's final occurrence, or false when
+// it repeats forever.
+func (e Event) LastStart() (time.Time, bool, error) {
+	rule, loc, err := e.Rule()
+	if err != nil || loc == nil {
+		return e.Start, err == nil, err
+	}
+	last, ok := rule.Last(e.Start, loc)
+	return last, ok, nil
+}
+
+// EventException overrides or cancels one occurrence of a recurring event.
+// The occurrence is identified by OriginalStart, the start the rule gives it.
+type EventException struct {
+	EventID       int
+	OriginalStart time.Time
+	Cancelled     bool
+	Start         time.Time
+	End           time.Time
+	Title         string
+	Opponent      string
+	Location      string
+}
+
+// Occurrence is one dated instance of an event with any exception applied.
+// One-off events have a single occurrence whose OriginalStart is its Start.
+type Occurrence struct {
+	Event
+	OriginalStart time.Time
+	Overridden    bool
+}
+
+// Occurrences expands e into the occurrences starting in [from, to). An
+// occurrence moved into the range by an exception is included, one moved
+// out of it is not. to must not be zero for series that repeat forever.
+func (e Event) Occurrences(exceptions []EventException, from, to time.Time) ([]Occurrence, error) {
+	rule, loc, err := e.Rule()
+	if err != nil {
+		return nil, fmt.Errorf("event %d: %w", e.ID, err)
+	}
+	inRange := func(t time.Time) bool {
+		return !t.Before(from) && (to.IsZero() || t.Before(to))
+	}
+	if loc == nil {
+		if !inRange(e.Start) {
+			return nil, nil
+		}
+		return []Occurrence{{Event: e, OriginalStart: e.Start}}, nil
+	}
+
+	byStart := make(map[int64]EventException, len(exceptions))
+	for _, x := range exceptions {
+		byStart[x.OriginalStart.UnixNano()] = x
+	}
+	length := e.End.Sub(e.Start)
+	occurrence := func(start time.Time) Occurrence {
+		o := Occurrence{Event: e, OriginalStart: start.UTC()}
+		o.Start, o.End = o.OriginalStart, o.OriginalStart.Add(length)
+		if x, ok := byStart[start.UnixNano()]; ok {
+			o.Overridden = true
+			o.Cancelled = o.Cancelled || x.Cancelled
+			o.Start, o.End = x.Start, x.End
+			o.Title, o.Opponent, o.Location = x.Title, x.Opponent, x.Location
+		}
+		return o
+	}
+
+	var occurrences []Occurrence
+	for _, start := range rule.Between(e.Start, loc, from, to) {
+		if o := occurrence(start); inRange(o.Start) {
+			occurrences = append(occurrences, o)
+		}
+	}
+	for _, x := range exceptions {
+		if inRange(x.OriginalStart) || !inRange(x.Start) || !rule.Includes(e.Start, loc, x.OriginalStart) {
+			continue
+		}
+		occurrences = append(occurrences, occurrence(x.OriginalStart))
+	}
+	sortOccurrences(occurrences)
+	return occurrences, nil
+}
+
+// ListOccurrences expands the team's events into their occurrences starting
+// in [from, to), in start order.
+func ListOccurrences(ctx context.Context, events EventStore, teamID int, from, to time.Time) ([]Occurrence, error) {
+	list, err := events.ListEvents(ctx, teamID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var occurrences []Occurrence
+	for _, e := range list {
+		var exceptions []EventException
+		if e.Recurrence != "" {
+			if exceptions, err = events.ListEventExceptions(ctx, e.ID); err != nil {
+				return nil, err
+			}
+		}
+		expanded, err := e.Occurrences(exceptions, from, to)
+		if err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, expanded...)
+	}
+	sortOccurrences(occurrences)
+	return occurrences, nil
+}
+
+func sortOccurrences(occurrences []Occurrence) {
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		if !occurrences[i].Start.Equal(occurrences[j].Start) {
+			return occurrences[i].Start.Before(occurrences[j].Start)
+		}
+		return occurrences[i].ID < occurrences[j].ID
+	})
+}
+
+// RSVPStatus is a member's answer to an event.
+type RSVPStatus string
+
+const (
+	RSVPYes   RSVPStatus = "yes"
+	RSVPNo    RSVPStatus = "no"
+	RSVPMaybe RSVPStatus = "maybe"
+)
+
+// Valid reports whether s is one of the known RSVP answers.
+func (s RSVPStatus) Valid() bool {
+	return s == RSVPYes || s == RSVPNo || s == RSVPMaybe
+}
+
+// RSVP is one member's answer to an event.
+type RSVP struct {
+	EventID   int
+	UserID    int
+	Username  string
+	Status    RSVPStatus
+	UpdatedAt time.Time
+}
+
+// EventStore persists team events and RSVPs.
+type EventStore interface {
+	// ListEvents returns the team's events that may have an occurrence
+	// starting in [from, to), in start order: one-off events starting in the
+	// range and recurring series that overlap it. A zero from or to leaves
+	// that side unbounded.
+	ListEvents(ctx context.Context, teamID int, from, to time.Time) ([]Event, error)
+	GetEvent(ctx context.Context, id int) (Event, error)
+	CreateEvent(ctx context.Context, e Event) (Event, error)
+	// UpdateEvent saves every field of e except TeamID, Cancelled and
+	// CreatedBy. Exceptions are kept and only apply to occurrences the new
+	// rule still has.
+	UpdateEvent(ctx context.Context, e Event) error
+	CancelEvent(ctx context.Context, id int) error
+	ListRSVPs(ctx context.Context, eventID int) ([]RSVP, error)
+	// SetRSVP records or replaces a member's answer.
+	SetRSVP(ctx context.Context, r RSVP) error
+	ListEventExceptions(ctx context.Context, eventID int) ([]EventException, error)
+	// SetEventException records or replaces the exception for one
+	// occurrence.
+	SetEventException(ctx context.Context, x EventException) error
+	// DeleteEventException restores an occurrence to what the rule gives.
+	DeleteEventException(ctx context.Context, eventID int, originalStart time.Time) error
+}
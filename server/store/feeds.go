@@ -0,0 +1,15 @@
+package store
+
+import "context"
+
+// FeedTokenStore persists the tokens calendar clients use to read a player's
+// feeds. Only a hash of each token is stored, and each player has at most
+// one token.
+type FeedTokenStore interface {
+	// SetFeedToken replaces the player's token hash, revoking any earlier
+	// token.
+	SetFeedToken(ctx context.Context, userID int, hash string) error
+	DeleteFeedToken(ctx context.Context, userID int) error
+	// FeedTokenUser returns the ID of the player whose token has hash.
+	FeedTokenUser(ctx context.Context, hash string) (int, error)
+}
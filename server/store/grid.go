@@ -0,0 +1,147 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Grid is a team's weekly scheduling grid. Each day listed in Days is cut
+// into SlotMinutes-long slots between Start and End, in the team's Timezone.
+// Weekdays that are not listed have no slots.
+type Grid struct {
+	TeamID      int
+	SlotMinutes int
+	Timezone    string // IANA name, e.g. "Europe/Berlin"
+	Days        []GridDay
+}
+
+// GridDay is the span of one weekday covered by a grid.
+type GridDay struct {
+	Weekday string
+	Start   string // HH:MM
+	End     string // HH:MM; "24:00" runs to midnight
+}
+
+// DefaultGrid is the grid of a team that has not configured one. It produces
+// the same 19:00 and 21:00 slots as the global defaults.
+func DefaultGrid(teamID int) Grid {
+	g := Grid{TeamID: teamID, SlotMinutes: 120, Timezone: "UTC"}
+	for _, day := range Weekdays {
+		g.Days = append(g.Days, GridDay{Weekday: day, Start: "19:00", End: "23:00"})
+	}
+	return g
+}
+
+// Validate reports the first problem with g, if any.
+func (g Grid) Validate() error {
+	if g.SlotMinutes < 15 || g.SlotMinutes > 240 || g.SlotMinutes%15 != 0 {
+		return errors.New("slot length must be a multiple of 15 minutes between 15 and 240")
+	}
+	if _, err := LoadTimezone(g.Timezone); err != nil {
+		return err
+	}
+	if len(g.Days) == 0 {
+		return errors.New("at least one weekday is required")
+	}
+	seen := map[string]bool{}
+	for _, d := range g.Days {
+		if WeekdayIndex(d.Weekday) < 0 {
+			return fmt.Errorf("unknown weekday %q", d.Weekday)
+		}
+		if seen[d.Weekday] {
+			return fmt.Errorf("%s is listed more than once", d.Weekday)
+		}
+		seen[d.Weekday] = true
+		start, err := ParseClock(d.Start)
+		if err != nil || start >= 24*60 {
+			return fmt.Errorf("invalid start time %q for %s", d.Start, d.Weekday)
+		}
+		end, err := ParseClock(d.End)
+		if err != nil {
+			return fmt.Errorf("invalid end time %q for %s", d.End, d.Weekday)
+		}
+		if end-start < g.SlotMinutes {
+			return fmt.Errorf("%s must span at least one %d minute slot", d.Weekday, g.SlotMinutes)
+		}
+	}
+	return nil
+}
+
+// Slots returns the time slots g produces, in display order. They have no ID
+// yet.
+func (g Grid) Slots() []TimeSlot {
+	var slots []TimeSlot
+	for _, d := range g.Days {
+		start, err1 := ParseClock(d.Start)
+		end, err2 := ParseClock(d.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		for t := start; t+g.SlotMinutes <= end; t += g.SlotMinutes {
+			slots = append(slots, TimeSlot{TeamID: g.TeamID, Weekday: d.Weekday, Time: FormatClock(t)})
+		}
+	}
+	sortTimeSlots(slots)
+	return slots
+}
+
+// ParseClock parses an HH:MM time of day into minutes after midnight. It
+// accepts "24:00" as the end of the day.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// FormatClock formats minutes after midnight as HH:MM.
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// LoadTimezone loads an IANA timezone by name. Unlike time.LoadLocation it
+// rejects the empty name and "Local", which depend on the server.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// WeekStart returns midnight at the start of the Monday of the week holding
+// t, in loc.
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// SlotInterval returns when slot takes place in the week starting at
+// weekStart, a Monday midnight from WeekStart. The slot's wall-clock time is
+// resolved in the grid's timezone for that particular date, so it follows
+// daylight saving changes; its length is always SlotMinutes.
+func (g Grid) SlotInterval(slot TimeSlot, weekStart time.Time) (Interval, error) {
+	loc, err := LoadTimezone(g.Timezone)
+	if err != nil {
+		return Interval{}, err
+	}
+	clock, err := ParseClock(slot.Time)
+	if err != nil {
+		return Interval{}, err
+	}
+	day := weekStart.In(loc)
+	start := time.Date(day.Year(), day.Month(), day.Day()+WeekdayIndex(slot.Weekday), clock/60, clock%60, 0, 0, loc)
+	return Interval{
+		Start: start.UTC(),
+		End:   start.Add(time.Duration(g.SlotMinutes) * time.Minute).UTC(),
+	}, nil
+}
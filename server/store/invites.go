@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// InviteLink is a shareable link that adds whoever opens it to a team. Only
+// a hash of its token is stored.
+type InviteLink struct {
+	ID        int
+	TeamID    int
+	Role      string // role given to players who join through the link
+	CreatedBy int
+	ExpiresAt time.Time // zero: never expires
+	MaxUses   int       // zero: unlimited
+	Uses      int
+	CreatedAt time.Time
+}
+
+// JoinRequest is a pending membership: either a captain invited a player by
+// battletag and the player has yet to accept, or a player asked to join and
+// a captain has yet to approve. A player has at most one pending request per
+// team, of either kind.
+type JoinRequest struct {
+	ID       int
+	TeamID   int
+	TeamName string
+	UserID   int
+	Username string
+	// Invited is true when the team invited the player, and false when the
+	// player asked to join.
+	Invited   bool
+	Role      string // role the membership will have
+	Message   string
+	CreatedBy int
+	CreatedAt time.Time
+}
+
+// InviteStore persists invite links and join requests. Both end in a row in
+// team_members, added in the same transaction that uses up the link or the
+// request.
+type InviteStore interface {
+	// CreateInviteLink stores a link under its token hash.
+	CreateInviteLink(ctx context.Context, l InviteLink, hash string) (InviteLink, error)
+	ListInviteLinks(ctx context.Context, teamID int) ([]InviteLink, error)
+	DeleteInviteLink(ctx context.Context, teamID, id int) error
+	// RedeemInviteLink adds the player to the link's team with the link's
+	// role and counts a use. It returns ErrNotFound for an unknown, expired
+	// or used up link, and ErrConflict, without using the link, when the
+	// player is already a member.
+	RedeemInviteLink(ctx context.Context, hash string, userID int, now time.Time) (Member, error)
+
+	// CreateJoinRequest returns ErrConflict when the player already has a
+	// pending request for the team or is already a member.
+	CreateJoinRequest(ctx context.Context, jr JoinRequest) (JoinRequest, error)
+	GetJoinRequest(ctx context.Context, id int) (JoinRequest, error)
+	// ListTeamJoinRequests returns the team's pending requests, oldest first.
+	ListTeamJoinRequests(ctx context.Context, teamID int) ([]JoinRequest, error)
+	// ListPlayerJoinRequests returns the player's pending requests, oldest
+	// first.
+	ListPlayerJoinRequests(ctx context.Context, userID int) ([]JoinRequest, error)
+	// AcceptJoinRequest adds the player to the team with role, or the
+	// request's role when role is empty, and deletes the request.
+	AcceptJoinRequest(ctx context.Context, id int, role string) (Member, error)
+	DeleteJoinRequest(ctx context.Context, id int) error
+}
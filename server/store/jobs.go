@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Job is a unit of background work for the jobs runner: a delayed job, a
+// run of a periodic job, or a retry of either.
+type Job struct {
+	ID   int
+	Kind string // selects the handler
+	// Key deduplicates jobs; at most one job is ever queued per non-empty
+	// key, which is how replicas agree on a single run of a periodic job.
+	Key         string
+	Payload     []byte // JSON
+	RunAt       time.Time
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	CreatedAt   time.Time
+}
+
+// JobStore persists the background job queue. Claimed jobs are leased
+// rather than locked, so a job whose worker died runs again once its lease
+// expires.
+type JobStore interface {
+	// EnqueueJob queues j to run at j.RunAt, or now when that is zero. It
+	// reports false, without error, when j has a key that was queued before.
+	EnqueueJob(ctx context.Context, j Job) (bool, error)
+	// ClaimJobs leases up to limit jobs due at now, oldest first, counting
+	// an attempt on each and hiding them from other claims for lease.
+	ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error)
+	// CompleteJob marks a job done.
+	CompleteJob(ctx context.Context, id int) error
+	// FailJob records a failed attempt and retries the job at next, or
+	// gives it up when next is zero.
+	FailJob(ctx context.Context, id int, next time.Time, lastError string) error
+	// PruneJobs deletes jobs that finished or were given up before the
+	// cutoff, and returns how many it deleted.
+	PruneJobs(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// MatchFormats lists the series lengths a match can be played as.
+var MatchFormats = []string{"bo1", "bo3", "bo5", "bo7"}
+
+// MapModes lists the Overwatch game modes, in display order.
+var MapModes = []string{"control", "escort", "hybrid", "push", "flashpoint", "clash"}
+
+// maxMatchMaps caps how many maps a match can record, replays of drawn maps
+// included.
+const maxMatchMaps = 12
+
+// Map and match outcomes.
+const (
+	OutcomeWin  = "win"
+	OutcomeLoss = "loss"
+	OutcomeDraw = "draw"
+)
+
+// MapResult is the result of one map of a match.
+type MapResult struct {
+	Number        int // 1-based order the map was played in
+	Map           string
+	Mode          string // one of MapModes
+	TeamScore     int
+	OpponentScore int
+	VODURL        string // empty when there is no recording of the map
+	Notes         string
+}
+
+// Outcome returns whether the team won, lost or drew the map.
+func (r MapResult) Outcome() string {
+	switch {
+	case r.TeamScore > r.OpponentScore:
+		return OutcomeWin
+	case r.TeamScore < r.OpponentScore:
+		return OutcomeLoss
+	}
+	return OutcomeDraw
+}
+
+// Match is a series a team played against an opponent. EventID links it to
+// the event it was scheduled as, and is zero for matches recorded on their
+// own. EventStart is the original start of the occurrence for recurring
+// events, and zero otherwise.
+type Match struct {
+	ID          int
+	TeamID      int
+	EventID     int
+	EventStart  time.Time
+	Opponent    string
+	Competition string // league or tournament, empty for scrims
+	Format      string // one of MatchFormats
+	PlayedAt    time.Time
+	VODURL      string
+	Notes       string
+	Maps        []MapResult // in Number order
+	CreatedBy   int         // zero once the creator's account is deleted
+}
+
+// WinsNeeded returns how many maps win the match, or zero for an unknown
+// format.
+func (m Match) WinsNeeded() int {
+	var bestOf int
+	if _, err := fmt.Sscanf(m.Format, "bo%d", &bestOf); err != nil {
+		return 0
+	}
+	return bestOf/2 + 1
+}
+
+// Score returns how many maps the team and its opponent won.
+func (m Match) Score() (won, lost int) {
+	for _, r := range m.Maps {
+		switch r.Outcome() {
+		case OutcomeWin:
+			won++
+		case OutcomeLoss:
+			lost++
+		}
+	}
+	return won, lost
+}
+
+// Outcome returns whether the team won, lost or drew the match on maps, or
+// the empty string before any map is recorded. A series cut short counts
+// for whoever was ahead.
+func (m Match) Outcome() string {
+	if len(m.Maps) == 0 {
+		return ""
+	}
+	won, lost := m.Score()
+	return MapResult{TeamScore: won, OpponentScore: lost}.Outcome()
+}
+
+// Validate reports the first problem with m, if any. Maps must be numbered
+// from 1 in order, and none can follow the map that decided the series.
+func (m Match) Validate() error {
+	if m.Opponent == "" {
+		return errors.New("opponent is required")
+	}
+	if len(m.Opponent) > 255 || len(m.Competition) > 255 {
+		return errors.New("opponent and competition must be at most 255 characters")
+	}
+	if !slices.Contains(MatchFormats, m.Format) {
+		return fmt.Errorf("format must be one of %v", MatchFormats)
+	}
+	if m.PlayedAt.IsZero() {
+		return errors.New("played_at is required")
+	}
+	if err := validateVODURL(m.VODURL); err != nil {
+		return err
+	}
+	if len(m.Maps) > maxMatchMaps {
+		return fmt.Errorf("a match can record at most %d maps", maxMatchMaps)
+	}
+	won, lost := 0, 0
+	for i, r := range m.Maps {
+		if r.Number != i+1 {
+			return errors.New("maps must be numbered from 1 in the order they were played")
+		}
+		if won == m.WinsNeeded() || lost == m.WinsNeeded() {
+			return fmt.Errorf("map %d was played after the %s was decided", r.Number, m.Format)
+		}
+		if r.Map == "" || len(r.Map) > 64 {
+			return fmt.Errorf("map %d needs a name of at most 64 characters", r.Number)
+		}
+		if !slices.Contains(MapModes, r.Mode) {
+			return fmt.Errorf("map %d mode must be one of %v", r.Number, MapModes)
+		}
+		if r.TeamScore < 0 || r.OpponentScore < 0 || r.TeamScore > 99 || r.OpponentScore > 99 {
+			return fmt.Errorf("map %d scores must be between 0 and 99", r.Number)
+		}
+		if err := validateVODURL(r.VODURL); err != nil {
+			return fmt.Errorf("map %d: %w", r.Number, err)
+		}
+		switch r.Outcome() {
+		case OutcomeWin:
+			won++
+		case OutcomeLoss:
+			lost++
+		}
+	}
+	return nil
+}
+
+func validateVODURL(s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(s) > 2048 {
+		return errors.New("vod_url must be an http or https URL")
+	}
+	return nil
+}
+
+// MatchStore persists match results.
+type MatchStore interface {
+	// ListMatches returns the team's matches played in [from, to), most
+	// recent first. A zero from or to leaves that side unbounded.
+	ListMatches(ctx context.Context, teamID int, from, to time.Time) ([]Match, error)
+	GetMatch(ctx context.Context, id int) (Match, error)
+	// GetEventMatch returns the match recorded for an event occurrence, as
+	// identified by Match.EventStart, or ErrNotFound.
+	GetEventMatch(ctx context.Context, eventID int, eventStart time.Time) (Match, error)
+	// CreateMatch records a match. It returns ErrConflict when the event
+	// occurrence already has one.
+	CreateMatch(ctx context.Context, m Match) (Match, error)
+	// UpdateMatch saves every field of m except TeamID, the event link and
+	// CreatedBy, replacing its maps.
+	UpdateMatch(ctx context.Context, m Match) error
+	DeleteMatch(ctx context.Context, id int) error
+}
@@ -0,0 +1,733 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memberKey struct{ teamID, userID int }
+
+// Memory implements every store interface in process memory. It mirrors the
+// constraints of the Postgres schema (unique keys and cascading deletes) so
+// handlers behave the same against either implementation.
+type Memory struct {
+	mu sync.Mutex
+
+	nextID       map[string]int
+	teams        map[int]Team
+	players      map[int]Player
+	members      map[memberKey]Member
+	slots        map[int]TimeSlot
+	grids        map[int]Grid
+	availability map[memberKey][]Interval
+	events       map[int]Event
+	rsvps        map[rsvpKey]RSVP
+	exceptions   map[exceptionKey]EventException
+	feedTokens   map[int]string // user ID to token hash
+	discord      map[int]DiscordSettings
+	outbox       map[int]*memoryOutbox
+	linkCodes    map[int]discordLinkCode // keyed by user ID
+	discordLinks map[int]DiscordLink     // keyed by user ID
+	emailPrefs   map[int]EmailPreferences
+	emailsSent   map[string]int // dedupe key to user ID
+	jobs         map[int]*memoryJob
+	inviteLinks  map[int]*memoryInviteLink
+	joinRequests map[int]JoinRequest
+	profiles     map[int]GameProfile
+	rosterLimits map[int]RosterLimits
+	matches      map[int]Match
+	apiTokens    map[int]*memoryAPIToken
+	sessions     map[int]*memorySession
+}
+
+// NewMemory returns a Store backed by a fresh Memory seeded with the same
+// default time slots as the initial migration.
+func NewMemory() *Store {
+	m := &Memory{
+		nextID:       map[string]int{},
+		teams:        map[int]Team{},
+		players:      map[int]Player{},
+		members:      map[memberKey]Member{},
+		slots:        map[int]TimeSlot{},
+		grids:        map[int]Grid{},
+		availability: map[memberKey][]Interval{},
+		events:       map[int]Event{},
+		rsvps:        map[rsvpKey]RSVP{},
+		exceptions:   map[exceptionKey]EventException{},
+		feedTokens:   map[int]string{},
+		discord:      map[int]DiscordSettings{},
+		outbox:       map[int]*memoryOutbox{},
+		linkCodes:    map[int]discordLinkCode{},
+		discordLinks: map[int]DiscordLink{},
+		emailPrefs:   map[int]EmailPreferences{},
+		emailsSent:   map[string]int{},
+		jobs:         map[int]*memoryJob{},
+		inviteLinks:  map[int]*memoryInviteLink{},
+		joinRequests: map[int]JoinRequest{},
+		profiles:     map[int]GameProfile{},
+		rosterLimits: map[int]RosterLimits{},
+		matches:      map[int]Match{},
+		apiTokens:    map[int]*memoryAPIToken{},
+		sessions:     map[int]*memorySession{},
+	}
+	for _, day := range Weekdays {
+		for _, t := range []string{"19:00", "21:00"} {
+			id := m.id("slots")
+			m.slots[id] = TimeSlot{ID: id, Weekday: day, Time: t}
+		}
+	}
+	return &Store{
+		Teams:         m,
+		Players:       m,
+		Memberships:   m,
+		TimeSlots:     m,
+		Grids:         m,
+		Availability:  m,
+		Events:        m,
+		FeedTokens:    m,
+		Notifications: m,
+		DiscordLinks:  m,
+		Email:         m,
+		Jobs:          m,
+		Invites:       m,
+		Profiles:      m,
+		Rosters:       m,
+		Matches:       m,
+		APITokens:     m,
+		Sessions:      m,
+	}
+}
+
+// id returns the next serial value for table. Callers must hold mu.
+func (m *Memory) id(table string) int {
+	m.nextID[table]++
+	return m.nextID[table]
+}
+
+func (m *Memory) ListTeams(ctx context.Context) ([]Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	teams := make([]Team, 0, len(m.teams))
+	for _, t := range m.teams {
+		teams = append(teams, t)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return teams, nil
+}
+
+func (m *Memory) GetTeam(ctx context.Context, id int) (Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.teams[id]
+	if !ok {
+		return Team{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *Memory) CreateTeam(ctx context.Context, name string) (Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := Team{ID: m.id("teams"), Name: name}
+	m.teams[t.ID] = t
+	return t, nil
+}
+
+func (m *Memory) UpdateTeam(ctx context.Context, id int, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.teams[id]
+	if !ok {
+		return ErrNotFound
+	}
+	t.Name = name
+	m.teams[id] = t
+	return nil
+}
+
+func (m *Memory) DeleteTeam(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.teams, id)
+	delete(m.grids, id)
+	delete(m.rosterLimits, id)
+	for playerID, p := range m.players {
+		if p.ActiveTeamID == id {
+			p.ActiveTeamID = 0
+			m.players[playerID] = p
+		}
+	}
+	for k := range m.members {
+		if k.teamID == id {
+			delete(m.members, k)
+		}
+	}
+	for k := range m.availability {
+		if k.teamID == id {
+			delete(m.availability, k)
+		}
+	}
+	for slotID, s := range m.slots {
+		if s.TeamID == id {
+			m.deleteSlotLocked(slotID)
+		}
+	}
+	for eventID, e := range m.events {
+		if e.TeamID == id {
+			m.deleteEventLocked(eventID)
+		}
+	}
+	for matchID, mt := range m.matches {
+		if mt.TeamID == id {
+			delete(m.matches, matchID)
+		}
+	}
+	delete(m.discord, id)
+	for outboxID, o := range m.outbox {
+		if o.TeamID == id {
+			delete(m.outbox, outboxID)
+		}
+	}
+	for linkID, l := range m.inviteLinks {
+		if l.TeamID == id {
+			delete(m.inviteLinks, linkID)
+		}
+	}
+	for reqID, jr := range m.joinRequests {
+		if jr.TeamID == id {
+			delete(m.joinRequests, reqID)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListPlayers(ctx context.Context) ([]Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	players := make([]Player, 0, len(m.players))
+	for _, p := range m.players {
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players, nil
+}
+
+func (m *Memory) GetPlayer(ctx context.Context, id int) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok {
+		return Player{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (m *Memory) GetPlayerByBattleNetID(ctx context.Context, battleNetID int64) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.players {
+		if p.BattleNetID == battleNetID {
+			return p, nil
+		}
+	}
+	return Player{}, ErrNotFound
+}
+
+func (m *Memory) GetPlayerByUsername(ctx context.Context, username string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found Player
+	for _, p := range m.players {
+		if strings.EqualFold(p.Username, username) && (found.ID == 0 || p.ID < found.ID) {
+			found = p
+		}
+	}
+	if found.ID == 0 {
+		return Player{}, ErrNotFound
+	}
+	return found, nil
+}
+
+func (m *Memory) UpsertBattleNetPlayer(ctx context.Context, battleNetID int64, username string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, p := range m.players {
+		if p.BattleNetID == battleNetID {
+			p.Username = username
+			m.players[id] = p
+			return p, nil
+		}
+	}
+	p := Player{ID: m.id("users"), BattleNetID: battleNetID, Username: username, Timezone: "UTC"}
+	m.players[p.ID] = p
+	return p, nil
+}
+
+func (m *Memory) UpdatePlayer(ctx context.Context, id int, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok {
+		return ErrNotFound
+	}
+	p.Username = username
+	m.players[id] = p
+	for k, mem := range m.members {
+		if k.userID == id {
+			mem.Username = username
+			m.members[k] = mem
+		}
+	}
+	return nil
+}
+
+func (m *Memory) SetPlayerAdmin(ctx context.Context, id int, admin bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok {
+		return ErrNotFound
+	}
+	p.IsAdmin = admin
+	m.players[id] = p
+	return nil
+}
+
+func (m *Memory) SetPlayerTimezone(ctx context.Context, id int, timezone string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok {
+		return ErrNotFound
+	}
+	p.Timezone = timezone
+	m.players[id] = p
+	return nil
+}
+
+func (m *Memory) SetActiveTeam(ctx context.Context, id, teamID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.members[memberKey{teamID, id}]; teamID != 0 && !ok {
+		return ErrNotFound
+	}
+	p.ActiveTeamID = teamID
+	m.players[id] = p
+	return nil
+}
+
+func (m *Memory) DeletePlayer(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.players, id)
+	delete(m.feedTokens, id)
+	delete(m.linkCodes, id)
+	delete(m.discordLinks, id)
+	delete(m.emailPrefs, id)
+	delete(m.profiles, id)
+	for key, userID := range m.emailsSent {
+		if userID == id {
+			delete(m.emailsSent, key)
+		}
+	}
+	for k := range m.members {
+		if k.userID == id {
+			delete(m.members, k)
+		}
+	}
+	for reqID, jr := range m.joinRequests {
+		switch {
+		case jr.UserID == id:
+			delete(m.joinRequests, reqID)
+		case jr.CreatedBy == id:
+			jr.CreatedBy = 0
+			m.joinRequests[reqID] = jr
+		}
+	}
+	for _, l := range m.inviteLinks {
+		if l.CreatedBy == id {
+			l.CreatedBy = 0
+		}
+	}
+	for tokenID, t := range m.apiTokens {
+		if t.UserID == id {
+			delete(m.apiTokens, tokenID)
+		}
+	}
+	for sessionID, sess := range m.sessions {
+		if sess.UserID == id {
+			delete(m.sessions, sessionID)
+		}
+	}
+	for k := range m.availability {
+		if k.userID == id {
+			delete(m.availability, k)
+		}
+	}
+	for k := range m.rsvps {
+		if k.userID == id {
+			delete(m.rsvps, k)
+		}
+	}
+	for eventID, e := range m.events {
+		if e.CreatedBy == id {
+			e.CreatedBy = 0
+			m.events[eventID] = e
+		}
+	}
+	for matchID, mt := range m.matches {
+		if mt.CreatedBy == id {
+			mt.CreatedBy = 0
+			m.matches[matchID] = mt
+		}
+	}
+	return nil
+}
+
+func (m *Memory) sortedMembers(keep func(Member) bool, less func(a, b Member) bool) []Member {
+	var members []Member
+	for _, mem := range m.members {
+		if keep(mem) {
+			mem.TeamName = m.teams[mem.TeamID].Name
+			members = append(members, mem)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return less(members[i], members[j]) })
+	return members
+}
+
+func (m *Memory) ListMembers(ctx context.Context, teamID int) ([]Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedMembers(
+		func(mem Member) bool { return mem.TeamID == teamID },
+		func(a, b Member) bool { return a.Username < b.Username },
+	), nil
+}
+
+func (m *Memory) ListMemberships(ctx context.Context, userID int) ([]Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedMembers(
+		func(mem Member) bool { return mem.UserID == userID },
+		func(a, b Member) bool { return a.TeamID < b.TeamID },
+	), nil
+}
+
+func (m *Memory) GetMember(ctx context.Context, teamID, userID int) (Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mem, ok := m.members[memberKey{teamID, userID}]
+	if !ok {
+		return Member{}, ErrNotFound
+	}
+	mem.TeamName = m.teams[teamID].Name
+	return mem, nil
+}
+
+func (m *Memory) AddMember(ctx context.Context, mem Member) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.addMemberLocked(mem)
+	return err
+}
+
+// addMemberLocked adds a membership, which settles any pending join request
+// between the player and the team. Callers must hold mu.
+func (m *Memory) addMemberLocked(mem Member) (Member, error) {
+	p, ok := m.players[mem.UserID]
+	if !ok {
+		return Member{}, ErrNotFound
+	}
+	if _, ok := m.teams[mem.TeamID]; !ok {
+		return Member{}, ErrNotFound
+	}
+	key := memberKey{mem.TeamID, mem.UserID}
+	if _, ok := m.members[key]; ok {
+		return Member{}, ErrConflict
+	}
+	if err := m.checkRoleLimitLocked(mem.TeamID, mem.UserID, mem.GameRole); err != nil {
+		return Member{}, err
+	}
+	mem.Username = p.Username
+	m.members[key] = mem
+	mem.TeamName = m.teams[mem.TeamID].Name
+	for id, jr := range m.joinRequests {
+		if jr.TeamID == mem.TeamID && jr.UserID == mem.UserID {
+			delete(m.joinRequests, id)
+		}
+	}
+	return mem, nil
+}
+
+func (m *Memory) UpdateMemberRole(ctx context.Context, teamID, userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memberKey{teamID, userID}
+	mem, ok := m.members[key]
+	if !ok {
+		return ErrNotFound
+	}
+	mem.Role = role
+	m.members[key] = mem
+	return nil
+}
+
+func (m *Memory) SetMemberGameRole(ctx context.Context, teamID, userID int, gameRole string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memberKey{teamID, userID}
+	mem, ok := m.members[key]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := m.checkRoleLimitLocked(teamID, userID, gameRole); err != nil {
+		return err
+	}
+	mem.GameRole = gameRole
+	m.members[key] = mem
+	return nil
+}
+
+func (m *Memory) RemoveMember(ctx context.Context, teamID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memberKey{teamID, userID}
+	if _, ok := m.members[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.members, key)
+	return nil
+}
+
+func (m *Memory) ListTimeSlots(ctx context.Context, teamID int) ([]TimeSlot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var slots []TimeSlot
+	for _, s := range m.slots {
+		if s.TeamID == teamID {
+			slots = append(slots, s)
+		}
+	}
+	sortTimeSlots(slots)
+	return slots, nil
+}
+
+func (m *Memory) GetTimeSlot(ctx context.Context, id int) (TimeSlot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.slots[id]
+	if !ok {
+		return TimeSlot{}, ErrNotFound
+	}
+	return s, nil
+}
+
+// slotTakenLocked reports whether another slot already occupies the same
+// team, weekday and time. Callers must hold mu.
+func (m *Memory) slotTakenLocked(slot TimeSlot) bool {
+	for _, s := range m.slots {
+		if s.ID != slot.ID && s.TeamID == slot.TeamID && s.Weekday == slot.Weekday && s.Time == slot.Time {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) CreateTimeSlot(ctx context.Context, slot TimeSlot) (TimeSlot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slot.TeamID != 0 {
+		if _, ok := m.teams[slot.TeamID]; !ok {
+			return TimeSlot{}, ErrNotFound
+		}
+	}
+	slot.ID = 0
+	if m.slotTakenLocked(slot) {
+		return TimeSlot{}, ErrConflict
+	}
+	slot.ID = m.id("slots")
+	m.slots[slot.ID] = slot
+	return slot, nil
+}
+
+func (m *Memory) UpdateTimeSlot(ctx context.Context, slot TimeSlot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.slots[slot.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	existing.Weekday = slot.Weekday
+	existing.Time = slot.Time
+	if m.slotTakenLocked(existing) {
+		return ErrConflict
+	}
+	m.slots[slot.ID] = existing
+	return nil
+}
+
+func (m *Memory) DeleteTimeSlot(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.slots[id]; !ok {
+		return ErrNotFound
+	}
+	m.deleteSlotLocked(id)
+	return nil
+}
+
+// deleteSlotLocked removes a slot. Callers must hold mu.
+func (m *Memory) deleteSlotLocked(id int) {
+	delete(m.slots, id)
+}
+
+func (m *Memory) GetGrid(ctx context.Context, teamID int) (Grid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[teamID]; !ok {
+		return Grid{}, ErrNotFound
+	}
+	return m.gridLocked(teamID), nil
+}
+
+// gridLocked returns the team's grid or the default. Callers must hold mu.
+func (m *Memory) gridLocked(teamID int) Grid {
+	if g, ok := m.grids[teamID]; ok {
+		g.Days = append([]GridDay(nil), g.Days...)
+		return g
+	}
+	return DefaultGrid(teamID)
+}
+
+func (m *Memory) SetGrid(ctx context.Context, g Grid) ([]TimeSlot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[g.TeamID]; !ok {
+		return nil, ErrNotFound
+	}
+	for id, s := range m.slots {
+		if s.TeamID == g.TeamID {
+			m.deleteSlotLocked(id)
+		}
+	}
+	slots := g.Slots()
+	for i := range slots {
+		slots[i].ID = m.id("slots")
+		m.slots[slots[i].ID] = slots[i]
+	}
+	g.Days = append([]GridDay(nil), g.Days...)
+	m.grids[g.TeamID] = g
+	return slots, nil
+}
+
+func (m *Memory) ListAvailability(ctx context.Context, teamID, userID int, from, to time.Time) ([]Interval, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var intervals []Interval
+	for _, iv := range m.availability[memberKey{teamID, userID}] {
+		if iv.Start.Before(to) && iv.End.After(from) {
+			intervals = append(intervals, iv)
+		}
+	}
+	return intervals, nil
+}
+
+func (m *Memory) ListTeamAvailability(ctx context.Context, teamID int, from, to time.Time) (map[int][]Interval, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byUser := map[int][]Interval{}
+	for k, intervals := range m.availability {
+		if k.teamID != teamID {
+			continue
+		}
+		for _, iv := range intervals {
+			if iv.Start.Before(to) && iv.End.After(from) {
+				byUser[k.userID] = append(byUser[k.userID], iv)
+			}
+		}
+	}
+	return byUser, nil
+}
+
+func (m *Memory) SetAvailability(ctx context.Context, teamID, userID int, from, to time.Time, intervals []Interval) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[userID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.teams[teamID]; !ok {
+		return ErrNotFound
+	}
+	key := memberKey{teamID, userID}
+	m.availability[key] = replaceWindow(m.availability[key], from, to, intervals)
+	return nil
+}
+
+func (m *Memory) PruneAvailability(ctx context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for k, intervals := range m.availability {
+		kept := intervals[:0]
+		for _, iv := range intervals {
+			if iv.End.After(before) {
+				kept = append(kept, iv)
+			} else {
+				n++
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.availability, k)
+		} else {
+			m.availability[k] = kept
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// memoryAPIToken is an api_tokens row.
+type memoryAPIToken struct {
+	APIToken
+	hash string
+}
+
+func (m *Memory) CreateAPIToken(ctx context.Context, t APIToken, hash string) (APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[t.UserID]; !ok {
+		return APIToken{}, ErrNotFound
+	}
+	for _, existing := range m.apiTokens {
+		if existing.hash == hash {
+			return APIToken{}, ErrConflict
+		}
+	}
+	t.ID = m.id("api_tokens")
+	t.Scopes = slices.Clone(t.Scopes)
+	t.LastUsedAt = time.Time{}
+	t.CreatedAt = time.Now().UTC()
+	m.apiTokens[t.ID] = &memoryAPIToken{APIToken: t, hash: hash}
+	return t, nil
+}
+
+func (m *Memory) ListAPITokens(ctx context.Context, userID int) ([]APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []APIToken
+	for _, t := range m.apiTokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t.APIToken)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+func (m *Memory) DeleteAPIToken(ctx context.Context, userID, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.apiTokens[id]
+	if !ok || t.UserID != userID {
+		return ErrNotFound
+	}
+	delete(m.apiTokens, id)
+	return nil
+}
+
+func (m *Memory) UseAPIToken(ctx context.Context, hash string, now time.Time) (APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.apiTokens {
+		if t.hash != hash {
+			continue
+		}
+		if !t.ExpiresAt.After(now) {
+			return APIToken{}, ErrNotFound
+		}
+		t.LastUsedAt = now
+		return t.APIToken, nil
+	}
+	return APIToken{}, ErrNotFound
+}
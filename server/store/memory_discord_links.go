@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type discordLinkCode struct {
+	hash    string
+	expires time.Time
+}
+
+func (m *Memory) SetDiscordLinkCode(ctx context.Context, userID int, hash string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[userID]; !ok {
+		return ErrNotFound
+	}
+	for id, c := range m.linkCodes {
+		if c.hash == hash && id != userID {
+			return ErrConflict
+		}
+	}
+	m.linkCodes[userID] = discordLinkCode{hash: hash, expires: expires}
+	return nil
+}
+
+func (m *Memory) RedeemDiscordLinkCode(ctx context.Context, hash string, l DiscordLink, now time.Time) (DiscordLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, c := range m.linkCodes {
+		if c.hash != hash {
+			continue
+		}
+		if !c.expires.After(now) {
+			return DiscordLink{}, ErrNotFound
+		}
+		delete(m.linkCodes, userID)
+		for id, existing := range m.discordLinks {
+			if existing.DiscordUserID == l.DiscordUserID {
+				delete(m.discordLinks, id)
+			}
+		}
+		l.UserID = userID
+		l.LinkedAt = now
+		m.discordLinks[userID] = l
+		return l, nil
+	}
+	return DiscordLink{}, ErrNotFound
+}
+
+func (m *Memory) GetDiscordLink(ctx context.Context, userID int) (DiscordLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.discordLinks[userID]
+	if !ok {
+		return DiscordLink{}, ErrNotFound
+	}
+	return l, nil
+}
+
+func (m *Memory) DiscordLinkUser(ctx context.Context, discordUserID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, l := range m.discordLinks {
+		if l.DiscordUserID == discordUserID {
+			return userID, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (m *Memory) DeleteDiscordLink(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.discordLinks[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.discordLinks, userID)
+	return nil
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *Memory) GetEmailPreferences(ctx context.Context, userID int) (EmailPreferences, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.emailPrefs[userID]
+	if !ok {
+		return EmailPreferences{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (m *Memory) ListEmailPreferences(ctx context.Context) ([]EmailPreferences, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var prefs []EmailPreferences
+	for _, p := range m.emailPrefs {
+		if p.Any() {
+			prefs = append(prefs, p)
+		}
+	}
+	sort.Slice(prefs, func(i, j int) bool { return prefs[i].UserID < prefs[j].UserID })
+	return prefs, nil
+}
+
+func (m *Memory) SetEmailPreferences(ctx context.Context, p EmailPreferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[p.UserID]; !ok {
+		return ErrNotFound
+	}
+	m.emailPrefs[p.UserID] = p
+	return nil
+}
+
+func (m *Memory) DeleteEmailPreferences(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.emailPrefs[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.emailPrefs, userID)
+	return nil
+}
+
+func (m *Memory) ClaimEmail(ctx context.Context, key string, userID int, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[userID]; !ok {
+		return false, ErrNotFound
+	}
+	if _, ok := m.emailsSent[key]; ok {
+		return false, nil
+	}
+	m.emailsSent[key] = userID
+	return true, nil
+}
+
+func (m *Memory) ReleaseEmail(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.emailsSent, key)
+	return nil
+}
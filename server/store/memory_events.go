@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type rsvpKey struct{ eventID, userID int }
+
+type exceptionKey struct {
+	eventID       int
+	originalStart int64 // UnixNano
+}
+
+func (m *Memory) ListEvents(ctx context.Context, teamID int, from, to time.Time) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []Event
+	for _, e := range m.events {
+		if e.TeamID != teamID {
+			continue
+		}
+		last, bounded, _ := e.LastStart()
+		if (!from.IsZero() && bounded && last.Before(from)) || (!to.IsZero() && !e.Start.Before(to)) {
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+func (m *Memory) GetEvent(ctx context.Context, id int) (Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[id]
+	if !ok {
+		return Event{}, ErrNotFound
+	}
+	return e, nil
+}
+
+func (m *Memory) CreateEvent(ctx context.Context, e Event) (Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[e.TeamID]; !ok {
+		return Event{}, ErrNotFound
+	}
+	if _, ok := m.players[e.CreatedBy]; !ok {
+		e.CreatedBy = 0
+	}
+	e.ID = m.id("events")
+	e.Start, e.End = e.Start.UTC(), e.End.UTC()
+	m.events[e.ID] = e
+	return e, nil
+}
+
+func (m *Memory) UpdateEvent(ctx context.Context, e Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.events[e.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	existing.Type = e.Type
+	existing.Title = e.Title
+	existing.Start, existing.End = e.Start.UTC(), e.End.UTC()
+	existing.Opponent = e.Opponent
+	existing.Location = e.Location
+	existing.Recurrence, existing.Timezone = e.Recurrence, e.Timezone
+	m.events[e.ID] = existing
+	return nil
+}
+
+func (m *Memory) CancelEvent(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[id]
+	if !ok {
+		return ErrNotFound
+	}
+	e.Cancelled = true
+	m.events[id] = e
+	return nil
+}
+
+// deleteEventLocked removes an event, its RSVPs and its exceptions. Callers
+// must hold mu.
+func (m *Memory) deleteEventLocked(id int) {
+	delete(m.events, id)
+	for k := range m.rsvps {
+		if k.eventID == id {
+			delete(m.rsvps, k)
+		}
+	}
+	for k := range m.exceptions {
+		if k.eventID == id {
+			delete(m.exceptions, k)
+		}
+	}
+}
+
+func (m *Memory) ListRSVPs(ctx context.Context, eventID int) ([]RSVP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rsvps []RSVP
+	for k, r := range m.rsvps {
+		if k.eventID == eventID {
+			r.Username = m.players[k.userID].Username
+			rsvps = append(rsvps, r)
+		}
+	}
+	sort.Slice(rsvps, func(i, j int) bool { return rsvps[i].Username < rsvps[j].Username })
+	return rsvps, nil
+}
+
+func (m *Memory) SetRSVP(ctx context.Context, r RSVP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.events[r.EventID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.players[r.UserID]; !ok {
+		return ErrNotFound
+	}
+	r.Username = ""
+	r.UpdatedAt = time.Now().UTC()
+	m.rsvps[rsvpKey{r.EventID, r.UserID}] = r
+	return nil
+}
+
+func (m *Memory) ListEventExceptions(ctx context.Context, eventID int) ([]EventException, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var exceptions []EventException
+	for k, x := range m.exceptions {
+		if k.eventID == eventID {
+			exceptions = append(exceptions, x)
+		}
+	}
+	sort.Slice(exceptions, func(i, j int) bool { return exceptions[i].OriginalStart.Before(exceptions[j].OriginalStart) })
+	return exceptions, nil
+}
+
+func (m *Memory) SetEventException(ctx context.Context, x EventException) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.events[x.EventID]; !ok {
+		return ErrNotFound
+	}
+	x.OriginalStart, x.Start, x.End = x.OriginalStart.UTC(), x.Start.UTC(), x.End.UTC()
+	m.exceptions[exceptionKey{x.EventID, x.OriginalStart.UnixNano()}] = x
+	return nil
+}
+
+func (m *Memory) DeleteEventException(ctx context.Context, eventID int, originalStart time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := exceptionKey{eventID, originalStart.UnixNano()}
+	if _, ok := m.exceptions[k]; !ok {
+		return ErrNotFound
+	}
+	delete(m.exceptions, k)
+	return nil
+}
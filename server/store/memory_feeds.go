@@ -0,0 +1,37 @@
+package store
+
+import "context"
+
+func (m *Memory) SetFeedToken(ctx context.Context, userID int, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[userID]; !ok {
+		return ErrNotFound
+	}
+	m.feedTokens[userID] = hash
+	return nil
+}
+
+func (m *Memory) DeleteFeedToken(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feedTokens[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.feedTokens, userID)
+	return nil
+}
+
+func (m *Memory) FeedTokenUser(ctx context.Context, hash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, h := range m.feedTokens {
+		if h == hash {
+			return userID, nil
+		}
+	}
+	return 0, ErrNotFound
+}
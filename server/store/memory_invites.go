@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// memoryInviteLink is an invite_links row.
+type memoryInviteLink struct {
+	InviteLink
+	hash string
+}
+
+func (m *Memory) CreateInviteLink(ctx context.Context, l InviteLink, hash string) (InviteLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[l.TeamID]; !ok {
+		return InviteLink{}, ErrNotFound
+	}
+	for _, existing := range m.inviteLinks {
+		if existing.hash == hash {
+			return InviteLink{}, ErrConflict
+		}
+	}
+	l.ID = m.id("invite_links")
+	l.Uses = 0
+	l.CreatedAt = time.Now().UTC()
+	m.inviteLinks[l.ID] = &memoryInviteLink{InviteLink: l, hash: hash}
+	return l, nil
+}
+
+func (m *Memory) ListInviteLinks(ctx context.Context, teamID int) ([]InviteLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var links []InviteLink
+	for _, l := range m.inviteLinks {
+		if l.TeamID == teamID {
+			links = append(links, l.InviteLink)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links, nil
+}
+
+func (m *Memory) DeleteInviteLink(ctx context.Context, teamID, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.inviteLinks[id]
+	if !ok || l.TeamID != teamID {
+		return ErrNotFound
+	}
+	delete(m.inviteLinks, id)
+	return nil
+}
+
+func (m *Memory) RedeemInviteLink(ctx context.Context, hash string, userID int, now time.Time) (Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, l := range m.inviteLinks {
+		if l.hash != hash {
+			continue
+		}
+		if !l.ExpiresAt.IsZero() && !l.ExpiresAt.After(now) || l.MaxUses > 0 && l.Uses >= l.MaxUses {
+			return Member{}, ErrNotFound
+		}
+		mem, err := m.addMemberLocked(Member{TeamID: l.TeamID, UserID: userID, Role: l.Role})
+		if err != nil {
+			return Member{}, err
+		}
+		l.Uses++
+		return mem, nil
+	}
+	return Member{}, ErrNotFound
+}
+
+func (m *Memory) CreateJoinRequest(ctx context.Context, jr JoinRequest) (JoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.teams[jr.TeamID]
+	if !ok {
+		return JoinRequest{}, ErrNotFound
+	}
+	p, ok := m.players[jr.UserID]
+	if !ok {
+		return JoinRequest{}, ErrNotFound
+	}
+	if _, ok := m.members[memberKey{jr.TeamID, jr.UserID}]; ok {
+		return JoinRequest{}, ErrConflict
+	}
+	for _, existing := range m.joinRequests {
+		if existing.TeamID == jr.TeamID && existing.UserID == jr.UserID {
+			return JoinRequest{}, ErrConflict
+		}
+	}
+	jr.ID = m.id("join_requests")
+	jr.TeamName = t.Name
+	jr.Username = p.Username
+	jr.CreatedAt = time.Now().UTC()
+	m.joinRequests[jr.ID] = jr
+	return jr, nil
+}
+
+func (m *Memory) GetJoinRequest(ctx context.Context, id int) (JoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jr, ok := m.joinRequests[id]
+	if !ok {
+		return JoinRequest{}, ErrNotFound
+	}
+	return m.joinRequestLocked(jr), nil
+}
+
+func (m *Memory) ListTeamJoinRequests(ctx context.Context, teamID int) ([]JoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedJoinRequests(func(jr JoinRequest) bool { return jr.TeamID == teamID }), nil
+}
+
+func (m *Memory) ListPlayerJoinRequests(ctx context.Context, userID int) ([]JoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedJoinRequests(func(jr JoinRequest) bool { return jr.UserID == userID }), nil
+}
+
+func (m *Memory) AcceptJoinRequest(ctx context.Context, id int, role string) (Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jr, ok := m.joinRequests[id]
+	if !ok {
+		return Member{}, ErrNotFound
+	}
+	if role == "" {
+		role = jr.Role
+	}
+	mem, err := m.addMemberLocked(Member{TeamID: jr.TeamID, UserID: jr.UserID, Role: role})
+	if err != nil {
+		return Member{}, err
+	}
+	delete(m.joinRequests, id)
+	return mem, nil
+}
+
+func (m *Memory) DeleteJoinRequest(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.joinRequests[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.joinRequests, id)
+	return nil
+}
+
+// joinRequestLocked fills in the current team name and username, which
+// Postgres joins in. Callers must hold mu.
+func (m *Memory) joinRequestLocked(jr JoinRequest) JoinRequest {
+	jr.TeamName = m.teams[jr.TeamID].Name
+	jr.Username = m.players[jr.UserID].Username
+	return jr
+}
+
+// sortedJoinRequests returns the requests matching keep, oldest first.
+// Callers must hold mu.
+func (m *Memory) sortedJoinRequests(keep func(JoinRequest) bool) []JoinRequest {
+	var out []JoinRequest
+	for _, jr := range m.joinRequests {
+		if keep(jr) {
+			out = append(out, m.joinRequestLocked(jr))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
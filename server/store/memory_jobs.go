@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// memoryJob is a jobs row. finished is when it completed or was given up.
+type memoryJob struct {
+	Job
+	finished time.Time
+}
+
+func (m *Memory) EnqueueJob(ctx context.Context, j Job) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if j.Key != "" {
+		for _, existing := range m.jobs {
+			if existing.Key == j.Key {
+				return false, nil
+			}
+		}
+	}
+	j.ID = m.id("jobs")
+	j.CreatedAt = time.Now().UTC()
+	if j.RunAt.IsZero() {
+		j.RunAt = j.CreatedAt
+	}
+	m.jobs[j.ID] = &memoryJob{Job: j}
+	return true, nil
+}
+
+func (m *Memory) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*memoryJob
+	for _, j := range m.jobs {
+		if j.finished.IsZero() && !j.RunAt.After(now) {
+			due = append(due, j)
+		}
+	}
+	sort.Slice(due, func(i, k int) bool {
+		if !due[i].RunAt.Equal(due[k].RunAt) {
+			return due[i].RunAt.Before(due[k].RunAt)
+		}
+		return due[i].ID < due[k].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]Job, 0, len(due))
+	for _, j := range due {
+		j.Attempts++
+		j.RunAt = now.Add(lease)
+		claimed = append(claimed, j.Job)
+	}
+	return claimed, nil
+}
+
+func (m *Memory) CompleteJob(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	j.finished = time.Now().UTC()
+	j.LastError = ""
+	return nil
+}
+
+func (m *Memory) FailJob(ctx context.Context, id int, next time.Time, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	j.LastError = lastError
+	if next.IsZero() {
+		j.finished = time.Now().UTC()
+	} else {
+		j.RunAt = next
+	}
+	return nil
+}
+
+func (m *Memory) PruneJobs(ctx context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for id, j := range m.jobs {
+		if !j.finished.IsZero() && j.finished.Before(before) {
+			delete(m.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
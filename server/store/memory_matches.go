@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func cloneMatch(mt Match) Match {
+	mt.Maps = append([]MapResult(nil), mt.Maps...)
+	return mt
+}
+
+func (m *Memory) ListMatches(ctx context.Context, teamID int, from, to time.Time) ([]Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[teamID]; !ok {
+		return nil, ErrNotFound
+	}
+	var matches []Match
+	for _, mt := range m.matches {
+		if mt.TeamID != teamID || (!from.IsZero() && mt.PlayedAt.Before(from)) || (!to.IsZero() && !mt.PlayedAt.Before(to)) {
+			continue
+		}
+		matches = append(matches, cloneMatch(mt))
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].PlayedAt.Equal(matches[j].PlayedAt) {
+			return matches[i].PlayedAt.After(matches[j].PlayedAt)
+		}
+		return matches[i].ID > matches[j].ID
+	})
+	return matches, nil
+}
+
+func (m *Memory) GetMatch(ctx context.Context, id int) (Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mt, ok := m.matches[id]
+	if !ok {
+		return Match{}, ErrNotFound
+	}
+	return cloneMatch(mt), nil
+}
+
+func (m *Memory) GetEventMatch(ctx context.Context, eventID int, eventStart time.Time) (Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, mt := range m.matches {
+		if mt.EventID == eventID && mt.EventStart.Equal(eventStart) {
+			return cloneMatch(mt), nil
+		}
+	}
+	return Match{}, ErrNotFound
+}
+
+func (m *Memory) CreateMatch(ctx context.Context, mt Match) (Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[mt.TeamID]; !ok {
+		return Match{}, ErrNotFound
+	}
+	if mt.EventID != 0 {
+		if _, ok := m.events[mt.EventID]; !ok {
+			return Match{}, ErrNotFound
+		}
+		for _, other := range m.matches {
+			if other.EventID == mt.EventID && other.EventStart.Equal(mt.EventStart) {
+				return Match{}, ErrConflict
+			}
+		}
+	}
+	if _, ok := m.players[mt.CreatedBy]; !ok {
+		mt.CreatedBy = 0
+	}
+	mt.ID = m.id("matches")
+	mt.PlayedAt, mt.EventStart = mt.PlayedAt.UTC(), mt.EventStart.UTC()
+	mt = cloneMatch(mt)
+	m.matches[mt.ID] = mt
+	return cloneMatch(mt), nil
+}
+
+func (m *Memory) UpdateMatch(ctx context.Context, mt Match) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.matches[mt.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	existing.Opponent, existing.Competition, existing.Format = mt.Opponent, mt.Competition, mt.Format
+	existing.PlayedAt = mt.PlayedAt.UTC()
+	existing.VODURL, existing.Notes = mt.VODURL, mt.Notes
+	existing.Maps = append([]MapResult(nil), mt.Maps...)
+	m.matches[mt.ID] = existing
+	return nil
+}
+
+func (m *Memory) DeleteMatch(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.matches[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.matches, id)
+	return nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// memoryOutbox is an outbox row. Sent and abandoned messages are kept so
+// their keys keep deduplicating.
+type memoryOutbox struct {
+	OutboxMessage
+	done bool
+}
+
+func (m *Memory) GetDiscordSettings(ctx context.Context, teamID int) (DiscordSettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.discord[teamID]
+	if !ok {
+		return DiscordSettings{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *Memory) ListDiscordSettings(ctx context.Context) ([]DiscordSettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var settings []DiscordSettings
+	for _, s := range m.discord {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].TeamID < settings[j].TeamID })
+	return settings, nil
+}
+
+func (m *Memory) SetDiscordSettings(ctx context.Context, s DiscordSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[s.TeamID]; !ok {
+		return ErrNotFound
+	}
+	m.discord[s.TeamID] = s
+	return nil
+}
+
+func (m *Memory) DeleteDiscordSettings(ctx context.Context, teamID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.discord[teamID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.discord, teamID)
+	return nil
+}
+
+func (m *Memory) EnqueueOutbox(ctx context.Context, msg OutboxMessage) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[msg.TeamID]; !ok {
+		return false, ErrNotFound
+	}
+	if msg.Key != "" {
+		for _, o := range m.outbox {
+			if o.Key == msg.Key {
+				return false, nil
+			}
+		}
+	}
+	msg.ID = m.id("outbox")
+	msg.CreatedAt = time.Now().UTC()
+	if msg.NextAttempt.IsZero() {
+		msg.NextAttempt = msg.CreatedAt
+	}
+	m.outbox[msg.ID] = &memoryOutbox{OutboxMessage: msg}
+	return true, nil
+}
+
+func (m *Memory) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*memoryOutbox
+	for _, o := range m.outbox {
+		if !o.done && !o.NextAttempt.After(now) {
+			due = append(due, o)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]OutboxMessage, 0, len(due))
+	for _, o := range due {
+		o.Attempts++
+		o.NextAttempt = now.Add(lease)
+		claimed = append(claimed, o.OutboxMessage)
+	}
+	return claimed, nil
+}
+
+func (m *Memory) MarkOutboxSent(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.outbox[id]
+	if !ok {
+		return ErrNotFound
+	}
+	o.done = true
+	o.LastError = ""
+	return nil
+}
+
+func (m *Memory) MarkOutboxFailed(ctx context.Context, id int, next time.Time, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.outbox[id]
+	if !ok {
+		return ErrNotFound
+	}
+	o.LastError = lastError
+	o.NextAttempt = next
+	o.done = next.IsZero()
+	return nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// cloneProfile copies p's slices so callers can't alias stored state.
+func cloneProfile(p GameProfile) GameProfile {
+	p.PreferredRoles = slices.Clone(p.PreferredRoles)
+	p.Ranks = slices.Clone(p.Ranks)
+	p.MainHeroes = slices.Clone(p.MainHeroes)
+	return p
+}
+
+// replaceRanks drops the ranks keep rejects and those on a role in ranks,
+// then adds ranks.
+func replaceRanks(existing, ranks []RoleRank, keep func(RoleRank) bool) []RoleRank {
+	out := slices.Clone(ranks)
+	for _, r := range existing {
+		taken := slices.ContainsFunc(ranks, func(n RoleRank) bool { return n.Role == r.Role })
+		if keep(r) && !taken {
+			out = append(out, r)
+		}
+	}
+	sortRanks(out)
+	return out
+}
+
+func (m *Memory) GetGameProfile(ctx context.Context, userID int) (GameProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[userID]; !ok {
+		return GameProfile{}, ErrNotFound
+	}
+	p, ok := m.profiles[userID]
+	if !ok {
+		return GameProfile{UserID: userID}, nil
+	}
+	return cloneProfile(p), nil
+}
+
+func (m *Memory) ListGameProfiles(ctx context.Context, userIDs []int) (map[int]GameProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profiles := map[int]GameProfile{}
+	for _, id := range userIDs {
+		if p, ok := m.profiles[id]; ok {
+			profiles[id] = cloneProfile(p)
+		}
+	}
+	return profiles, nil
+}
+
+func (m *Memory) SetGameProfile(ctx context.Context, p GameProfile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[p.UserID]; !ok {
+		return ErrNotFound
+	}
+	old := m.profiles[p.UserID]
+	entered := make([]RoleRank, len(p.Ranks))
+	for i, r := range p.Ranks {
+		r.Synced = false
+		entered[i] = r
+	}
+	p = cloneProfile(p)
+	p.Ranks = replaceRanks(old.Ranks, entered, func(r RoleRank) bool { return r.Synced })
+	p.SyncedAt, p.SyncError = old.SyncedAt, old.SyncError
+	m.profiles[p.UserID] = p
+	return nil
+}
+
+func (m *Memory) ListStaleGameProfiles(ctx context.Context, before time.Time, limit int) ([]GameProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stale []GameProfile
+	for _, p := range m.profiles {
+		if p.Region != "" && p.SyncedAt.Before(before) {
+			stale = append(stale, cloneProfile(p))
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		if !stale[i].SyncedAt.Equal(stale[j].SyncedAt) {
+			return stale[i].SyncedAt.Before(stale[j].SyncedAt)
+		}
+		return stale[i].UserID < stale[j].UserID
+	})
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+func (m *Memory) SetSyncedRanks(ctx context.Context, userID int, ranks []RoleRank, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.profiles[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	synced := make([]RoleRank, len(ranks))
+	for i, r := range ranks {
+		r.Synced = true
+		synced[i] = r
+	}
+	p.Ranks = replaceRanks(p.Ranks, synced, func(r RoleRank) bool { return !r.Synced })
+	p.SyncedAt, p.SyncError = now, ""
+	m.profiles[userID] = p
+	return nil
+}
+
+func (m *Memory) SetSyncError(ctx context.Context, userID int, message string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.profiles[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	p.SyncedAt, p.SyncError = now, message
+	m.profiles[userID] = p
+	return nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import "context"
+
+// checkRoleLimitLocked returns ErrRoleFull when giving userID gameRole
+// would take the team past its limit. Callers must hold mu.
+func (m *Memory) checkRoleLimitLocked(teamID, userID int, gameRole string) error {
+	if gameRole == "" {
+		return nil
+	}
+	limits, ok := m.rosterLimits[teamID]
+	if !ok {
+		limits = DefaultRosterLimits(teamID)
+	}
+	limit := limits.Limit(gameRole)
+	if limit == 0 {
+		return nil
+	}
+	n := 0
+	for k, mem := range m.members {
+		if k.teamID == teamID && k.userID != userID && mem.GameRole == gameRole {
+			n++
+		}
+	}
+	if n >= limit {
+		return ErrRoleFull
+	}
+	return nil
+}
+
+func (m *Memory) GetRosterLimits(ctx context.Context, teamID int) (RosterLimits, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[teamID]; !ok {
+		return RosterLimits{}, ErrNotFound
+	}
+	if l, ok := m.rosterLimits[teamID]; ok {
+		return l, nil
+	}
+	return DefaultRosterLimits(teamID), nil
+}
+
+func (m *Memory) SetRosterLimits(ctx context.Context, l RosterLimits) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.teams[l.TeamID]; !ok {
+		return ErrNotFound
+	}
+	m.rosterLimits[l.TeamID] = l
+	return nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// memorySession is a sessions row.
+type memorySession struct {
+	Session
+	hash string
+}
+
+// active reports whether the session is neither past its absolute timeout
+// nor idle since idleSince.
+func (s *memorySession) active(now, idleSince time.Time) bool {
+	return s.ExpiresAt.After(now) && s.LastSeenAt.After(idleSince)
+}
+
+func (m *Memory) sessionByHash(hash string) *memorySession {
+	for _, s := range m.sessions {
+		if s.hash == hash {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateSession(ctx context.Context, s Session, hash string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.players[s.UserID]; s.UserID != 0 && !ok {
+		return Session{}, ErrNotFound
+	}
+	if m.sessionByHash(hash) != nil {
+		return Session{}, ErrConflict
+	}
+	s.ID = m.id("sessions")
+	s.Data = slices.Clone(s.Data)
+	s.CreatedAt = time.Now().UTC()
+	s.LastSeenAt = s.CreatedAt
+	m.sessions[s.ID] = &memorySession{Session: s, hash: hash}
+	return s, nil
+}
+
+func (m *Memory) TouchSession(ctx context.Context, hash string, now, idleSince time.Time) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.sessionByHash(hash)
+	if s == nil || !s.active(now, idleSince) {
+		return Session{}, ErrNotFound
+	}
+	s.LastSeenAt = now
+	sess := s.Session
+	sess.Data = slices.Clone(s.Data)
+	return sess, nil
+}
+
+func (m *Memory) UpdateSession(ctx context.Context, hash string, userID int, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.sessionByHash(hash)
+	if s == nil {
+		return ErrNotFound
+	}
+	if _, ok := m.players[userID]; userID != 0 && !ok {
+		return ErrNotFound
+	}
+	s.UserID = userID
+	s.Data = slices.Clone(data)
+	return nil
+}
+
+func (m *Memory) DeleteSession(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.sessionByHash(hash)
+	if s == nil {
+		return ErrNotFound
+	}
+	delete(m.sessions, s.ID)
+	return nil
+}
+
+func (m *Memory) ListSessions(ctx context.Context, userID int, now, idleSince time.Time) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sessions []Session
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.active(now, idleSince) {
+			sessions = append(sessions, s.Session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (m *Memory) RevokeSession(ctx context.Context, userID, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID {
+		return ErrNotFound
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *Memory) RevokeSessions(ctx context.Context, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for id, s := range m.sessions {
+		if s.UserID == userID {
+			delete(m.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) PruneSessions(ctx context.Context, now, idleSince time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for id, s := range m.sessions {
+		if !s.active(now, idleSince) {
+			delete(m.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
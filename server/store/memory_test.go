@@ -0,0 +1,225 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+)
+
+func TestMemoryDeleteTeamCascades(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 42, "John#1234")
+	if err := s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: player.ID, Role: "player"}); err != nil {
+		t.Fatal(err)
+	}
+	slot, err := s.TimeSlots.CreateTimeSlot(ctx, store.TimeSlot{TeamID: team.ID, Weekday: "Monday", Time: "18:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2025, 5, 5, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	if err := s.Availability.SetAvailability(ctx, team.ID, player.ID, from, to, []store.Interval{{Start: from, End: from.Add(time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := s.Events.CreateEvent(ctx, store.Event{TeamID: team.ID, Type: store.EventScrim, Title: "Scrim", Start: from, End: from.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, err := s.Matches.CreateMatch(ctx, store.Match{TeamID: team.ID, EventID: event.ID, Opponent: "Bravo", Format: "bo1", PlayedAt: from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Matches.CreateMatch(ctx, store.Match{TeamID: team.ID, EventID: event.ID, Opponent: "Bravo", Format: "bo1", PlayedAt: from}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("second result for one event returned %v, want ErrConflict", err)
+	}
+
+	if err := s.Teams.DeleteTeam(ctx, team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Memberships.GetMember(ctx, team.ID, player.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("membership survived team deletion: %v", err)
+	}
+	if _, err := s.TimeSlots.GetTimeSlot(ctx, slot.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("team slot survived team deletion: %v", err)
+	}
+	if _, err := s.Players.GetPlayer(ctx, player.ID); err != nil {
+		t.Errorf("player should not be deleted with the team: %v", err)
+	}
+	if got, _ := s.Availability.ListAvailability(ctx, team.ID, player.ID, from, to); len(got) != 0 {
+		t.Errorf("availability survived team deletion: %v", got)
+	}
+	if _, err := s.Events.GetEvent(ctx, event.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("event survived team deletion: %v", err)
+	}
+	if _, err := s.Matches.GetMatch(ctx, match.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("match survived team deletion: %v", err)
+	}
+}
+
+func TestMemoryConstraints(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	if err := s.Memberships.AddMember(ctx, store.Member{TeamID: team.ID, UserID: 99, Role: "player"}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("adding an unknown user returned %v, want ErrNotFound", err)
+	}
+	if _, err := s.TimeSlots.CreateTimeSlot(ctx, store.TimeSlot{Weekday: "Monday", Time: "19:00"}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("duplicate global slot returned %v, want ErrConflict", err)
+	}
+
+	first, _ := s.Players.UpsertBattleNetPlayer(ctx, 7, "Old#1")
+	second, _ := s.Players.UpsertBattleNetPlayer(ctx, 7, "New#1")
+	if first.ID != second.ID || second.Username != "New#1" {
+		t.Errorf("upsert created a duplicate player: %+v %+v", first, second)
+	}
+}
+
+func TestMemorySessionTimeouts(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 7, "John#1")
+	now := time.Now().UTC()
+	idle := time.Hour
+
+	active, _ := s.Sessions.CreateSession(ctx, store.Session{UserID: player.ID, Data: []byte("a"), ExpiresAt: now.Add(24 * time.Hour)}, "active")
+	s.Sessions.CreateSession(ctx, store.Session{UserID: player.ID, ExpiresAt: now.Add(time.Minute)}, "ending")
+	if _, err := s.Sessions.CreateSession(ctx, store.Session{UserID: player.ID, ExpiresAt: now.Add(time.Hour)}, "active"); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("duplicate session hash returned %v, want ErrConflict", err)
+	}
+
+	// Using a session keeps it from going idle, but not past its expiry
+	later := now.Add(50 * time.Minute)
+	if got, err := s.Sessions.TouchSession(ctx, "active", later, later.Add(-idle)); err != nil || got.ID != active.ID || string(got.Data) != "a" {
+		t.Errorf("TouchSession = %+v, %v", got, err)
+	}
+	if _, err := s.Sessions.TouchSession(ctx, "ending", later, later.Add(-idle)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expired session returned %v, want ErrNotFound", err)
+	}
+	idleAt := later.Add(idle)
+	if _, err := s.Sessions.TouchSession(ctx, "active", idleAt, idleAt.Add(-idle)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("idle session returned %v, want ErrNotFound", err)
+	}
+	if list, _ := s.Sessions.ListSessions(ctx, player.ID, later, later.Add(-idle)); len(list) != 1 || list[0].ID != active.ID {
+		t.Errorf("ListSessions = %+v, want only the active session", list)
+	}
+
+	if n, err := s.Sessions.PruneSessions(ctx, later, later.Add(-idle)); err != nil || n != 1 {
+		t.Errorf("PruneSessions = %d, %v, want 1", n, err)
+	}
+	s.Players.DeletePlayer(ctx, player.ID)
+	if n, _ := s.Sessions.RevokeSessions(ctx, player.ID); n != 0 {
+		t.Errorf("%d sessions survived deleting their player", n)
+	}
+}
+
+func TestMemorySetGrid(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	grid := store.Grid{TeamID: team.ID, SlotMinutes: 60, Timezone: "Europe/Berlin", Days: []store.GridDay{
+		{Weekday: "Monday", Start: "18:00", End: "22:00"},
+		{Weekday: "Sunday", Start: "22:00", End: "24:00"},
+	}}
+	if err := grid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Grids.SetGrid(ctx, grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 6 || first[5].Weekday != "Sunday" || first[5].Time != "23:00" {
+		t.Fatalf("unexpected slots: %+v", first)
+	}
+
+	grid.Days = grid.Days[:1]
+	second, err := s.Grids.SetGrid(ctx, grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, _ := s.TimeSlots.ListTimeSlots(ctx, team.ID)
+	if len(second) != 4 || len(listed) != 4 {
+		t.Errorf("old slots were not replaced: set %d, listed %d", len(second), len(listed))
+	}
+
+	stored, err := s.Grids.GetGrid(ctx, team.ID)
+	if err != nil || stored.Timezone != "Europe/Berlin" || stored.SlotMinutes != 60 || len(stored.Days) != 1 {
+		t.Errorf("GetGrid = %+v, %v", stored, err)
+	}
+}
+
+func TestMemorySetAvailabilityReplacesWindow(t *testing.T) {
+	s := store.NewMemory()
+	ctx := context.Background()
+
+	team, _ := s.Teams.CreateTeam(ctx, "Alpha")
+	player, _ := s.Players.UpsertBattleNetPlayer(ctx, 42, "John#1234")
+	at := func(day, hour int) time.Time { return time.Date(2025, 5, day, hour, 0, 0, 0, time.UTC) }
+
+	// A long block running across two days
+	if err := s.Availability.SetAvailability(ctx, team.ID, player.ID, at(5, 0), at(7, 0), []store.Interval{{Start: at(5, 18), End: at(6, 22)}}); err != nil {
+		t.Fatal(err)
+	}
+	// Rewriting the second day keeps the first day's part of the block
+	if err := s.Availability.SetAvailability(ctx, team.ID, player.ID, at(6, 0), at(7, 0), []store.Interval{{Start: at(6, 19), End: at(6, 20)}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.Availability.ListAvailability(ctx, team.ID, player.ID, at(1, 0), at(10, 0))
+	want := []store.Interval{{Start: at(5, 18), End: at(6, 0)}, {Start: at(6, 19), End: at(6, 20)}}
+	if len(got) != len(want) || !got[0].Start.Equal(want[0].Start) || !got[0].End.Equal(want[0].End) || !got[1].Start.Equal(want[1].Start) {
+		t.Errorf("availability = %v, want %v", got, want)
+	}
+}
+
+func TestSlotIntervalFollowsDST(t *testing.T) {
+	grid := store.Grid{SlotMinutes: 120, Timezone: "America/New_York"}
+	loc, _ := store.LoadTimezone(grid.Timezone)
+	slot := store.TimeSlot{Weekday: "Monday", Time: "19:00"}
+
+	// US daylight saving time starts on Sunday 8 March 2026
+	before := store.WeekStart(time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC), loc)
+	after := before.AddDate(0, 0, 7)
+
+	cases := []struct {
+		week time.Time
+		want time.Time
+	}{
+		{before, time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)}, // 19:00 EST
+		{after, time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC)}, // 19:00 EDT
+	}
+	for _, c := range cases {
+		iv, err := grid.SlotInterval(slot, c.week)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !iv.Start.Equal(c.want) || iv.End.Sub(iv.Start) != 2*time.Hour {
+			t.Errorf("week of %s: got %v-%v, want start %v", c.week.Format("2006-01-02"), iv.Start, iv.End, c.want)
+		}
+	}
+}
+
+func TestGridValidate(t *testing.T) {
+	cases := map[string]store.Grid{
+		"odd slot length": {SlotMinutes: 50, Timezone: "UTC", Days: []store.GridDay{{Weekday: "Monday", Start: "18:00", End: "22:00"}}},
+		"bad timezone":    {SlotMinutes: 60, Timezone: "Mars/Olympus", Days: []store.GridDay{{Weekday: "Monday", Start: "18:00", End: "22:00"}}},
+		"unknown weekday": {SlotMinutes: 60, Timezone: "UTC", Days: []store.GridDay{{Weekday: "Funday", Start: "18:00", End: "22:00"}}},
+		"too short":       {SlotMinutes: 120, Timezone: "UTC", Days: []store.GridDay{{Weekday: "Monday", Start: "21:00", End: "22:00"}}},
+		"no days":         {SlotMinutes: 60, Timezone: "UTC"},
+	}
+	for name, g := range cases {
+		if g.Validate() == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+	if err := store.DefaultGrid(1).Validate(); err != nil {
+		t.Errorf("default grid is invalid: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// DiscordSettings is a team's Discord webhook and when it is notified
+// before events.
+type DiscordSettings struct {
+	TeamID     int
+	WebhookURL string
+	// ReminderMinutes is how long before an event a reminder is posted.
+	// Zero turns reminders off.
+	ReminderMinutes int
+	// Quorum is the number of yes RSVPs an event needs. When fewer members
+	// have said yes QuorumMinutes before it starts, a warning is posted.
+	// Zero turns the check off.
+	Quorum        int
+	QuorumMinutes int
+}
+
+// OutboxMessage is a notification waiting to be delivered to a team's
+// Discord webhook. Messages are retried until they are sent or given up.
+type OutboxMessage struct {
+	ID     int
+	TeamID int
+	// Key deduplicates scheduled notifications such as reminders; at most
+	// one message is ever queued per non-empty key.
+	Key         string
+	Payload     []byte // JSON webhook body
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	CreatedAt   time.Time
+}
+
+// NotificationStore persists Discord settings and the notification outbox.
+type NotificationStore interface {
+	// GetDiscordSettings returns ErrNotFound when the team has no webhook.
+	GetDiscordSettings(ctx context.Context, teamID int) (DiscordSettings, error)
+	ListDiscordSettings(ctx context.Context) ([]DiscordSettings, error)
+	SetDiscordSettings(ctx context.Context, s DiscordSettings) error
+	DeleteDiscordSettings(ctx context.Context, teamID int) error
+
+	// EnqueueOutbox queues m for delivery at m.NextAttempt (now when zero).
+	// It reports false, without error, when a message with the same
+	// non-empty key was queued before.
+	EnqueueOutbox(ctx context.Context, m OutboxMessage) (bool, error)
+	// ClaimOutbox returns up to limit messages due at now and hides them
+	// from other callers for lease, so concurrent workers don't deliver the
+	// same message twice.
+	ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, id int) error
+	// MarkOutboxFailed records a failed attempt. The message is retried at
+	// next, or never again when next is zero.
+	MarkOutboxFailed(ctx context.Context, id int, next time.Time, lastError string) error
+}
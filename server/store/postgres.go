@@ -0,0 +1,523 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres implements every store interface on top of a *sql.DB.
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres returns a Store backed by db.
+func NewPostgres(db *sql.DB) *Store {
+	p := &Postgres{db: db}
+	return &Store{
+		Teams:         p,
+		Players:       p,
+		Memberships:   p,
+		TimeSlots:     p,
+		Grids:         p,
+		Availability:  p,
+		Events:        p,
+		FeedTokens:    p,
+		Notifications: p,
+		DiscordLinks:  p,
+		Email:         p,
+		Jobs:          p,
+		Invites:       p,
+		Profiles:      p,
+		Rosters:       p,
+		Matches:       p,
+		APITokens:     p,
+		Sessions:      p,
+	}
+}
+
+// mapError translates driver errors into the package's sentinel errors.
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505": // unique_violation
+			return ErrConflict
+		case "23503": // foreign_key_violation
+			return ErrNotFound
+		}
+	}
+	return err
+}
+
+// expectRows returns ErrNotFound when an UPDATE or DELETE matched nothing.
+func expectRows(res sql.Result, err error) error {
+	if err != nil {
+		return mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// nullableID maps the zero ID to NULL.
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+func (p *Postgres) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *Postgres) ListTeams(ctx context.Context) ([]Team, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT id, name FROM teams ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+func (p *Postgres) GetTeam(ctx context.Context, id int) (Team, error) {
+	var t Team
+	err := p.db.QueryRowContext(ctx, "SELECT id, name FROM teams WHERE id = $1", id).Scan(&t.ID, &t.Name)
+	return t, mapError(err)
+}
+
+func (p *Postgres) CreateTeam(ctx context.Context, name string) (Team, error) {
+	t := Team{Name: name}
+	err := p.db.QueryRowContext(ctx, "INSERT INTO teams (name) VALUES ($1) RETURNING id", name).Scan(&t.ID)
+	return t, mapError(err)
+}
+
+func (p *Postgres) UpdateTeam(ctx context.Context, id int, name string) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE teams SET name = $1 WHERE id = $2", name, id))
+}
+
+func (p *Postgres) DeleteTeam(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM teams WHERE id = $1", id))
+}
+
+const playerColumns = "SELECT id, user_id, username, is_admin, timezone, COALESCE(active_team_id, 0) FROM users"
+
+// playerFields returns the scan destinations for playerColumns.
+func playerFields(pl *Player) []any {
+	return []any{&pl.ID, &pl.BattleNetID, &pl.Username, &pl.IsAdmin, &pl.Timezone, &pl.ActiveTeamID}
+}
+
+func (p *Postgres) ListPlayers(ctx context.Context) ([]Player, error) {
+	rows, err := p.db.QueryContext(ctx, playerColumns+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []Player
+	for rows.Next() {
+		var pl Player
+		if err := rows.Scan(playerFields(&pl)...); err != nil {
+			return nil, err
+		}
+		players = append(players, pl)
+	}
+	return players, rows.Err()
+}
+
+func (p *Postgres) GetPlayer(ctx context.Context, id int) (Player, error) {
+	var pl Player
+	err := p.db.QueryRowContext(ctx, playerColumns+" WHERE id = $1", id).
+		Scan(playerFields(&pl)...)
+	return pl, mapError(err)
+}
+
+func (p *Postgres) GetPlayerByBattleNetID(ctx context.Context, battleNetID int64) (Player, error) {
+	var pl Player
+	err := p.db.QueryRowContext(ctx, playerColumns+" WHERE user_id = $1", battleNetID).
+		Scan(playerFields(&pl)...)
+	return pl, mapError(err)
+}
+
+func (p *Postgres) GetPlayerByUsername(ctx context.Context, username string) (Player, error) {
+	var pl Player
+	err := p.db.QueryRowContext(ctx, playerColumns+" WHERE lower(username) = lower($1) ORDER BY id LIMIT 1", username).
+		Scan(playerFields(&pl)...)
+	return pl, mapError(err)
+}
+
+func (p *Postgres) UpsertBattleNetPlayer(ctx context.Context, battleNetID int64, username string) (Player, error) {
+	pl, err := p.GetPlayerByBattleNetID(ctx, battleNetID)
+	if err == nil {
+		if pl.Username != username {
+			if err := p.UpdatePlayer(ctx, pl.ID, username); err != nil {
+				return Player{}, err
+			}
+			pl.Username = username
+		}
+		return pl, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return Player{}, err
+	}
+
+	pl = Player{BattleNetID: battleNetID, Username: username, Timezone: "UTC"}
+	err = p.db.QueryRowContext(ctx, "INSERT INTO users (username, user_id) VALUES ($1, $2) RETURNING id", username, battleNetID).
+		Scan(&pl.ID)
+	return pl, mapError(err)
+}
+
+func (p *Postgres) UpdatePlayer(ctx context.Context, id int, username string) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE users SET username = $1 WHERE id = $2", username, id))
+}
+
+func (p *Postgres) SetPlayerAdmin(ctx context.Context, id int, admin bool) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE users SET is_admin = $1 WHERE id = $2", admin, id))
+}
+
+func (p *Postgres) SetPlayerTimezone(ctx context.Context, id int, timezone string) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE users SET timezone = $1 WHERE id = $2", timezone, id))
+}
+
+func (p *Postgres) SetActiveTeam(ctx context.Context, id, teamID int) error {
+	if teamID == 0 {
+		return expectRows(p.db.ExecContext(ctx, "UPDATE users SET active_team_id = NULL WHERE id = $1", id))
+	}
+	return expectRows(p.db.ExecContext(ctx, `
+		UPDATE users SET active_team_id = $2
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM team_members WHERE user_id = $1 AND team_id = $2)`, id, teamID))
+}
+
+func (p *Postgres) DeletePlayer(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id))
+}
+
+const memberColumns = `
+	SELECT tm.team_id, t.name, tm.user_id, u.username, tm.role, tm.game_role
+	FROM team_members tm
+	JOIN users u ON u.id = tm.user_id
+	JOIN teams t ON t.id = tm.team_id`
+
+func scanMembers(rows *sql.Rows) ([]Member, error) {
+	defer rows.Close()
+	var members []Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.TeamID, &m.TeamName, &m.UserID, &m.Username, &m.Role, &m.GameRole); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (p *Postgres) ListMembers(ctx context.Context, teamID int) ([]Member, error) {
+	rows, err := p.db.QueryContext(ctx, memberColumns+" WHERE tm.team_id = $1 ORDER BY u.username", teamID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMembers(rows)
+}
+
+func (p *Postgres) ListMemberships(ctx context.Context, userID int) ([]Member, error) {
+	rows, err := p.db.QueryContext(ctx, memberColumns+" WHERE tm.user_id = $1 ORDER BY tm.team_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMembers(rows)
+}
+
+func (p *Postgres) GetMember(ctx context.Context, teamID, userID int) (Member, error) {
+	var m Member
+	err := p.db.QueryRowContext(ctx, memberColumns+" WHERE tm.team_id = $1 AND tm.user_id = $2", teamID, userID).
+		Scan(&m.TeamID, &m.TeamName, &m.UserID, &m.Username, &m.Role, &m.GameRole)
+	return m, mapError(err)
+}
+
+func (p *Postgres) AddMember(ctx context.Context, m Member) error {
+	if m.GameRole == "" {
+		_, err := addMember(ctx, p.db, m)
+		return err
+	}
+	return p.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkRoleLimit(ctx, tx, m.TeamID, m.UserID, m.GameRole); err != nil {
+			return err
+		}
+		_, err := addMember(ctx, tx, m)
+		return err
+	})
+}
+
+// addMember adds a membership, which settles any pending join request
+// between the player and the team.
+func addMember(ctx context.Context, q queryer, m Member) (Member, error) {
+	err := q.QueryRowContext(ctx, `
+		WITH settled AS (DELETE FROM join_requests WHERE user_id = $1 AND team_id = $2),
+		added AS (INSERT INTO team_members (user_id, team_id, role, game_role) VALUES ($1, $2, $3, $4) RETURNING user_id)
+		SELECT u.username, t.name FROM added JOIN users u ON u.id = added.user_id JOIN teams t ON t.id = $2`,
+		m.UserID, m.TeamID, m.Role, m.GameRole).Scan(&m.Username, &m.TeamName)
+	return m, mapError(err)
+}
+
+func (p *Postgres) UpdateMemberRole(ctx context.Context, teamID, userID int, role string) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE team_members SET role = $1 WHERE user_id = $2 AND team_id = $3", role, userID, teamID))
+}
+
+func (p *Postgres) SetMemberGameRole(ctx context.Context, teamID, userID int, gameRole string) error {
+	return p.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkRoleLimit(ctx, tx, teamID, userID, gameRole); err != nil {
+			return err
+		}
+		return expectRows(tx.ExecContext(ctx, "UPDATE team_members SET game_role = $1 WHERE user_id = $2 AND team_id = $3", gameRole, userID, teamID))
+	})
+}
+
+func (p *Postgres) RemoveMember(ctx context.Context, teamID, userID int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM team_members WHERE user_id = $1 AND team_id = $2", userID, teamID))
+}
+
+func (p *Postgres) ListTimeSlots(ctx context.Context, teamID int) ([]TimeSlot, error) {
+	return listTimeSlots(ctx, p.db, teamID)
+}
+
+func listTimeSlots(ctx context.Context, q queryer, teamID int) ([]TimeSlot, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT slot_id, COALESCE(team_id, 0), weekday, to_char(time, 'HH24:MI')
+		FROM time_slots
+		WHERE team_id IS NOT DISTINCT FROM $1`, nullableID(teamID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slots []TimeSlot
+	for rows.Next() {
+		var s TimeSlot
+		if err := rows.Scan(&s.ID, &s.TeamID, &s.Weekday, &s.Time); err != nil {
+			return nil, err
+		}
+		slots = append(slots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortTimeSlots(slots)
+	return slots, nil
+}
+
+func (p *Postgres) GetTimeSlot(ctx context.Context, id int) (TimeSlot, error) {
+	var s TimeSlot
+	err := p.db.QueryRowContext(ctx, `
+		SELECT slot_id, COALESCE(team_id, 0), weekday, to_char(time, 'HH24:MI')
+		FROM time_slots WHERE slot_id = $1`, id).
+		Scan(&s.ID, &s.TeamID, &s.Weekday, &s.Time)
+	return s, mapError(err)
+}
+
+func (p *Postgres) CreateTimeSlot(ctx context.Context, slot TimeSlot) (TimeSlot, error) {
+	err := p.db.QueryRowContext(ctx, "INSERT INTO time_slots (weekday, time, team_id) VALUES ($1, $2, $3) RETURNING slot_id",
+		slot.Weekday, slot.Time, nullableID(slot.TeamID)).Scan(&slot.ID)
+	return slot, mapError(err)
+}
+
+func (p *Postgres) UpdateTimeSlot(ctx context.Context, slot TimeSlot) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE time_slots SET weekday = $1, time = $2 WHERE slot_id = $3",
+		slot.Weekday, slot.Time, slot.ID))
+}
+
+func (p *Postgres) DeleteTimeSlot(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM time_slots WHERE slot_id = $1", id))
+}
+
+// queryer is the subset of *sql.DB and *sql.Tx used by helpers that run
+// inside or outside a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (p *Postgres) GetGrid(ctx context.Context, teamID int) (Grid, error) {
+	if _, err := p.GetTeam(ctx, teamID); err != nil {
+		return Grid{}, err
+	}
+	return getGrid(ctx, p.db, teamID)
+}
+
+func getGrid(ctx context.Context, q queryer, teamID int) (Grid, error) {
+	g := Grid{TeamID: teamID}
+	err := q.QueryRowContext(ctx, "SELECT slot_minutes, timezone FROM team_grids WHERE team_id = $1", teamID).
+		Scan(&g.SlotMinutes, &g.Timezone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultGrid(teamID), nil
+	}
+	if err != nil {
+		return Grid{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM team_grid_days WHERE team_id = $1`, teamID)
+	if err != nil {
+		return Grid{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d GridDay
+		if err := rows.Scan(&d.Weekday, &d.Start, &d.End); err != nil {
+			return Grid{}, err
+		}
+		g.Days = append(g.Days, d)
+	}
+	sort.Slice(g.Days, func(i, j int) bool {
+		return WeekdayIndex(g.Days[i].Weekday) < WeekdayIndex(g.Days[j].Weekday)
+	})
+	return g, rows.Err()
+}
+
+func (p *Postgres) SetGrid(ctx context.Context, g Grid) ([]TimeSlot, error) {
+	slots := g.Slots()
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		stmts := []string{
+			"DELETE FROM time_slots WHERE team_id = $1",
+			"DELETE FROM team_grid_days WHERE team_id = $1",
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt, g.TeamID); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO team_grids (team_id, slot_minutes, timezone) VALUES ($1, $2, $3)
+			ON CONFLICT (team_id) DO UPDATE SET slot_minutes = EXCLUDED.slot_minutes, timezone = EXCLUDED.timezone`,
+			g.TeamID, g.SlotMinutes, g.Timezone)
+		if err != nil {
+			return mapError(err)
+		}
+		for _, d := range g.Days {
+			_, err := tx.ExecContext(ctx, "INSERT INTO team_grid_days (team_id, weekday, start_time, end_time) VALUES ($1, $2, $3, $4)",
+				g.TeamID, d.Weekday, d.Start, d.End)
+			if err != nil {
+				return mapError(err)
+			}
+		}
+		for i := range slots {
+			err := tx.QueryRowContext(ctx, "INSERT INTO time_slots (weekday, time, team_id) VALUES ($1, $2, $3) RETURNING slot_id",
+				slots[i].Weekday, slots[i].Time, g.TeamID).Scan(&slots[i].ID)
+			if err != nil {
+				return mapError(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+func (p *Postgres) ListAvailability(ctx context.Context, teamID, userID int, from, to time.Time) ([]Interval, error) {
+	return listAvailability(ctx, p.db, teamID, userID, from, to)
+}
+
+func listAvailability(ctx context.Context, q queryer, teamID, userID int, from, to time.Time) ([]Interval, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT starts_at, ends_at FROM availability
+		WHERE team_id = $1 AND user_id = $2 AND starts_at < $4 AND ends_at > $3
+		ORDER BY starts_at`, teamID, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var intervals []Interval
+	for rows.Next() {
+		var iv Interval
+		if err := rows.Scan(&iv.Start, &iv.End); err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, Interval{Start: iv.Start.UTC(), End: iv.End.UTC()})
+	}
+	return intervals, rows.Err()
+}
+
+func (p *Postgres) ListTeamAvailability(ctx context.Context, teamID int, from, to time.Time) (map[int][]Interval, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT user_id, starts_at, ends_at FROM availability
+		WHERE team_id = $1 AND starts_at < $3 AND ends_at > $2
+		ORDER BY user_id, starts_at`, teamID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byUser := map[int][]Interval{}
+	for rows.Next() {
+		var userID int
+		var iv Interval
+		if err := rows.Scan(&userID, &iv.Start, &iv.End); err != nil {
+			return nil, err
+		}
+		byUser[userID] = append(byUser[userID], Interval{Start: iv.Start.UTC(), End: iv.End.UTC()})
+	}
+	return byUser, rows.Err()
+}
+
+func (p *Postgres) SetAvailability(ctx context.Context, teamID, userID int, from, to time.Time, intervals []Interval) error {
+	return p.inTx(ctx, func(tx *sql.Tx) error {
+		// Rows straddling the window edges are rewritten with only the part
+		// outside the window kept
+		existing, err := listAvailability(ctx, tx, teamID, userID, from, to)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM availability WHERE team_id = $1 AND user_id = $2 AND starts_at < $4 AND ends_at > $3",
+			teamID, userID, from, to)
+		if err != nil {
+			return err
+		}
+		for _, iv := range replaceWindow(existing, from, to, intervals) {
+			_, err := tx.ExecContext(ctx, "INSERT INTO availability (user_id, team_id, starts_at, ends_at) VALUES ($1, $2, $3, $4)",
+				userID, teamID, iv.Start, iv.End)
+			if err != nil {
+				return mapError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Postgres) PruneAvailability(ctx context.Context, before time.Time) (int, error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM availability WHERE ends_at <= $1", before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
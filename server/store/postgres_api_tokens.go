@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const apiTokenColumns = `
+	SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+	FROM api_tokens`
+
+func scanAPIToken(row interface{ Scan(...any) error }) (APIToken, error) {
+	var t APIToken
+	var lastUsed sql.NullTime
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, pq.Array(&t.Scopes), &t.ExpiresAt, &lastUsed, &t.CreatedAt)
+	t.LastUsedAt = lastUsed.Time
+	return t, err
+}
+
+func (p *Postgres) CreateAPIToken(ctx context.Context, t APIToken, hash string) (APIToken, error) {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (user_id, token_hash, name, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		t.UserID, hash, t.Name, pq.Array(t.Scopes), t.ExpiresAt).
+		Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return APIToken{}, mapError(err)
+	}
+	t.LastUsedAt = time.Time{}
+	return t, nil
+}
+
+func (p *Postgres) ListAPITokens(ctx context.Context, userID int) ([]APIToken, error) {
+	rows, err := p.db.QueryContext(ctx, apiTokenColumns+" WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (p *Postgres) DeleteAPIToken(ctx context.Context, userID, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", id, userID))
+}
+
+func (p *Postgres) UseAPIToken(ctx context.Context, hash string, now time.Time) (APIToken, error) {
+	t, err := scanAPIToken(p.db.QueryRowContext(ctx, `
+		UPDATE api_tokens SET last_used_at = $2
+		WHERE token_hash = $1 AND expires_at > $2
+		RETURNING id, user_id, name, scopes, expires_at, last_used_at, created_at`, hash, now))
+	return t, mapError(err)
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (p *Postgres) SetDiscordLinkCode(ctx context.Context, userID int, hash string, expires time.Time) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO discord_link_codes (user_id, code_hash, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET code_hash = EXCLUDED.code_hash, expires_at = EXCLUDED.expires_at`,
+		userID, hash, expires)
+	return mapError(err)
+}
+
+func (p *Postgres) RedeemDiscordLinkCode(ctx context.Context, hash string, l DiscordLink, now time.Time) (DiscordLink, error) {
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM discord_link_codes WHERE code_hash = $1 AND expires_at > $2
+			RETURNING user_id`, hash, now).Scan(&l.UserID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM discord_links WHERE discord_user_id = $1 AND user_id <> $2", l.DiscordUserID, l.UserID); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO discord_links (user_id, discord_user_id, discord_username, linked_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET discord_user_id = EXCLUDED.discord_user_id,
+				discord_username = EXCLUDED.discord_username, linked_at = EXCLUDED.linked_at
+			RETURNING linked_at`,
+			l.UserID, l.DiscordUserID, l.DiscordUsername, now).Scan(&l.LinkedAt)
+	})
+	if err != nil {
+		return DiscordLink{}, mapError(err)
+	}
+	return l, nil
+}
+
+func (p *Postgres) GetDiscordLink(ctx context.Context, userID int) (DiscordLink, error) {
+	var l DiscordLink
+	err := p.db.QueryRowContext(ctx, `
+		SELECT user_id, discord_user_id, discord_username, linked_at
+		FROM discord_links WHERE user_id = $1`, userID).
+		Scan(&l.UserID, &l.DiscordUserID, &l.DiscordUsername, &l.LinkedAt)
+	return l, mapError(err)
+}
+
+func (p *Postgres) DiscordLinkUser(ctx context.Context, discordUserID string) (int, error) {
+	var userID int
+	err := p.db.QueryRowContext(ctx, "SELECT user_id FROM discord_links WHERE discord_user_id = $1", discordUserID).Scan(&userID)
+	return userID, mapError(err)
+}
+
+func (p *Postgres) DeleteDiscordLink(ctx context.Context, userID int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM discord_links WHERE user_id = $1", userID))
+}
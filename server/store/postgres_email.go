@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (p *Postgres) GetEmailPreferences(ctx context.Context, userID int) (EmailPreferences, error) {
+	var e EmailPreferences
+	err := p.db.QueryRowContext(ctx, `
+		SELECT user_id, email, reminders, reminder_minutes, digest, availability_alerts
+		FROM email_preferences WHERE user_id = $1`, userID).
+		Scan(&e.UserID, &e.Email, &e.Reminders, &e.ReminderMinutes, &e.Digest, &e.AvailabilityAlerts)
+	return e, mapError(err)
+}
+
+func (p *Postgres) ListEmailPreferences(ctx context.Context) ([]EmailPreferences, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT user_id, email, reminders, reminder_minutes, digest, availability_alerts
+		FROM email_preferences WHERE reminders OR digest OR availability_alerts
+		ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []EmailPreferences
+	for rows.Next() {
+		var e EmailPreferences
+		if err := rows.Scan(&e.UserID, &e.Email, &e.Reminders, &e.ReminderMinutes, &e.Digest, &e.AvailabilityAlerts); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, e)
+	}
+	return prefs, rows.Err()
+}
+
+func (p *Postgres) SetEmailPreferences(ctx context.Context, e EmailPreferences) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO email_preferences (user_id, email, reminders, reminder_minutes, digest, availability_alerts)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET email = EXCLUDED.email, reminders = EXCLUDED.reminders,
+			reminder_minutes = EXCLUDED.reminder_minutes, digest = EXCLUDED.digest,
+			availability_alerts = EXCLUDED.availability_alerts, updated_at = now()`,
+		e.UserID, e.Email, e.Reminders, e.ReminderMinutes, e.Digest, e.AvailabilityAlerts)
+	return mapError(err)
+}
+
+func (p *Postgres) DeleteEmailPreferences(ctx context.Context, userID int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM email_preferences WHERE user_id = $1", userID))
+}
+
+func (p *Postgres) ClaimEmail(ctx context.Context, key string, userID int, now time.Time) (bool, error) {
+	res, err := p.db.ExecContext(ctx, `
+		INSERT INTO email_deliveries (dedupe_key, user_id, sent_at) VALUES ($1, $2, $3)
+		ON CONFLICT (dedupe_key) DO NOTHING`, key, userID, now)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *Postgres) ReleaseEmail(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM email_deliveries WHERE dedupe_key = $1", key)
+	return err
+}
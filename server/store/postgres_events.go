@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const eventColumns = `
+	SELECT id, team_id, type, title, starts_at, ends_at, opponent, location,
+		recurrence, timezone, cancelled_at IS NOT NULL, COALESCE(created_by, 0)
+	FROM events`
+
+func scanEvent(row interface{ Scan(...any) error }) (Event, error) {
+	var e Event
+	err := row.Scan(&e.ID, &e.TeamID, &e.Type, &e.Title, &e.Start, &e.End, &e.Opponent, &e.Location, &e.Recurrence, &e.Timezone, &e.Cancelled, &e.CreatedBy)
+	e.Start, e.End = e.Start.UTC(), e.End.UTC()
+	return e, err
+}
+
+// nullableTime maps the zero time to NULL.
+func nullableTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// lastStart is the value of events.last_starts_at: the start of the final
+// occurrence, or NULL for series that repeat forever.
+func lastStart(e Event) (sql.NullTime, error) {
+	last, ok, err := e.LastStart()
+	return sql.NullTime{Time: last, Valid: ok}, err
+}
+
+func (p *Postgres) ListEvents(ctx context.Context, teamID int, from, to time.Time) ([]Event, error) {
+	rows, err := p.db.QueryContext(ctx, eventColumns+`
+		WHERE team_id = $1
+			AND ($2::timestamptz IS NULL OR last_starts_at IS NULL OR last_starts_at >= $2)
+			AND ($3::timestamptz IS NULL OR starts_at < $3)
+		ORDER BY starts_at, id`, teamID, nullableTime(from), nullableTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (p *Postgres) GetEvent(ctx context.Context, id int) (Event, error) {
+	e, err := scanEvent(p.db.QueryRowContext(ctx, eventColumns+" WHERE id = $1", id))
+	return e, mapError(err)
+}
+
+func (p *Postgres) CreateEvent(ctx context.Context, e Event) (Event, error) {
+	last, err := lastStart(e)
+	if err != nil {
+		return e, err
+	}
+	err = p.db.QueryRowContext(ctx, `
+		INSERT INTO events (team_id, type, title, starts_at, ends_at, opponent, location, recurrence, timezone, last_starts_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		e.TeamID, e.Type, e.Title, e.Start, e.End, e.Opponent, e.Location, e.Recurrence, e.Timezone, last, nullableID(e.CreatedBy)).Scan(&e.ID)
+	e.Start, e.End = e.Start.UTC(), e.End.UTC()
+	return e, mapError(err)
+}
+
+func (p *Postgres) UpdateEvent(ctx context.Context, e Event) error {
+	last, err := lastStart(e)
+	if err != nil {
+		return err
+	}
+	return expectRows(p.db.ExecContext(ctx, `
+		UPDATE events SET type = $1, title = $2, starts_at = $3, ends_at = $4, opponent = $5, location = $6,
+			recurrence = $7, timezone = $8, last_starts_at = $9
+		WHERE id = $10`,
+		e.Type, e.Title, e.Start, e.End, e.Opponent, e.Location, e.Recurrence, e.Timezone, last, e.ID))
+}
+
+func (p *Postgres) CancelEvent(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE events SET cancelled_at = COALESCE(cancelled_at, now()) WHERE id = $1", id))
+}
+
+func (p *Postgres) ListRSVPs(ctx context.Context, eventID int) ([]RSVP, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT r.event_id, r.user_id, u.username, r.status, r.updated_at
+		FROM event_rsvps r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.event_id = $1
+		ORDER BY u.username`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rsvps []RSVP
+	for rows.Next() {
+		var r RSVP
+		if err := rows.Scan(&r.EventID, &r.UserID, &r.Username, &r.Status, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rsvps = append(rsvps, r)
+	}
+	return rsvps, rows.Err()
+}
+
+func (p *Postgres) SetRSVP(ctx context.Context, r RSVP) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO event_rsvps (event_id, user_id, status) VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET status = EXCLUDED.status, updated_at = now()`,
+		r.EventID, r.UserID, r.Status)
+	return mapError(err)
+}
+
+func (p *Postgres) ListEventExceptions(ctx context.Context, eventID int) ([]EventException, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT event_id, original_starts_at, cancelled, starts_at, ends_at, title, opponent, location
+		FROM event_exceptions
+		WHERE event_id = $1
+		ORDER BY original_starts_at`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []EventException
+	for rows.Next() {
+		var x EventException
+		if err := rows.Scan(&x.EventID, &x.OriginalStart, &x.Cancelled, &x.Start, &x.End, &x.Title, &x.Opponent, &x.Location); err != nil {
+			return nil, err
+		}
+		x.OriginalStart, x.Start, x.End = x.OriginalStart.UTC(), x.Start.UTC(), x.End.UTC()
+		exceptions = append(exceptions, x)
+	}
+	return exceptions, rows.Err()
+}
+
+func (p *Postgres) SetEventException(ctx context.Context, x EventException) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO event_exceptions (event_id, original_starts_at, cancelled, starts_at, ends_at, title, opponent, location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (event_id, original_starts_at) DO UPDATE SET
+			cancelled = EXCLUDED.cancelled, starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at,
+			title = EXCLUDED.title, opponent = EXCLUDED.opponent, location = EXCLUDED.location`,
+		x.EventID, x.OriginalStart, x.Cancelled, x.Start, x.End, x.Title, x.Opponent, x.Location)
+	return mapError(err)
+}
+
+func (p *Postgres) DeleteEventException(ctx context.Context, eventID int, originalStart time.Time) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM event_exceptions WHERE event_id = $1 AND original_starts_at = $2", eventID, originalStart))
+}
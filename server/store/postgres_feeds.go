@@ -0,0 +1,21 @@
+package store
+
+import "context"
+
+func (p *Postgres) SetFeedToken(ctx context.Context, userID int, hash string) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO calendar_feed_tokens (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()`,
+		userID, hash)
+	return mapError(err)
+}
+
+func (p *Postgres) DeleteFeedToken(ctx context.Context, userID int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM calendar_feed_tokens WHERE user_id = $1", userID))
+}
+
+func (p *Postgres) FeedTokenUser(ctx context.Context, hash string) (int, error) {
+	var userID int
+	err := p.db.QueryRowContext(ctx, "SELECT user_id FROM calendar_feed_tokens WHERE token_hash = $1", hash).Scan(&userID)
+	return userID, mapError(err)
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const inviteLinkColumns = `
+	SELECT id, team_id, role, COALESCE(created_by, 0), expires_at, max_uses, uses, created_at
+	FROM invite_links`
+
+func scanInviteLink(row interface{ Scan(...any) error }) (InviteLink, error) {
+	var l InviteLink
+	var expires sql.NullTime
+	err := row.Scan(&l.ID, &l.TeamID, &l.Role, &l.CreatedBy, &expires, &l.MaxUses, &l.Uses, &l.CreatedAt)
+	l.ExpiresAt = expires.Time
+	return l, err
+}
+
+func (p *Postgres) CreateInviteLink(ctx context.Context, l InviteLink, hash string) (InviteLink, error) {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO invite_links (team_id, token_hash, role, created_by, expires_at, max_uses)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, uses, created_at`,
+		l.TeamID, hash, l.Role, nullableID(l.CreatedBy), nullableTime(l.ExpiresAt), l.MaxUses).
+		Scan(&l.ID, &l.Uses, &l.CreatedAt)
+	if err != nil {
+		return InviteLink{}, mapError(err)
+	}
+	return l, nil
+}
+
+func (p *Postgres) ListInviteLinks(ctx context.Context, teamID int) ([]InviteLink, error) {
+	rows, err := p.db.QueryContext(ctx, inviteLinkColumns+" WHERE team_id = $1 ORDER BY id", teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var links []InviteLink
+	for rows.Next() {
+		l, err := scanInviteLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (p *Postgres) DeleteInviteLink(ctx context.Context, teamID, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM invite_links WHERE id = $1 AND team_id = $2", id, teamID))
+}
+
+func (p *Postgres) RedeemInviteLink(ctx context.Context, hash string, userID int, now time.Time) (Member, error) {
+	var m Member
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		// The row lock keeps concurrent redemptions from going over max_uses
+		err := tx.QueryRowContext(ctx, `
+			SELECT team_id, role FROM invite_links
+			WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > $2)
+				AND (max_uses = 0 OR uses < max_uses)
+			FOR UPDATE`, hash, now).Scan(&m.TeamID, &m.Role)
+		if err != nil {
+			return err
+		}
+		m.UserID = userID
+		if m, err = addMember(ctx, tx, m); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE invite_links SET uses = uses + 1 WHERE token_hash = $1", hash)
+		return err
+	})
+	if err != nil {
+		return Member{}, mapError(err)
+	}
+	return m, nil
+}
+
+const joinRequestColumns = `
+	SELECT jr.id, jr.team_id, t.name, jr.user_id, u.username, jr.invited, jr.role, jr.message,
+		COALESCE(jr.created_by, 0), jr.created_at
+	FROM join_requests jr
+	JOIN teams t ON t.id = jr.team_id
+	JOIN users u ON u.id = jr.user_id`
+
+func scanJoinRequest(row interface{ Scan(...any) error }) (JoinRequest, error) {
+	var jr JoinRequest
+	err := row.Scan(&jr.ID, &jr.TeamID, &jr.TeamName, &jr.UserID, &jr.Username, &jr.Invited, &jr.Role, &jr.Message, &jr.CreatedBy, &jr.CreatedAt)
+	return jr, err
+}
+
+func (p *Postgres) listJoinRequests(ctx context.Context, where string, arg any) ([]JoinRequest, error) {
+	rows, err := p.db.QueryContext(ctx, joinRequestColumns+" WHERE "+where+" ORDER BY jr.id", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []JoinRequest
+	for rows.Next() {
+		jr, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, jr)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) CreateJoinRequest(ctx context.Context, jr JoinRequest) (JoinRequest, error) {
+	var id int
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		var member bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM team_members WHERE team_id = $1 AND user_id = $2)", jr.TeamID, jr.UserID).Scan(&member)
+		if err != nil {
+			return err
+		}
+		if member {
+			return ErrConflict
+		}
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO join_requests (team_id, user_id, invited, role, message, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`,
+			jr.TeamID, jr.UserID, jr.Invited, jr.Role, jr.Message, nullableID(jr.CreatedBy)).Scan(&id)
+	})
+	if err != nil {
+		return JoinRequest{}, mapError(err)
+	}
+	return p.GetJoinRequest(ctx, id)
+}
+
+func (p *Postgres) GetJoinRequest(ctx context.Context, id int) (JoinRequest, error) {
+	jr, err := scanJoinRequest(p.db.QueryRowContext(ctx, joinRequestColumns+" WHERE jr.id = $1", id))
+	return jr, mapError(err)
+}
+
+func (p *Postgres) ListTeamJoinRequests(ctx context.Context, teamID int) ([]JoinRequest, error) {
+	return p.listJoinRequests(ctx, "jr.team_id = $1", teamID)
+}
+
+func (p *Postgres) ListPlayerJoinRequests(ctx context.Context, userID int) ([]JoinRequest, error) {
+	return p.listJoinRequests(ctx, "jr.user_id = $1", userID)
+}
+
+func (p *Postgres) AcceptJoinRequest(ctx context.Context, id int, role string) (Member, error) {
+	var m Member
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT team_id, user_id, CASE WHEN $2 = '' THEN role ELSE $2 END
+			FROM join_requests WHERE id = $1 FOR UPDATE`, id, role).Scan(&m.TeamID, &m.UserID, &m.Role)
+		if err != nil {
+			return err
+		}
+		// addMember deletes the request
+		m, err = addMember(ctx, tx, m)
+		return err
+	})
+	if err != nil {
+		return Member{}, mapError(err)
+	}
+	return m, nil
+}
+
+func (p *Postgres) DeleteJoinRequest(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM join_requests WHERE id = $1", id))
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (p *Postgres) EnqueueJob(ctx context.Context, j Job) (bool, error) {
+	var key sql.NullString
+	if j.Key != "" {
+		key = sql.NullString{String: j.Key, Valid: true}
+	}
+	res, err := p.db.ExecContext(ctx, `
+		INSERT INTO jobs (kind, dedupe_key, payload, run_at, max_attempts)
+		VALUES ($1, $2, $3, COALESCE($4, now()), $5)
+		ON CONFLICT (dedupe_key) DO NOTHING`,
+		j.Kind, key, j.Payload, nullableTime(j.RunAt), j.MaxAttempts)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *Postgres) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		UPDATE jobs j
+		SET attempts = j.attempts + 1, run_at = $2
+		FROM (
+			SELECT id FROM jobs
+			WHERE finished_at IS NULL AND run_at <= $1
+			ORDER BY run_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE j.id = due.id
+		RETURNING j.id, j.kind, COALESCE(j.dedupe_key, ''), j.payload, j.run_at, j.attempts, j.max_attempts, j.last_error, j.created_at`,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claimed []Job
+	for rows.Next() {
+		var j Job
+		if err := rows.Scan(&j.ID, &j.Kind, &j.Key, &j.Payload, &j.RunAt, &j.Attempts, &j.MaxAttempts, &j.LastError, &j.CreatedAt); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, j)
+	}
+	return claimed, rows.Err()
+}
+
+func (p *Postgres) CompleteJob(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE jobs SET finished_at = now(), last_error = '' WHERE id = $1", id))
+}
+
+func (p *Postgres) FailJob(ctx context.Context, id int, next time.Time, lastError string) error {
+	return expectRows(p.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET last_error = $2,
+			run_at = COALESCE($3, run_at),
+			finished_at = CASE WHEN $3::timestamptz IS NULL THEN now() END
+		WHERE id = $1`, id, lastError, nullableTime(next)))
+}
+
+func (p *Postgres) PruneJobs(ctx context.Context, before time.Time) (int, error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM jobs WHERE finished_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
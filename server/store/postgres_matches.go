@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const matchColumns = `
+	SELECT id, team_id, COALESCE(event_id, 0), event_starts_at, opponent, competition, format,
+		played_at, vod_url, notes, COALESCE(created_by, 0)
+	FROM matches`
+
+func scanMatch(row interface{ Scan(...any) error }) (Match, error) {
+	var m Match
+	var eventStart sql.NullTime
+	err := row.Scan(&m.ID, &m.TeamID, &m.EventID, &eventStart, &m.Opponent, &m.Competition, &m.Format,
+		&m.PlayedAt, &m.VODURL, &m.Notes, &m.CreatedBy)
+	m.PlayedAt = m.PlayedAt.UTC()
+	if eventStart.Valid {
+		m.EventStart = eventStart.Time.UTC()
+	}
+	return m, err
+}
+
+// loadMatchMaps fills in the maps of matches.
+func loadMatchMaps(ctx context.Context, q queryer, matches []Match) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(matches))
+	ids := make([]int64, len(matches))
+	for i, m := range matches {
+		index[m.ID] = i
+		ids[i] = int64(m.ID)
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT match_id, number, map, mode, team_score, opponent_score, vod_url, notes FROM match_maps
+		WHERE match_id = ANY($1)
+		ORDER BY match_id, number`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var matchID int
+		var r MapResult
+		if err := rows.Scan(&matchID, &r.Number, &r.Map, &r.Mode, &r.TeamScore, &r.OpponentScore, &r.VODURL, &r.Notes); err != nil {
+			return err
+		}
+		m := &matches[index[matchID]]
+		m.Maps = append(m.Maps, r)
+	}
+	return rows.Err()
+}
+
+// saveMatchMaps replaces the maps of a match.
+func saveMatchMaps(ctx context.Context, tx *sql.Tx, matchID int, maps []MapResult) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM match_maps WHERE match_id = $1", matchID); err != nil {
+		return err
+	}
+	for _, r := range maps {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO match_maps (match_id, number, map, mode, team_score, opponent_score, vod_url, notes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			matchID, r.Number, r.Map, r.Mode, r.TeamScore, r.OpponentScore, r.VODURL, r.Notes)
+		if err != nil {
+			return mapError(err)
+		}
+	}
+	return nil
+}
+
+func (p *Postgres) ListMatches(ctx context.Context, teamID int, from, to time.Time) ([]Match, error) {
+	if _, err := p.GetTeam(ctx, teamID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, matchColumns+`
+		WHERE team_id = $1
+			AND ($2::timestamptz IS NULL OR played_at >= $2)
+			AND ($3::timestamptz IS NULL OR played_at < $3)
+		ORDER BY played_at DESC, id DESC`, teamID, nullableTime(from), nullableTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return matches, loadMatchMaps(ctx, p.db, matches)
+}
+
+func (p *Postgres) getMatch(ctx context.Context, where string, args ...any) (Match, error) {
+	m, err := scanMatch(p.db.QueryRowContext(ctx, matchColumns+" WHERE "+where, args...))
+	if err != nil {
+		return Match{}, mapError(err)
+	}
+	matches := []Match{m}
+	if err := loadMatchMaps(ctx, p.db, matches); err != nil {
+		return Match{}, err
+	}
+	return matches[0], nil
+}
+
+func (p *Postgres) GetMatch(ctx context.Context, id int) (Match, error) {
+	return p.getMatch(ctx, "id = $1", id)
+}
+
+func (p *Postgres) GetEventMatch(ctx context.Context, eventID int, eventStart time.Time) (Match, error) {
+	return p.getMatch(ctx, "event_id = $1 AND event_starts_at IS NOT DISTINCT FROM $2", eventID, nullableTime(eventStart))
+}
+
+func (p *Postgres) CreateMatch(ctx context.Context, m Match) (Match, error) {
+	err := p.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO matches (team_id, event_id, event_starts_at, opponent, competition, format, played_at, vod_url, notes, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+			m.TeamID, nullableID(m.EventID), nullableTime(m.EventStart), m.Opponent, m.Competition, m.Format,
+			m.PlayedAt, m.VODURL, m.Notes, nullableID(m.CreatedBy)).Scan(&m.ID)
+		if err != nil {
+			return mapError(err)
+		}
+		return saveMatchMaps(ctx, tx, m.ID, m.Maps)
+	})
+	m.PlayedAt, m.EventStart = m.PlayedAt.UTC(), m.EventStart.UTC()
+	return m, err
+}
+
+func (p *Postgres) UpdateMatch(ctx context.Context, m Match) error {
+	return p.inTx(ctx, func(tx *sql.Tx) error {
+		err := expectRows(tx.ExecContext(ctx, `
+			UPDATE matches SET opponent = $1, competition = $2, format = $3, played_at = $4, vod_url = $5, notes = $6
+			WHERE id = $7`,
+			m.Opponent, m.Competition, m.Format, m.PlayedAt, m.VODURL, m.Notes, m.ID))
+		if err != nil {
+			return err
+		}
+		return saveMatchMaps(ctx, tx, m.ID, m.Maps)
+	})
+}
+
+func (p *Postgres) DeleteMatch(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM matches WHERE id = $1", id))
+}
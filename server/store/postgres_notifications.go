@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (p *Postgres) GetDiscordSettings(ctx context.Context, teamID int) (DiscordSettings, error) {
+	var s DiscordSettings
+	err := p.db.QueryRowContext(ctx, `
+		SELECT team_id, webhook_url, reminder_minutes, quorum, quorum_minutes
+		FROM team_discord_settings WHERE team_id = $1`, teamID).
+		Scan(&s.TeamID, &s.WebhookURL, &s.ReminderMinutes, &s.Quorum, &s.QuorumMinutes)
+	return s, mapError(err)
+}
+
+func (p *Postgres) ListDiscordSettings(ctx context.Context) ([]DiscordSettings, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT team_id, webhook_url, reminder_minutes, quorum, quorum_minutes
+		FROM team_discord_settings ORDER BY team_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []DiscordSettings
+	for rows.Next() {
+		var s DiscordSettings
+		if err := rows.Scan(&s.TeamID, &s.WebhookURL, &s.ReminderMinutes, &s.Quorum, &s.QuorumMinutes); err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (p *Postgres) SetDiscordSettings(ctx context.Context, s DiscordSettings) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO team_discord_settings (team_id, webhook_url, reminder_minutes, quorum, quorum_minutes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (team_id) DO UPDATE SET webhook_url = EXCLUDED.webhook_url,
+			reminder_minutes = EXCLUDED.reminder_minutes, quorum = EXCLUDED.quorum,
+			quorum_minutes = EXCLUDED.quorum_minutes`,
+		s.TeamID, s.WebhookURL, s.ReminderMinutes, s.Quorum, s.QuorumMinutes)
+	return mapError(err)
+}
+
+func (p *Postgres) DeleteDiscordSettings(ctx context.Context, teamID int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM team_discord_settings WHERE team_id = $1", teamID))
+}
+
+func (p *Postgres) EnqueueOutbox(ctx context.Context, m OutboxMessage) (bool, error) {
+	var key sql.NullString
+	if m.Key != "" {
+		key = sql.NullString{String: m.Key, Valid: true}
+	}
+	res, err := p.db.ExecContext(ctx, `
+		INSERT INTO notification_outbox (team_id, dedupe_key, payload, next_attempt_at)
+		VALUES ($1, $2, $3, COALESCE($4, now()))
+		ON CONFLICT (dedupe_key) DO NOTHING`,
+		m.TeamID, key, m.Payload, nullableTime(m.NextAttempt))
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (p *Postgres) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		UPDATE notification_outbox o
+		SET attempts = o.attempts + 1, next_attempt_at = $2
+		FROM (
+			SELECT id FROM notification_outbox
+			WHERE done_at IS NULL AND next_attempt_at <= $1
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE o.id = due.id
+		RETURNING o.id, o.team_id, COALESCE(o.dedupe_key, ''), o.payload, o.attempts, o.next_attempt_at, o.last_error, o.created_at`,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claimed []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err := rows.Scan(&m.ID, &m.TeamID, &m.Key, &m.Payload, &m.Attempts, &m.NextAttempt, &m.LastError, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, m)
+	}
+	return claimed, rows.Err()
+}
+
+func (p *Postgres) MarkOutboxSent(ctx context.Context, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE notification_outbox SET done_at = now(), last_error = '' WHERE id = $1", id))
+}
+
+func (p *Postgres) MarkOutboxFailed(ctx context.Context, id int, next time.Time, lastError string) error {
+	return expectRows(p.db.ExecContext(ctx, `
+		UPDATE notification_outbox
+		SET last_error = $2,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			done_at = CASE WHEN $3::timestamptz IS NULL THEN now() END
+		WHERE id = $1`, id, lastError, nullableTime(next)))
+}
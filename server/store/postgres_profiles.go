@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const profileColumns = "SELECT user_id, region, preferred_roles, main_heroes, synced_at, sync_error FROM game_profiles"
+
+func scanProfile(row interface{ Scan(...any) error }) (GameProfile, error) {
+	var p GameProfile
+	var syncedAt sql.NullTime
+	err := row.Scan(&p.UserID, &p.Region, pq.Array(&p.PreferredRoles), pq.Array(&p.MainHeroes), &syncedAt, &p.SyncError)
+	p.SyncedAt = syncedAt.Time
+	return p, err
+}
+
+// loadRanks fills in the ranks of profiles.
+func loadRanks(ctx context.Context, q queryer, profiles []GameProfile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(profiles))
+	ids := make([]int64, len(profiles))
+	for i, p := range profiles {
+		index[p.UserID] = i
+		ids[i] = int64(p.UserID)
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT user_id, role, division, tier, sr, synced FROM game_profile_ranks
+		WHERE user_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		var r RoleRank
+		if err := rows.Scan(&userID, &r.Role, &r.Division, &r.Tier, &r.SR, &r.Synced); err != nil {
+			return err
+		}
+		p := &profiles[index[userID]]
+		p.Ranks = append(p.Ranks, r)
+	}
+	for i := range profiles {
+		sortRanks(profiles[i].Ranks)
+	}
+	return rows.Err()
+}
+
+func (p *Postgres) GetGameProfile(ctx context.Context, userID int) (GameProfile, error) {
+	if _, err := p.GetPlayer(ctx, userID); err != nil {
+		return GameProfile{}, err
+	}
+	profile, err := scanProfile(p.db.QueryRowContext(ctx, profileColumns+" WHERE user_id = $1", userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return GameProfile{UserID: userID}, nil
+	}
+	if err != nil {
+		return GameProfile{}, err
+	}
+	profiles := []GameProfile{profile}
+	if err := loadRanks(ctx, p.db, profiles); err != nil {
+		return GameProfile{}, err
+	}
+	return profiles[0], nil
+}
+
+func (p *Postgres) ListGameProfiles(ctx context.Context, userIDs []int) (map[int]GameProfile, error) {
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+	rows, err := p.db.QueryContext(ctx, profileColumns+" WHERE user_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []GameProfile
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadRanks(ctx, p.db, list); err != nil {
+		return nil, err
+	}
+	profiles := make(map[int]GameProfile, len(list))
+	for _, profile := range list {
+		profiles[profile.UserID] = profile
+	}
+	return profiles, nil
+}
+
+func (p *Postgres) SetGameProfile(ctx context.Context, profile GameProfile) error {
+	return p.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO game_profiles (user_id, region, preferred_roles, main_heroes) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET region = EXCLUDED.region,
+				preferred_roles = EXCLUDED.preferred_roles, main_heroes = EXCLUDED.main_heroes`,
+			profile.UserID, profile.Region, pq.Array(nonNil(profile.PreferredRoles)), pq.Array(nonNil(profile.MainHeroes)))
+		if err != nil {
+			return mapError(err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM game_profile_ranks WHERE user_id = $1 AND NOT synced", profile.UserID); err != nil {
+			return err
+		}
+		return saveRanks(ctx, tx, profile.UserID, profile.Ranks, false)
+	})
+}
+
+// saveRanks stores ranks, replacing any rank on the same role.
+func saveRanks(ctx context.Context, tx *sql.Tx, userID int, ranks []RoleRank, synced bool) error {
+	for _, r := range ranks {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO game_profile_ranks (user_id, role, division, tier, sr, synced) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (user_id, role) DO UPDATE SET division = EXCLUDED.division, tier = EXCLUDED.tier,
+				sr = EXCLUDED.sr, synced = EXCLUDED.synced`,
+			userID, r.Role, r.Division, r.Tier, r.SR, synced)
+		if err != nil {
+			return mapError(err)
+		}
+	}
+	return nil
+}
+
+// nonNil turns a nil slice into an empty one, since the array columns are
+// NOT NULL.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func (p *Postgres) ListStaleGameProfiles(ctx context.Context, before time.Time, limit int) ([]GameProfile, error) {
+	rows, err := p.db.QueryContext(ctx, profileColumns+`
+		WHERE region <> '' AND (synced_at IS NULL OR synced_at < $1)
+		ORDER BY synced_at NULLS FIRST, user_id LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []GameProfile
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return profiles, loadRanks(ctx, p.db, profiles)
+}
+
+func (p *Postgres) SetSyncedRanks(ctx context.Context, userID int, ranks []RoleRank, now time.Time) error {
+	return p.inTx(ctx, func(tx *sql.Tx) error {
+		err := expectRows(tx.ExecContext(ctx, "UPDATE game_profiles SET synced_at = $2, sync_error = '' WHERE user_id = $1", userID, now))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM game_profile_ranks WHERE user_id = $1 AND synced", userID); err != nil {
+			return err
+		}
+		return saveRanks(ctx, tx, userID, ranks, true)
+	})
+}
+
+func (p *Postgres) SetSyncError(ctx context.Context, userID int, message string, now time.Time) error {
+	return expectRows(p.db.ExecContext(ctx, "UPDATE game_profiles SET synced_at = $2, sync_error = $3 WHERE user_id = $1", userID, now, message))
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func getRosterLimits(ctx context.Context, q queryer, teamID int) (RosterLimits, error) {
+	l := RosterLimits{TeamID: teamID}
+	err := q.QueryRowContext(ctx, "SELECT tank, dps, support FROM team_roster_limits WHERE team_id = $1", teamID).
+		Scan(&l.Tank, &l.DPS, &l.Support)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultRosterLimits(teamID), nil
+	}
+	return l, err
+}
+
+// checkRoleLimit returns ErrRoleFull when giving userID gameRole would take
+// the team past its limit. It locks the team row so concurrent additions
+// count each other.
+func checkRoleLimit(ctx context.Context, tx *sql.Tx, teamID, userID int, gameRole string) error {
+	var id int
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM teams WHERE id = $1 FOR UPDATE", teamID).Scan(&id); err != nil {
+		return mapError(err)
+	}
+	if gameRole == "" {
+		return nil
+	}
+	limits, err := getRosterLimits(ctx, tx, teamID)
+	if err != nil {
+		return err
+	}
+	limit := limits.Limit(gameRole)
+	if limit == 0 {
+		return nil
+	}
+	var n int
+	err = tx.QueryRowContext(ctx, "SELECT count(*) FROM team_members WHERE team_id = $1 AND game_role = $2 AND user_id <> $3",
+		teamID, gameRole, userID).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n >= limit {
+		return ErrRoleFull
+	}
+	return nil
+}
+
+func (p *Postgres) GetRosterLimits(ctx context.Context, teamID int) (RosterLimits, error) {
+	if _, err := p.GetTeam(ctx, teamID); err != nil {
+		return RosterLimits{}, err
+	}
+	return getRosterLimits(ctx, p.db, teamID)
+}
+
+func (p *Postgres) SetRosterLimits(ctx context.Context, l RosterLimits) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO team_roster_limits (team_id, tank, dps, support) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (team_id) DO UPDATE SET tank = EXCLUDED.tank, dps = EXCLUDED.dps, support = EXCLUDED.support`,
+		l.TeamID, l.Tank, l.DPS, l.Support)
+	return mapError(err)
+}
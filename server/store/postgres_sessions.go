@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const sessionColumns = `
+	SELECT id, user_id, data, user_agent, created_at, last_seen_at, expires_at
+	FROM sessions`
+
+func scanSession(row interface{ Scan(...any) error }) (Session, error) {
+	var s Session
+	var userID sql.NullInt64
+	err := row.Scan(&s.ID, &userID, &s.Data, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+	s.UserID = int(userID.Int64)
+	return s, err
+}
+
+func (p *Postgres) CreateSession(ctx context.Context, s Session, hash string) (Session, error) {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO sessions (session_hash, user_id, data, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_seen_at`,
+		hash, nullableID(s.UserID), s.Data, s.UserAgent, s.ExpiresAt).
+		Scan(&s.ID, &s.CreatedAt, &s.LastSeenAt)
+	if err != nil {
+		return Session{}, mapError(err)
+	}
+	return s, nil
+}
+
+func (p *Postgres) TouchSession(ctx context.Context, hash string, now, idleSince time.Time) (Session, error) {
+	s, err := scanSession(p.db.QueryRowContext(ctx, `
+		UPDATE sessions SET last_seen_at = $2
+		WHERE session_hash = $1 AND expires_at > $2 AND last_seen_at > $3
+		RETURNING id, user_id, data, user_agent, created_at, last_seen_at, expires_at`, hash, now, idleSince))
+	return s, mapError(err)
+}
+
+func (p *Postgres) UpdateSession(ctx context.Context, hash string, userID int, data []byte) error {
+	return expectRows(p.db.ExecContext(ctx,
+		"UPDATE sessions SET user_id = $2, data = $3 WHERE session_hash = $1", hash, nullableID(userID), data))
+}
+
+func (p *Postgres) DeleteSession(ctx context.Context, hash string) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM sessions WHERE session_hash = $1", hash))
+}
+
+func (p *Postgres) ListSessions(ctx context.Context, userID int, now, idleSince time.Time) ([]Session, error) {
+	rows, err := p.db.QueryContext(ctx, sessionColumns+`
+		WHERE user_id = $1 AND expires_at > $2 AND last_seen_at > $3
+		ORDER BY last_seen_at DESC, id DESC`, userID, now, idleSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (p *Postgres) RevokeSession(ctx context.Context, userID, id int) error {
+	return expectRows(p.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1 AND user_id = $2", id, userID))
+}
+
+func (p *Postgres) RevokeSessions(ctx context.Context, userID int) (int, error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (p *Postgres) PruneSessions(ctx context.Context, now, idleSince time.Time) (int, error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= $1 OR last_seen_at <= $2", now, idleSince)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
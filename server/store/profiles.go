@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GameRoles lists the Overwatch roles, in display order.
+var GameRoles = []string{"tank", "dps", "support"}
+
+// RankDivisions lists the competitive divisions, lowest first.
+var RankDivisions = []string{"bronze", "silver", "gold", "platinum", "diamond", "master", "grandmaster", "champion"}
+
+// Regions lists the Battle.net regions a player can play in.
+var Regions = []string{"us", "eu", "kr", "tw"}
+
+// maxMainHeroes caps how many heroes a player can list as mains.
+const maxMainHeroes = 5
+
+// RoleRank is a player's competitive rank on one role.
+type RoleRank struct {
+	Role     string
+	Division string // one of RankDivisions, or empty when only SR is known
+	Tier     int    // 1 (highest) to 5 within the division, zero with no division
+	SR       int    // skill rating, zero when unknown
+	// Synced is set on ranks fetched by the stats client rather than entered
+	// by the player.
+	Synced bool
+}
+
+// Validate reports the first problem with r, if any.
+func (r RoleRank) Validate() error {
+	if !slices.Contains(GameRoles, r.Role) {
+		return fmt.Errorf("unknown role %q", r.Role)
+	}
+	if r.Division == "" && r.SR == 0 {
+		return fmt.Errorf("%s rank needs a division or an SR", r.Role)
+	}
+	if r.Division != "" {
+		if !slices.Contains(RankDivisions, r.Division) {
+			return fmt.Errorf("unknown division %q", r.Division)
+		}
+		if r.Tier < 1 || r.Tier > 5 {
+			return fmt.Errorf("%s tier must be between 1 and 5", r.Role)
+		}
+	} else if r.Tier != 0 {
+		return fmt.Errorf("%s tier needs a division", r.Role)
+	}
+	if r.SR < 0 || r.SR > 5000 {
+		return fmt.Errorf("%s SR must be between 0 and 5000", r.Role)
+	}
+	return nil
+}
+
+// GameProfile is a player's Overwatch metadata.
+type GameProfile struct {
+	UserID int
+	Region string // one of Regions, or empty
+	// PreferredRoles lists the roles the player wants to play, most
+	// preferred first.
+	PreferredRoles []string
+	Ranks          []RoleRank // at most one per role, in GameRoles order
+	MainHeroes     []string
+	// SyncedAt is when the stats client last tried to fetch the player's
+	// ranks, and SyncError why that attempt failed, if it did.
+	SyncedAt  time.Time
+	SyncError string
+}
+
+// Rank returns the player's rank on role.
+func (p GameProfile) Rank(role string) (RoleRank, bool) {
+	for _, r := range p.Ranks {
+		if r.Role == role {
+			return r, true
+		}
+	}
+	return RoleRank{}, false
+}
+
+// Validate reports the first problem with p, if any.
+func (p GameProfile) Validate() error {
+	if p.Region != "" && !slices.Contains(Regions, p.Region) {
+		return fmt.Errorf("unknown region %q", p.Region)
+	}
+	for i, role := range p.PreferredRoles {
+		if !slices.Contains(GameRoles, role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		if slices.Contains(p.PreferredRoles[:i], role) {
+			return fmt.Errorf("%s is listed more than once", role)
+		}
+	}
+	for i, r := range p.Ranks {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		for _, prev := range p.Ranks[:i] {
+			if prev.Role == r.Role {
+				return fmt.Errorf("%s rank is listed more than once", r.Role)
+			}
+		}
+	}
+	if len(p.MainHeroes) > maxMainHeroes {
+		return fmt.Errorf("at most %d main heroes can be listed", maxMainHeroes)
+	}
+	for _, h := range p.MainHeroes {
+		if h == "" || len(h) > 32 || strings.TrimSpace(h) != h {
+			return errors.New("hero names must be 1 to 32 characters without surrounding spaces")
+		}
+	}
+	return nil
+}
+
+// sortRanks puts ranks in GameRoles order.
+func sortRanks(ranks []RoleRank) {
+	slices.SortFunc(ranks, func(a, b RoleRank) int {
+		return slices.Index(GameRoles, a.Role) - slices.Index(GameRoles, b.Role)
+	})
+}
+
+// ProfileStore persists players' game profiles. Ranks the player entered and
+// ranks the stats client synced share one slot per role: whichever was saved
+// last wins.
+type ProfileStore interface {
+	// GetGameProfile returns the player's profile, which is empty when they
+	// have not filled it in, or ErrNotFound when the player doesn't exist.
+	GetGameProfile(ctx context.Context, userID int) (GameProfile, error)
+	// ListGameProfiles returns the profiles of the given players, keyed by
+	// user ID. Players who have not filled theirs in are left out.
+	ListGameProfiles(ctx context.Context, userIDs []int) (map[int]GameProfile, error)
+	// SetGameProfile saves what the player entered: region, preferred roles,
+	// main heroes and ranks. The ranks replace every rank the player entered
+	// before, and a synced rank on the same role; other synced ranks are kept.
+	SetGameProfile(ctx context.Context, p GameProfile) error
+	// ListStaleGameProfiles returns up to limit profiles with a region that
+	// were last synced before the cutoff, or never, least recently synced
+	// first.
+	ListStaleGameProfiles(ctx context.Context, before time.Time, limit int) ([]GameProfile, error)
+	// SetSyncedRanks replaces the player's synced ranks, and any rank they
+	// entered on the same roles, and records a successful sync at now.
+	SetSyncedRanks(ctx context.Context, userID int, ranks []RoleRank, now time.Time) error
+	// SetSyncError records a failed sync at now, keeping the ranks, so the
+	// player isn't tried again until their profile is stale once more.
+	SetSyncError(ctx context.Context, userID int, message string, now time.Time) error
+}
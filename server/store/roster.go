@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrRoleFull is returned when giving a member an in-game role would take
+// the team past its roster limit for that role.
+var ErrRoleFull = errors.New("role is full")
+
+// LineupSize is how many players of each in-game role a match lineup takes.
+var LineupSize = map[string]int{"tank": 1, "dps": 2, "support": 2}
+
+// maxRoleLimit bounds the roster limits a team can set.
+const maxRoleLimit = 20
+
+// RosterLimits caps how many members of a team can hold each in-game role.
+// Zero leaves a role uncapped.
+type RosterLimits struct {
+	TeamID  int
+	Tank    int
+	DPS     int
+	Support int
+}
+
+// DefaultRosterLimits are the limits of a team that has not set its own:
+// a full lineup with a substitute for every slot.
+func DefaultRosterLimits(teamID int) RosterLimits {
+	return RosterLimits{TeamID: teamID, Tank: 2, DPS: 4, Support: 4}
+}
+
+// Limit returns the cap on role, or zero when it is uncapped.
+func (l RosterLimits) Limit(role string) int {
+	switch role {
+	case "tank":
+		return l.Tank
+	case "dps":
+		return l.DPS
+	case "support":
+		return l.Support
+	}
+	return 0
+}
+
+// Validate reports the first problem with l, if any.
+func (l RosterLimits) Validate() error {
+	for _, role := range GameRoles {
+		n := l.Limit(role)
+		if n != 0 && (n < LineupSize[role] || n > maxRoleLimit) {
+			return fmt.Errorf("%s limit must be 0 (no limit) or between %d and %d", role, LineupSize[role], maxRoleLimit)
+		}
+	}
+	return nil
+}
+
+// RosterStore persists each team's roster limits.
+type RosterStore interface {
+	// GetRosterLimits returns the team's limits, or DefaultRosterLimits when
+	// it has not set any.
+	GetRosterLimits(ctx context.Context, teamID int) (RosterLimits, error)
+	// SetRosterLimits replaces the team's limits. Members already past a
+	// lowered limit keep their roles.
+	SetRosterLimits(ctx context.Context, l RosterLimits) error
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Session is a browser login session. Its values are kept server-side so it
+// can be listed and revoked; the cookie only carries a random session ID,
+// and only a hash of that is stored.
+type Session struct {
+	ID         int
+	UserID     int    // zero until the player logs in
+	Data       []byte // the encoded session values
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time // absolute timeout, however active the session is
+}
+
+// SessionStore persists login sessions. A session is active until its
+// ExpiresAt, unless it goes unused past the idle timeout: methods taking
+// idleSince treat sessions last seen by then as expired.
+type SessionStore interface {
+	// CreateSession stores a session under its hash.
+	CreateSession(ctx context.Context, s Session, hash string) (Session, error)
+	// TouchSession returns the active session with hash and records now as
+	// its last use. It returns ErrNotFound for an unknown or expired
+	// session.
+	TouchSession(ctx context.Context, hash string, now, idleSince time.Time) (Session, error)
+	// UpdateSession replaces the player and values of the session with hash.
+	UpdateSession(ctx context.Context, hash string, userID int, data []byte) error
+	// DeleteSession ends the session with hash, as logging out does.
+	DeleteSession(ctx context.Context, hash string) error
+	// ListSessions returns the player's active sessions, most recently used
+	// first.
+	ListSessions(ctx context.Context, userID int, now, idleSince time.Time) ([]Session, error)
+	// RevokeSession ends one of the player's sessions.
+	RevokeSession(ctx context.Context, userID, id int) error
+	// RevokeSessions ends every session of the player, and returns how many
+	// it ended.
+	RevokeSessions(ctx context.Context, userID int) (int, error)
+	// PruneSessions deletes expired sessions, and returns how many it
+	// deleted.
+	PruneSessions(ctx context.Context, now, idleSince time.Time) (int, error)
+}
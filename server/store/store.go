@@ -0,0 +1,185 @@
+// Package store defines the persistence interfaces used by the API handlers,
+// along with a Postgres implementation and an in-memory implementation for
+// tests and local development.
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested row, or a row it references,
+	// does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write would violate a uniqueness rule.
+	ErrConflict = errors.New("already exists")
+)
+
+// Weekdays lists the weekday names used by time slots, in display order.
+var Weekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// Team is a row in the teams table.
+type Team struct {
+	ID   int
+	Name string
+}
+
+// Player is a row in the users table. ID is the internal primary key and
+// BattleNetID is the account ID reported by Battle.net.
+type Player struct {
+	ID          int
+	BattleNetID int64
+	Username    string
+	IsAdmin     bool   // organization-wide administrator
+	Timezone    string // IANA name availability is shown in by default
+	// ActiveTeamID is the team the player last chose to work in, or zero.
+	// It may name a team they have since left.
+	ActiveTeamID int
+}
+
+// Member is a player's membership in a team. Role is their team role, such
+// as captain or sub, and GameRole the in-game role they fill on the roster:
+// one of GameRoles, or empty when unassigned.
+type Member struct {
+	TeamID   int
+	TeamName string
+	UserID   int
+	Username string
+	Role     string
+	GameRole string
+}
+
+// TimeSlot is a weekly slot a player can mark themselves available for.
+// TeamID is zero for the global default slots.
+type TimeSlot struct {
+	ID      int
+	TeamID  int
+	Weekday string
+	Time    string // HH:MM
+}
+
+// TeamStore persists teams.
+type TeamStore interface {
+	ListTeams(ctx context.Context) ([]Team, error)
+	GetTeam(ctx context.Context, id int) (Team, error)
+	CreateTeam(ctx context.Context, name string) (Team, error)
+	UpdateTeam(ctx context.Context, id int, name string) error
+	DeleteTeam(ctx context.Context, id int) error
+}
+
+// PlayerStore persists players.
+type PlayerStore interface {
+	ListPlayers(ctx context.Context) ([]Player, error)
+	GetPlayer(ctx context.Context, id int) (Player, error)
+	GetPlayerByBattleNetID(ctx context.Context, battleNetID int64) (Player, error)
+	// GetPlayerByUsername finds a player by battletag, ignoring case.
+	GetPlayerByUsername(ctx context.Context, username string) (Player, error)
+	// UpsertBattleNetPlayer returns the player with the given Battle.net ID,
+	// creating it if needed.
+	UpsertBattleNetPlayer(ctx context.Context, battleNetID int64, username string) (Player, error)
+	UpdatePlayer(ctx context.Context, id int, username string) error
+	SetPlayerAdmin(ctx context.Context, id int, admin bool) error
+	SetPlayerTimezone(ctx context.Context, id int, timezone string) error
+	// SetActiveTeam records the team the player works in, which must be one
+	// they belong to (ErrNotFound otherwise). Zero clears it.
+	SetActiveTeam(ctx context.Context, id, teamID int) error
+	DeletePlayer(ctx context.Context, id int) error
+}
+
+// MembershipStore persists team memberships.
+type MembershipStore interface {
+	ListMembers(ctx context.Context, teamID int) ([]Member, error)
+	ListMemberships(ctx context.Context, userID int) ([]Member, error)
+	GetMember(ctx context.Context, teamID, userID int) (Member, error)
+	// AddMember adds m to the team. It returns ErrRoleFull when m's game
+	// role is already at the team's roster limit.
+	AddMember(ctx context.Context, m Member) error
+	UpdateMemberRole(ctx context.Context, teamID, userID int, role string) error
+	// SetMemberGameRole assigns a member's in-game role, or clears it when
+	// gameRole is empty, returning ErrRoleFull like AddMember.
+	SetMemberGameRole(ctx context.Context, teamID, userID int, gameRole string) error
+	RemoveMember(ctx context.Context, teamID, userID int) error
+}
+
+// TimeSlotStore persists weekly time slots.
+type TimeSlotStore interface {
+	// ListTimeSlots returns the slots belonging to teamID, or the global
+	// default slots when teamID is zero.
+	ListTimeSlots(ctx context.Context, teamID int) ([]TimeSlot, error)
+	GetTimeSlot(ctx context.Context, id int) (TimeSlot, error)
+	CreateTimeSlot(ctx context.Context, slot TimeSlot) (TimeSlot, error)
+	UpdateTimeSlot(ctx context.Context, slot TimeSlot) error
+	DeleteTimeSlot(ctx context.Context, id int) error
+}
+
+// GridStore persists each team's weekly scheduling grid.
+type GridStore interface {
+	// GetGrid returns the team's grid, or DefaultGrid when it has not set one.
+	GetGrid(ctx context.Context, teamID int) (Grid, error)
+	// SetGrid replaces the team's grid and regenerates its time slots.
+	// Availability is kept as time intervals, so it carries over to any new
+	// slot it still covers.
+	SetGrid(ctx context.Context, g Grid) ([]TimeSlot, error)
+}
+
+// AvailabilityStore persists when players are available, as UTC intervals.
+type AvailabilityStore interface {
+	// ListAvailability returns the player's merged intervals for the team
+	// that overlap [from, to), in start order.
+	ListAvailability(ctx context.Context, teamID, userID int, from, to time.Time) ([]Interval, error)
+	// ListTeamAvailability returns the same for every player on the team,
+	// keyed by user ID.
+	ListTeamAvailability(ctx context.Context, teamID int, from, to time.Time) (map[int][]Interval, error)
+	// SetAvailability replaces the player's availability for the team within
+	// [from, to). Intervals are clipped to that window and time outside it is
+	// left alone.
+	SetAvailability(ctx context.Context, teamID, userID int, from, to time.Time, intervals []Interval) error
+	// PruneAvailability deletes every interval that ended by before, and
+	// returns how many it deleted.
+	PruneAvailability(ctx context.Context, before time.Time) (int, error)
+}
+
+// Store bundles every store the API depends on.
+type Store struct {
+	Teams         TeamStore
+	Players       PlayerStore
+	Memberships   MembershipStore
+	TimeSlots     TimeSlotStore
+	Grids         GridStore
+	Availability  AvailabilityStore
+	Events        EventStore
+	FeedTokens    FeedTokenStore
+	Notifications NotificationStore
+	DiscordLinks  DiscordLinkStore
+	Email         EmailStore
+	Jobs          JobStore
+	Invites       InviteStore
+	Profiles      ProfileStore
+	Rosters       RosterStore
+	Matches       MatchStore
+	APITokens     APITokenStore
+	Sessions      SessionStore
+}
+
+// WeekdayIndex returns the position of day in Weekdays, or -1.
+func WeekdayIndex(day string) int {
+	for i, d := range Weekdays {
+		if d == day {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortTimeSlots(slots []TimeSlot) {
+	sort.Slice(slots, func(i, j int) bool {
+		di, dj := WeekdayIndex(slots[i].Weekday), WeekdayIndex(slots[j].Weekday)
+		if di != dj {
+			return di < dj
+		}
+		return slots[i].Time < slots[j].Time
+	})
+}
@@ -1,155 +1,245 @@
 package user_account
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
 	"strconv"
 
-	"github.com/gorilla/sessions"
+	"github.com/KhrisKringle/Vivacity_website-main/server/middleware"
+	"github.com/KhrisKringle/Vivacity_website-main/server/store"
+
 	"github.com/markbates/goth"
 )
 
-type User struct {
-	Username string
-	Team     string
-}
-
-// and returns their internal database ID and their team ID.
-func HandleBlizzardAuth(db *sql.DB, user goth.User) (err error) {
-	// Check if the user already exists in the database by their Blizzard UserID
-	blizzardUserID, _ := strconv.ParseInt(user.UserID, 10, 64)
-	query := "SELECT id FROM users WHERE user_id = $1" // Assuming 'id' is your primary key
-	err = db.QueryRow(query, blizzardUserID).Scan(&user.UserID)
-
-	if err == sql.ErrNoRows {
-		// If the user does not exist, insert a new record and get their new primary key ID
-		insertQuery := "INSERT INTO users (username, user_id) VALUES ($1, $2) RETURNING id"
-		err = db.QueryRow(insertQuery, user.NickName, blizzardUserID).Scan(&user.UserID)
-		if err != nil {
-			return fmt.Errorf("failed to insert user: %v", err)
-		}
-		log.Printf("Created new user %s with internal ID: %s", user.NickName, user.UserID)
-	} else if err != nil {
-		return fmt.Errorf("failed to check user: %v", err)
+// HandleBlizzardAuth creates or updates the account for a Battle.net login
+// and returns it, including the internal database ID.
+func HandleBlizzardAuth(ctx context.Context, players store.PlayerStore, user goth.User) (store.Player, error) {
+	blizzardUserID, err := strconv.ParseInt(user.UserID, 10, 64)
+	if err != nil {
+		return store.Player{}, fmt.Errorf("invalid Battle.net user ID %q: %v", user.UserID, err)
 	}
-
-	return nil
-}
-
-func ProfileHandler(w http.ResponseWriter, r *http.Request, store *sessions.CookieStore, db *sql.DB) {
-	// Retrieve session
-	session, err := store.Get(r, "vivacity-session")
+	player, err := players.UpsertBattleNetPlayer(ctx, blizzardUserID, user.NickName)
 	if err != nil {
-		log.Printf("Error retrieving session: %v", err)
-		http.Error(w, "Session error", http.StatusInternalServerError)
-		return
+		return store.Player{}, fmt.Errorf("failed to save user: %v", err)
 	}
-	log.Printf("Session data: %v", session.Values)
+	log.Printf("User %s has internal ID: %d", player.Username, player.ID)
+	return player, nil
+}
 
-	// Get userID
-	var userID int64
-	if val, ok := session.Values["UserID"]; ok && val != nil {
-		switch v := val.(type) {
-		case int64:
-			userID = v
-		case int:
-			userID = int64(v)
-		case string:
-			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-				userID = id
-			} else {
-				log.Printf("Invalid UserID in session on line 63: %v", v)
-				http.Redirect(w, r, "/", http.StatusFound)
-				return
-			}
-		default:
-			log.Printf("Unsupported UserID type in session on line 68: %T", val)
-			http.Redirect(w, r, "/", http.StatusFound)
-			return
-		}
-	}
+var profileTemplate = template.Must(template.New("profile").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<title>Profile Page</title>
+	<script src="https://cdn.tailwindcss.com"></script>
+</head>
+<body class="bg-gray-900 text-white flex items-center justify-center min-h-screen py-8">
+	<div class="text-center">
+		<h1 class="text-4xl font-bold">Welcome, {{.Battletag}}</h1>
+		{{if .Memberships}}
+		<p class="text-xl mt-2">Your teams:</p>
+		<ul class="mt-2 space-y-2">
+			{{range .Memberships}}
+			<li class="flex items-center justify-center gap-3">
+				<a href="/team-profile?team_id={{.TeamID}}" class="text-cyan-400 hover:underline">{{.TeamName}}</a>
+				<span class="text-orange-500">{{.Role}}</span>
+				{{if eq .TeamID $.ActiveTeamID}}
+				<span class="text-sm text-green-400">(active)</span>
+				{{else}}
+				<button data-team-id="{{.TeamID}}" class="switch-team text-sm bg-gray-700 hover:bg-gray-600 py-1 px-2 rounded">Make active</button>
+				{{end}}
+			</li>
+			{{end}}
+		</ul>
+		{{else}}
+		<p class="text-xl mt-2">You are not on a team yet.</p>
+		{{end}}
+		<div id="discord" class="mt-6">
+			<p id="discord-status" class="text-lg"></p>
+			<button id="discord-link" class="hidden mt-2 bg-indigo-600 hover:bg-indigo-700 py-1 px-3 rounded">Get a link code</button>
+			<button id="discord-unlink" class="hidden mt-2 bg-gray-700 hover:bg-gray-600 py-1 px-3 rounded">Unlink Discord</button>
+			<p id="discord-code" class="hidden mt-2">Run <code class="text-cyan-400">/link <span id="discord-code-value"></span></code> in Discord before <span id="discord-code-expires"></span>.</p>
+		</div>
+		<div id="api-tokens" class="mt-6">
+			<p class="text-lg">API tokens</p>
+			<ul id="api-token-list" class="mt-2 space-y-2"></ul>
+			<form id="api-token-form" class="mt-2 space-y-2">
+				<input id="api-token-name" required maxlength="255" placeholder="Token name" class="bg-gray-800 py-1 px-2 rounded">
+				<div class="flex flex-wrap justify-center gap-3">
+					{{range .APIScopes}}
+					<label class="text-sm"><input type="checkbox" name="scope" value="{{.}}"> {{.}}</label>
+					{{end}}
+				</div>
+				<button class="bg-indigo-600 hover:bg-indigo-700 py-1 px-3 rounded">Create token</button>
+			</form>
+			<p id="api-token-new" class="hidden mt-2">Copy your new token now; it won't be shown again: <code id="api-token-value" class="text-cyan-400 break-all"></code></p>
+			<p id="api-token-error" class="hidden mt-2 text-red-400"></p>
+		</div>
+		<div id="sessions" class="mt-6">
+			<p class="text-lg">Login sessions</p>
+			<ul id="session-list" class="mt-2 space-y-2"></ul>
+			<button id="logout-everywhere" class="mt-2 bg-gray-700 hover:bg-gray-600 py-1 px-3 rounded">Log out everywhere</button>
+		</div>
+		<a href="/" class="mt-4 inline-block bg-red-500 hover:bg-red-700 text-white font-bold py-2 px-4 rounded">Home</a>
+	</div>
+	<script>
+		document.querySelectorAll('.switch-team').forEach(button => {
+			button.addEventListener('click', () => {
+				fetch('/api/players/{{.UserID}}/active-team', {
+					method: 'PUT',
+					headers: { 'Content-Type': 'application/json' },
+					body: JSON.stringify({ team_id: Number(button.dataset.teamId) })
+				}).then(response => {
+					if (response.ok) window.location.reload();
+				});
+			});
+		});
 
-	log.Printf("Fetching profile for UserID: %d", userID)
+		// Link the player's Discord account with a one-time code for the
+		// bot's /link command
+		const discordLink = '/api/players/{{.UserID}}/discord-link';
+		const discordStatus = document.getElementById('discord-status');
+		const linkButton = document.getElementById('discord-link');
+		const unlinkButton = document.getElementById('discord-unlink');
+		fetch(discordLink).then(response => {
+			if (response.ok) {
+				return response.json().then(link => {
+					discordStatus.textContent = 'Discord: linked to ' + link.discord_username;
+					unlinkButton.classList.remove('hidden');
+				});
+			}
+			discordStatus.textContent = 'Discord: not linked';
+			linkButton.classList.remove('hidden');
+		});
+		linkButton.addEventListener('click', () => {
+			fetch(discordLink, { method: 'POST' })
+				.then(response => response.ok ? response.json() : Promise.reject(response))
+				.then(code => {
+					document.getElementById('discord-code-value').textContent = code.code;
+					document.getElementById('discord-code-expires').textContent = new Date(code.expires_at).toLocaleTimeString();
+					document.getElementById('discord-code').classList.remove('hidden');
+				});
+		});
+		unlinkButton.addEventListener('click', () => {
+			fetch(discordLink, { method: 'DELETE' }).then(response => {
+				if (response.ok) window.location.reload();
+			});
+		});
 
-	// Fetch user data
-	var u User
-	err = db.QueryRow("SELECT username FROM users WHERE user_id = $1", userID).
-		Scan(&u.Username)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("User not found for ID: %d", userID)
-			http.Error(w, "User not found", http.StatusNotFound)
-		} else {
-			log.Printf("Database error: %v", err)
-			http.Error(w, "Database error", http.StatusInternalServerError)
+		// Personal API tokens for bots and scripts. A new token is only shown
+		// once, right after it is created.
+		const apiTokens = '/api/players/{{.UserID}}/api-tokens';
+		const tokenList = document.getElementById('api-token-list');
+		const tokenError = document.getElementById('api-token-error');
+		function showTokenError(response) {
+			response.json().then(body => {
+				tokenError.textContent = body.error.message;
+				tokenError.classList.remove('hidden');
+			});
 		}
-		return
-	}
-	log.Printf("User data: Username=%s", u.Username)
-
-	var teamName string
-	var battletag string
-	var TeamID int
-	if u.Team == "" {
-		TeamID = 0
-		log.Printf("User %s is not assigned to any team", u.Username)
-		log.Printf("You are not assigned to any team. Please contact an administrator.")
-	} else {
-		TeamID, err = strconv.Atoi(u.Team)
-		if err != nil {
-			log.Printf("Invalid team ID: %v", err)
-			http.Error(w, "Invalid team ID", http.StatusInternalServerError)
-			return
+		function loadTokens() {
+			fetch(apiTokens)
+				.then(response => response.ok ? response.json() : [])
+				.then(tokens => {
+					tokenList.replaceChildren(...tokens.map(token => {
+						const item = document.createElement('li');
+						item.className = 'flex items-center justify-center gap-3';
+						const label = document.createElement('span');
+						label.textContent = token.name + ' (' + token.scopes.join(', ') + '), expires ' + new Date(token.expires_at).toLocaleDateString();
+						const revoke = document.createElement('button');
+						revoke.className = 'text-sm bg-gray-700 hover:bg-gray-600 py-1 px-2 rounded';
+						revoke.textContent = 'Revoke';
+						revoke.addEventListener('click', () => {
+							fetch(apiTokens + '/' + token.token_id, { method: 'DELETE' }).then(response => {
+								if (response.ok) loadTokens();
+								else showTokenError(response);
+							});
+						});
+						item.append(label, revoke);
+						return item;
+					}));
+				});
 		}
-	}
+		loadTokens();
+		document.getElementById('api-token-form').addEventListener('submit', event => {
+			event.preventDefault();
+			tokenError.classList.add('hidden');
+			const scopes = [...document.querySelectorAll('input[name=scope]:checked')].map(box => box.value);
+			fetch(apiTokens, {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ name: document.getElementById('api-token-name').value, scopes: scopes })
+			}).then(response => {
+				if (!response.ok) return showTokenError(response);
+				return response.json().then(token => {
+					document.getElementById('api-token-value').textContent = token.token;
+					document.getElementById('api-token-new').classList.remove('hidden');
+					event.target.reset();
+					loadTokens();
+				});
+			});
+		});
 
-	if TeamID == 0 {
-		session.Values["TeamID"] = 0
-		session.Values["TeamName"] = "No Team Assigned"
-		battletag = session.Values["battletag"].(string)
-		err = session.Save(r, w)
-		if err != nil {
-			log.Printf("Error saving session: %v", err)
-			http.Error(w, "Session save error", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		err = db.QueryRow("SELECT name FROM teams WHERE id = $1", TeamID).Scan(&teamName)
-		if err != nil {
-			log.Printf("Database error fetching team name: %v", err)
-			http.Error(w, "Database error", http.StatusInternalServerError)
-			return
-		}
-		session.Values["TeamID"] = TeamID
-		session.Values["TeamName"] = teamName
-		battletag = session.Values["battletag"].(string)
-		err = session.Save(r, w)
-		if err != nil {
-			log.Printf("Error saving session: %v", err)
-			http.Error(w, "Session save error", http.StatusInternalServerError)
-			return
+		// Login sessions. Ending the one this page was loaded with, or all of
+		// them, logs this browser out too.
+		const sessions = '/api/players/{{.UserID}}/sessions';
+		const sessionList = document.getElementById('session-list');
+		function loadSessions() {
+			fetch(sessions)
+				.then(response => response.ok ? response.json() : [])
+				.then(list => {
+					sessionList.replaceChildren(...list.map(session => {
+						const item = document.createElement('li');
+						item.className = 'flex items-center justify-center gap-3';
+						const label = document.createElement('span');
+						label.textContent = (session.user_agent || 'Unknown browser') + ', last used ' + new Date(session.last_seen_at).toLocaleString();
+						const end = document.createElement('button');
+						end.className = 'text-sm bg-gray-700 hover:bg-gray-600 py-1 px-2 rounded';
+						end.textContent = 'Log out';
+						end.addEventListener('click', () => {
+							fetch(sessions + '/' + session.session_id, { method: 'DELETE' }).then(response => {
+								if (response.ok) loadSessions();
+							});
+						});
+						item.append(label, end);
+						return item;
+					}));
+				});
 		}
+		loadSessions();
+		document.getElementById('logout-everywhere').addEventListener('click', () => {
+			fetch(sessions, { method: 'DELETE' }).then(response => {
+				if (response.ok) window.location.href = '/';
+			});
+		});
+	</script>
+</body>
+</html>
+`))
+
+// profilePage is what the profile template renders.
+type profilePage struct {
+	*middleware.Principal
+	APIScopes []string
+}
+
+// ProfileHandler shows the logged in player's profile: every team they
+// belong to, their role on each and which one is active, their linked
+// Discord account or a button for a /link code, their personal API tokens
+// and their login sessions. It runs behind the session middleware.
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	caller, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprintf(w, `
-		<!DOCTYPE html>
-		<html lang="en">
-		<head>
-			<meta charset="UTF-8">
-			<title>Profile Page</title>
-			<script src="https://cdn.tailwindcss.com"></script>
-		</head>
-		<body class="bg-gray-900 text-white flex items-center justify-center h-screen">
-			<div class="text-center">
-				<h1 class="text-4xl font-bold">Welcome, %s</h1>
-				<p class="text-xl mt-2">Your Team is: %s</p>
-				<a href="/" class="mt-4 inline-block bg-red-500 hover:bg-red-700 text-white font-bold py-2 px-4 rounded">Home</a>
-			</div>
-		</body>
-		</html>
-	`, battletag, session.Values["TeamName"])
-
-	log.Printf("Successfully served profile page for user %s", userID)
+	if err := profileTemplate.Execute(w, profilePage{Principal: caller, APIScopes: store.APIScopes}); err != nil {
+		log.Printf("Error rendering profile for user %d: %v", caller.UserID, err)
+		return
+	}
+	log.Printf("Successfully served profile page for user %d", caller.UserID)
 }
@@ -28,8 +28,8 @@ document.addEventListener('DOMContentLoaded', () => {
             document.getElementById('teamName').textContent = 'Schedule Not Found';
         });
 
-    // Fetch schedule data
-    fetch(`/api/teams/${team_id}/schedule`)
+    // Fetch this week's slots, shown in the player's own timezone
+    fetch(`/api/teams/${team_id}/availability`)
         .then(response => {
             if (!response.ok) {
                 throw new Error('Could not load schedule');
@@ -53,22 +53,25 @@ document.addEventListener('DOMContentLoaded', () => {
                     // and the new formatted date/time on the right.
                     scheduleItem.innerHTML = `
                         <div>
-                            <p class="weekday">${item.weekday}</p>
+                            <p class="weekday">${item.day}</p>
                         </div>
                         <p class="time">${formattedDateTime}</p>
                     `;
 
+                    // Slots the player already marked start out selected
+                    if (item.available) {
+                        scheduleItem.classList.add('selected');
+                        selectedTimeslots.push({ slot_id: item.slot_id });
+                    }
+
                     // *** ADD THIS EVENT LISTENER ***
                     // This will toggle the 'selected' class on click.
                     scheduleItem.addEventListener('click', () => {
                         scheduleItem.classList.toggle('selected');
 
-                        const timeSlot = {
-                            weekday: item.weekday,
-                            time: item.time
-                        };
+                        const timeSlot = { slot_id: item.slot_id };
                         // Check if the timeslot is already selected
-                        const index = selectedTimeslots.findIndex(slot => slot.day === timeSlot.day && slot.time === timeSlot.time);
+                        const index = selectedTimeslots.findIndex(slot => slot.slot_id === timeSlot.slot_id);
 
                         if (index > -1) {
                             // If it is, remove it from the array
@@ -107,8 +110,6 @@ document.addEventListener('DOMContentLoaded', () => {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
-                // You might want to include a player ID here in a real application
-                player_id: 'current_player_id', 
                 selected_slots: selectedTimeslots
             })
         })
@@ -1,49 +1,21 @@
 // This script runs once the entire HTML document has been loaded and parsed.
 document.addEventListener('DOMContentLoaded', () => {
 
-    // --- Step 1: Get the Team ID from the URL ---
-    // The URL looks like "/team-profile?team_id=1". This code extracts the "1".
+    // --- Step 1: Work out which team to show ---
+    // The URL looks like "/team-profile?team_id=1". Without a team_id, the
+    // player's active team is shown instead.
     const params = new URLSearchParams(window.location.search);
-    const team_id = params.get('team_id');
-
-    // If no team_id is found in the URL, we can't load a profile.
-    // Display an error message and stop the script.
-    if (!team_id) {
-        document.getElementById('teamName').textContent = 'Error: No Team ID Provided';
-        document.getElementById('playersGrid').innerHTML = '<p class="text-red-400">Please go back to the teams page and select a team.</p>';
-        console.error("No team_id found in URL parameters.");
-        return; // Stop the function from proceeding further
-    }
-
-    // --- Step 2: Fetch the Team's Data from the API ---
-    // Use the team_id to call the specific API endpoint for that team.
-    fetch(`/api/teams/${team_id}`)
-        .then(async response => {
-            // If the server returns an error (like 404 Not Found), handle it.
-            if (!response.ok) {
-                const text = await response.text();
-                throw new Error(text || `Team with ID ${team_id} not found`);
-            }
-            // If the response is successful, parse the JSON data.
-            return response.json();
-        })
-        .then(data => {
-            // --- Step 3: Populate the Page with the Fetched Data ---
-            // Call our helper function to update the HTML with the team's info.
-            populateTeamData(data);
-        })
-        .catch(error => {
-            // If any part of the fetch process fails, display an informative error message.
-            console.error('Error fetching team data:', error);
-            const teamNameElement = document.getElementById('teamName');
-            if (teamNameElement) {
-                teamNameElement.textContent = 'Error: Team Could Not Be Loaded';
-            }
-            const playersGrid = document.getElementById('playersGrid');
-            if (playersGrid) {
-                playersGrid.innerHTML = `<p style="color: #ffdddd;">Details: ${error.message}</p>`;
-            }
-        });
+    resolveTeamId(params).then(team_id => {
+        // If there is no team to show, we can't load a profile.
+        // Display an error message and stop.
+        if (!team_id) {
+            document.getElementById('teamName').textContent = 'Error: No Team ID Provided';
+            document.getElementById('playersGrid').innerHTML = '<p class="text-red-400">Please go back to the teams page and select a team.</p>';
+            console.error("No team_id found in URL parameters and no active team.");
+            return;
+        }
+        loadTeam(team_id);
+    });
 
     // --- Step 4: Check Authentication Status ---
     // This part runs independently to update the navigation buttons.
@@ -94,30 +66,28 @@ function populateTeamData(data) {
         playersGrid.innerHTML = '';
 
         if (data.members && data.members.length > 0) {
-            // Define the desired order for roles.
+            // Define the desired order: in-game roles first, then staff.
             const roleOrder = {
-                'Tank': 1,
-                'DPS': 2,
-                'Support': 3,
-                'Coach': 4,
-                'Manager': 5
+                'tank': 1,
+                'dps': 2,
+                'support': 3,
+                'coach': 4,
+                'manager': 5
             };
+            const orderOf = member => roleOrder[member.game_role || member.role.toLowerCase()] || 99;
 
             // Sort the members array based on the role order.
-            data.members.sort((a, b) => {
-                const orderA = roleOrder[a.role] || 99;
-                const orderB = roleOrder[b.role] || 99;
-                return orderA - orderB;
-            });
+            data.members.sort((a, b) => orderOf(a) - orderOf(b));
 
             // Loop through the sorted members and create a card for each.
             data.members.forEach(member => {
                 const playerCard = document.createElement('div');
                 playerCard.className = 'player-card';
 
+                const roles = member.game_role ? `${member.game_role} · ${member.role}` : member.role;
                 playerCard.innerHTML = `
                     <span class="text-xl text-cyan-400">${member.username}</span>
-                    <span class="text-lg text-orange-500">${member.role}</span>
+                    <span class="text-lg text-orange-500">${roles}</span>
                 `;
                 playersGrid.appendChild(playerCard);
             });
@@ -126,4 +96,98 @@ function populateTeamData(data) {
             playersGrid.innerHTML = '<p class="text-cyan-400 col-span-full">This team has no players yet.</p>';
         }
     }
-}
\ No newline at end of file
+}
+
+/**
+ * Resolves the team to show: the team_id in the URL, or else the logged in
+ * player's active team.
+ * @param {URLSearchParams} params - The page's query parameters.
+ * @returns {Promise<string|null>} The team ID, or null when there is none.
+ */
+function resolveTeamId(params) {
+    const fromURL = params.get('team_id');
+    if (fromURL) {
+        return Promise.resolve(fromURL);
+    }
+    return fetch('/auth/status')
+        .then(response => response.ok ? response.json() : null)
+        .then(user => (user && user.active_team_id) ? String(user.active_team_id) : null)
+        .catch(() => null);
+}
+
+/**
+ * Fetches a team and populates the page with it.
+ * @param {string} team_id - The team to load.
+ */
+function loadTeam(team_id) {
+    // --- Step 2: Fetch the Team's Data from the API ---
+    // Use the team_id to call the specific API endpoint for that team.
+    fetch(`/api/teams/${team_id}`)
+        .then(async response => {
+            // If the server returns an error (like 404 Not Found), handle it.
+            if (!response.ok) {
+                const text = await response.text();
+                throw new Error(text || `Team with ID ${team_id} not found`);
+            }
+            // If the response is successful, parse the JSON data.
+            return response.json();
+        })
+        .then(data => {
+            // --- Step 3: Populate the Page with the Fetched Data ---
+            // Call our helper function to update the HTML with the team's info.
+            populateTeamData(data);
+            loadRoster(team_id);
+        })
+        .catch(error => {
+            // If any part of the fetch process fails, display an informative error message.
+            console.error('Error fetching team data:', error);
+            const teamNameElement = document.getElementById('teamName');
+            if (teamNameElement) {
+                teamNameElement.textContent = 'Error: Team Could Not Be Loaded';
+            }
+            const playersGrid = document.getElementById('playersGrid');
+            if (playersGrid) {
+                playersGrid.innerHTML = `<p style="color: #ffdddd;">Details: ${error.message}</p>`;
+            }
+        });
+}
+
+/**
+ * Fetches the team's roster and shows whether it can field a full lineup,
+ * how each in-game role is filled, and any composition warnings.
+ * @param {string} team_id - The team to load.
+ */
+function loadRoster(team_id) {
+    fetch(`/api/teams/${team_id}/roster`)
+        .then(response => response.ok ? response.json() : null)
+        .then(roster => {
+            if (!roster) {
+                return;
+            }
+            const status = document.getElementById('rosterStatus');
+            status.textContent = roster.can_field_lineup
+                ? 'Can field a full lineup'
+                : "Can't field a full lineup yet";
+            status.className = `text-lg mb-2 ${roster.can_field_lineup ? 'text-green-400' : 'text-red-400'}`;
+
+            // One count per role, e.g. "dps 2/2 + 1 sub"
+            const roles = document.getElementById('rosterRoles');
+            roles.innerHTML = '';
+            roster.roles.forEach(role => {
+                const span = document.createElement('span');
+                span.className = 'text-cyan-400';
+                const subs = role.subs.length === 1 ? '1 sub' : `${role.subs.length} subs`;
+                span.textContent = `${role.role} ${role.starters.length}/${role.needed} + ${subs}`;
+                roles.appendChild(span);
+            });
+
+            const warnings = document.getElementById('rosterWarnings');
+            warnings.innerHTML = '';
+            roster.warnings.forEach(warning => {
+                const li = document.createElement('li');
+                li.textContent = warning.message;
+                warnings.appendChild(li);
+            });
+        })
+        .catch(error => console.error('Error fetching roster:', error));
+}
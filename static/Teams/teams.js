@@ -46,7 +46,7 @@ document.addEventListener('DOMContentLoaded', () => {
                     // *** Correction is here ***
                     // The link now correctly points to the team profile page,
                     // passing the team's ID in the URL.
-                    teamCard.href = `/team-profile?team_id=${team.ID}`;
+                    teamCard.href = `/team-profile?team_id=${team.id}`;
                     teamCard.className = 'team-card'; // Use this class for styling in your CSS.
 
                     teamCard.innerHTML = `